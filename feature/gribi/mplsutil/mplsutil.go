@@ -11,6 +11,7 @@ import (
 	"github.com/open-traffic-generator/snappi/gosnappi"
 	"github.com/openconfig/featureprofiles/internal/attrs"
 	"github.com/openconfig/featureprofiles/internal/fptest"
+	"github.com/openconfig/featureprofiles/internal/gribi"
 	"github.com/openconfig/gribigo/chk"
 	"github.com/openconfig/gribigo/client"
 	"github.com/openconfig/gribigo/compliance"
@@ -149,108 +150,49 @@ func (g *GRIBIMPLSTest) ConfigureDevices(t *testing.T, dut *ondatra.DUTDevice, a
 
 // ProgramGRIBI performs the programming operations specified by the mode of the test.
 func (g *GRIBIMPLSTest) ProgramGRIBI(t *testing.T) {
+	ni := g.defaultNIName
+	nhg, _ := gribi.NHGEntry(1, map[uint64]uint64{1: 1}, ni, fluent.InstalledInRIB)
 	switch g.mode {
 	case PushToMPLS:
 		if len(g.args.LabelsToPush) == 0 {
 			t.Fatalf("invalid number of labels to push, got: %d, want: >0", len(g.args.LabelsToPush))
 		}
-
-		g.result = g.modify(t, []fluent.GRIBIEntry{
-			fluent.NextHopEntry().
-				WithNetworkInstance(g.defaultNIName).
-				WithIndex(1).
-				WithIPAddress(ATEDst.IPv4).
-				WithPushedLabelStack(g.args.LabelsToPush...),
-			fluent.NextHopGroupEntry().
-				WithNetworkInstance(g.defaultNIName).
-				WithID(1).
-				AddNextHop(1, 1),
-			fluent.LabelEntry().
-				WithLabel(100).
-				WithNetworkInstance(g.defaultNIName).
-				WithNextHopGroup(1),
-		})
+		nh, _ := gribi.MPLSNHEntry(1, ATEDst.IPv4, ni, gribi.Push(g.args.LabelsToPush...), fluent.InstalledInRIB)
+		label, _ := gribi.LabelEntry(100, 1, ni, "", fluent.InstalledInRIB)
+		g.result = g.modify(t, []fluent.GRIBIEntry{nh, nhg, label})
 	case PushToIP:
 		if len(g.args.LabelsToPush) == 0 {
 			t.Fatalf("invalid number of labels to push, got: %d, want: >0", len(g.args.LabelsToPush))
 		}
+		nh, _ := gribi.MPLSNHEntry(1, ATEDst.IPv4, ni, gribi.Push(g.args.LabelsToPush...), fluent.InstalledInRIB)
 		g.result = g.modify(t, []fluent.GRIBIEntry{
-			fluent.NextHopEntry().
-				WithNetworkInstance(g.defaultNIName).
-				WithIndex(1).
-				WithPushedLabelStack(g.args.LabelsToPush...).
-				WithIPAddress(ATEDst.IPv4),
-			fluent.NextHopGroupEntry().
-				WithNetworkInstance(g.defaultNIName).
-				WithID(1).
-				AddNextHop(1, 1),
+			nh,
+			nhg,
 			fluent.IPv4Entry().
 				WithPrefix(dutRoutedIPv4Prefix).
-				WithNetworkInstance(g.defaultNIName).
-				WithNextHopGroupNetworkInstance(g.defaultNIName).
+				WithNetworkInstance(ni).
+				WithNextHopGroupNetworkInstance(ni).
 				WithNextHopGroup(1),
 		})
 	case PopTopLabel:
-		g.result = g.modify(t, []fluent.GRIBIEntry{
-			fluent.NextHopEntry().
-				WithNetworkInstance(g.defaultNIName).
-				WithIndex(1).
-				WithIPAddress(ATEDst.IPv4).
-				WithPopTopLabel(),
-			fluent.NextHopGroupEntry().
-				WithNetworkInstance(g.defaultNIName).
-				WithID(1).
-				AddNextHop(1, 1),
-			fluent.LabelEntry().
-				WithLabel(staticMPLSToATE).
-				WithNetworkInstance(g.defaultNIName).
-				WithNextHopGroupNetworkInstance(g.defaultNIName).
-				WithNextHopGroup(1),
-		})
+		nh, _ := gribi.MPLSNHEntry(1, ATEDst.IPv4, ni, gribi.Pop(), fluent.InstalledInRIB)
+		label, _ := gribi.LabelEntry(staticMPLSToATE, 1, ni, ni, fluent.InstalledInRIB)
+		g.result = g.modify(t, []fluent.GRIBIEntry{nh, nhg, label})
 	case PopNLabels:
 		if len(g.args.LabelsToPop) == 0 {
 			t.Fatalf("invalid number of labels to pop, got: %d, want: >0", len(g.args.LabelsToPop))
 		}
-		g.result = g.modify(t, []fluent.GRIBIEntry{
-			fluent.NextHopEntry().
-				WithNetworkInstance(g.defaultNIName).
-				WithIndex(1).
-				WithIPAddress(ATEDst.IPv4),
-			fluent.NextHopGroupEntry().
-				WithNetworkInstance(g.defaultNIName).
-				WithID(1).
-				AddNextHop(1, 1),
-			fluent.LabelEntry().
-				WithLabel(100).
-				WithPoppedLabelStack(g.args.LabelsToPop...).
-				WithNetworkInstance(g.defaultNIName).
-				WithNextHopGroup(1),
-		})
+		nh, _ := gribi.NHEntry(1, ATEDst.IPv4, ni, fluent.InstalledInRIB)
+		label, _ := gribi.LabelEntry(100, 1, ni, "", fluent.InstalledInRIB, g.args.LabelsToPop...)
+		g.result = g.modify(t, []fluent.GRIBIEntry{nh, nhg, label})
 	case PopOnePushN:
 		if len(g.args.LabelsToPush) == 0 {
 			t.Fatalf("invalid number of labels to push, got: %d, want: >0", len(g.args.LabelsToPush))
 		}
-		g.result = g.modify(t, []fluent.GRIBIEntry{
-			fluent.NextHopEntry().
-				WithNetworkInstance(g.defaultNIName).
-				WithIndex(1).
-				WithIPAddress("192.0.2.2").
-				WithPopTopLabel().
-				WithPushedLabelStack(g.args.LabelsToPush...),
-			fluent.NextHopGroupEntry().
-				WithNetworkInstance(g.defaultNIName).
-				WithID(1).
-				AddNextHop(1, 1),
-			fluent.LabelEntry().
-				WithLabel(100).
-				WithNetworkInstance(g.defaultNIName).
-				WithNextHopGroup(1),
-
-			fluent.LabelEntry().
-				WithLabel(200).
-				WithNetworkInstance(g.defaultNIName).
-				WithNextHopGroup(1),
-		})
+		nh, _ := gribi.MPLSNHEntry(1, "192.0.2.2", ni, gribi.LabelAction{PopTop: true, Push: g.args.LabelsToPush}, fluent.InstalledInRIB)
+		label100, _ := gribi.LabelEntry(100, 1, ni, "", fluent.InstalledInRIB)
+		label200, _ := gribi.LabelEntry(200, 1, ni, "", fluent.InstalledInRIB)
+		g.result = g.modify(t, []fluent.GRIBIEntry{nh, nhg, label100, label200})
 	default:
 		t.Fatalf("invalid test mode specified")
 	}
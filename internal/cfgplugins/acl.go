@@ -2,10 +2,13 @@ package cfgplugins
 
 import (
 	"fmt"
+	"slices"
+	"strings"
 	"testing"
 
 	"github.com/openconfig/featureprofiles/internal/deviations"
 	"github.com/openconfig/featureprofiles/internal/helpers"
+	"github.com/openconfig/featureprofiles/internal/vendorcli"
 	"github.com/openconfig/ondatra"
 	"github.com/openconfig/ondatra/gnmi"
 	"github.com/openconfig/ondatra/gnmi/oc"
@@ -29,26 +32,48 @@ type AclParams struct {
 	DefaultPermit bool
 	ACLType       oc.E_Acl_ACL_TYPE
 	Intf          string
-	Ingress       bool
-	Terms         []AclTerm
-	Update        bool
+	// Interfaces are further interfaces that the ACL is bound to in addition to Intf.
+	Interfaces []string
+	Ingress    bool
+	Terms      []AclTerm
+	Update     bool
 }
 
 type AclTerm struct {
-	SeqID             uint32
-	Description       string
-	Permit            bool
-	IPSrc             string
-	IPDst             string
-	L4SrcPort         uint32
-	L4SrcPortRange    string
-	L4DstPort         uint32
+	SeqID       uint32
+	Description string
+	Permit      bool
+	IPSrc       string
+	IPDst       string
+	L4SrcPort   uint32
+	// L4SrcPortRange is a range of source ports as "low..high", or else the name of a port set.
+	L4SrcPortRange string
+	L4DstPort      uint32
+	// L4DstPortRange is a range of destination ports as "low..high", or else the name of a port
+	// set.
 	L4DstPortRange    string
 	ICMPCode          int64
 	ICMPType          int64
 	IPInitialFragment bool
-	Protocol          uint8
-	Log               bool
+	// Protocol is the IPv4 protocol or the IPv6 next header.
+	Protocol uint8
+	Log      bool
+	// TCPFlags are matched all together, or any of them with TCPFlagsMatchAny.
+	TCPFlags         []oc.E_PacketMatchTypes_TCP_FLAGS
+	TCPFlagsMatchAny bool
+	DSCPSet          []uint8
+	// HopLimit is the IPv6 hop limit or the IPv4 TTL.
+	HopLimit uint8
+	// SrcMAC, DstMAC and Ethertype are matched by ACL_L2 ACLs.
+	SrcMAC    string
+	DstMAC    string
+	Ethertype uint16
+	// MPLSLabelStart to MPLSLabelEnd is the range of labels matched by ACL_MPLS ACLs, with
+	// MPLSLabelEnd 0 for a single label. MPLSTrafficClass and MPLSTTL are not matched if 0.
+	MPLSLabelStart   uint32
+	MPLSLabelEnd     uint32
+	MPLSTrafficClass uint8
+	MPLSTTL          uint8
 }
 
 var (
@@ -136,6 +161,12 @@ func createACLEntry(aclSet *oc.Acl_AclSet, term AclTerm, aclType oc.E_Acl_ACL_TY
 				icmp.Type = oc.E_Icmpv4Types_TYPE(term.ICMPType)
 			}
 		}
+		if len(term.DSCPSet) > 0 {
+			ipv4.SetDscpSet(term.DSCPSet)
+		}
+		if term.HopLimit != 0 {
+			ipv4.SetHopLimit(term.HopLimit)
+		}
 	case oc.Acl_ACL_TYPE_ACL_IPV6:
 		ipv6 := entry.GetOrCreateIpv6()
 		if term.IPSrc != "" {
@@ -152,6 +183,39 @@ func createACLEntry(aclSet *oc.Acl_AclSet, term AclTerm, aclType oc.E_Acl_ACL_TY
 				icmp.Type = oc.E_Icmpv6Types_TYPE(term.ICMPType)
 			}
 		}
+		if len(term.DSCPSet) > 0 {
+			ipv6.SetDscpSet(term.DSCPSet)
+		}
+		if term.HopLimit != 0 {
+			ipv6.SetHopLimit(term.HopLimit)
+		}
+	case oc.Acl_ACL_TYPE_ACL_L2:
+		l2 := entry.GetOrCreateL2()
+		if term.SrcMAC != "" {
+			l2.SetSourceMac(term.SrcMAC)
+		}
+		if term.DstMAC != "" {
+			l2.SetDestinationMac(term.DstMAC)
+		}
+		if term.Ethertype != 0 {
+			l2.SetEthertype(oc.UnionUint16(term.Ethertype))
+		}
+	case oc.Acl_ACL_TYPE_ACL_MPLS:
+		mpls := entry.GetOrCreateMpls()
+		if term.MPLSLabelStart != 0 {
+			end := term.MPLSLabelEnd
+			if end == 0 {
+				end = term.MPLSLabelStart
+			}
+			mpls.SetStartLabelValue(oc.UnionUint32(term.MPLSLabelStart))
+			mpls.SetEndLabelValue(oc.UnionUint32(end))
+		}
+		if term.MPLSTrafficClass != 0 {
+			mpls.SetTrafficClass(term.MPLSTrafficClass)
+		}
+		if term.MPLSTTL != 0 {
+			mpls.SetTtlValue(term.MPLSTTL)
+		}
 	}
 
 	if term.Protocol == TCPProtocolNum || term.Protocol == UDPProtocolNum {
@@ -159,16 +223,41 @@ func createACLEntry(aclSet *oc.Acl_AclSet, term AclTerm, aclType oc.E_Acl_ACL_TY
 		if term.L4SrcPort != 0 {
 			transport.SourcePort = oc.UnionUint16(term.L4SrcPort)
 		}
-		if term.L4SrcPortRange != "" {
+		if strings.Contains(term.L4SrcPortRange, "..") {
+			transport.SourcePort = oc.UnionString(term.L4SrcPortRange)
+		} else if term.L4SrcPortRange != "" {
 			transport.SourcePortSet = ygot.String(term.L4SrcPortRange)
 		}
 		if term.L4DstPort != 0 {
 			transport.DestinationPort = oc.UnionUint16(term.L4DstPort)
 		}
-		if term.L4DstPortRange != "" {
+		if strings.Contains(term.L4DstPortRange, "..") {
+			transport.DestinationPort = oc.UnionString(term.L4DstPortRange)
+		} else if term.L4DstPortRange != "" {
 			transport.DestinationPortSet = ygot.String(term.L4DstPortRange)
 		}
 	}
+	if len(term.TCPFlags) > 0 {
+		transport := entry.GetOrCreateTransport()
+		transport.SetDetailMode(oc.Transport_DetailMode_EXPLICIT)
+		transport.SetExplicitTcpFlags(term.TCPFlags)
+		if term.TCPFlagsMatchAny {
+			transport.SetExplicitDetailMatchMode(oc.Transport_ExplicitDetailMatchMode_ANY)
+		} else {
+			transport.SetExplicitDetailMatchMode(oc.Transport_ExplicitDetailMatchMode_ALL)
+		}
+	}
+}
+
+// aclInterfaces returns the interfaces that the ACL of params is bound to.
+func aclInterfaces(params AclParams) []string {
+	var intfs []string
+	for _, intf := range append([]string{params.Intf}, params.Interfaces...) {
+		if intf != "" && !slices.Contains(intfs, intf) {
+			intfs = append(intfs, intf)
+		}
+	}
+	return intfs
 }
 
 func ConfigureACL(t *testing.T, dut *ondatra.DUTDevice, batch *gnmi.SetBatch, params AclParams) {
@@ -206,57 +295,51 @@ func ConfigureACL(t *testing.T, dut *ondatra.DUTDevice, batch *gnmi.SetBatch, pa
 	t.Logf("Creating ACL %s", params.Name)
 	gnmi.BatchReplace(batch, gnmi.OC().Acl().AclSet(params.Name, params.ACLType).Config(), aclSet)
 
-	aclIface := acl.GetOrCreateInterface(params.Intf)
-	if params.Ingress {
-		aclIface.GetOrCreateIngressAclSet(params.Name, params.ACLType)
-	} else {
-		aclIface.GetOrCreateEgressAclSet(params.Name, params.ACLType)
-	}
-	aclIface.GetOrCreateInterfaceRef().Interface = ygot.String(params.Intf)
-	aclIface.GetOrCreateInterfaceRef().Subinterface = ygot.Uint32(0)
+	for _, intf := range aclInterfaces(params) {
+		aclIface := acl.GetOrCreateInterface(intf)
+		if params.Ingress {
+			aclIface.GetOrCreateIngressAclSet(params.Name, params.ACLType)
+		} else {
+			aclIface.GetOrCreateEgressAclSet(params.Name, params.ACLType)
+		}
+		aclIface.GetOrCreateInterfaceRef().Interface = ygot.String(intf)
+		aclIface.GetOrCreateInterfaceRef().Subinterface = ygot.Uint32(0)
 
-	t.Logf("Applying ACL %s to Interface %s", params.Name, params.Intf)
-	gnmi.BatchReplace(batch, gnmi.OC().Acl().Interface(params.Intf).Config(), aclIface)
+		t.Logf("Applying ACL %s to Interface %s", params.Name, intf)
+		gnmi.BatchReplace(batch, gnmi.OC().Acl().Interface(intf).Config(), aclIface)
+	}
 }
 
 func DeleteACL(t *testing.T, batch *gnmi.SetBatch, params AclParams) {
 	t.Helper()
 
-	if params.Name == "" || params.ACLType == oc.Acl_ACL_TYPE_UNSET || params.Intf == "" {
+	if params.Name == "" || params.ACLType == oc.Acl_ACL_TYPE_UNSET || len(aclInterfaces(params)) == 0 {
 		t.Fatal("unable to delete ACL, missing required parameters")
 		return
 	}
 
-	if params.Ingress {
-		t.Logf("Removing Ingress ACL from Interface %s", params.Intf)
-		gnmi.BatchDelete(batch, gnmi.OC().Acl().Interface(params.Intf).IngressAclSet(params.Name, params.ACLType).Config())
-	} else {
-		t.Logf("Removing Egress ACL from Interface %s", params.Intf)
-		gnmi.BatchDelete(batch, gnmi.OC().Acl().Interface(params.Intf).EgressAclSet(params.Name, params.ACLType).Config())
+	for _, intf := range aclInterfaces(params) {
+		if params.Ingress {
+			t.Logf("Removing Ingress ACL from Interface %s", intf)
+			gnmi.BatchDelete(batch, gnmi.OC().Acl().Interface(intf).IngressAclSet(params.Name, params.ACLType).Config())
+		} else {
+			t.Logf("Removing Egress ACL from Interface %s", intf)
+			gnmi.BatchDelete(batch, gnmi.OC().Acl().Interface(intf).EgressAclSet(params.Name, params.ACLType).Config())
+		}
 	}
 
 	t.Log("Deleting ACL")
 	gnmi.BatchDelete(batch, gnmi.OC().Acl().AclSet(params.Name, params.ACLType).Config())
 }
 func EnableACLCountersFromCLI(t *testing.T, dut *ondatra.DUTDevice, params AclParams) {
-	switch dut.Vendor() {
-	case ondatra.ARISTA:
-		var ipStr string
-		switch params.ACLType {
-		case oc.Acl_ACL_TYPE_ACL_IPV4:
-			ipStr = "ip"
-		case oc.Acl_ACL_TYPE_ACL_IPV6:
-			ipStr = "ipv6"
-		}
-
-		countersCommand := fmt.Sprintf(`%s access-list %s
-	counters per-entry
-	!`, ipStr, params.Name)
-		helpers.GnmiCLIConfig(t, dut, countersCommand)
-		return
-	default:
+	if !vendorcli.ACLCounters.Supported(dut) {
 		t.Logf("ACL counter enabling not implemented for vendor %s, skipping", dut.Vendor())
+		return
 	}
+	vendorcli.ACLCounters.Config(t, dut, vendorcli.ACLCountersParams{
+		IPv6: params.ACLType == oc.Acl_ACL_TYPE_ACL_IPV6,
+		Name: params.Name,
+	})
 }
 
 func ConfigureNDPRulesFromCLI(t *testing.T, dut *ondatra.DUTDevice, params AclParams) {
@@ -0,0 +1,776 @@
+// Copyright 2026 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cfgplugins
+
+import (
+	"fmt"
+	"maps"
+	"math"
+	"net"
+	"net/netip"
+	"slices"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/open-traffic-generator/snappi/gosnappi"
+	"github.com/openconfig/featureprofiles/internal/attrs"
+	"github.com/openconfig/ondatra"
+	"github.com/openconfig/ondatra/gnmi"
+	"github.com/openconfig/ondatra/gnmi/oc"
+	"github.com/openconfig/ygnmi/ygnmi"
+)
+
+const (
+	aclCounterTimeout = 30 * time.Second
+	aclFlowSrcPort    = 49152
+	aclFlowDstPort    = 49153
+	aclFlowHopLimit   = 64
+	aclFlowMPLSLabel  = 1000
+	// aclFlowEthertype is the IEEE local experimental EtherType of L2 flows of terms without
+	// EtherType.
+	aclFlowEthertype = 0x88B5
+	aclEthertypeIPv4 = 0x0800
+	aclEthertypeIPv6 = 0x86DD
+	// maxMPLSLabel is the largest 20-bit MPLS label.
+	maxMPLSLabel = 1<<20 - 1
+)
+
+// icmpv4Types are the ICMPv4 types of the ACL terms that ACL flows can send.
+var icmpv4Types = map[oc.E_Icmpv4Types_TYPE]uint32{
+	oc.Icmpv4Types_TYPE_ECHO_REPLY:           0,
+	oc.Icmpv4Types_TYPE_DST_UNREACHABLE:      3,
+	oc.Icmpv4Types_TYPE_REDIRECT:             5,
+	oc.Icmpv4Types_TYPE_ECHO:                 8,
+	oc.Icmpv4Types_TYPE_ROUTER_ADVERTISEMENT: 9,
+	oc.Icmpv4Types_TYPE_ROUTER_SOLICITATION:  10,
+	oc.Icmpv4Types_TYPE_TIME_EXCEEDED:        11,
+	oc.Icmpv4Types_TYPE_PARAM_PROBLEM:        12,
+	oc.Icmpv4Types_TYPE_TIMESTAMP:            13,
+	oc.Icmpv4Types_TYPE_TIMESTAMP_REPLY:      14,
+}
+
+// icmpv4Codes are the ICMPv4 codes of the ACL terms that ACL flows can send.
+var icmpv4Codes = map[oc.E_Icmpv4Types_CODE]uint32{
+	oc.Icmpv4Types_CODE_UNSET:                                        0,
+	oc.Icmpv4Types_CODE_ECHO_REPLY_NONE:                              0,
+	oc.Icmpv4Types_CODE_ECHO_NO_CODE:                                 0,
+	oc.Icmpv4Types_CODE_DST_UNREACHABLE_NET:                          0,
+	oc.Icmpv4Types_CODE_DST_UNREACHABLE_HOST:                         1,
+	oc.Icmpv4Types_CODE_DST_UNREACHABLE_PROTOCOL:                     2,
+	oc.Icmpv4Types_CODE_DST_UNREACHABLE_PORT:                         3,
+	oc.Icmpv4Types_CODE_DST_UNREACHABLE_CANNOT_FRAGMENT:              4,
+	oc.Icmpv4Types_CODE_DST_UNREACHABLE_SRC_ROUTE_FAILED:             5,
+	oc.Icmpv4Types_CODE_DST_UNREACHABLE_ADMIN_PROHIBITED:             13,
+	oc.Icmpv4Types_CODE_REDIRECT_NETWORK:                             0,
+	oc.Icmpv4Types_CODE_REDIRECT_HOST:                                1,
+	oc.Icmpv4Types_CODE_ROUTER_ADVERTISEMENT_NORMAL:                  0,
+	oc.Icmpv4Types_CODE_ROUTER_SELECTION_NO_CODE:                     0,
+	oc.Icmpv4Types_CODE_TIME_EXCEEDED_IN_TRANSIT:                     0,
+	oc.Icmpv4Types_CODE_TIME_EXCEEDED_FRAGMENT_REASSEMBLY_IN_TRANSIT: 1,
+	oc.Icmpv4Types_CODE_PARAM_PROBLEM_POINTER_INDICATES_ERR:          0,
+	oc.Icmpv4Types_CODE_PARAM_PROBLEM_MISSING_REQ_OPTION:             1,
+	oc.Icmpv4Types_CODE_PARAM_PROBLEM_BAD_LENGTH:                     2,
+	oc.Icmpv4Types_CODE_TIMESTAMP_NO_CODE:                            0,
+	oc.Icmpv4Types_CODE_TIMESTAMP_REPLY_NO_CODE:                      0,
+}
+
+// icmpv6Codes are the ICMPv6 codes of the ACL terms that ACL flows can send. The ICMPv6
+// header of OTG flows only sends code 0.
+var icmpv6Codes = map[oc.E_Icmpv6Types_CODE]uint32{
+	oc.Icmpv6Types_CODE_UNSET:                           0,
+	oc.Icmpv6Types_CODE_DST_UNREACHABLE_NO_ROUTE_TO_DST: 0,
+	oc.Icmpv6Types_CODE_PACKET_TOO_BIG_NO_CODE:          0,
+	oc.Icmpv6Types_CODE_TIME_EXCEEDED_HOP_LIMIT:         0,
+	oc.Icmpv6Types_CODE_PARAM_PROBLEM_ERR_HDR_FIELD:     0,
+	oc.Icmpv6Types_CODE_ECHO_REQUEST_NO_CODE:            0,
+	oc.Icmpv6Types_CODE_ECHO_REPLY_NO_CODE:              0,
+	oc.Icmpv6Types_CODE_ROUTER_SOLICITATION_NO_CODE:     0,
+	oc.Icmpv6Types_CODE_ROUTER_ADVERTISEMENT_NO_CODE:    0,
+	oc.Icmpv6Types_CODE_NEIGHBOR_SOLICITATION_NO_CODE:   0,
+	oc.Icmpv6Types_CODE_NEIGHBOR_ADVERTISEMENT_NO_CODE:  0,
+	oc.Icmpv6Types_CODE_REDIRECT_NO_CODE:                0,
+}
+
+// icmpv6Types are the ICMPv6 types of the ACL terms that ACL flows can send.
+var icmpv6Types = map[oc.E_Icmpv6Types_TYPE]uint32{
+	oc.Icmpv6Types_TYPE_DESTINATION_UNREACHABLE: 1,
+	oc.Icmpv6Types_TYPE_PACKET_TOO_BIG:          2,
+	oc.Icmpv6Types_TYPE_TIME_EXCEEDED:           3,
+	oc.Icmpv6Types_TYPE_PARAMETER_PROBLEM:       4,
+	oc.Icmpv6Types_TYPE_ECHO_REQUEST:            128,
+	oc.Icmpv6Types_TYPE_ECHO_REPLY:              129,
+	oc.Icmpv6Types_TYPE_ROUTER_SOLICITATION:     133,
+	oc.Icmpv6Types_TYPE_ROUTER_ADVERTISEMENT:    134,
+	oc.Icmpv6Types_TYPE_NEIGHBOR_SOLICITATION:   135,
+	oc.Icmpv6Types_TYPE_NEIGHBOR_ADVERTISEMENT:  136,
+	oc.Icmpv6Types_TYPE_REDIRECT:                137,
+}
+
+// aclPacket is the packet that an ACL flow sends, with ICMP types and codes as the enum values
+// of AclTerm.
+type aclPacket struct {
+	src, dst         netip.Addr
+	protocol         uint8
+	srcPort, dstPort uint32
+	tcpFlags         []oc.E_PacketMatchTypes_TCP_FLAGS
+	icmpType         int64
+	icmpCode         int64
+	dscp             uint8
+	hopLimit         uint8
+	srcMAC, dstMAC   string
+	ethertype        uint16
+	label            uint32
+	trafficClass     uint8
+	ttl              uint8
+}
+
+// icmpHeader returns the ICMP type and code of p as sent on the wire, or an error if ACL flows
+// cannot send them.
+func icmpHeader(p *aclPacket) (uint32, uint32, error) {
+	switch p.protocol {
+	case ICMPv4ProtocolNum:
+		icmpType, ok := icmpv4Types[oc.E_Icmpv4Types_TYPE(p.icmpType)]
+		if !ok {
+			return 0, 0, fmt.Errorf("ICMPv4 type %v is not supported", oc.E_Icmpv4Types_TYPE(p.icmpType))
+		}
+		code, ok := icmpv4Codes[oc.E_Icmpv4Types_CODE(p.icmpCode)]
+		if !ok {
+			return 0, 0, fmt.Errorf("ICMPv4 code %v is not supported", oc.E_Icmpv4Types_CODE(p.icmpCode))
+		}
+		return icmpType, code, nil
+	case ICMPv6ProtocolNum:
+		icmpType, ok := icmpv6Types[oc.E_Icmpv6Types_TYPE(p.icmpType)]
+		if !ok {
+			return 0, 0, fmt.Errorf("ICMPv6 type %v is not supported", oc.E_Icmpv6Types_TYPE(p.icmpType))
+		}
+		code, ok := icmpv6Codes[oc.E_Icmpv6Types_CODE(p.icmpCode)]
+		if !ok {
+			return 0, 0, fmt.Errorf("ICMPv6 code %v is not supported", oc.E_Icmpv6Types_CODE(p.icmpCode))
+		}
+		return icmpType, code, nil
+	}
+	return 0, 0, fmt.Errorf("protocol %d is not ICMP", p.protocol)
+}
+
+// portRange returns the ports of a range "low..high", or "low high".
+func portRange(r string) (uint32, uint32, error) {
+	low, high, ok := strings.Cut(r, "..")
+	if !ok {
+		low, high, ok = strings.Cut(r, " ")
+	}
+	if !ok {
+		return 0, 0, fmt.Errorf("port set %q is not a port range", r)
+	}
+	l, err := strconv.ParseUint(strings.TrimSpace(low), 10, 16)
+	if err != nil {
+		return 0, 0, fmt.Errorf("port range %q: %v", r, err)
+	}
+	h, err := strconv.ParseUint(strings.TrimSpace(high), 10, 16)
+	if err != nil {
+		return 0, 0, fmt.Errorf("port range %q: %v", r, err)
+	}
+	return uint32(l), uint32(h), nil
+}
+
+// matchesPort returns whether port is the port or in the port range of a term.
+func matchesPort(port, termPort uint32, termRange string) (bool, error) {
+	if termPort != 0 && port != termPort {
+		return false, nil
+	}
+	if termRange == "" {
+		return true, nil
+	}
+	low, high, err := portRange(termRange)
+	if err != nil {
+		return false, err
+	}
+	return port >= low && port <= high, nil
+}
+
+// matchesPrefix returns whether addr is in prefix, or prefix is empty.
+func matchesPrefix(addr netip.Addr, prefix string) (bool, error) {
+	if prefix == "" {
+		return true, nil
+	}
+	p, err := netip.ParsePrefix(prefix)
+	if err != nil {
+		return false, err
+	}
+	return p.Contains(addr), nil
+}
+
+// matches returns whether the term of an ACL of type aclType matches p.
+func (term AclTerm) matches(aclType oc.E_Acl_ACL_TYPE, p *aclPacket) (bool, error) {
+	switch aclType {
+	case oc.Acl_ACL_TYPE_ACL_L2:
+		return (term.SrcMAC == "" || strings.EqualFold(term.SrcMAC, p.srcMAC)) &&
+			(term.DstMAC == "" || strings.EqualFold(term.DstMAC, p.dstMAC)) &&
+			(term.Ethertype == 0 || term.Ethertype == p.ethertype), nil
+	case oc.Acl_ACL_TYPE_ACL_MPLS:
+		end := max(term.MPLSLabelEnd, term.MPLSLabelStart)
+		return (term.MPLSLabelStart == 0 || p.label >= term.MPLSLabelStart && p.label <= end) &&
+			(term.MPLSTrafficClass == 0 || term.MPLSTrafficClass == p.trafficClass) &&
+			(term.MPLSTTL == 0 || term.MPLSTTL == p.ttl), nil
+	}
+
+	for _, m := range []struct {
+		addr   netip.Addr
+		prefix string
+	}{{p.src, term.IPSrc}, {p.dst, term.IPDst}} {
+		if ok, err := matchesPrefix(m.addr, m.prefix); !ok || err != nil {
+			return false, err
+		}
+	}
+	if term.Protocol != 0 && term.Protocol != p.protocol {
+		return false, nil
+	}
+	if term.Protocol == ICMPv4ProtocolNum || term.Protocol == ICMPv6ProtocolNum {
+		if term.ICMPType != 0 && term.ICMPType != p.icmpType || term.ICMPCode != 0 && term.ICMPCode != p.icmpCode {
+			return false, nil
+		}
+	}
+	if term.Protocol == TCPProtocolNum || term.Protocol == UDPProtocolNum {
+		if ok, err := matchesPort(p.srcPort, term.L4SrcPort, term.L4SrcPortRange); !ok || err != nil {
+			return false, err
+		}
+		if ok, err := matchesPort(p.dstPort, term.L4DstPort, term.L4DstPortRange); !ok || err != nil {
+			return false, err
+		}
+	}
+	if len(term.TCPFlags) > 0 {
+		if p.protocol != TCPProtocolNum {
+			return false, nil
+		}
+		matched := 0
+		for _, f := range term.TCPFlags {
+			if slices.Contains(p.tcpFlags, f) {
+				matched++
+			}
+		}
+		if matched == 0 || !term.TCPFlagsMatchAny && matched < len(term.TCPFlags) {
+			return false, nil
+		}
+	}
+	if len(term.DSCPSet) > 0 && !slices.Contains(term.DSCPSet, p.dscp) {
+		return false, nil
+	}
+	return term.HopLimit == 0 || term.HopLimit == p.hopLimit, nil
+}
+
+// termPacket returns a packet from src to dst that the term matches.
+func termPacket(term AclTerm, aclType oc.E_Acl_ACL_TYPE, src, dst *attrs.Attributes) (*aclPacket, error) {
+	p := &aclPacket{
+		protocol:  UDPProtocolNum,
+		srcPort:   aclFlowSrcPort,
+		dstPort:   aclFlowDstPort,
+		hopLimit:  aclFlowHopLimit,
+		srcMAC:    src.MAC,
+		dstMAC:    term.DstMAC,
+		ethertype: aclEthertypeIPv4,
+		label:     aclFlowMPLSLabel,
+		ttl:       aclFlowHopLimit,
+	}
+	switch aclType {
+	case oc.Acl_ACL_TYPE_ACL_L2:
+		p.ethertype = aclFlowEthertype
+		if term.Ethertype != 0 {
+			p.ethertype = term.Ethertype
+		}
+		fallthrough
+	case oc.Acl_ACL_TYPE_ACL_MPLS:
+		// L2 and MPLS flows are sent from port to port, without address resolution.
+		if p.dstMAC == "" {
+			p.dstMAC = dst.MAC
+		}
+	}
+	srcIP, dstIP := src.IPv4, dst.IPv4
+	if aclType == oc.Acl_ACL_TYPE_ACL_IPV6 || p.ethertype == aclEthertypeIPv6 {
+		srcIP, dstIP = src.IPv6, dst.IPv6
+		p.ethertype = aclEthertypeIPv6
+	}
+	for _, a := range []struct {
+		addr   *netip.Addr
+		prefix string
+		dflt   string
+	}{{&p.src, term.IPSrc, srcIP}, {&p.dst, term.IPDst, dstIP}} {
+		addr := a.dflt
+		if pfx, err := netip.ParsePrefix(a.prefix); err == nil && pfx.Bits() > 0 {
+			addr = pfx.Addr().String()
+		}
+		var err error
+		if *a.addr, err = netip.ParseAddr(addr); err != nil {
+			return nil, fmt.Errorf("term %d: %v", term.SeqID, err)
+		}
+	}
+
+	if term.Protocol != 0 {
+		p.protocol = term.Protocol
+	} else if len(term.TCPFlags) > 0 {
+		p.protocol = TCPProtocolNum
+	}
+	p.tcpFlags = term.TCPFlags
+	p.icmpType, p.icmpCode = term.ICMPType, term.ICMPCode
+	if p.protocol == ICMPv4ProtocolNum && p.icmpType == 0 {
+		p.icmpType = int64(oc.Icmpv4Types_TYPE_ECHO)
+	}
+	if p.protocol == ICMPv6ProtocolNum && p.icmpType == 0 {
+		p.icmpType = int64(oc.Icmpv6Types_TYPE_ECHO_REQUEST)
+	}
+	if p.protocol == ICMPv4ProtocolNum || p.protocol == ICMPv6ProtocolNum {
+		if _, _, err := icmpHeader(p); err != nil {
+			return nil, fmt.Errorf("term %d: %v", term.SeqID, err)
+		}
+	}
+	for _, port := range []struct {
+		port      *uint32
+		termPort  uint32
+		termRange string
+	}{{&p.srcPort, term.L4SrcPort, term.L4SrcPortRange}, {&p.dstPort, term.L4DstPort, term.L4DstPortRange}} {
+		switch {
+		case port.termPort != 0:
+			*port.port = port.termPort
+		case port.termRange != "":
+			low, _, err := portRange(port.termRange)
+			if err != nil {
+				return nil, fmt.Errorf("term %d: %v", term.SeqID, err)
+			}
+			*port.port = low
+		}
+	}
+	if len(term.DSCPSet) > 0 {
+		p.dscp = term.DSCPSet[0]
+	}
+	if term.HopLimit != 0 {
+		p.hopLimit = term.HopLimit
+	}
+
+	if term.SrcMAC != "" {
+		p.srcMAC = term.SrcMAC
+	}
+	if term.MPLSLabelStart != 0 {
+		p.label = term.MPLSLabelStart
+	}
+	p.trafficClass = term.MPLSTrafficClass
+	if term.MPLSTTL != 0 {
+		p.ttl = term.MPLSTTL
+	}
+	return p, nil
+}
+
+// nextMAC returns the MAC address following mac.
+func nextMAC(mac string) (string, error) {
+	hw, err := net.ParseMAC(mac)
+	if err != nil {
+		return "", err
+	}
+	for i := len(hw) - 1; i >= 0; i-- {
+		hw[i]++
+		if hw[i] != 0 {
+			break
+		}
+	}
+	return hw.String(), nil
+}
+
+// outsidePrefix returns the address following prefix.
+func outsidePrefix(prefix string) (netip.Addr, error) {
+	p, err := netip.ParsePrefix(prefix)
+	if err != nil {
+		return netip.Addr{}, err
+	}
+	p = p.Masked()
+	last := p.Addr().AsSlice()
+	for i := p.Bits(); i < len(last)*8; i++ {
+		last[i/8] |= 0x80 >> (i % 8)
+	}
+	addr, _ := netip.AddrFromSlice(last)
+	if next := addr.Next(); next.IsValid() {
+		return next, nil
+	}
+	return p.Addr().Prev(), nil
+}
+
+// outsideRange returns the value following low..high, or preceding it if high is maxVal.
+func outsideRange(low, high, maxVal uint32) (uint32, error) {
+	switch {
+	case high < maxVal:
+		return high + 1, nil
+	case low > 0:
+		return low - 1, nil
+	}
+	return 0, fmt.Errorf("range %d..%d covers all values", low, high)
+}
+
+// nextPort returns a port outside the port or the port range of a term, following it where
+// possible.
+func nextPort(termPort uint32, termRange string) (uint32, error) {
+	low, high := termPort, termPort
+	if termRange != "" {
+		var err error
+		if low, high, err = portRange(termRange); err != nil {
+			return 0, err
+		}
+	}
+	return outsideRange(low, high, math.MaxUint16)
+}
+
+// otherUint8 returns a value other than v, for TTLs and hop limits.
+func otherUint8(v uint8) uint8 {
+	if v == math.MaxUint8 {
+		return v - 1
+	}
+	return v + 1
+}
+
+// nonMatchingPacket returns a copy of the packet p matching the term with a field changed so
+// that the term does not match it. The layer 4 and other header fields are changed before the
+// addresses, to keep the packet on the route of the matching packet where possible.
+func nonMatchingPacket(term AclTerm, aclType oc.E_Acl_ACL_TYPE, p *aclPacket) (*aclPacket, error) {
+	n := *p
+	var err error
+	switch aclType {
+	case oc.Acl_ACL_TYPE_ACL_L2:
+		switch {
+		case term.SrcMAC != "":
+			n.srcMAC, err = nextMAC(term.SrcMAC)
+		case term.DstMAC != "":
+			n.dstMAC, err = nextMAC(term.DstMAC)
+		case term.Ethertype != 0:
+			n.ethertype = term.Ethertype + 1
+			if term.Ethertype == math.MaxUint16 {
+				n.ethertype = term.Ethertype - 1
+			}
+		default:
+			err = fmt.Errorf("term %d matches all packets", term.SeqID)
+		}
+		return &n, err
+	case oc.Acl_ACL_TYPE_ACL_MPLS:
+		switch {
+		case term.MPLSLabelStart != 0:
+			n.label, err = outsideRange(term.MPLSLabelStart, max(term.MPLSLabelEnd, term.MPLSLabelStart), maxMPLSLabel)
+		case term.MPLSTrafficClass != 0:
+			n.trafficClass = (term.MPLSTrafficClass + 1) % 8
+		case term.MPLSTTL != 0:
+			n.ttl = otherUint8(term.MPLSTTL)
+		default:
+			err = fmt.Errorf("term %d matches all packets", term.SeqID)
+		}
+		return &n, err
+	}
+
+	l4 := term.Protocol == TCPProtocolNum || term.Protocol == UDPProtocolNum
+	switch {
+	case l4 && (term.L4SrcPort != 0 || term.L4SrcPortRange != ""):
+		n.srcPort, err = nextPort(term.L4SrcPort, term.L4SrcPortRange)
+	case l4 && (term.L4DstPort != 0 || term.L4DstPortRange != ""):
+		n.dstPort, err = nextPort(term.L4DstPort, term.L4DstPortRange)
+	case len(term.TCPFlags) > 0:
+		// Without flags the packet matches none of the flags of the term.
+		n.tcpFlags = nil
+	case term.Protocol == ICMPv4ProtocolNum && term.ICMPType != 0:
+		n.icmpType, n.icmpCode = int64(oc.Icmpv4Types_TYPE_ECHO), 0
+		if term.ICMPType == n.icmpType {
+			n.icmpType = int64(oc.Icmpv4Types_TYPE_ECHO_REPLY)
+		}
+	case term.Protocol == ICMPv6ProtocolNum && term.ICMPType != 0:
+		n.icmpType, n.icmpCode = int64(oc.Icmpv6Types_TYPE_ECHO_REQUEST), 0
+		if term.ICMPType == n.icmpType {
+			n.icmpType = int64(oc.Icmpv6Types_TYPE_ECHO_REPLY)
+		}
+	case len(term.DSCPSet) > 0:
+		for dscp := range uint8(64) {
+			if !slices.Contains(term.DSCPSet, dscp) {
+				n.dscp = dscp
+				break
+			}
+		}
+	case term.HopLimit != 0:
+		n.hopLimit = otherUint8(term.HopLimit)
+	case term.Protocol != 0:
+		n.protocol = TCPProtocolNum
+		if term.Protocol == TCPProtocolNum {
+			n.protocol = UDPProtocolNum
+		}
+		n.srcPort, n.dstPort = aclFlowSrcPort, aclFlowDstPort
+	case term.IPSrc != "" && term.IPSrc != matchAllV4 && term.IPSrc != matchAllV6:
+		n.src, err = outsidePrefix(term.IPSrc)
+	case term.IPDst != "" && term.IPDst != matchAllV4 && term.IPDst != matchAllV6:
+		n.dst, err = outsidePrefix(term.IPDst)
+	default:
+		err = fmt.Errorf("term %d matches all packets", term.SeqID)
+	}
+	return &n, err
+}
+
+// ACLTermFlow is an OTG flow of a term of an ACL, sending packets that the term matches or
+// not.
+type ACLTermFlow struct {
+	Name  string
+	SeqID uint32
+	Match bool
+	// EntryID is the sequence ID of the ACL entry expected to match the packets of the flow:
+	// SeqID for a matching flow unless an earlier entry matches, else the first entry that
+	// matches the packets.
+	EntryID uint32
+
+	packet *aclPacket
+}
+
+// AclFlowParams are the parameters of the OTG flows of the terms of an ACL.
+type AclFlowParams struct {
+	// Src and Dst are the ATE ports that the flows are sent from and to, whose devices are
+	// named as attrs.Attributes.AddToOTG names them.
+	Src, Dst *attrs.Attributes
+	// SrcPort and DstPort are the names of the OTG ports of Src and Dst, which the flows of L2
+	// and MPLS ACLs are sent from and to as raw frames.
+	SrcPort, DstPort string
+	Packets          uint32
+	PPS              uint64
+	FrameSize        uint32
+}
+
+// aclEntries returns the terms of the ACL of params in the order that they are evaluated,
+// with the NDP rules of IPv6 ACLs and the default rule.
+func aclEntries(params AclParams) []AclTerm {
+	terms := slices.Clone(params.Terms)
+	if params.ACLType == oc.Acl_ACL_TYPE_ACL_IPV6 {
+		terms = append(terms, ndpACLRules...)
+	}
+	slices.SortStableFunc(terms, func(a, b AclTerm) int { return int(a.SeqID) - int(b.SeqID) })
+	return terms
+}
+
+// matchingEntry returns the sequence ID of the first entry of the ACL of params matching p.
+func matchingEntry(params AclParams, p *aclPacket) (uint32, error) {
+	for _, term := range aclEntries(params) {
+		ok, err := term.matches(params.ACLType, p)
+		if err != nil {
+			return 0, fmt.Errorf("term %d: %v", term.SeqID, err)
+		}
+		if ok {
+			return term.SeqID, nil
+		}
+	}
+	return DefaultEntryID, nil
+}
+
+// aclTermFlows returns a matching and a non-matching flow of each term of the ACL of params.
+func aclTermFlows(params AclParams, src, dst *attrs.Attributes) ([]*ACLTermFlow, error) {
+	var flows []*ACLTermFlow
+	for _, term := range params.Terms {
+		if term.SeqID == DefaultEntryID {
+			continue
+		}
+		match, err := termPacket(term, params.ACLType, src, dst)
+		if err != nil {
+			return nil, err
+		}
+		nonMatch, err := nonMatchingPacket(term, params.ACLType, match)
+		if err != nil {
+			return nil, err
+		}
+		for _, f := range []*ACLTermFlow{
+			{Name: fmt.Sprintf("%s-%d-match", params.Name, term.SeqID), SeqID: term.SeqID, Match: true, packet: match},
+			{Name: fmt.Sprintf("%s-%d-no-match", params.Name, term.SeqID), SeqID: term.SeqID, packet: nonMatch},
+		} {
+			if ok, err := term.matches(params.ACLType, f.packet); err != nil || ok != f.Match {
+				return nil, fmt.Errorf("term %d: flow %s does not match as expected: %v", term.SeqID, f.Name, err)
+			}
+			if f.EntryID, err = matchingEntry(params, f.packet); err != nil {
+				return nil, err
+			}
+			flows = append(flows, f)
+		}
+	}
+	return flows, nil
+}
+
+// AddACLTermFlows adds to top a flow sending packets that each term of the ACL of params
+// matches, and a flow sending packets that differ in one field that the term matches.
+func AddACLTermFlows(t *testing.T, top gosnappi.Config, params AclParams, flowParams AclFlowParams) []*ACLTermFlow {
+	t.Helper()
+	flows, err := aclTermFlows(params, flowParams.Src, flowParams.Dst)
+	if err != nil {
+		t.Fatalf("Failed to generate flows of ACL %s: %v", params.Name, err)
+	}
+	for _, f := range flows {
+		if err := addACLFlow(top, params.ACLType, flowParams, f); err != nil {
+			t.Fatalf("Failed to add flow %s of ACL %s: %v", f.Name, params.Name, err)
+		}
+	}
+	return flows
+}
+
+func addACLFlow(top gosnappi.Config, aclType oc.E_Acl_ACL_TYPE, flowParams AclFlowParams, f *ACLTermFlow) error {
+	p := f.packet
+	raw := aclType == oc.Acl_ACL_TYPE_ACL_L2 || aclType == oc.Acl_ACL_TYPE_ACL_MPLS
+	if raw && (flowParams.SrcPort == "" || flowParams.DstPort == "") {
+		return fmt.Errorf("flows of %v ACLs need the source and destination ports", aclType)
+	}
+	flow := top.Flows().Add().SetName(f.Name)
+	flow.Metrics().SetEnable(true)
+	ipv6 := p.ethertype == aclEthertypeIPv6
+	switch {
+	case raw:
+		flow.TxRx().Port().SetTxName(flowParams.SrcPort).SetRxNames([]string{flowParams.DstPort})
+	case ipv6:
+		flow.TxRx().Device().SetTxNames([]string{flowParams.Src.Name + ".IPv6"}).SetRxNames([]string{flowParams.Dst.Name + ".IPv6"})
+	default:
+		flow.TxRx().Device().SetTxNames([]string{flowParams.Src.Name + ".IPv4"}).SetRxNames([]string{flowParams.Dst.Name + ".IPv4"})
+	}
+	flow.Duration().FixedPackets().SetPackets(flowParams.Packets)
+	if flowParams.PPS != 0 {
+		flow.Rate().SetPps(flowParams.PPS)
+	}
+	if flowParams.FrameSize != 0 {
+		flow.Size().SetFixed(flowParams.FrameSize)
+	}
+
+	eth := flow.Packet().Add().Ethernet()
+	eth.Src().SetValue(p.srcMAC)
+	if p.dstMAC != "" {
+		eth.Dst().SetValue(p.dstMAC)
+	}
+	switch aclType {
+	case oc.Acl_ACL_TYPE_ACL_L2:
+		eth.EtherType().SetValue(uint32(p.ethertype))
+		if p.ethertype != aclEthertypeIPv4 && p.ethertype != aclEthertypeIPv6 {
+			return nil
+		}
+	case oc.Acl_ACL_TYPE_ACL_MPLS:
+		mpls := flow.Packet().Add().Mpls()
+		mpls.Label().SetValue(p.label)
+		mpls.TrafficClass().SetValue(uint32(p.trafficClass))
+		mpls.TimeToLive().SetValue(uint32(p.ttl))
+		mpls.BottomOfStack().SetValue(1)
+		return nil
+	}
+	return addACLIPHeaders(flow, p, ipv6)
+}
+
+// addACLIPHeaders adds the IP and layer 4 headers of p to flow.
+func addACLIPHeaders(flow gosnappi.Flow, p *aclPacket, ipv6 bool) error {
+	if ipv6 {
+		ip := flow.Packet().Add().Ipv6()
+		ip.Src().SetValue(p.src.String())
+		ip.Dst().SetValue(p.dst.String())
+		ip.TrafficClass().SetValue(uint32(p.dscp) << 2)
+		ip.HopLimit().SetValue(uint32(p.hopLimit))
+		ip.NextHeader().SetValue(uint32(p.protocol))
+	} else {
+		ip := flow.Packet().Add().Ipv4()
+		ip.Src().SetValue(p.src.String())
+		ip.Dst().SetValue(p.dst.String())
+		ip.Priority().Dscp().Phb().SetValue(uint32(p.dscp))
+		ip.TimeToLive().SetValue(uint32(p.hopLimit))
+		ip.Protocol().SetValue(uint32(p.protocol))
+	}
+
+	switch p.protocol {
+	case TCPProtocolNum:
+		tcp := flow.Packet().Add().Tcp()
+		tcp.SrcPort().SetValue(p.srcPort)
+		tcp.DstPort().SetValue(p.dstPort)
+		for _, flag := range p.tcpFlags {
+			switch flag {
+			case oc.PacketMatchTypes_TCP_FLAGS_TCP_ACK:
+				tcp.CtlAck().SetValue(1)
+			case oc.PacketMatchTypes_TCP_FLAGS_TCP_CWR:
+				tcp.EcnCwr().SetValue(1)
+			case oc.PacketMatchTypes_TCP_FLAGS_TCP_ECE:
+				tcp.EcnEcho().SetValue(1)
+			case oc.PacketMatchTypes_TCP_FLAGS_TCP_FIN:
+				tcp.CtlFin().SetValue(1)
+			case oc.PacketMatchTypes_TCP_FLAGS_TCP_PSH:
+				tcp.CtlPsh().SetValue(1)
+			case oc.PacketMatchTypes_TCP_FLAGS_TCP_RST:
+				tcp.CtlRst().SetValue(1)
+			case oc.PacketMatchTypes_TCP_FLAGS_TCP_SYN:
+				tcp.CtlSyn().SetValue(1)
+			case oc.PacketMatchTypes_TCP_FLAGS_TCP_URG:
+				tcp.CtlUrg().SetValue(1)
+			}
+		}
+	case UDPProtocolNum:
+		udp := flow.Packet().Add().Udp()
+		udp.SrcPort().SetValue(p.srcPort)
+		udp.DstPort().SetValue(p.dstPort)
+	case ICMPv4ProtocolNum:
+		icmpType, code, err := icmpHeader(p)
+		if err != nil {
+			return err
+		}
+		echo := flow.Packet().Add().Icmp().Echo()
+		echo.Type().SetValue(icmpType)
+		echo.Code().SetValue(code)
+	case ICMPv6ProtocolNum:
+		icmpType, code, err := icmpHeader(p)
+		if err != nil {
+			return err
+		}
+		echo := flow.Packet().Add().Icmpv6().Echo()
+		echo.Type().SetValue(icmpType)
+		echo.Code().SetValue(code)
+	}
+	return nil
+}
+
+// ACLEntryCounters returns the matched packets of the entries of the ACL of params by sequence
+// ID, to take before the traffic and pass to VerifyACLTermCounters.
+func ACLEntryCounters(t *testing.T, dut *ondatra.DUTDevice, params AclParams) map[uint32]uint64 {
+	t.Helper()
+	counters := map[uint32]uint64{}
+	aclSet := gnmi.Get(t, dut, gnmi.OC().Acl().AclSet(params.Name, params.ACLType).State())
+	for seqID, entry := range aclSet.AclEntry {
+		counters[seqID] = entry.GetMatchedPackets()
+	}
+	return counters
+}
+
+// matchedSince returns the packets matched since a counter was before. A counter lower than
+// before was reset, e.g. by applying the ACL again, so all its packets are counted.
+func matchedSince(matched, before uint64) uint64 {
+	if matched < before {
+		return matched
+	}
+	return matched - before
+}
+
+// VerifyACLTermCounters checks after the ATE sent flows that the matched packets of each entry
+// of the ACL of params grew since before at least by the packets of the flows expected to match
+// the entry. Entries whose counters were reset are checked against their new counts.
+func VerifyACLTermCounters(t *testing.T, dut *ondatra.DUTDevice, ate *ondatra.ATEDevice, params AclParams, flows []*ACLTermFlow, before map[uint32]uint64) {
+	t.Helper()
+	want := map[uint32]uint64{}
+	for _, f := range flows {
+		want[f.EntryID] += gnmi.Get(t, ate.OTG(), gnmi.OTG().Flow(f.Name).Counters().OutPkts().State())
+	}
+	for _, seqID := range slices.Sorted(maps.Keys(want)) {
+		var got uint64
+		path := gnmi.OC().Acl().AclSet(params.Name, params.ACLType).AclEntry(seqID).MatchedPackets().State()
+		_, ok := gnmi.Watch(t, dut, path, aclCounterTimeout, func(val *ygnmi.Value[uint64]) bool {
+			matched, present := val.Val()
+			got = matchedSince(matched, before[seqID])
+			return present && got >= want[seqID]
+		}).Await(t)
+		if !ok {
+			t.Errorf("Matched packets of entry %d of ACL %s got %d, want at least %d", seqID, params.Name, got, want[seqID])
+			continue
+		}
+		t.Logf("Matched packets of entry %d of ACL %s got %d, want at least %d", seqID, params.Name, got, want[seqID])
+	}
+}
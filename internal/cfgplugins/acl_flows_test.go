@@ -0,0 +1,243 @@
+// Copyright 2026 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cfgplugins
+
+import (
+	"net/netip"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/open-traffic-generator/snappi/gosnappi"
+	"github.com/openconfig/featureprofiles/internal/attrs"
+	"github.com/openconfig/ondatra/gnmi/oc"
+)
+
+var (
+	aclTestSrc = &attrs.Attributes{Name: "atePort1", MAC: "02:00:01:01:01:01", IPv4: "192.0.2.2", IPv6: "2001:db8::2"}
+	aclTestDst = &attrs.Attributes{Name: "atePort2", MAC: "02:00:02:01:01:01", IPv4: "192.0.2.6", IPv6: "2001:db8::6"}
+)
+
+func TestCreateACLEntry(t *testing.T) {
+	aclSet := (&oc.Root{}).GetOrCreateAcl().GetOrCreateAclSet("acl", oc.Acl_ACL_TYPE_ACL_IPV6)
+	createACLEntry(aclSet, AclTerm{
+		SeqID:          10,
+		Protocol:       TCPProtocolNum,
+		L4SrcPortRange: "1000..2000",
+		L4DstPortRange: "ports",
+		TCPFlags:       []oc.E_PacketMatchTypes_TCP_FLAGS{oc.PacketMatchTypes_TCP_FLAGS_TCP_SYN},
+		DSCPSet:        []uint8{46},
+		HopLimit:       1,
+	}, oc.Acl_ACL_TYPE_ACL_IPV6)
+	entry := aclSet.GetAclEntry(10)
+	transport := entry.GetTransport()
+	if got := transport.GetSourcePort(); got != oc.UnionString("1000..2000") {
+		t.Errorf("Source port got %v, want range 1000..2000", got)
+	}
+	if got := transport.GetDestinationPortSet(); got != "ports" {
+		t.Errorf("Destination port set got %q, want ports", got)
+	}
+	if got := transport.GetExplicitDetailMatchMode(); got != oc.Transport_ExplicitDetailMatchMode_ALL {
+		t.Errorf("TCP flags match mode got %v, want ALL", got)
+	}
+	if ipv6 := entry.GetIpv6(); ipv6.GetHopLimit() != 1 || !cmp.Equal(ipv6.GetDscpSet(), []uint8{46}) {
+		t.Errorf("IPv6 conditions got %+v, want hop limit 1 and DSCP 46", ipv6)
+	}
+
+	mplsSet := (&oc.Root{}).GetOrCreateAcl().GetOrCreateAclSet("mpls", oc.Acl_ACL_TYPE_ACL_MPLS)
+	createACLEntry(mplsSet, AclTerm{SeqID: 10, MPLSLabelStart: 100, MPLSTTL: 1}, oc.Acl_ACL_TYPE_ACL_MPLS)
+	mpls := mplsSet.GetAclEntry(10).GetMpls()
+	if mpls.GetStartLabelValue() != oc.UnionUint32(100) || mpls.GetEndLabelValue() != oc.UnionUint32(100) || mpls.GetTtlValue() != 1 {
+		t.Errorf("MPLS conditions got %+v, want label 100 and TTL 1", mpls)
+	}
+}
+
+func TestACLTermFlows(t *testing.T) {
+	params := AclParams{
+		Name:    "acl",
+		ACLType: oc.Acl_ACL_TYPE_ACL_IPV4,
+		Terms: []AclTerm{
+			{SeqID: 10, IPSrc: "198.51.100.0/24", Protocol: TCPProtocolNum, L4DstPortRange: "1000..2000"},
+			{SeqID: 20, Protocol: TCPProtocolNum, TCPFlags: []oc.E_PacketMatchTypes_TCP_FLAGS{oc.PacketMatchTypes_TCP_FLAGS_TCP_SYN, oc.PacketMatchTypes_TCP_FLAGS_TCP_ACK}},
+			{SeqID: 30, Protocol: ICMPv4ProtocolNum, ICMPType: int64(oc.Icmpv4Types_TYPE_ECHO)},
+			{SeqID: 40, DSCPSet: []uint8{46, 48}},
+			{SeqID: 50, IPDst: "203.0.113.0/24"},
+		},
+	}
+	flows, err := aclTermFlows(params, aclTestSrc, aclTestDst)
+	if err != nil {
+		t.Fatalf("aclTermFlows() got error: %v", err)
+	}
+	got := map[string]uint32{}
+	for _, f := range flows {
+		got[f.Name] = f.EntryID
+	}
+	want := map[string]uint32{
+		"acl-10-match":    10,
+		"acl-10-no-match": DefaultEntryID,
+		"acl-20-match":    20,
+		"acl-20-no-match": DefaultEntryID,
+		"acl-30-match":    30,
+		"acl-30-no-match": DefaultEntryID,
+		"acl-40-match":    40,
+		"acl-40-no-match": DefaultEntryID,
+		"acl-50-match":    50,
+		"acl-50-no-match": DefaultEntryID,
+	}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("aclTermFlows() entries diff (-want +got):\n%s", diff)
+	}
+	if got := flows[1].packet.dstPort; got != 2001 {
+		t.Errorf("Non-matching flow of term 10 destination port got %d, want 2001", got)
+	}
+	// A term shadowed by an earlier term is matched by the earlier term.
+	params.Terms = append(params.Terms, AclTerm{SeqID: 60, DSCPSet: []uint8{46}, HopLimit: 1})
+	flows, err = aclTermFlows(params, aclTestSrc, aclTestDst)
+	if err != nil {
+		t.Fatalf("aclTermFlows() got error: %v", err)
+	}
+	if got := flows[len(flows)-2].EntryID; got != 40 {
+		t.Errorf("Matching flow of shadowed term 60 entry got %d, want 40", got)
+	}
+
+	for _, tc := range []struct {
+		desc    string
+		aclType oc.E_Acl_ACL_TYPE
+		term    AclTerm
+	}{
+		{"term matching all packets", oc.Acl_ACL_TYPE_ACL_IPV4, AclTerm{SeqID: 10, IPSrc: matchAllV4}},
+		{"ICMPv4 code without value", oc.Acl_ACL_TYPE_ACL_IPV4, AclTerm{SeqID: 10, Protocol: ICMPv4ProtocolNum, ICMPType: int64(oc.Icmpv4Types_TYPE_DST_UNREACHABLE), ICMPCode: int64(oc.Icmpv4Types_CODE_DST_UNREACHABLE_PRECEDENCE_CUTOFF)}},
+		{"nonzero ICMPv6 code", oc.Acl_ACL_TYPE_ACL_IPV6, AclTerm{SeqID: 10, Protocol: ICMPv6ProtocolNum, ICMPType: int64(oc.Icmpv6Types_TYPE_DESTINATION_UNREACHABLE), ICMPCode: int64(oc.Icmpv6Types_CODE_DST_UNREACHABLE_PORT)}},
+	} {
+		params := AclParams{Name: "acl", ACLType: tc.aclType, Terms: []AclTerm{tc.term}}
+		if _, err := aclTermFlows(params, aclTestSrc, aclTestDst); err == nil {
+			t.Errorf("aclTermFlows() of a %s got no error", tc.desc)
+		}
+	}
+	badRange := AclTerm{SeqID: 10, Protocol: UDPProtocolNum, L4DstPortRange: "1000..high"}
+	if _, err := nonMatchingPacket(badRange, oc.Acl_ACL_TYPE_ACL_IPV4, &aclPacket{}); err == nil {
+		t.Errorf("nonMatchingPacket() of an invalid port range got no error")
+	}
+}
+
+func TestNonMatchingPacketBoundaries(t *testing.T) {
+	for _, tc := range []struct {
+		desc    string
+		aclType oc.E_Acl_ACL_TYPE
+		term    AclTerm
+		want    aclPacket
+	}{
+		{"port range at the top", oc.Acl_ACL_TYPE_ACL_IPV4, AclTerm{Protocol: TCPProtocolNum, L4DstPortRange: "60000..65535"}, aclPacket{dstPort: 59999}},
+		{"top port", oc.Acl_ACL_TYPE_ACL_IPV4, AclTerm{Protocol: UDPProtocolNum, L4SrcPort: 65535}, aclPacket{srcPort: 65534}},
+		{"port range below the top", oc.Acl_ACL_TYPE_ACL_IPV4, AclTerm{Protocol: TCPProtocolNum, L4DstPortRange: "1000..65534"}, aclPacket{dstPort: 65535}},
+		{"hop limit 255", oc.Acl_ACL_TYPE_ACL_IPV6, AclTerm{HopLimit: 255}, aclPacket{hopLimit: 254}},
+		{"MPLS TTL 255", oc.Acl_ACL_TYPE_ACL_MPLS, AclTerm{MPLSTTL: 255}, aclPacket{ttl: 254}},
+		{"top MPLS label", oc.Acl_ACL_TYPE_ACL_MPLS, AclTerm{MPLSLabelStart: 1000, MPLSLabelEnd: maxMPLSLabel}, aclPacket{label: 999}},
+		{"top EtherType", oc.Acl_ACL_TYPE_ACL_L2, AclTerm{Ethertype: 0xFFFF}, aclPacket{ethertype: 0xFFFE}},
+	} {
+		got, err := nonMatchingPacket(tc.term, tc.aclType, &aclPacket{})
+		if err != nil {
+			t.Errorf("nonMatchingPacket() of %s got error: %v", tc.desc, err)
+			continue
+		}
+		if diff := cmp.Diff(tc.want, *got, cmp.AllowUnexported(aclPacket{}), cmp.Comparer(func(a, b netip.Addr) bool { return a == b })); diff != "" {
+			t.Errorf("nonMatchingPacket() of %s diff (-want +got):\n%s", tc.desc, diff)
+		}
+	}
+	all := AclTerm{SeqID: 10, Protocol: TCPProtocolNum, L4DstPortRange: "0..65535"}
+	if _, err := nonMatchingPacket(all, oc.Acl_ACL_TYPE_ACL_IPV4, &aclPacket{}); err == nil {
+		t.Errorf("nonMatchingPacket() of a range of all ports got no error")
+	}
+}
+
+func TestMatchedSince(t *testing.T) {
+	for _, tc := range []struct {
+		matched, before, want uint64
+	}{
+		{matched: 150, before: 100, want: 50},
+		{matched: 100, before: 100, want: 0},
+		// The counter was reset since before.
+		{matched: 30, before: 100, want: 30},
+	} {
+		if got := matchedSince(tc.matched, tc.before); got != tc.want {
+			t.Errorf("matchedSince(%d, %d) got %d, want %d", tc.matched, tc.before, got, tc.want)
+		}
+	}
+}
+
+func TestAddACLTermFlows(t *testing.T) {
+	params := AclParams{
+		Name:    "acl6",
+		ACLType: oc.Acl_ACL_TYPE_ACL_IPV6,
+		Terms: []AclTerm{
+			{SeqID: 10, IPSrc: "2001:db8:1::/64", Protocol: UDPProtocolNum, L4SrcPort: 53, HopLimit: 255},
+		},
+	}
+	top := gosnappi.NewConfig()
+	flows := AddACLTermFlows(t, top, params, AclFlowParams{Src: aclTestSrc, Dst: aclTestDst, Packets: 1000, PPS: 100})
+	if len(flows) != 2 || len(top.Flows().Items()) != 2 {
+		t.Fatalf("AddACLTermFlows() got %d flows, want 2", len(flows))
+	}
+	match := top.Flows().Items()[0]
+	ip := match.Packet().Items()[1].Ipv6()
+	if ip.Src().Value() != "2001:db8:1::" || ip.HopLimit().Value() != 255 {
+		t.Errorf("Matching flow IPv6 header got %v hop limit %d, want 2001:db8:1:: hop limit 255", ip.Src().Value(), ip.HopLimit().Value())
+	}
+	if got := match.Packet().Items()[2].Udp().SrcPort().Value(); got != 53 {
+		t.Errorf("Matching flow UDP source port got %d, want 53", got)
+	}
+	if got := top.Flows().Items()[1].Packet().Items()[2].Udp().SrcPort().Value(); got != 54 {
+		t.Errorf("Non-matching flow UDP source port got %d, want 54", got)
+	}
+}
+
+func TestAddACLTermFlowsRaw(t *testing.T) {
+	flowParams := AclFlowParams{Src: aclTestSrc, Dst: aclTestDst, SrcPort: "port1", DstPort: "port2", Packets: 1000}
+	l2 := AclParams{Name: "l2", ACLType: oc.Acl_ACL_TYPE_ACL_L2, Terms: []AclTerm{{SeqID: 10, SrcMAC: "02:00:01:01:01:10"}}}
+	top := gosnappi.NewConfig()
+	AddACLTermFlows(t, top, l2, flowParams)
+	for _, flow := range top.Flows().Items() {
+		if got := flow.TxRx().Port().TxName(); got != "port1" {
+			t.Errorf("Flow %s is sent from %q, want port1", flow.Name(), got)
+		}
+		items := flow.Packet().Items()
+		if len(items) != 1 || items[0].Ethernet().EtherType().Value() != aclFlowEthertype {
+			t.Errorf("Flow %s packet got %v, want an Ethernet header with EtherType %#x", flow.Name(), items, aclFlowEthertype)
+		}
+		if got := items[0].Ethernet().Dst().Value(); got != aclTestDst.MAC {
+			t.Errorf("Flow %s destination MAC got %s, want %s", flow.Name(), got, aclTestDst.MAC)
+		}
+	}
+
+	mpls := AclParams{Name: "mpls", ACLType: oc.Acl_ACL_TYPE_ACL_MPLS, Terms: []AclTerm{{SeqID: 10, MPLSLabelStart: 100, MPLSLabelEnd: 200}}}
+	top = gosnappi.NewConfig()
+	AddACLTermFlows(t, top, mpls, flowParams)
+	var labels []uint32
+	for _, flow := range top.Flows().Items() {
+		items := flow.Packet().Items()
+		if len(items) != 2 {
+			t.Fatalf("Flow %s packet got %d headers, want Ethernet and MPLS", flow.Name(), len(items))
+		}
+		labels = append(labels, items[1].Mpls().Label().Value())
+	}
+	if diff := cmp.Diff([]uint32{100, 201}, labels); diff != "" {
+		t.Errorf("MPLS flow labels diff (-want +got):\n%s", diff)
+	}
+
+	flowParams.SrcPort = ""
+	f := &ACLTermFlow{Name: "f", packet: &aclPacket{}}
+	if err := addACLFlow(gosnappi.NewConfig(), oc.Acl_ACL_TYPE_ACL_L2, flowParams, f); err == nil {
+		t.Errorf("addACLFlow() of an L2 flow without ports got no error")
+	}
+}
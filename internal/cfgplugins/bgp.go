@@ -177,6 +177,12 @@ type BGPSession struct {
 	ATEPorts        []*attrs.Attributes
 	afiTypes        []oc.E_BgpTypes_AFI_SAFI_TYPE
 	networkInstance string
+
+	neighbors     []*bgpSessionNeighbor
+	loopback      string
+	underlayPorts map[string]BGPUnderlay
+	// dutCLI is the configuration OpenConfig does not model, pushed after DUTConf.
+	dutCLI []string
 }
 
 // BGPConfig holds all parameters needed to configure BGP on the DUT.
@@ -324,6 +330,7 @@ func (bs *BGPSession) WithEBGP(t *testing.T, afiTypes []oc.E_BgpTypes_AFI_SAFI_T
 				bgp4Peer.SetAsNumber(asNumbers[i])
 				bgp4Peer.SetAsType(gosnappi.BgpV4PeerAsType.EBGP)
 				bgp4Peer.LearnedInformationFilter().SetUnicastIpv4Prefix(true)
+				bs.neighbors = append(bs.neighbors, &bgpSessionNeighbor{port: otgPort.Name, address: ipv4.Gateway(), afiType: afiType, otgV4: bgp4Peer})
 			case oc.BgpTypes_AFI_SAFI_TYPE_IPV6_UNICAST:
 				ipv6 := devices[i].Ethernets().Items()[0].Ipv6Addresses().Items()[0]
 				bgp6Peer := bgp.Ipv6Interfaces().Add().SetIpv6Name(ipv6.Name()).Peers().Add().SetName(devices[i].Name() + ".BGP6.peer")
@@ -331,13 +338,20 @@ func (bs *BGPSession) WithEBGP(t *testing.T, afiTypes []oc.E_BgpTypes_AFI_SAFI_T
 				bgp6Peer.SetAsNumber(asNumbers[i])
 				bgp6Peer.SetAsType(gosnappi.BgpV6PeerAsType.EBGP)
 				bgp6Peer.LearnedInformationFilter().SetUnicastIpv6Prefix(true)
+				bs.neighbors = append(bs.neighbors, &bgpSessionNeighbor{port: otgPort.Name, address: ipv6.Gateway(), afiType: afiType, otgV6: bgp6Peer})
 			}
 		}
 	}
 
 	niProtocol := bs.DUTConf.GetOrCreateNetworkInstance(bs.networkInstance).GetOrCreateProtocol(PTBGP, bgpName)
 	neighborConfig := bs.buildNeigborConfig(isSamePG, isSameAS, bgpPorts)
-	niProtocol.Bgp = BuildBGPOCConfig(t, bs.DUT, dutPort1.IPv4, afiTypes, neighborConfig)
+	bgp := BuildBGPOCConfig(t, bs.DUT, dutPort1.IPv4, afiTypes, neighborConfig)
+	// Keep the neighbors added by WithIBGP or WithEBGPMultihop before.
+	if niProtocol.Bgp == nil {
+		niProtocol.Bgp = bgp
+	} else if err := ygot.MergeStructInto(niProtocol.Bgp, bgp, &ygot.MergeOverwriteExistingFields{}); err != nil {
+		t.Fatalf("Failed to merge eBGP config: %v", err)
+	}
 
 	err := bs.configureRoutingPolicy()
 	if err != nil {
@@ -380,6 +394,12 @@ func (bs *BGPSession) PushDUT(t testing.TB) error {
 		for i := 0; i < len(bs.DUTPorts); i++ {
 			fptest.AssignToNetworkInstance(t, bs.DUT, bs.OndatraDUTPorts[i].Name(), bs.networkInstance, 0)
 		}
+		if bs.loopback != "" {
+			fptest.AssignToNetworkInstance(t, bs.DUT, bs.loopback, bs.networkInstance, 0)
+		}
+	}
+	if len(bs.dutCLI) > 0 {
+		helpers.GnmiCLIConfig(t, bs.DUT, strings.Join(bs.dutCLI, ""))
 	}
 	if deviations.ExplicitPortSpeed(bs.DUT) {
 		for i := 0; i < len(bs.DUTPorts); i++ {
@@ -0,0 +1,407 @@
+// Copyright 2026 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cfgplugins
+
+import (
+	"fmt"
+	"net/netip"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/openconfig/featureprofiles/internal/deviations"
+	"github.com/openconfig/ondatra"
+	"github.com/openconfig/ondatra/gnmi/oc"
+)
+
+// ParseRoutingPolicy compiles routing policies written in a line based DSL to an
+// oc.RoutingPolicy. Text after # is a comment and indentation is ignored. For example:
+//
+//	prefix-set PS-V4 198.51.100.0/24 203.0.113.0/24[24..28]
+//	community-set CS-IN 100:100 200:.*
+//	ext-community-set LBW link-bandwidth:23456:.*
+//	as-path-set AP-PEER ^65502_
+//
+//	policy IMPORT
+//	  statement 10
+//	    match prefix-set PS-V4             # any | invert
+//	    match community-set CS-IN any      # any | all | invert
+//	    match as-path-set AP-PEER
+//	    set local-pref 200
+//	    set med +10                        # N | +N | -N
+//	    set as-path-prepend 65501 2
+//	    set community add 300:300          # add | remove | replace
+//	    next-statement                     # accept | reject | next-statement
+//	  statement 20
+//	    call OTHER-POLICY
+//	    set next-hop self
+//	    set community-ref remove CS-IN
+//	    accept
+//
+// Other conditions are "match ext-community-set", "match med" and "match local-pref", and
+// other actions are "set ext-community" and "set ext-community-ref". A statement has at most
+// one community and one ext-community action, either inline or by reference.
+func ParseRoutingPolicy(dsl string) (*oc.RoutingPolicy, error) {
+	p := &policyParser{rp: (&oc.Root{}).GetOrCreateRoutingPolicy()}
+	for i, line := range strings.Split(dsl, "\n") {
+		if j := strings.Index(line, "#"); j >= 0 {
+			line = line[:j]
+		}
+		fields := strings.Fields(line)
+		if len(fields) == 0 {
+			continue
+		}
+		if err := p.parse(fields); err != nil {
+			return nil, fmt.Errorf("line %d %q: %v", i+1, strings.TrimSpace(line), err)
+		}
+	}
+	return p.rp, nil
+}
+
+// RoutingPolicyFromDSL compiles the routing policies of dsl as ParseRoutingPolicy does and
+// adjusts them to the deviations of the DUT.
+func RoutingPolicyFromDSL(t *testing.T, dut *ondatra.DUTDevice, dsl string) *oc.RoutingPolicy {
+	t.Helper()
+	rp, err := ParseRoutingPolicy(dsl)
+	if err != nil {
+		t.Fatalf("Failed to parse routing policy: %v", err)
+	}
+	if deviations.SkipPrefixSetMode(dut) {
+		for _, ps := range rp.GetDefinedSets().PrefixSet {
+			ps.Mode = oc.PrefixSet_Mode_UNSET
+		}
+	}
+	for _, pd := range rp.PolicyDefinition {
+		for _, stmt := range pd.Statement.Values() {
+			bgpConds := stmt.GetConditions().GetBgpConditions()
+			if m := bgpConds.GetMatchCommunitySet(); m != nil && deviations.BGPConditionsMatchCommunitySetUnsupported(dut) {
+				bgpConds.SetCommunitySet(m.GetCommunitySet())
+				bgpConds.MatchCommunitySet = nil
+			}
+			sc := stmt.GetActions().GetBgpActions().GetSetCommunity()
+			if sc == nil {
+				continue
+			}
+			if deviations.BgpActionsSetCommunityMethodUnsupported(dut) {
+				sc.Method = oc.SetCommunity_Method_UNSET
+			}
+			if ref := sc.GetReference(); len(ref.GetCommunitySetRefs()) == 1 && deviations.BgpCommunitySetRefsUnsupported(dut) {
+				ref.SetCommunitySetRef(ref.GetCommunitySetRefs()[0])
+				ref.CommunitySetRefs = nil
+			}
+		}
+	}
+	return rp
+}
+
+// policyParser holds the policy and statement that lines of the DSL apply to.
+type policyParser struct {
+	rp   *oc.RoutingPolicy
+	pd   *oc.RoutingPolicy_PolicyDefinition
+	stmt *oc.RoutingPolicy_PolicyDefinition_Statement
+}
+
+func (p *policyParser) parse(f []string) error {
+	bgpSets := p.rp.GetOrCreateDefinedSets().GetOrCreateBgpDefinedSets()
+	switch f[0] {
+	case "prefix-set":
+		if len(f) < 3 {
+			return fmt.Errorf("want prefix-set <name> <prefix>...")
+		}
+		return p.prefixSet(f[1], f[2:])
+	case "community-set":
+		if len(f) < 3 {
+			return fmt.Errorf("want community-set <name> <community>...")
+		}
+		cs := bgpSets.GetOrCreateCommunitySet(f[1])
+		for _, m := range f[2:] {
+			cs.CommunityMember = append(cs.CommunityMember, oc.UnionString(m))
+		}
+		return nil
+	case "ext-community-set":
+		if len(f) < 3 {
+			return fmt.Errorf("want ext-community-set <name> <community>...")
+		}
+		ecs := bgpSets.GetOrCreateExtCommunitySet(f[1])
+		ecs.ExtCommunityMember = append(ecs.ExtCommunityMember, f[2:]...)
+		return nil
+	case "as-path-set":
+		if len(f) < 3 {
+			return fmt.Errorf("want as-path-set <name> <regex>...")
+		}
+		aps := bgpSets.GetOrCreateAsPathSet(f[1])
+		aps.AsPathSetMember = append(aps.AsPathSetMember, f[2:]...)
+		return nil
+	case "policy":
+		if len(f) != 2 {
+			return fmt.Errorf("want policy <name>")
+		}
+		p.pd, p.stmt = p.rp.GetOrCreatePolicyDefinition(f[1]), nil
+		return nil
+	case "statement":
+		if p.pd == nil {
+			return fmt.Errorf("statement outside of a policy")
+		}
+		if len(f) != 2 {
+			return fmt.Errorf("want statement <name>")
+		}
+		stmt, err := p.pd.AppendNewStatement(f[1])
+		if err != nil {
+			return err
+		}
+		p.stmt = stmt
+		return nil
+	}
+
+	if p.stmt == nil {
+		return fmt.Errorf("%s outside of a statement", f[0])
+	}
+	switch f[0] {
+	case "match":
+		return p.match(f[1:])
+	case "call":
+		if len(f) != 2 {
+			return fmt.Errorf("want call <policy>")
+		}
+		p.stmt.GetOrCreateConditions().SetCallPolicy(f[1])
+	case "set":
+		return p.set(f[1:])
+	case "accept":
+		p.stmt.GetOrCreateActions().SetPolicyResult(oc.RoutingPolicy_PolicyResultType_ACCEPT_ROUTE)
+	case "reject":
+		p.stmt.GetOrCreateActions().SetPolicyResult(oc.RoutingPolicy_PolicyResultType_REJECT_ROUTE)
+	case "next-statement":
+		p.stmt.GetOrCreateActions().SetPolicyResult(oc.RoutingPolicy_PolicyResultType_NEXT_STATEMENT)
+	default:
+		return fmt.Errorf("unknown keyword %q", f[0])
+	}
+	return nil
+}
+
+// prefixSet adds prefixes written as <prefix> or <prefix>[<min>..<max>] to a prefix set whose
+// mode follows the address families of its prefixes.
+func (p *policyParser) prefixSet(name string, prefixes []string) error {
+	ps := p.rp.GetOrCreateDefinedSets().GetOrCreatePrefixSet(name)
+	for _, s := range prefixes {
+		prefix, lengths := s, "exact"
+		if i := strings.Index(s, "["); i >= 0 && strings.HasSuffix(s, "]") {
+			prefix, lengths = s[:i], s[i+1:len(s)-1]
+		}
+		pfx, err := netip.ParsePrefix(prefix)
+		if err != nil {
+			return err
+		}
+		mode := oc.PrefixSet_Mode_IPV4
+		if pfx.Addr().Is6() {
+			mode = oc.PrefixSet_Mode_IPV6
+		}
+		if ps.Mode != oc.PrefixSet_Mode_UNSET && ps.Mode != mode {
+			mode = oc.PrefixSet_Mode_MIXED
+		}
+		ps.SetMode(mode)
+		ps.GetOrCreatePrefix(prefix, lengths)
+	}
+	return nil
+}
+
+func (p *policyParser) match(f []string) error {
+	if len(f) < 2 {
+		return fmt.Errorf("want match <condition> <value>")
+	}
+	conds := p.stmt.GetOrCreateConditions()
+	opt := ""
+	if len(f) == 3 {
+		opt = strings.ToUpper(f[2])
+	}
+	setOpts := map[string]oc.E_RoutingPolicy_MatchSetOptionsType{
+		"":       oc.RoutingPolicy_MatchSetOptionsType_ANY,
+		"ANY":    oc.RoutingPolicy_MatchSetOptionsType_ANY,
+		"ALL":    oc.RoutingPolicy_MatchSetOptionsType_ALL,
+		"INVERT": oc.RoutingPolicy_MatchSetOptionsType_INVERT,
+	}
+	switch f[0] {
+	case "prefix-set":
+		restricted := map[string]oc.E_RoutingPolicy_MatchSetOptionsRestrictedType{
+			"":       oc.RoutingPolicy_MatchSetOptionsRestrictedType_ANY,
+			"ANY":    oc.RoutingPolicy_MatchSetOptionsRestrictedType_ANY,
+			"INVERT": oc.RoutingPolicy_MatchSetOptionsRestrictedType_INVERT,
+		}
+		o, ok := restricted[opt]
+		if !ok || len(f) > 3 {
+			return fmt.Errorf("want match prefix-set <name> [any|invert]")
+		}
+		m := conds.GetOrCreateMatchPrefixSet()
+		m.SetPrefixSet(f[1])
+		m.SetMatchSetOptions(o)
+		return nil
+	case "med", "local-pref":
+		v, err := strconv.ParseUint(f[1], 10, 32)
+		if err != nil || len(f) != 2 {
+			return fmt.Errorf("want match %s <value>", f[0])
+		}
+		if f[0] == "med" {
+			conds.GetOrCreateBgpConditions().SetMedEq(uint32(v))
+		} else {
+			conds.GetOrCreateBgpConditions().SetLocalPrefEq(uint32(v))
+		}
+		return nil
+	}
+
+	o, ok := setOpts[opt]
+	if !ok || len(f) > 3 {
+		return fmt.Errorf("want match %s <name> [any|all|invert]", f[0])
+	}
+	bgpConds := conds.GetOrCreateBgpConditions()
+	switch f[0] {
+	case "community-set":
+		m := bgpConds.GetOrCreateMatchCommunitySet()
+		m.SetCommunitySet(f[1])
+		m.SetMatchSetOptions(o)
+	case "ext-community-set":
+		m := bgpConds.GetOrCreateMatchExtCommunitySet()
+		m.SetExtCommunitySet(f[1])
+		m.SetMatchSetOptions(o)
+	case "as-path-set":
+		m := bgpConds.GetOrCreateMatchAsPathSet()
+		m.SetAsPathSet(f[1])
+		m.SetMatchSetOptions(o)
+	default:
+		return fmt.Errorf("unknown condition %q", f[0])
+	}
+	return nil
+}
+
+func (p *policyParser) set(f []string) error {
+	if len(f) < 2 {
+		return fmt.Errorf("want set <action> <value>")
+	}
+	actions := p.stmt.GetOrCreateActions().GetOrCreateBgpActions()
+	switch f[0] {
+	case "local-pref":
+		if len(f) != 2 {
+			return fmt.Errorf("want set local-pref <value>")
+		}
+		v, err := strconv.ParseUint(f[1], 10, 32)
+		if err != nil {
+			return err
+		}
+		actions.SetSetLocalPref(uint32(v))
+	case "med":
+		if len(f) != 2 {
+			return fmt.Errorf("want set med N|+N|-N")
+		}
+		medAction := oc.BgpPolicy_BgpSetMedAction_SET
+		switch f[1][0] {
+		case '+':
+			medAction = oc.BgpPolicy_BgpSetMedAction_ADD
+		case '-':
+			medAction = oc.BgpPolicy_BgpSetMedAction_SUBTRACT
+		}
+		v, err := strconv.ParseUint(strings.TrimLeft(f[1], "+-"), 10, 32)
+		if err != nil {
+			return err
+		}
+		actions.SetSetMed(oc.UnionUint32(v))
+		actions.SetSetMedAction(medAction)
+	case "next-hop":
+		if len(f) != 2 {
+			return fmt.Errorf("want set next-hop self|<address>")
+		}
+		if strings.EqualFold(f[1], "self") {
+			actions.SetSetNextHop(oc.BgpActions_SetNextHop_SELF)
+			return nil
+		}
+		if _, err := netip.ParseAddr(f[1]); err != nil {
+			return err
+		}
+		actions.SetSetNextHop(oc.UnionString(f[1]))
+	case "as-path-prepend":
+		if len(f) > 3 {
+			return fmt.Errorf("want set as-path-prepend <asn> [<count>]")
+		}
+		asn, err := strconv.ParseUint(f[1], 10, 32)
+		if err != nil {
+			return err
+		}
+		prepend := actions.GetOrCreateSetAsPathPrepend()
+		prepend.SetAsn(uint32(asn))
+		if len(f) > 2 {
+			n, err := strconv.ParseUint(f[2], 10, 8)
+			if err != nil {
+				return err
+			}
+			prepend.SetRepeatN(uint8(n))
+		}
+	case "community", "community-ref", "ext-community", "ext-community-ref":
+		if len(f) < 3 {
+			return fmt.Errorf("want set %s add|remove|replace <value>...", f[0])
+		}
+		opts := map[string]oc.E_BgpPolicy_BgpSetCommunityOptionType{
+			"add":     oc.BgpPolicy_BgpSetCommunityOptionType_ADD,
+			"remove":  oc.BgpPolicy_BgpSetCommunityOptionType_REMOVE,
+			"replace": oc.BgpPolicy_BgpSetCommunityOptionType_REPLACE,
+		}
+		o, ok := opts[f[1]]
+		if !ok {
+			return fmt.Errorf("unknown option %q, want add, remove or replace", f[1])
+		}
+		return setCommunity(actions, f[0], o, f[2:])
+	default:
+		return fmt.Errorf("unknown action %q", f[0])
+	}
+	return nil
+}
+
+// setCommunity sets the set-community or set-ext-community action of kind to values. A
+// statement holds a single action of each, so it fails if the action is already set.
+func setCommunity(actions *oc.RoutingPolicy_PolicyDefinition_Statement_Actions_BgpActions, kind string, opt oc.E_BgpPolicy_BgpSetCommunityOptionType, values []string) error {
+	switch kind {
+	case "community", "community-ref":
+		if actions.GetSetCommunity() != nil {
+			return fmt.Errorf("statement already sets community")
+		}
+	case "ext-community", "ext-community-ref":
+		if actions.GetSetExtCommunity() != nil {
+			return fmt.Errorf("statement already sets ext-community")
+		}
+	}
+	switch kind {
+	case "community":
+		sc := actions.GetOrCreateSetCommunity()
+		sc.SetOptions(opt)
+		sc.SetMethod(oc.SetCommunity_Method_INLINE)
+		for _, v := range values {
+			sc.GetOrCreateInline().Communities = append(sc.GetOrCreateInline().Communities, oc.UnionString(v))
+		}
+	case "community-ref":
+		sc := actions.GetOrCreateSetCommunity()
+		sc.SetOptions(opt)
+		sc.SetMethod(oc.SetCommunity_Method_REFERENCE)
+		sc.GetOrCreateReference().SetCommunitySetRefs(values)
+	case "ext-community":
+		sc := actions.GetOrCreateSetExtCommunity()
+		sc.SetOptions(opt)
+		sc.SetMethod(oc.SetCommunity_Method_INLINE)
+		for _, v := range values {
+			sc.GetOrCreateInline().Communities = append(sc.GetOrCreateInline().Communities, oc.UnionString(v))
+		}
+	case "ext-community-ref":
+		sc := actions.GetOrCreateSetExtCommunity()
+		sc.SetOptions(opt)
+		sc.SetMethod(oc.SetCommunity_Method_REFERENCE)
+		sc.GetOrCreateReference().SetExtCommunitySetRefs(values)
+	}
+	return nil
+}
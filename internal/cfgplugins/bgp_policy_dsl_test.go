@@ -0,0 +1,220 @@
+// Copyright 2026 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cfgplugins
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/openconfig/ondatra"
+	"github.com/openconfig/ondatra/gnmi/oc"
+	"github.com/openconfig/ygot/ygot"
+
+	mpb "github.com/openconfig/featureprofiles/proto/metadata_go_proto"
+	opb "github.com/openconfig/ondatra/proto"
+)
+
+const testPolicyDSL = `
+prefix-set PS-V4 198.51.100.0/24 203.0.113.0/24[24..28]
+community-set CS-IN 100:100 200:.*
+community-set CS-ADD 300:300
+ext-community-set LBW link-bandwidth:23456:.*
+as-path-set AP-PEER ^65502_
+
+policy TAG
+  statement 10
+    match as-path-set AP-PEER
+    set community-ref add CS-ADD
+    accept
+
+policy IMPORT
+  statement reject-lbw
+    match ext-community-set LBW
+    reject
+  statement 10
+    match prefix-set PS-V4
+    match community-set CS-IN any
+    set local-pref 200
+    set med +10
+    set community remove 200:.*
+    next-statement
+  statement 20
+    call TAG
+    set as-path-prepend 65501 2
+    set next-hop self
+    accept
+`
+
+func TestParseRoutingPolicy(t *testing.T) {
+	rp, err := ParseRoutingPolicy(testPolicyDSL)
+	if err != nil {
+		t.Fatalf("ParseRoutingPolicy() got error: %v", err)
+	}
+	ps := rp.GetDefinedSets().GetPrefixSet("PS-V4")
+	if ps.GetMode() != oc.PrefixSet_Mode_IPV4 || ps.GetPrefix("203.0.113.0/24", "24..28") == nil || ps.GetPrefix("198.51.100.0/24", "exact") == nil {
+		t.Errorf("Prefix set PS-V4 got %+v, want IPv4 prefixes with mask lengths", ps)
+	}
+	var stmts []string
+	for _, stmt := range rp.GetPolicyDefinition("IMPORT").Statement.Values() {
+		stmts = append(stmts, stmt.GetName())
+	}
+	if diff := cmp.Diff([]string{"reject-lbw", "10", "20"}, stmts); diff != "" {
+		t.Errorf("IMPORT statements diff (-want +got):\n%s", diff)
+	}
+	stmt := rp.GetPolicyDefinition("IMPORT").GetStatement("10")
+	if got := stmt.GetActions().GetBgpActions(); got.GetSetMed() != oc.UnionUint32(10) || got.GetSetMedAction() != oc.BgpPolicy_BgpSetMedAction_ADD {
+		t.Errorf("Statement 10 MED action got %v %v, want ADD 10", got.GetSetMedAction(), got.GetSetMed())
+	}
+	if got := stmt.GetActions().GetPolicyResult(); got != oc.RoutingPolicy_PolicyResultType_NEXT_STATEMENT {
+		t.Errorf("Statement 10 result got %v, want NEXT_STATEMENT", got)
+	}
+	if got := rp.GetPolicyDefinition("IMPORT").GetStatement("20").GetConditions().GetCallPolicy(); got != "TAG" {
+		t.Errorf("Statement 20 call policy got %q, want TAG", got)
+	}
+
+	for _, tc := range []struct {
+		dsl     string
+		wantErr string
+	}{
+		{"statement 10", "line 1"},
+		{"policy P\n  accept", "line 2"},
+		{"policy P\n  statement 10\n    match prefix-set PS all", "line 3"},
+		{"prefix-set PS 192.0.2.0", "line 1"},
+		{"policy P\n  statement 10\n    set med abc", "line 3"},
+		{"policy P\n  statement 10\n    drop", "unknown keyword"},
+		{"policy P\n  statement 10\n    set local-pref 200 300", "want set local-pref"},
+		{"policy P\n  statement 10\n    set next-hop self 192.0.2.1", "want set next-hop"},
+		{"policy P\n  statement 10\n    set community add 300:300\n    set community-ref remove CS-IN", "already sets community"},
+		{"policy P\n  statement 10\n    set ext-community add rt:1:1\n    set ext-community remove rt:2:2", "already sets ext-community"},
+	} {
+		if _, err := ParseRoutingPolicy(tc.dsl); err == nil || !strings.Contains(err.Error(), tc.wantErr) {
+			t.Errorf("ParseRoutingPolicy(%q) got error %v, want error containing %q", tc.dsl, err, tc.wantErr)
+		}
+	}
+}
+
+func TestEvaluateRoutingPolicy(t *testing.T) {
+	rp, err := ParseRoutingPolicy(testPolicyDSL)
+	if err != nil {
+		t.Fatalf("ParseRoutingPolicy() got error: %v", err)
+	}
+	routes := []*BGPRIBRoute{{
+		Prefix:      "198.51.100.0/24",
+		ASPath:      []uint32{65502},
+		Communities: []string{"100:100", "200:1"},
+		MED:         ygot.Uint32(5),
+	}, {
+		Prefix:      "203.0.113.16/28",
+		ASPath:      []uint32{65503},
+		Communities: []string{"200:2"},
+	}, {
+		Prefix: "203.0.113.0/30",
+		ASPath: []uint32{65502},
+	}, {
+		Prefix:         "198.51.100.0/24",
+		PathID:         2,
+		ASPath:         []uint32{65502},
+		ExtCommunities: []string{LinkBandwidthExtCommunity(23456, 1e6)},
+	}}
+
+	got, err := EvaluateRoutingPolicy(rp, []string{"IMPORT"}, routes, PolicyEvalOptions{NextHopSelf: "192.0.2.1"})
+	if err != nil {
+		t.Fatalf("EvaluateRoutingPolicy() got error: %v", err)
+	}
+	want := []*BGPRIBRoute{{
+		Prefix:      "198.51.100.0/24",
+		ASPath:      []uint32{65501, 65501, 65502},
+		Communities: []string{"100:100", "300:300"},
+		MED:         ygot.Uint32(15),
+		LocalPref:   ygot.Uint32(200),
+		NextHop:     "192.0.2.1",
+	}, {
+		Prefix:      "203.0.113.0/30",
+		ASPath:      []uint32{65501, 65501, 65502},
+		Communities: []string{"300:300"},
+		NextHop:     "192.0.2.1",
+	}}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("EvaluateRoutingPolicy() diff (-want +got):\n%s", diff)
+	}
+	if diffs := DiffBGPRIBRoutes(got, want, true); len(diffs) != 0 {
+		t.Errorf("DiffBGPRIBRoutes() of the evaluated routes got %v, want none", diffs)
+	}
+
+	got, err = EvaluateRoutingPolicy(rp, []string{"TAG"}, routes, PolicyEvalOptions{DefaultAccept: true})
+	if err != nil {
+		t.Fatalf("EvaluateRoutingPolicy() got error: %v", err)
+	}
+	if len(got) != len(routes) || !cmp.Equal(got[1].Communities, []string{"200:2"}) {
+		t.Errorf("EvaluateRoutingPolicy() with default accept got %v, want all routes with only matching routes tagged", got)
+	}
+
+	if _, err := EvaluateRoutingPolicy(rp, []string{"MISSING"}, routes, PolicyEvalOptions{}); err == nil {
+		t.Errorf("EvaluateRoutingPolicy() of an undefined policy got no error")
+	}
+}
+
+func TestEvaluateRoutingPolicyCommunityMethod(t *testing.T) {
+	rp, err := ParseRoutingPolicy(`
+community-set CS-IN 100:100
+policy P
+  statement 10
+    set community-ref remove CS-IN
+    accept
+`)
+	if err != nil {
+		t.Fatalf("ParseRoutingPolicy() got error: %v", err)
+	}
+	sc := rp.GetPolicyDefinition("P").GetStatement("10").GetActions().GetBgpActions().GetSetCommunity()
+	sc.GetOrCreateInline().SetCommunities([]oc.RoutingPolicy_PolicyDefinition_Statement_Actions_BgpActions_SetCommunity_Inline_Communities_Union{oc.UnionString("300:300")})
+	routes := []*BGPRIBRoute{{Prefix: "198.51.100.0/24", Communities: []string{"100:100", "300:300"}}}
+
+	for _, tc := range []struct {
+		method oc.E_SetCommunity_Method
+		want   []string
+	}{
+		{oc.SetCommunity_Method_REFERENCE, []string{"300:300"}},
+		{oc.SetCommunity_Method_INLINE, []string{"100:100"}},
+		{oc.SetCommunity_Method_UNSET, []string{}},
+	} {
+		sc.SetMethod(tc.method)
+		got, err := EvaluateRoutingPolicy(rp, []string{"P"}, routes, PolicyEvalOptions{})
+		if err != nil {
+			t.Fatalf("EvaluateRoutingPolicy() with method %v got error: %v", tc.method, err)
+		}
+		if len(got) != 1 || !cmp.Equal(got[0].Communities, tc.want) {
+			t.Errorf("EvaluateRoutingPolicy() with method %v got %v, want communities %v", tc.method, got, tc.want)
+		}
+	}
+}
+
+func TestRoutingPolicyFromDSL(t *testing.T) {
+	md := &mpb.Metadata{PlatformExceptions: []*mpb.Metadata_PlatformExceptions{{
+		Platform:   &mpb.Metadata_Platform{Vendor: opb.Device_CISCO},
+		Deviations: &mpb.Metadata_Deviations{BgpConditionsMatchCommunitySetUnsupported: true, SkipPrefixSetMode: true},
+	}}}
+	var rp *oc.RoutingPolicy
+	DryRun(t, Platform{Vendor: ondatra.CISCO, Metadata: md}, func(t *testing.T, dut *ondatra.DUTDevice) {
+		rp = RoutingPolicyFromDSL(t, dut, testPolicyDSL)
+	})
+	bgpConds := rp.GetPolicyDefinition("IMPORT").GetStatement("10").GetConditions().GetBgpConditions()
+	if bgpConds.GetMatchCommunitySet() != nil || bgpConds.GetCommunitySet() != "CS-IN" {
+		t.Errorf("Statement 10 BGP conditions got %+v, want community-set leaf CS-IN", bgpConds)
+	}
+	if got := rp.GetDefinedSets().GetPrefixSet("PS-V4").GetMode(); got != oc.PrefixSet_Mode_UNSET {
+		t.Errorf("Prefix set PS-V4 mode got %v, want unset", got)
+	}
+}
@@ -0,0 +1,434 @@
+// Copyright 2026 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cfgplugins
+
+import (
+	"fmt"
+	"net/netip"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/openconfig/ondatra/gnmi/oc"
+	"github.com/openconfig/ygot/ygot"
+)
+
+// maxCallPolicyDepth bounds the nesting of call-policy conditions, which also catches loops.
+const maxCallPolicyDepth = 16
+
+// PolicyEvalOptions are the options of EvaluateRoutingPolicy.
+type PolicyEvalOptions struct {
+	// DefaultAccept accepts routes that no policy of the chain accepts or rejects, as the
+	// ACCEPT_ROUTE default import or export policy does.
+	DefaultAccept bool
+	// NextHopSelf is the next hop that set-next-hop SELF sets.
+	NextHopSelf string
+}
+
+// EvaluateRoutingPolicy applies the chain of policies of rp to routes as a DUT would, and
+// returns the routes that the chain accepts with the attributes its actions set. Attributes
+// that BGP itself changes when advertising a route, like the AS path towards an eBGP
+// neighbor, are not applied. Conditions and actions that are not modeled return an error.
+func EvaluateRoutingPolicy(rp *oc.RoutingPolicy, policies []string, routes []*BGPRIBRoute, opts PolicyEvalOptions) ([]*BGPRIBRoute, error) {
+	e := &policyEvaluator{rp: rp, opts: opts}
+	var accepted []*BGPRIBRoute
+	for _, route := range routes {
+		r := cloneBGPRIBRoute(route)
+		result := oc.RoutingPolicy_PolicyResultType_UNSET
+		for _, name := range policies {
+			var err error
+			if result, err = e.policy(name, r, 0); err != nil {
+				return nil, fmt.Errorf("route %s: %v", route.key(), err)
+			}
+			if result != oc.RoutingPolicy_PolicyResultType_UNSET {
+				break
+			}
+		}
+		if result == oc.RoutingPolicy_PolicyResultType_ACCEPT_ROUTE || result == oc.RoutingPolicy_PolicyResultType_UNSET && opts.DefaultAccept {
+			accepted = append(accepted, r)
+		}
+	}
+	return accepted, nil
+}
+
+func cloneBGPRIBRoute(r *BGPRIBRoute) *BGPRIBRoute {
+	c := *r
+	c.ASPath = slices.Clone(r.ASPath)
+	c.Communities = slices.Clone(r.Communities)
+	c.ExtCommunities = slices.Clone(r.ExtCommunities)
+	if r.MED != nil {
+		c.MED = ygot.Uint32(*r.MED)
+	}
+	if r.LocalPref != nil {
+		c.LocalPref = ygot.Uint32(*r.LocalPref)
+	}
+	return &c
+}
+
+type policyEvaluator struct {
+	rp   *oc.RoutingPolicy
+	opts PolicyEvalOptions
+}
+
+// policy applies the statements of the named policy to r, and returns ACCEPT_ROUTE or
+// REJECT_ROUTE if a matching statement has that result, or UNSET otherwise.
+func (e *policyEvaluator) policy(name string, r *BGPRIBRoute, depth int) (oc.E_RoutingPolicy_PolicyResultType, error) {
+	if depth > maxCallPolicyDepth {
+		return 0, fmt.Errorf("policy %s nested deeper than %d calls", name, maxCallPolicyDepth)
+	}
+	pd := e.rp.GetPolicyDefinition(name)
+	if pd == nil {
+		return 0, fmt.Errorf("policy %s is not defined", name)
+	}
+	for _, stmt := range pd.Statement.Values() {
+		match, err := e.conditions(stmt.GetConditions(), r, depth)
+		if err != nil {
+			return 0, fmt.Errorf("policy %s statement %s: %v", name, stmt.GetName(), err)
+		}
+		if !match {
+			continue
+		}
+		if err := e.actions(stmt.GetActions().GetBgpActions(), r); err != nil {
+			return 0, fmt.Errorf("policy %s statement %s: %v", name, stmt.GetName(), err)
+		}
+		switch result := stmt.GetActions().GetPolicyResult(); result {
+		case oc.RoutingPolicy_PolicyResultType_ACCEPT_ROUTE, oc.RoutingPolicy_PolicyResultType_REJECT_ROUTE:
+			return result, nil
+		}
+	}
+	return oc.RoutingPolicy_PolicyResultType_UNSET, nil
+}
+
+// conditions reports whether r matches all conditions of a statement. The actions of a called
+// policy only apply to r if it accepts the route.
+func (e *policyEvaluator) conditions(c *oc.RoutingPolicy_PolicyDefinition_Statement_Conditions, r *BGPRIBRoute, depth int) (bool, error) {
+	if c == nil {
+		return true, nil
+	}
+	if c.MatchTagSet != nil || c.MatchNeighborSet != nil || c.MatchInterface != nil || c.InstallProtocolEq != oc.PolicyTypes_INSTALL_PROTOCOL_TYPE_UNSET {
+		return false, fmt.Errorf("unsupported condition in %+v", c)
+	}
+	if m := c.GetMatchPrefixSet(); m != nil {
+		in, err := e.inPrefixSet(m.GetPrefixSet(), r.Prefix)
+		if err != nil {
+			return false, err
+		}
+		if in == (m.GetMatchSetOptions() == oc.RoutingPolicy_MatchSetOptionsRestrictedType_INVERT) {
+			return false, nil
+		}
+	}
+	if bc := c.GetBgpConditions(); bc != nil {
+		match, err := e.bgpConditions(bc, r)
+		if err != nil || !match {
+			return false, err
+		}
+	}
+	if c.CallPolicy != nil {
+		called := cloneBGPRIBRoute(r)
+		result, err := e.policy(c.GetCallPolicy(), called, depth+1)
+		if err != nil || result != oc.RoutingPolicy_PolicyResultType_ACCEPT_ROUTE {
+			return false, err
+		}
+		*r = *called
+	}
+	return true, nil
+}
+
+func (e *policyEvaluator) inPrefixSet(name, prefix string) (bool, error) {
+	ps := e.rp.GetDefinedSets().GetPrefixSet(name)
+	if ps == nil {
+		return false, fmt.Errorf("prefix-set %s is not defined", name)
+	}
+	pfx, err := netip.ParsePrefix(prefix)
+	if err != nil {
+		return false, err
+	}
+	for key := range ps.Prefix {
+		entry, err := netip.ParsePrefix(key.IpPrefix)
+		if err != nil {
+			return false, fmt.Errorf("prefix-set %s: %v", name, err)
+		}
+		if entry.Addr().Is4() != pfx.Addr().Is4() || pfx.Bits() < entry.Bits() || !entry.Contains(pfx.Addr()) {
+			continue
+		}
+		lo, hi := entry.Bits(), entry.Bits()
+		if key.MasklengthRange != "exact" {
+			if _, err := fmt.Sscanf(key.MasklengthRange, "%d..%d", &lo, &hi); err != nil {
+				return false, fmt.Errorf("prefix-set %s: masklength-range %q: %v", name, key.MasklengthRange, err)
+			}
+		}
+		if pfx.Bits() >= lo && pfx.Bits() <= hi {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+func (e *policyEvaluator) bgpConditions(bc *oc.RoutingPolicy_PolicyDefinition_Statement_Conditions_BgpConditions, r *BGPRIBRoute) (bool, error) {
+	if bc.AsPathLength != nil || bc.CommunityCount != nil || bc.ExtCommunityCount != nil || len(bc.NextHopIn) > 0 || bc.OriginEq != oc.BgpPolicy_BgpOriginAttrType_UNSET || bc.RouteType != oc.BgpConditions_RouteType_UNSET || len(bc.AfiSafiIn) > 0 {
+		return false, fmt.Errorf("unsupported BGP condition in %+v", bc)
+	}
+	if bc.MedEq != nil && (r.MED == nil || *r.MED != bc.GetMedEq()) {
+		return false, nil
+	}
+	if bc.LocalPrefEq != nil && (r.LocalPref == nil || *r.LocalPref != bc.GetLocalPrefEq()) {
+		return false, nil
+	}
+	sets := e.rp.GetDefinedSets().GetBgpDefinedSets()
+	type setMatch struct {
+		kind    string
+		name    string
+		defined bool
+		opts    oc.E_RoutingPolicy_MatchSetOptionsType
+		members []string
+		values  []string
+		match   func(member, value string) (bool, error)
+	}
+	communitySet := func(name string, opts oc.E_RoutingPolicy_MatchSetOptionsType) setMatch {
+		cs := sets.GetCommunitySet(name)
+		var members []string
+		for _, m := range cs.GetCommunityMember() {
+			members = append(members, communityString(m))
+		}
+		return setMatch{"community-set", name, cs != nil, opts, members, r.Communities, matchCommunity}
+	}
+	var matches []setMatch
+	if bc.CommunitySet != nil {
+		matches = append(matches, communitySet(bc.GetCommunitySet(), oc.RoutingPolicy_MatchSetOptionsType_ANY))
+	}
+	if m := bc.GetMatchCommunitySet(); m != nil {
+		matches = append(matches, communitySet(m.GetCommunitySet(), m.GetMatchSetOptions()))
+	}
+	if m := bc.GetMatchExtCommunitySet(); m != nil {
+		ecs := sets.GetExtCommunitySet(m.GetExtCommunitySet())
+		matches = append(matches, setMatch{"ext-community-set", m.GetExtCommunitySet(), ecs != nil, m.GetMatchSetOptions(), ecs.GetExtCommunityMember(), r.ExtCommunities, matchExtCommunity})
+	}
+	if m := bc.GetMatchAsPathSet(); m != nil {
+		aps := sets.GetAsPathSet(m.GetAsPathSet())
+		path := make([]string, len(r.ASPath))
+		for i, as := range r.ASPath {
+			path[i] = strconv.FormatUint(uint64(as), 10)
+		}
+		matches = append(matches, setMatch{"as-path-set", m.GetAsPathSet(), aps != nil, m.GetMatchSetOptions(), aps.GetAsPathSetMember(), []string{strings.Join(path, " ")}, matchASPath})
+	}
+
+	for _, m := range matches {
+		if !m.defined {
+			return false, fmt.Errorf("%s %s is not defined", m.kind, m.name)
+		}
+		matched := 0
+		for _, member := range m.members {
+			for _, v := range m.values {
+				ok, err := m.match(member, v)
+				if err != nil {
+					return false, fmt.Errorf("%s %s: %v", m.kind, m.name, err)
+				}
+				if ok {
+					matched++
+					break
+				}
+			}
+		}
+		var match bool
+		switch m.opts {
+		case oc.RoutingPolicy_MatchSetOptionsType_ALL:
+			match = matched == len(m.members)
+		case oc.RoutingPolicy_MatchSetOptionsType_INVERT:
+			match = matched == 0
+		default:
+			match = matched > 0
+		}
+		if !match {
+			return false, nil
+		}
+	}
+	return true, nil
+}
+
+// matchCommunity reports whether a community matches a member of a community set, which may
+// be a regular expression.
+func matchCommunity(member, community string) (bool, error) {
+	if member == community {
+		return true, nil
+	}
+	return regexp.MatchString("^(?:"+member+")$", community)
+}
+
+func matchExtCommunity(member, community string) (bool, error) {
+	if normalizeExtCommunity(member) == normalizeExtCommunity(community) {
+		return true, nil
+	}
+	return regexp.MatchString("^(?:"+member+")$", community)
+}
+
+// matchASPath reports whether the AS path, with the AS numbers separated by spaces, matches
+// the regular expression of an as-path-set member, where _ matches a boundary between AS
+// numbers.
+func matchASPath(member, path string) (bool, error) {
+	return regexp.MatchString(strings.ReplaceAll(member, "_", "(?:^| |$)"), path)
+}
+
+func (e *policyEvaluator) actions(a *oc.RoutingPolicy_PolicyDefinition_Statement_Actions_BgpActions, r *BGPRIBRoute) error {
+	if a == nil {
+		return nil
+	}
+	if a.SetRouteOrigin != oc.BgpPolicy_BgpOriginAttrType_UNSET {
+		return fmt.Errorf("unsupported action set-route-origin")
+	}
+	if a.SetLocalPref != nil {
+		r.LocalPref = ygot.Uint32(a.GetSetLocalPref())
+	}
+	switch med := a.GetSetMed().(type) {
+	case nil:
+	case oc.UnionUint32:
+		v := uint32(med)
+		switch a.GetSetMedAction() {
+		case oc.BgpPolicy_BgpSetMedAction_ADD:
+			v += r.med()
+		case oc.BgpPolicy_BgpSetMedAction_SUBTRACT:
+			v = r.med() - min(v, r.med())
+		}
+		r.MED = ygot.Uint32(v)
+	default:
+		return fmt.Errorf("unsupported set-med %v", med)
+	}
+	switch nh := a.GetSetNextHop().(type) {
+	case nil:
+	case oc.UnionString:
+		r.NextHop = string(nh)
+	case oc.E_BgpActions_SetNextHop:
+		if nh != oc.BgpActions_SetNextHop_SELF || e.opts.NextHopSelf == "" {
+			return fmt.Errorf("unsupported set-next-hop %v without PolicyEvalOptions.NextHopSelf", nh)
+		}
+		r.NextHop = e.opts.NextHopSelf
+	}
+	if p := a.GetSetAsPathPrepend(); p != nil {
+		asn := p.GetAsn()
+		if p.GetUseLastAs() && len(r.ASPath) > 0 {
+			asn = r.ASPath[0]
+		}
+		n := int(p.GetRepeatN())
+		if p.RepeatN == nil {
+			n = 1
+		}
+		var prepend []uint32
+		for range n {
+			prepend = append(prepend, asn)
+		}
+		r.ASPath = append(prepend, r.ASPath...)
+	}
+	// A set-community applies the communities of its method, or of both when the method is
+	// unset as it is for devices without support for it.
+	if sc := a.GetSetCommunity(); sc != nil {
+		var values []string
+		var refs []string
+		if sc.GetMethod() != oc.SetCommunity_Method_REFERENCE {
+			for _, c := range sc.GetInline().GetCommunities() {
+				values = append(values, communityString(c))
+			}
+		}
+		if sc.GetMethod() != oc.SetCommunity_Method_INLINE {
+			refs = sc.GetReference().GetCommunitySetRefs()
+			if ref := sc.GetReference().GetCommunitySetRef(); ref != "" {
+				refs = append(refs, ref)
+			}
+		}
+		for _, ref := range refs {
+			cs := e.rp.GetDefinedSets().GetBgpDefinedSets().GetCommunitySet(ref)
+			if cs == nil {
+				return fmt.Errorf("community-set %s is not defined", ref)
+			}
+			for _, m := range cs.GetCommunityMember() {
+				values = append(values, communityString(m))
+			}
+		}
+		var err error
+		if r.Communities, err = setCommunities(r.Communities, values, sc.GetOptions(), matchCommunity); err != nil {
+			return err
+		}
+	}
+	if sc := a.GetSetExtCommunity(); sc != nil {
+		var values []string
+		var refs []string
+		if sc.GetMethod() != oc.SetCommunity_Method_REFERENCE {
+			for _, c := range sc.GetInline().GetCommunities() {
+				values = append(values, communityString(c))
+			}
+		}
+		if sc.GetMethod() != oc.SetCommunity_Method_INLINE {
+			refs = sc.GetReference().GetExtCommunitySetRefs()
+			if ref := sc.GetReference().GetExtCommunitySetRef(); ref != "" {
+				refs = append(refs, ref)
+			}
+		}
+		for _, ref := range refs {
+			ecs := e.rp.GetDefinedSets().GetBgpDefinedSets().GetExtCommunitySet(ref)
+			if ecs == nil {
+				return fmt.Errorf("ext-community-set %s is not defined", ref)
+			}
+			values = append(values, ecs.GetExtCommunityMember()...)
+		}
+		var err error
+		if r.ExtCommunities, err = setCommunities(r.ExtCommunities, values, sc.GetOptions(), matchExtCommunity); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (r *BGPRIBRoute) med() uint32 {
+	if r.MED == nil {
+		return 0
+	}
+	return *r.MED
+}
+
+// setCommunities returns communities after adding, removing or replacing values. Removed
+// values may be regular expressions.
+func setCommunities(communities, values []string, opt oc.E_BgpPolicy_BgpSetCommunityOptionType, match func(member, value string) (bool, error)) ([]string, error) {
+	switch opt {
+	case oc.BgpPolicy_BgpSetCommunityOptionType_ADD:
+		for _, v := range values {
+			if !slices.Contains(communities, v) {
+				communities = append(communities, v)
+			}
+		}
+	case oc.BgpPolicy_BgpSetCommunityOptionType_REMOVE:
+		var kept []string
+		for _, c := range communities {
+			removed := false
+			for _, v := range values {
+				ok, err := match(v, c)
+				if err != nil {
+					return nil, err
+				}
+				removed = removed || ok
+			}
+			if !removed {
+				kept = append(kept, c)
+			}
+		}
+		communities = kept
+	case oc.BgpPolicy_BgpSetCommunityOptionType_REPLACE:
+		communities = slices.Clone(values)
+	default:
+		return nil, fmt.Errorf("unsupported set-community option %v", opt)
+	}
+	if communities == nil {
+		communities = []string{}
+	}
+	return communities, nil
+}
@@ -0,0 +1,472 @@
+// Copyright 2026 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cfgplugins
+
+import (
+	"encoding/binary"
+	"fmt"
+	"maps"
+	"math"
+	"net"
+	"reflect"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/openconfig/featureprofiles/internal/deviations"
+	"github.com/openconfig/ondatra"
+	"github.com/openconfig/ondatra/gnmi"
+	"github.com/openconfig/ondatra/gnmi/oc"
+	"github.com/openconfig/ondatra/gnmi/oc/netinstbgp"
+	"github.com/openconfig/ygnmi/ygnmi"
+)
+
+// BGPRIBType is a RIB of BGP routes of a network instance.
+type BGPRIBType int
+
+const (
+	// BGPLocRIB is the loc-rib of routes selected by the DUT.
+	BGPLocRIB BGPRIBType = iota
+	// BGPAdjRIBInPre is the adj-rib-in of a neighbor before import policy.
+	BGPAdjRIBInPre
+	// BGPAdjRIBInPost is the adj-rib-in of a neighbor after import policy.
+	BGPAdjRIBInPost
+	// BGPAdjRIBOutPre is the adj-rib-out of a neighbor before export policy.
+	BGPAdjRIBOutPre
+	// BGPAdjRIBOutPost is the adj-rib-out of a neighbor after export policy.
+	BGPAdjRIBOutPost
+)
+
+// String returns the name of the RIB in the OpenConfig model.
+func (r BGPRIBType) String() string {
+	switch r {
+	case BGPLocRIB:
+		return "loc-rib"
+	case BGPAdjRIBInPre:
+		return "adj-rib-in-pre"
+	case BGPAdjRIBInPost:
+		return "adj-rib-in-post"
+	case BGPAdjRIBOutPre:
+		return "adj-rib-out-pre"
+	case BGPAdjRIBOutPost:
+		return "adj-rib-out-post"
+	}
+	return fmt.Sprintf("BGPRIBType(%d)", int(r))
+}
+
+// ribField returns the name of the field of the RIB in the oc structs.
+func (r BGPRIBType) ribField() string {
+	return map[BGPRIBType]string{
+		BGPLocRIB:        "LocRib",
+		BGPAdjRIBInPre:   "AdjRibInPre",
+		BGPAdjRIBInPost:  "AdjRibInPost",
+		BGPAdjRIBOutPre:  "AdjRibOutPre",
+		BGPAdjRIBOutPost: "AdjRibOutPost",
+	}[r]
+}
+
+// BGPRIB selects the routes of a BGP RIB of the DUT.
+type BGPRIB struct {
+	// NetworkInstance is the network instance of the BGP protocol, or the default network
+	// instance if empty.
+	NetworkInstance string
+	AFISAFI         oc.E_BgpTypes_AFI_SAFI_TYPE
+	RIB             BGPRIBType
+	// Neighbor is the neighbor of an adj-rib, which is ignored for the loc-rib.
+	Neighbor string
+	// Exact reports routes of the RIB that are not expected.
+	Exact bool
+}
+
+// BGPRIBRoute is a BGP route with its path attributes. Attributes that are nil are not
+// compared, so an empty slice expects a route without any communities or AS path.
+type BGPRIBRoute struct {
+	Prefix string
+	// Origin is the neighbor address or the protocol a loc-rib route was learned from, for
+	// example "192.0.2.2" or "STATIC". It is empty for adj-rib routes, and routes expected
+	// without an origin match a route of the prefix and path ID from any origin.
+	Origin string
+	PathID uint32
+	ASPath []uint32
+	// Communities are standard communities formatted as "<as>:<value>" or the name of a well
+	// known community.
+	Communities []string
+	// ExtCommunities are extended communities as in the OpenConfig model, for example
+	// "route-target:65000:100" or those returned by LinkBandwidthExtCommunity.
+	ExtCommunities []string
+	MED            *uint32
+	LocalPref      *uint32
+	NextHop        string
+}
+
+// key identifies the route in a RIB with add-paths and routes from several origins.
+func (r *BGPRIBRoute) key() string {
+	k := r.Prefix
+	if r.Origin != "" {
+		k += " origin " + r.Origin
+	}
+	if r.PathID != 0 {
+		k += fmt.Sprintf(" path-id %d", r.PathID)
+	}
+	return k
+}
+
+// LinkBandwidthExtCommunity returns the link-bandwidth extended community of globalAS and
+// bandwidth in bytes per second as BGPRIBRoute reports it, matching the community built by
+// otgconfighelpers.CreateBGPLinkBandwidthExtCommunity with the same arguments.
+func LinkBandwidthExtCommunity(globalAS uint32, bandwidth float32) string {
+	return fmt.Sprintf("link-bandwidth:%d:%s", globalAS, strconv.FormatFloat(float64(bandwidth), 'f', -1, 32))
+}
+
+// normalizeExtCommunity expands the K, M and G suffixes of link-bandwidth communities so that
+// "link-bandwidth:23456:1M" matches "link-bandwidth:23456:1000000".
+func normalizeExtCommunity(c string) string {
+	parts := strings.Split(c, ":")
+	if len(parts) != 3 || parts[0] != "link-bandwidth" {
+		return c
+	}
+	bw, mult := parts[2], 1.0
+	if n := len(bw); n > 0 {
+		switch bw[n-1] {
+		case 'K':
+			mult = 1e3
+		case 'M':
+			mult = 1e6
+		case 'G':
+			mult = 1e9
+		}
+		if mult != 1 {
+			bw = bw[:n-1]
+		}
+	}
+	f, err := strconv.ParseFloat(bw, 32)
+	if err != nil {
+		return c
+	}
+	as, err := strconv.ParseUint(parts[1], 10, 32)
+	if err != nil {
+		return c
+	}
+	return LinkBandwidthExtCommunity(uint32(as), float32(f*mult))
+}
+
+// extCommunityString formats an extended community reported in binary as in the
+// OpenConfig model.
+func extCommunityString(b []byte) string {
+	if len(b) != 8 {
+		return fmt.Sprintf("0x%x", b)
+	}
+	kind := map[byte]string{0x02: "route-target", 0x03: "route-origin"}[b[1]]
+	switch {
+	case b[0] == 0x40 && b[1] == 0x04:
+		return LinkBandwidthExtCommunity(uint32(binary.BigEndian.Uint16(b[2:4])), math.Float32frombits(binary.BigEndian.Uint32(b[4:8])))
+	case kind != "" && b[0]&0x3f == 0x00:
+		return fmt.Sprintf("%s:%d:%d", kind, binary.BigEndian.Uint16(b[2:4]), binary.BigEndian.Uint32(b[4:8]))
+	case kind != "" && b[0]&0x3f == 0x01:
+		return fmt.Sprintf("%s:%s:%d", kind, net.IP(b[2:6]), binary.BigEndian.Uint16(b[6:8]))
+	case kind != "" && b[0]&0x3f == 0x02:
+		return fmt.Sprintf("%s:%d:%d", kind, binary.BigEndian.Uint32(b[2:6]), binary.BigEndian.Uint16(b[6:8]))
+	}
+	return fmt.Sprintf("0x%x", b)
+}
+
+// communityString formats a standard community of a RIB or routing policy union as
+// "<as>:<value>".
+func communityString(c any) string {
+	switch v := c.(type) {
+	case oc.UnionUint32:
+		return fmt.Sprintf("%d:%d", uint32(v)>>16, uint32(v)&0xffff)
+	case oc.UnionString:
+		return string(v)
+	case oc.E_BgpTypes_BGP_WELL_KNOWN_STD_COMMUNITY:
+		return v.String()
+	}
+	return fmt.Sprint(c)
+}
+
+// ribIndex returns the value of the index field of a route, or false if it is unset.
+func ribIndex(route reflect.Value, field string) (uint64, bool) {
+	f := route.FieldByName(field)
+	if !f.IsValid() || f.IsNil() {
+		return 0, false
+	}
+	return f.Elem().Uint(), true
+}
+
+// ribRouteTable returns the struct with the Route list of the routes selected by sel.
+func ribRouteTable(rib *oc.NetworkInstance_Protocol_Bgp_Rib, sel BGPRIB) (reflect.Value, error) {
+	var afi reflect.Value
+	switch sel.AFISAFI {
+	case oc.BgpTypes_AFI_SAFI_TYPE_IPV4_UNICAST:
+		afi = reflect.ValueOf(rib.GetAfiSafi(sel.AFISAFI).GetIpv4Unicast())
+	case oc.BgpTypes_AFI_SAFI_TYPE_IPV6_UNICAST:
+		afi = reflect.ValueOf(rib.GetAfiSafi(sel.AFISAFI).GetIpv6Unicast())
+	default:
+		return reflect.Value{}, fmt.Errorf("unsupported AFI-SAFI %v", sel.AFISAFI)
+	}
+	if afi.IsNil() {
+		return reflect.Value{}, nil
+	}
+	afi = afi.Elem()
+	if sel.RIB != BGPLocRIB {
+		afi = afi.FieldByName("Neighbor").MapIndex(reflect.ValueOf(sel.Neighbor))
+		if !afi.IsValid() {
+			return reflect.Value{}, nil
+		}
+		afi = afi.Elem()
+	}
+	field := sel.RIB.ribField()
+	if field == "" {
+		return reflect.Value{}, fmt.Errorf("unsupported RIB %v", sel.RIB)
+	}
+	table := afi.FieldByName(field)
+	if table.IsNil() {
+		return reflect.Value{}, nil
+	}
+	return table.Elem(), nil
+}
+
+// BGPRIBRoutes returns the routes selected by sel from rib with the path attributes and
+// communities they reference, sorted by prefix, origin and path ID.
+func BGPRIBRoutes(rib *oc.NetworkInstance_Protocol_Bgp_Rib, sel BGPRIB) ([]*BGPRIBRoute, error) {
+	table, err := ribRouteTable(rib, sel)
+	if err != nil || !table.IsValid() {
+		return nil, err
+	}
+	var routes []*BGPRIBRoute
+	for it := table.FieldByName("Route").MapRange(); it.Next(); {
+		rv := it.Value().Elem()
+		r := &BGPRIBRoute{
+			Prefix: rv.FieldByName("Prefix").Elem().String(),
+		}
+		if id := rv.FieldByName("PathId"); !id.IsNil() {
+			r.PathID = uint32(id.Elem().Uint())
+		}
+		if o := rv.FieldByName("Origin"); o.IsValid() && !o.IsNil() {
+			r.Origin = ribOrigin(o.Interface())
+		}
+		if idx, ok := ribIndex(rv, "AttrIndex"); ok {
+			attrs := rib.GetAttrSet(idx)
+			if attrs == nil {
+				return nil, fmt.Errorf("route %s references missing attr-set %d", r.key(), idx)
+			}
+			r.ASPath = ribASPath(attrs)
+			r.MED, r.LocalPref, r.NextHop = attrs.Med, attrs.LocalPref, attrs.GetNextHop()
+		}
+		if idx, ok := ribIndex(rv, "CommunityIndex"); ok {
+			c := rib.GetCommunity(idx)
+			if c == nil {
+				return nil, fmt.Errorf("route %s references missing community %d", r.key(), idx)
+			}
+			for _, v := range c.GetCommunity() {
+				r.Communities = append(r.Communities, communityString(v))
+			}
+		}
+		if idx, ok := ribIndex(rv, "ExtCommunityIndex"); ok {
+			c := rib.GetExtCommunity(idx)
+			if c == nil {
+				return nil, fmt.Errorf("route %s references missing ext-community %d", r.key(), idx)
+			}
+			for _, v := range c.GetExtCommunity() {
+				switch v := v.(type) {
+				case oc.Binary:
+					r.ExtCommunities = append(r.ExtCommunities, extCommunityString(v))
+				case oc.UnionString:
+					r.ExtCommunities = append(r.ExtCommunities, string(v))
+				}
+			}
+		}
+		routes = append(routes, r)
+	}
+	sort.Slice(routes, func(i, j int) bool {
+		if routes[i].Prefix != routes[j].Prefix {
+			return routes[i].Prefix < routes[j].Prefix
+		}
+		if routes[i].Origin != routes[j].Origin {
+			return routes[i].Origin < routes[j].Origin
+		}
+		return routes[i].PathID < routes[j].PathID
+	})
+	return routes, nil
+}
+
+// ribOrigin formats the origin key of a loc-rib route, a neighbor address or a protocol.
+func ribOrigin(o any) string {
+	switch v := o.(type) {
+	case oc.UnionString:
+		return string(v)
+	case oc.E_PolicyTypes_INSTALL_PROTOCOL_TYPE:
+		return v.String()
+	}
+	return fmt.Sprint(o)
+}
+
+// ribASPath returns the AS path of attrs, preferring the 4-octet AS path when reported.
+func ribASPath(attrs *oc.NetworkInstance_Protocol_Bgp_Rib_AttrSet) []uint32 {
+	segments := map[uint32][]uint32{}
+	for i, s := range attrs.AsSegment {
+		segments[i] = s.GetMember()
+	}
+	if len(attrs.As4Segment) > 0 {
+		segments = map[uint32][]uint32{}
+		for i, s := range attrs.As4Segment {
+			segments[i] = s.GetMember()
+		}
+	}
+	path := []uint32{}
+	for _, i := range slices.Sorted(maps.Keys(segments)) {
+		path = append(path, segments[i]...)
+	}
+	return path
+}
+
+// DiffBGPRIBRoutes compares the routes of a RIB with the expected routes and returns the
+// differences keyed by prefix. Routes that are not expected are only reported if exact is set.
+func DiffBGPRIBRoutes(got, want []*BGPRIBRoute, exact bool) map[string]string {
+	gotByKey := map[string]*BGPRIBRoute{}
+	for _, r := range got {
+		gotByKey[r.key()] = r
+	}
+	diffs := map[string][]string{}
+	for _, w := range want {
+		g, ok := gotByKey[w.key()]
+		if !ok && w.Origin == "" {
+			// Match the first remaining route of the prefix and path ID from any origin.
+			for _, r := range got {
+				if r.Prefix == w.Prefix && r.PathID == w.PathID && gotByKey[r.key()] == r {
+					g, ok = r, true
+					break
+				}
+			}
+		}
+		if !ok {
+			diffs[w.Prefix] = append(diffs[w.Prefix], fmt.Sprintf("%s: missing", w.key()))
+			continue
+		}
+		delete(gotByKey, g.key())
+		for _, d := range diffBGPRIBRoute(g, w) {
+			diffs[w.Prefix] = append(diffs[w.Prefix], fmt.Sprintf("%s: %s", w.key(), d))
+		}
+	}
+	if exact {
+		for _, g := range gotByKey {
+			diffs[g.Prefix] = append(diffs[g.Prefix], fmt.Sprintf("%s: unexpected", g.key()))
+		}
+	}
+	out := map[string]string{}
+	for prefix, d := range diffs {
+		sort.Strings(d)
+		out[prefix] = strings.Join(d, "\n")
+	}
+	return out
+}
+
+// diffBGPRIBRoute returns the attributes of got that differ from the attributes set in want.
+func diffBGPRIBRoute(got, want *BGPRIBRoute) []string {
+	var diffs []string
+	if want.ASPath != nil && !slices.Equal(got.ASPath, want.ASPath) {
+		diffs = append(diffs, fmt.Sprintf("AS path got %v, want %v", got.ASPath, want.ASPath))
+	}
+	if want.Communities != nil && !sameSet(got.Communities, want.Communities, nil) {
+		diffs = append(diffs, fmt.Sprintf("communities got %v, want %v", got.Communities, want.Communities))
+	}
+	if want.ExtCommunities != nil && !sameSet(got.ExtCommunities, want.ExtCommunities, normalizeExtCommunity) {
+		diffs = append(diffs, fmt.Sprintf("extended communities got %v, want %v", got.ExtCommunities, want.ExtCommunities))
+	}
+	if want.MED != nil && (got.MED == nil || *got.MED != *want.MED) {
+		diffs = append(diffs, fmt.Sprintf("MED got %s, want %d", optionalUint32(got.MED), *want.MED))
+	}
+	if want.LocalPref != nil && (got.LocalPref == nil || *got.LocalPref != *want.LocalPref) {
+		diffs = append(diffs, fmt.Sprintf("local preference got %s, want %d", optionalUint32(got.LocalPref), *want.LocalPref))
+	}
+	if want.NextHop != "" && got.NextHop != want.NextHop {
+		diffs = append(diffs, fmt.Sprintf("next hop got %q, want %q", got.NextHop, want.NextHop))
+	}
+	return diffs
+}
+
+// sameSet reports whether a and b hold the same values in any order after normalize.
+func sameSet(a, b []string, normalize func(string) string) bool {
+	norm := func(s []string) []string {
+		out := slices.Clone(s)
+		if normalize != nil {
+			for i := range out {
+				out[i] = normalize(out[i])
+			}
+		}
+		slices.Sort(out)
+		return out
+	}
+	return slices.Equal(norm(a), norm(b))
+}
+
+func optionalUint32(v *uint32) string {
+	if v == nil {
+		return "unset"
+	}
+	return strconv.FormatUint(uint64(*v), 10)
+}
+
+// bgpRIBPath returns the path of the BGP RIB of the network instance selected by sel.
+func bgpRIBPath(dut *ondatra.DUTDevice, sel BGPRIB) *netinstbgp.NetworkInstance_Protocol_Bgp_RibPath {
+	ni := sel.NetworkInstance
+	if ni == "" {
+		ni = deviations.DefaultNetworkInstance(dut)
+	}
+	return gnmi.OC().NetworkInstance(ni).Protocol(PTBGP, bgpName).Bgp().Rib()
+}
+
+// GetBGPRIBRoutes returns the routes of the BGP RIB of the DUT selected by sel.
+func GetBGPRIBRoutes(t *testing.T, dut *ondatra.DUTDevice, sel BGPRIB) []*BGPRIBRoute {
+	t.Helper()
+	routes, err := BGPRIBRoutes(gnmi.Get(t, dut, bgpRIBPath(dut, sel).State()), sel)
+	if err != nil {
+		t.Fatalf("Failed to read BGP %v of neighbor %q: %v", sel.RIB, sel.Neighbor, err)
+	}
+	return routes
+}
+
+// VerifyBGPRIB waits for the BGP RIB of the DUT selected by sel to hold the routes of want
+// with their attributes, and reports the differences per prefix otherwise.
+func VerifyBGPRIB(t *testing.T, dut *ondatra.DUTDevice, sel BGPRIB, want []*BGPRIBRoute) {
+	t.Helper()
+	var diffs map[string]string
+	_, ok := gnmi.Watch(t, dut, bgpRIBPath(dut, sel).State(), routeTimeout, func(val *ygnmi.Value[*oc.NetworkInstance_Protocol_Bgp_Rib]) bool {
+		rib, present := val.Val()
+		if !present {
+			return false
+		}
+		got, err := BGPRIBRoutes(rib, sel)
+		if err != nil {
+			diffs = map[string]string{"": err.Error()}
+			return false
+		}
+		diffs = DiffBGPRIBRoutes(got, want, sel.Exact)
+		return len(diffs) == 0
+	}).Await(t)
+	if ok {
+		t.Logf("BGP %v of neighbor %q holds the %d expected routes", sel.RIB, sel.Neighbor, len(want))
+		return
+	}
+	if diffs == nil {
+		t.Errorf("BGP %v of neighbor %q is not reported", sel.RIB, sel.Neighbor)
+		return
+	}
+	for _, prefix := range slices.Sorted(maps.Keys(diffs)) {
+		t.Errorf("BGP %v of neighbor %q differs:\n%s", sel.RIB, sel.Neighbor, diffs[prefix])
+	}
+}
@@ -0,0 +1,175 @@
+// Copyright 2026 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cfgplugins
+
+import (
+	"encoding/binary"
+	"math"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/openconfig/ondatra/gnmi/oc"
+	"github.com/openconfig/ygot/ygot"
+)
+
+// ribWithRoutes returns a RIB with 198.51.100.0/24 in the loc-rib and the adj-rib-in-post of
+// neighbor 192.0.2.2, and 198.51.101.0/24 only in the loc-rib.
+func ribWithRoutes() *oc.NetworkInstance_Protocol_Bgp_Rib {
+	rib := &oc.NetworkInstance_Protocol_Bgp_Rib{}
+	attrs := rib.GetOrCreateAttrSet(0)
+	attrs.SetMed(50)
+	attrs.SetLocalPref(200)
+	attrs.SetNextHop("192.0.2.2")
+	attrs.GetOrCreateAsSegment(0).SetMember([]uint32{65502, 65503})
+	rib.GetOrCreateCommunity(0).SetCommunity([]oc.NetworkInstance_Protocol_Bgp_Rib_Community_Community_Union{
+		oc.UnionUint32(100<<16 | 100),
+		oc.BgpTypes_BGP_WELL_KNOWN_STD_COMMUNITY_NO_EXPORT,
+	})
+	lbw := make([]byte, 8)
+	lbw[0], lbw[1] = 0x40, 0x04
+	binary.BigEndian.PutUint16(lbw[2:4], 23456)
+	binary.BigEndian.PutUint32(lbw[4:8], math.Float32bits(1e6))
+	rib.GetOrCreateExtCommunity(1).SetExtCommunity([]oc.NetworkInstance_Protocol_Bgp_Rib_ExtCommunity_ExtCommunity_Union{
+		oc.Binary(lbw),
+		oc.UnionString("route-target:65000:100"),
+	})
+
+	v4 := rib.GetOrCreateAfiSafi(oc.BgpTypes_AFI_SAFI_TYPE_IPV4_UNICAST).GetOrCreateIpv4Unicast()
+	for _, prefix := range []string{"198.51.100.0/24", "198.51.101.0/24"} {
+		r := v4.GetOrCreateLocRib().GetOrCreateRoute(prefix, oc.UnionString("192.0.2.2"), 0)
+		r.SetAttrIndex(0)
+		r.SetCommunityIndex(0)
+	}
+	r := v4.GetOrCreateNeighbor("192.0.2.2").GetOrCreateAdjRibInPost().GetOrCreateRoute("198.51.100.0/24", 0)
+	r.SetAttrIndex(0)
+	r.SetExtCommunityIndex(1)
+	return rib
+}
+
+func TestBGPRIBRoutes(t *testing.T) {
+	sel := BGPRIB{AFISAFI: oc.BgpTypes_AFI_SAFI_TYPE_IPV4_UNICAST, RIB: BGPAdjRIBInPost, Neighbor: "192.0.2.2"}
+	got, err := BGPRIBRoutes(ribWithRoutes(), sel)
+	if err != nil {
+		t.Fatalf("BGPRIBRoutes() got error: %v", err)
+	}
+	want := []*BGPRIBRoute{{
+		Prefix:         "198.51.100.0/24",
+		ASPath:         []uint32{65502, 65503},
+		ExtCommunities: []string{"link-bandwidth:23456:1000000", "route-target:65000:100"},
+		MED:            ygot.Uint32(50),
+		LocalPref:      ygot.Uint32(200),
+		NextHop:        "192.0.2.2",
+	}}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("BGPRIBRoutes() diff (-want +got):\n%s", diff)
+	}
+
+	sel.Neighbor = "192.0.2.6"
+	if got, err := BGPRIBRoutes(ribWithRoutes(), sel); err != nil || len(got) != 0 {
+		t.Errorf("BGPRIBRoutes() of an unknown neighbor got %v, %v, want no routes", got, err)
+	}
+}
+
+func TestDiffBGPRIBRoutes(t *testing.T) {
+	got, err := BGPRIBRoutes(ribWithRoutes(), BGPRIB{AFISAFI: oc.BgpTypes_AFI_SAFI_TYPE_IPV4_UNICAST, RIB: BGPLocRIB})
+	if err != nil {
+		t.Fatalf("BGPRIBRoutes() got error: %v", err)
+	}
+	tests := []struct {
+		desc  string
+		want  []*BGPRIBRoute
+		exact bool
+		diffs map[string]string
+	}{{
+		desc: "match",
+		want: []*BGPRIBRoute{{
+			Prefix:      "198.51.100.0/24",
+			ASPath:      []uint32{65502, 65503},
+			Communities: []string{"NO_EXPORT", "100:100"},
+			LocalPref:   ygot.Uint32(200),
+		}},
+		diffs: map[string]string{},
+	}, {
+		desc: "attributes",
+		want: []*BGPRIBRoute{{
+			Prefix:         "198.51.100.0/24",
+			MED:            ygot.Uint32(10),
+			NextHop:        "192.0.2.6",
+			ExtCommunities: []string{},
+		}, {
+			Prefix: "203.0.113.0/24",
+		}},
+		diffs: map[string]string{
+			"198.51.100.0/24": "198.51.100.0/24: MED got 50, want 10\n198.51.100.0/24: next hop got \"192.0.2.2\", want \"192.0.2.6\"",
+			"203.0.113.0/24":  "203.0.113.0/24: missing",
+		},
+	}, {
+		desc:  "exact",
+		want:  []*BGPRIBRoute{{Prefix: "198.51.100.0/24"}},
+		exact: true,
+		diffs: map[string]string{"198.51.101.0/24": "198.51.101.0/24 origin 192.0.2.2: unexpected"},
+	}}
+	for _, tc := range tests {
+		t.Run(tc.desc, func(t *testing.T) {
+			if diff := cmp.Diff(tc.diffs, DiffBGPRIBRoutes(got, tc.want, tc.exact)); diff != "" {
+				t.Errorf("DiffBGPRIBRoutes() diff (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestBGPRIBRoutesOrigins(t *testing.T) {
+	rib := ribWithRoutes()
+	v4 := rib.GetAfiSafi(oc.BgpTypes_AFI_SAFI_TYPE_IPV4_UNICAST).GetIpv4Unicast()
+	v4.GetLocRib().GetOrCreateRoute("198.51.100.0/24", oc.PolicyTypes_INSTALL_PROTOCOL_TYPE_STATIC, 0).SetAttrIndex(0)
+	got, err := BGPRIBRoutes(rib, BGPRIB{AFISAFI: oc.BgpTypes_AFI_SAFI_TYPE_IPV4_UNICAST, RIB: BGPLocRIB})
+	if err != nil {
+		t.Fatalf("BGPRIBRoutes() got error: %v", err)
+	}
+	var keys []string
+	for _, r := range got {
+		keys = append(keys, r.key())
+	}
+	wantKeys := []string{
+		"198.51.100.0/24 origin 192.0.2.2",
+		"198.51.100.0/24 origin STATIC",
+		"198.51.101.0/24 origin 192.0.2.2",
+	}
+	if diff := cmp.Diff(wantKeys, keys); diff != "" {
+		t.Errorf("BGPRIBRoutes() keys diff (-want +got):\n%s", diff)
+	}
+
+	want := []*BGPRIBRoute{
+		{Prefix: "198.51.100.0/24", Origin: "STATIC", Communities: []string{}},
+		{Prefix: "198.51.100.0/24", Origin: "192.0.2.2", Communities: []string{"100:100", "NO_EXPORT"}},
+		{Prefix: "198.51.101.0/24"},
+	}
+	if diffs := DiffBGPRIBRoutes(got, want, true); len(diffs) != 0 {
+		t.Errorf("DiffBGPRIBRoutes() of both origins got %v, want none", diffs)
+	}
+	wantDiffs := map[string]string{"198.51.100.0/24": "198.51.100.0/24 origin STATIC: unexpected"}
+	if diff := cmp.Diff(wantDiffs, DiffBGPRIBRoutes(got, want[1:], true)); diff != "" {
+		t.Errorf("DiffBGPRIBRoutes() without the STATIC route diff (-want +got):\n%s", diff)
+	}
+}
+
+func TestLinkBandwidthExtCommunity(t *testing.T) {
+	want := LinkBandwidthExtCommunity(23456, 1e6)
+	for _, c := range []string{"link-bandwidth:23456:1M", "link-bandwidth:23456:1000K", "link-bandwidth:23456:1000000"} {
+		if got := normalizeExtCommunity(c); got != want {
+			t.Errorf("normalizeExtCommunity(%q) got %q, want %q", c, got, want)
+		}
+	}
+}
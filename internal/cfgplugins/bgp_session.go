@@ -0,0 +1,451 @@
+// Copyright 2026 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cfgplugins
+
+import (
+	"fmt"
+	"sort"
+	"testing"
+
+	"github.com/open-traffic-generator/snappi/gosnappi"
+	"github.com/openconfig/featureprofiles/internal/attrs"
+	"github.com/openconfig/featureprofiles/internal/deviations"
+	"github.com/openconfig/featureprofiles/internal/vendorcli"
+	"github.com/openconfig/ondatra/gnmi/oc"
+	"github.com/openconfig/ondatra/netutil"
+)
+
+const (
+	// BGPPeerGroupIBGP for the iBGP peers of WithIBGP
+	BGPPeerGroupIBGP = "BGP-PEER-GROUP-IBGP"
+	// BGPPeerGroupMultihop for the eBGP peers of WithEBGPMultihop
+	BGPPeerGroupMultihop = "BGP-PEER-GROUP-MULTIHOP"
+
+	bgpUnderlayISISName = "DEFAULT"
+	bgpUnderlayISISArea = "49.0001"
+)
+
+var dutLoopback = &attrs.Attributes{
+	Desc:    "BGP loopback",
+	IPv4:    "203.0.113.1",
+	IPv4Len: 32,
+	IPv6:    "2001:db8:203::1",
+	IPv6Len: 128,
+}
+
+// BGPUnderlay is the routing between the loopbacks of the DUT and the ATE that iBGP and
+// multihop eBGP sessions are established over.
+type BGPUnderlay int
+
+const (
+	// BGPUnderlayStatic routes the loopbacks of the ATE with static routes on the DUT.
+	BGPUnderlayStatic BGPUnderlay = iota
+	// BGPUnderlayISIS advertises the loopbacks of the DUT and the ATE with ISIS.
+	BGPUnderlayISIS
+)
+
+// bgpSessionNeighbor is a BGP neighbor of the DUT configured by a BGPSession, with its peers on
+// the ATE, if any.
+type bgpSessionNeighbor struct {
+	port    string
+	address string
+	afiType oc.E_BgpTypes_AFI_SAFI_TYPE
+	ibgp    bool
+	otgV4   gosnappi.BgpV4Peer
+	otgV6   gosnappi.BgpV6Peer
+}
+
+// ateLoopback returns the loopback attributes of the ATE behind the i-th port of a session.
+func ateLoopback(i int) *attrs.Attributes {
+	return &attrs.Attributes{
+		IPv4:    fmt.Sprintf("203.0.113.1%d", i+1),
+		IPv4Len: 32,
+		IPv6:    fmt.Sprintf("2001:db8:203::1%d", i+1),
+		IPv6Len: 128,
+	}
+}
+
+// ateAS returns the AS of the eBGP peers of the ATE behind the i-th port of a session:
+// AteAS1 to AteAS4 for the first four ports, and the following AS numbers after them.
+func ateAS(i int) uint32 {
+	return AteAS1 + uint32(i)
+}
+
+// bgp returns the BGP configuration of the DUT, creating it with the global configuration of
+// the session if needed.
+func (bs *BGPSession) bgp(afiTypes []oc.E_BgpTypes_AFI_SAFI_TYPE) *oc.NetworkInstance_Protocol_Bgp {
+	bgp := bs.DUTConf.GetOrCreateNetworkInstance(bs.networkInstance).GetOrCreateProtocol(PTBGP, bgpName).GetOrCreateBgp()
+	global := bgp.GetOrCreateGlobal()
+	if global.As == nil {
+		global.SetAs(DutAS)
+	}
+	if global.RouterId == nil {
+		global.SetRouterId(dutPort1.IPv4)
+	}
+	for _, afiType := range afiTypes {
+		global.GetOrCreateAfiSafi(afiType).SetEnabled(true)
+	}
+	return bgp
+}
+
+// otgDevices returns the devices of the ATE topology by port order, or nil if the session has
+// no ATE.
+func (bs *BGPSession) otgDevices() []gosnappi.Device {
+	if bs.ATETop == nil {
+		return nil
+	}
+	devices := bs.ATETop.Devices().Items()
+	sort.Slice(devices, func(i, j int) bool { return devices[i].Name() < devices[j].Name() })
+	return devices
+}
+
+// addAFITypes records the AFI types the ATE resolves the DUT addresses of in PushAndStartATE.
+func (bs *BGPSession) addAFITypes(afiTypes []oc.E_BgpTypes_AFI_SAFI_TYPE) {
+	for _, afiType := range afiTypes {
+		if !containsValue(bs.afiTypes, afiType) {
+			bs.afiTypes = append(bs.afiTypes, afiType)
+		}
+	}
+}
+
+// WithIBGP adds iBGP sessions between a loopback of the DUT and loopbacks of the ATE behind
+// bgpPorts, which are reached over the underlay. Without an ATE, only the DUT is configured.
+func (bs *BGPSession) WithIBGP(t *testing.T, afiTypes []oc.E_BgpTypes_AFI_SAFI_TYPE, bgpPorts []string, underlay BGPUnderlay) *BGPSession {
+	bs.withLoopbackPeers(t, afiTypes, bgpPorts, underlay, true, 0)
+	return bs
+}
+
+// WithEBGPMultihop adds eBGP sessions between a loopback of the DUT and loopbacks of the ATE
+// behind bgpPorts, which are reached over the underlay, with the given multihop TTL. With
+// ttlSecurity, the DUT also only accepts packets of the peers sent with a TTL of 255 less the
+// hops to the peer, and the ATE peers send packets with a TTL of 255. The DUT must support the
+// vendorcli.BGPTTLSecurity feature for ttlSecurity, as OpenConfig does not model it.
+func (bs *BGPSession) WithEBGPMultihop(t *testing.T, afiTypes []oc.E_BgpTypes_AFI_SAFI_TYPE, bgpPorts []string, underlay BGPUnderlay, multihopTTL uint8, ttlSecurity bool) *BGPSession {
+	if multihopTTL < 2 {
+		t.Fatalf("Multihop TTL %d is less than 2", multihopTTL)
+	}
+	for _, n := range bs.withLoopbackPeers(t, afiTypes, bgpPorts, underlay, false, multihopTTL) {
+		if !ttlSecurity {
+			continue
+		}
+		params := vendorcli.BGPTTLSecurityParams{AS: DutAS, Neighbor: n.address, MaxHops: multihopTTL}
+		if bs.networkInstance != deviations.DefaultNetworkInstance(bs.DUT) {
+			params.VRF = bs.networkInstance
+		}
+		bs.dutCLI = append(bs.dutCLI, vendorcli.BGPTTLSecurity.MustRender(t, bs.DUT, params))
+		if n.otgV4 != nil {
+			n.otgV4.Advanced().SetTimeToLive(255)
+		}
+		if n.otgV6 != nil {
+			n.otgV6.Advanced().SetTimeToLive(255)
+		}
+	}
+	return bs
+}
+
+// withLoopbackPeers configures BGP sessions between loopbacks of the DUT and the ATE behind
+// bgpPorts, and returns their neighbors.
+func (bs *BGPSession) withLoopbackPeers(t *testing.T, afiTypes []oc.E_BgpTypes_AFI_SAFI_TYPE, bgpPorts []string, underlay BGPUnderlay, ibgp bool, multihopTTL uint8) []*bgpSessionNeighbor {
+	t.Helper()
+	for _, afiType := range afiTypes {
+		if afiType != oc.BgpTypes_AFI_SAFI_TYPE_IPV4_UNICAST && afiType != oc.BgpTypes_AFI_SAFI_TYPE_IPV6_UNICAST {
+			t.Fatalf("Unsupported AFI type: %v", afiType)
+		}
+	}
+	bs.addAFITypes(afiTypes)
+	bs.configureDUTLoopback(t)
+
+	bgp := bs.bgp(afiTypes)
+	pgName, asType4, asType6 := BGPPeerGroupIBGP, gosnappi.BgpV4PeerAsType.IBGP, gosnappi.BgpV6PeerAsType.IBGP
+	if !ibgp {
+		pgName, asType4, asType6 = BGPPeerGroupMultihop, gosnappi.BgpV4PeerAsType.EBGP, gosnappi.BgpV6PeerAsType.EBGP
+	}
+	if bgp.GetPeerGroup(pgName) == nil {
+		bgp.AppendPeerGroup(getPeerGroup(pgName, bs.DUT, afiTypes))
+	}
+
+	devices := bs.otgDevices()
+	var neighbors []*bgpSessionNeighbor
+	for i, atePort := range bs.ATEPorts {
+		if !containsValue(bgpPorts, atePort.Name) {
+			continue
+		}
+		lo := ateLoopback(i)
+		bs.configureUnderlay(t, i, lo, underlay)
+		as := DutAS
+		if !ibgp {
+			as = ateAS(i)
+		}
+
+		var dev gosnappi.Device
+		var otgLo4 gosnappi.DeviceIpv4Loopback
+		var otgLo6 gosnappi.DeviceIpv6Loopback
+		if devices != nil {
+			dev = devices[i]
+			otgLo4, otgLo6 = otgLoopbacks(dev, lo)
+			if !dev.HasBgp() {
+				dev.Bgp().SetRouterId(lo.IPv4)
+			}
+		}
+
+		for _, afiType := range afiTypes {
+			n := &bgpSessionNeighbor{port: atePort.Name, afiType: afiType, ibgp: ibgp}
+			switch afiType {
+			case oc.BgpTypes_AFI_SAFI_TYPE_IPV4_UNICAST:
+				n.address = lo.IPv4
+				if dev != nil {
+					n.otgV4 = dev.Bgp().Ipv4Interfaces().Add().SetIpv4Name(otgLo4.Name()).Peers().Add().SetName(dev.Name() + ".BGP4.lo.peer")
+					n.otgV4.SetPeerAddress(dutLoopback.IPv4).SetAsNumber(as).SetAsType(asType4)
+					n.otgV4.LearnedInformationFilter().SetUnicastIpv4Prefix(true)
+					if multihopTTL > 0 {
+						n.otgV4.Advanced().SetTimeToLive(uint32(multihopTTL))
+					}
+				}
+			case oc.BgpTypes_AFI_SAFI_TYPE_IPV6_UNICAST:
+				n.address = lo.IPv6
+				if dev != nil {
+					n.otgV6 = dev.Bgp().Ipv6Interfaces().Add().SetIpv6Name(otgLo6.Name()).Peers().Add().SetName(dev.Name() + ".BGP6.lo.peer")
+					n.otgV6.SetPeerAddress(dutLoopback.IPv6).SetAsNumber(as).SetAsType(asType6)
+					n.otgV6.LearnedInformationFilter().SetUnicastIpv6Prefix(true)
+					if multihopTTL > 0 {
+						n.otgV6.Advanced().SetTimeToLive(uint32(multihopTTL))
+					}
+				}
+			}
+
+			if bgp.GetNeighbor(n.address) != nil {
+				t.Fatalf("BGP neighbor %s on port %s is already configured", n.address, n.port)
+			}
+			nbr := bgp.GetOrCreateNeighbor(n.address)
+			nbr.SetPeerAs(as)
+			nbr.SetPeerGroup(pgName)
+			nbr.GetOrCreateTransport().SetLocalAddress(bs.loopback)
+			nbr.GetOrCreateAfiSafi(afiType).SetEnabled(true)
+			if multihopTTL > 0 {
+				mh := nbr.GetOrCreateEbgpMultihop()
+				mh.SetEnabled(true)
+				mh.SetMultihopTtl(multihopTTL)
+			}
+			bs.neighbors = append(bs.neighbors, n)
+			neighbors = append(neighbors, n)
+		}
+	}
+
+	if bs.DUTConf.GetRoutingPolicy().GetPolicyDefinition(RPLPermitAll) == nil {
+		if err := bs.configureRoutingPolicy(); err != nil {
+			t.Fatalf("Failed to configure routing policy: %v", err)
+		}
+	}
+	return neighbors
+}
+
+// configureDUTLoopback adds the loopback the DUT establishes BGP sessions from.
+func (bs *BGPSession) configureDUTLoopback(t *testing.T) {
+	t.Helper()
+	if bs.loopback != "" {
+		return
+	}
+	bs.loopback = netutil.LoopbackInterface(t, bs.DUT, 0)
+	lo := dutLoopback.ConfigOCInterface(bs.DUTConf.GetOrCreateInterface(bs.loopback), bs.DUT)
+	lo.Type = oc.IETFInterfaces_InterfaceType_softwareLoopback
+	lo.Ethernet = nil
+}
+
+// otgLoopbacks returns the IPv4 and IPv6 loopbacks of an ATE device, adding them if needed.
+func otgLoopbacks(dev gosnappi.Device, lo *attrs.Attributes) (gosnappi.DeviceIpv4Loopback, gosnappi.DeviceIpv6Loopback) {
+	for _, lo4 := range dev.Ipv4Loopbacks().Items() {
+		for _, lo6 := range dev.Ipv6Loopbacks().Items() {
+			return lo4, lo6
+		}
+	}
+	eth := dev.Ethernets().Items()[0]
+	lo4 := dev.Ipv4Loopbacks().Add().SetName(dev.Name() + ".Loopback4").SetEthName(eth.Name()).SetAddress(lo.IPv4)
+	lo6 := dev.Ipv6Loopbacks().Add().SetName(dev.Name() + ".Loopback6").SetEthName(eth.Name()).SetAddress(lo.IPv6)
+	return lo4, lo6
+}
+
+// configureUnderlay routes between the loopback of the DUT and the loopback lo of the ATE
+// behind the i-th port of the session.
+func (bs *BGPSession) configureUnderlay(t *testing.T, i int, lo *attrs.Attributes, underlay BGPUnderlay) {
+	t.Helper()
+	port := bs.ATEPorts[i].Name
+	if bs.underlayPorts == nil {
+		bs.underlayPorts = map[string]BGPUnderlay{}
+	}
+	if u, ok := bs.underlayPorts[port]; ok {
+		if u != underlay {
+			t.Fatalf("Port %s already has underlay %v, cannot add underlay %v", port, u, underlay)
+		}
+		return
+	}
+	bs.underlayPorts[port] = underlay
+
+	ni := bs.DUTConf.GetOrCreateNetworkInstance(bs.networkInstance)
+	switch underlay {
+	case BGPUnderlayStatic:
+		static := ni.GetOrCreateProtocol(oc.PolicyTypes_INSTALL_PROTOCOL_TYPE_STATIC, deviations.StaticProtocolName(bs.DUT))
+		static.GetOrCreateStatic(lo.IPv4CIDR()).GetOrCreateNextHop("0").SetNextHop(oc.UnionString(bs.ATEPorts[i].IPv4))
+		static.GetOrCreateStatic(lo.IPv6CIDR()).GetOrCreateNextHop("0").SetNextHop(oc.UnionString(bs.ATEPorts[i].IPv6))
+	case BGPUnderlayISIS:
+		isis := bs.underlayISIS(ni)
+		intfName := bs.OndatraDUTPorts[i].Name()
+		if deviations.ExplicitInterfaceInDefaultVRF(bs.DUT) || deviations.InterfaceRefInterfaceIDFormat(bs.DUT) {
+			intfName += ".0"
+		}
+		configureUnderlayISISInterface(bs, isis.GetOrCreateInterface(intfName))
+		if devices := bs.otgDevices(); devices != nil {
+			addUnderlayISISToOTG(devices[i], i, lo)
+		}
+	default:
+		t.Fatalf("Unsupported BGP underlay: %v", underlay)
+	}
+}
+
+// underlayISIS returns the ISIS underlay configuration of the DUT, creating it with its loopback
+// as a passive interface if needed.
+func (bs *BGPSession) underlayISIS(ni *oc.NetworkInstance) *oc.NetworkInstance_Protocol_Isis {
+	protocol := ni.GetOrCreateProtocol(oc.PolicyTypes_INSTALL_PROTOCOL_TYPE_ISIS, bgpUnderlayISISName)
+	if protocol.Isis != nil {
+		return protocol.Isis
+	}
+	protocol.SetEnabled(true)
+	isis := protocol.GetOrCreateIsis()
+	global := isis.GetOrCreateGlobal()
+	if deviations.ISISInstanceEnabledRequired(bs.DUT) {
+		global.SetInstance(bgpUnderlayISISName)
+	}
+	global.Net = []string{fmt.Sprintf("%s.1920.0000.2001.00", bgpUnderlayISISArea)}
+	global.SetLevelCapability(oc.Isis_LevelType_LEVEL_2)
+	global.GetOrCreateAf(oc.IsisTypes_AFI_TYPE_IPV4, oc.IsisTypes_SAFI_TYPE_UNICAST).SetEnabled(true)
+	global.GetOrCreateAf(oc.IsisTypes_AFI_TYPE_IPV6, oc.IsisTypes_SAFI_TYPE_UNICAST).SetEnabled(true)
+	level := isis.GetOrCreateLevel(2)
+	level.SetMetricStyle(oc.Isis_MetricStyle_WIDE_METRIC)
+	if deviations.ISISLevelEnabled(bs.DUT) {
+		level.SetEnabled(true)
+	}
+	lo := isis.GetOrCreateInterface(bs.loopback)
+	configureUnderlayISISInterface(bs, lo)
+	lo.SetPassive(true)
+	return isis
+}
+
+func configureUnderlayISISInterface(bs *BGPSession, intf *oc.NetworkInstance_Protocol_Isis_Interface) {
+	intf.SetEnabled(true)
+	intf.SetCircuitType(oc.Isis_CircuitType_POINT_TO_POINT)
+	if deviations.ISISInterfaceLevel1DisableRequired(bs.DUT) {
+		intf.GetOrCreateLevel(1).SetEnabled(false)
+	} else {
+		intf.GetOrCreateLevel(2).SetEnabled(true)
+	}
+	if !deviations.ISISInterfaceAfiUnsupported(bs.DUT) {
+		intf.GetOrCreateAf(oc.IsisTypes_AFI_TYPE_IPV4, oc.IsisTypes_SAFI_TYPE_UNICAST).SetEnabled(true)
+		intf.GetOrCreateAf(oc.IsisTypes_AFI_TYPE_IPV6, oc.IsisTypes_SAFI_TYPE_UNICAST).SetEnabled(true)
+	}
+}
+
+// addUnderlayISISToOTG runs ISIS on the i-th ATE device, advertising its loopback lo.
+func addUnderlayISISToOTG(dev gosnappi.Device, i int, lo *attrs.Attributes) {
+	isis := dev.Isis().SetSystemId(fmt.Sprintf("6400000000%02d", i+1)).SetName(dev.Name() + ".ISIS")
+	isis.Basic().SetHostname(isis.Name()).SetLearnedLspFilter(true)
+	isis.Advanced().SetAreaAddresses([]string{"490001"})
+	isis.Interfaces().Add().
+		SetEthName(dev.Ethernets().Items()[0].Name()).
+		SetName(dev.Name() + ".ISIS.intf").
+		SetNetworkType(gosnappi.IsisInterfaceNetworkType.POINT_TO_POINT).
+		SetLevelType(gosnappi.IsisInterfaceLevelType.LEVEL_2).
+		SetMetric(10)
+	isis.V4Routes().Add().SetName(dev.Name() + ".ISIS.lo4").Addresses().Add().SetAddress(lo.IPv4).SetPrefix(uint32(lo.IPv4Len))
+	isis.V6Routes().Add().SetName(dev.Name() + ".ISIS.lo6").Addresses().Add().SetAddress(lo.IPv6).SetPrefix(uint32(lo.IPv6Len))
+}
+
+// sessionNeighbors returns the neighbors of the session towards the ATE behind ports, failing t
+// if a port has none.
+func (bs *BGPSession) sessionNeighbors(t *testing.T, ports []string) []*bgpSessionNeighbor {
+	t.Helper()
+	var neighbors []*bgpSessionNeighbor
+	for _, port := range ports {
+		found := false
+		for _, n := range bs.neighbors {
+			if n.port == port {
+				neighbors = append(neighbors, n)
+				found = true
+			}
+		}
+		if !found {
+			t.Fatalf("No BGP neighbor on port %s", port)
+		}
+	}
+	return neighbors
+}
+
+func (bs *BGPSession) dutNeighbor(n *bgpSessionNeighbor) *oc.NetworkInstance_Protocol_Bgp_Neighbor {
+	return bs.bgp(nil).GetOrCreateNeighbor(n.address)
+}
+
+// WithRouteReflector makes the DUT a route reflector of the cluster clusterID, with the iBGP
+// peers of the ATE behind clientPorts as its clients.
+func (bs *BGPSession) WithRouteReflector(t *testing.T, clusterID string, clientPorts []string) *BGPSession {
+	for _, n := range bs.sessionNeighbors(t, clientPorts) {
+		if !n.ibgp {
+			t.Fatalf("BGP neighbor %s on port %s is not an iBGP neighbor", n.address, n.port)
+		}
+		rr := bs.dutNeighbor(n).GetOrCreateRouteReflector()
+		rr.SetRouteReflectorClusterId(oc.UnionString(clusterID))
+		rr.SetRouteReflectorClient(true)
+	}
+	return bs
+}
+
+// WithBFD attaches BFD with the given intervals in microseconds and detection multiplier to the
+// BGP neighbors behind bgpPorts. It fails sessions with an ATE, since the OTG API of gosnappi
+// v1.59.1 has no BFD peers and the BFD sessions of the DUT would never come up.
+func (bs *BGPSession) WithBFD(t *testing.T, bgpPorts []string, minIntervalMicros uint32, multiplier uint8) *BGPSession {
+	t.Helper()
+	if bs.ATETop != nil {
+		t.Fatalf("BFD peers cannot be configured on the ATE: gosnappi v1.59.1 does not support BFD")
+	}
+	for _, n := range bs.sessionNeighbors(t, bgpPorts) {
+		bfd := bs.dutNeighbor(n).GetOrCreateEnableBfd()
+		bfd.SetEnabled(true)
+		bfd.SetDesiredMinimumTxInterval(minIntervalMicros)
+		bfd.SetRequiredMinimumReceive(minIntervalMicros)
+		bfd.SetDetectionMultiplier(multiplier)
+	}
+	return bs
+}
+
+// WithAddPaths enables add-paths for the AFI-SAFI afiType of the BGP neighbors of the ATE behind
+// bgpPorts: the DUT sends up to sendMax paths per prefix if send is set, and receives multiple
+// paths if receive is set. The ATE peers advertise the add-paths capability.
+func (bs *BGPSession) WithAddPaths(t *testing.T, bgpPorts []string, afiType oc.E_BgpTypes_AFI_SAFI_TYPE, send, receive bool, sendMax uint8) *BGPSession {
+	for _, n := range bs.sessionNeighbors(t, bgpPorts) {
+		if n.afiType != afiType {
+			continue
+		}
+		ap := bs.dutNeighbor(n).GetOrCreateAfiSafi(afiType).GetOrCreateAddPaths()
+		ap.SetReceive(receive)
+		ap.SetSend(send)
+		if send {
+			ap.SetSendMax(sendMax)
+		}
+		if n.otgV4 != nil {
+			n.otgV4.Capability().SetIpv4UnicastAddPath(true)
+		}
+		if n.otgV6 != nil {
+			n.otgV6.Capability().SetIpv6UnicastAddPath(true)
+		}
+	}
+	return bs
+}
@@ -0,0 +1,127 @@
+// Copyright 2026 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cfgplugins
+
+import (
+	"maps"
+	"slices"
+	"testing"
+
+	"github.com/open-traffic-generator/snappi/gosnappi"
+	"github.com/openconfig/ondatra"
+	"github.com/openconfig/ondatra/gnmi/oc"
+)
+
+// sessionATETop returns an ATE topology with a device per ATE port of bs, as NewBGPSession
+// builds with an ATE.
+func sessionATETop(bs *BGPSession) gosnappi.Config {
+	top := gosnappi.NewConfig()
+	for i, ap := range bs.ATEPorts {
+		dev := top.Devices().Add().SetName(ap.Name + ".Dev")
+		eth := dev.Ethernets().Add().SetName(ap.Name + ".Eth").SetMac(ap.MAC)
+		eth.Connection().SetPortName(ap.Name)
+		eth.Ipv4Addresses().Add().SetName(ap.Name + ".IPv4").SetAddress(ap.IPv4).SetGateway(bs.DUTPorts[i].IPv4).SetPrefix(uint32(ap.IPv4Len))
+		eth.Ipv6Addresses().Add().SetName(ap.Name + ".IPv6").SetAddress(ap.IPv6).SetGateway(bs.DUTPorts[i].IPv6).SetPrefix(uint32(ap.IPv6Len))
+	}
+	return top
+}
+
+func TestBGPSessionBFD(t *testing.T) {
+	v4 := []oc.E_BgpTypes_AFI_SAFI_TYPE{oc.BgpTypes_AFI_SAFI_TYPE_IPV4_UNICAST}
+	var bs *BGPSession
+	DryRun(t, Platform{Vendor: ondatra.ARISTA}, func(t *testing.T, dut *ondatra.DUTDevice) {
+		bs = NewBGPSession(t, PortCount2, nil)
+		bs.WithEBGPMultihop(t, v4, []string{"port2"}, BGPUnderlayStatic, 2, false).
+			WithBFD(t, []string{"port2"}, 300000, 3)
+	})
+	bgp := bs.DUTConf.GetNetworkInstance(bs.networkInstance).GetProtocol(PTBGP, bgpName).GetBgp()
+	if bfd := bgp.GetNeighbor("203.0.113.12").GetEnableBfd(); !bfd.GetEnabled() || bfd.GetDetectionMultiplier() != 3 || bfd.GetDesiredMinimumTxInterval() != 300000 {
+		t.Errorf("Multihop neighbor BFD got %+v, want enabled with 300ms interval and multiplier 3", bfd)
+	}
+}
+
+func TestATEAS(t *testing.T) {
+	for i, want := range map[int]uint32{0: AteAS1, 1: AteAS2, 2: AteAS3, 3: AteAS4, 4: AteAS4 + 1, 7: AteAS4 + 4} {
+		if got := ateAS(i); got != want {
+			t.Errorf("ateAS(%d) got %d, want %d", i, got, want)
+		}
+	}
+}
+
+func TestBGPSessionLoopbackPeers(t *testing.T) {
+	v4 := []oc.E_BgpTypes_AFI_SAFI_TYPE{oc.BgpTypes_AFI_SAFI_TYPE_IPV4_UNICAST}
+	var bs *BGPSession
+	DryRun(t, Platform{Vendor: ondatra.ARISTA}, func(t *testing.T, dut *ondatra.DUTDevice) {
+		bs = NewBGPSession(t, PortCount2, nil)
+		bs.ATETop = sessionATETop(bs)
+		bs.WithIBGP(t, v4, []string{"port1"}, BGPUnderlayISIS).
+			WithRouteReflector(t, "203.0.113.1", []string{"port1"}).
+			WithAddPaths(t, []string{"port1"}, oc.BgpTypes_AFI_SAFI_TYPE_IPV4_UNICAST, true, true, 4).
+			WithEBGPMultihop(t, v4, []string{"port2"}, BGPUnderlayStatic, 2, true)
+	})
+
+	ni := bs.DUTConf.GetNetworkInstance(bs.networkInstance)
+	bgp := ni.GetProtocol(PTBGP, bgpName).GetBgp()
+	if got := bs.DUTConf.GetInterface("Loopback0").GetType(); got != oc.IETFInterfaces_InterfaceType_softwareLoopback {
+		t.Errorf("Loopback0 type got %v, want softwareLoopback", got)
+	}
+
+	ibgp := bgp.GetNeighbor("203.0.113.11")
+	if got := ibgp.GetPeerAs(); got != DutAS {
+		t.Errorf("iBGP neighbor peer AS got %d, want %d", got, DutAS)
+	}
+	if got := ibgp.GetTransport().GetLocalAddress(); got != "Loopback0" {
+		t.Errorf("iBGP neighbor local address got %q, want Loopback0", got)
+	}
+	if rr := ibgp.GetRouteReflector(); !rr.GetRouteReflectorClient() || rr.GetRouteReflectorClusterId() != oc.UnionString("203.0.113.1") {
+		t.Errorf("iBGP neighbor route reflector got %+v, want client of cluster 203.0.113.1", rr)
+	}
+	if ap := ibgp.GetAfiSafi(oc.BgpTypes_AFI_SAFI_TYPE_IPV4_UNICAST).GetAddPaths(); !ap.GetSend() || !ap.GetReceive() || ap.GetSendMax() != 4 {
+		t.Errorf("iBGP neighbor add-paths got %+v, want send 4 and receive", ap)
+	}
+	isis := ni.GetProtocol(oc.PolicyTypes_INSTALL_PROTOCOL_TYPE_ISIS, bgpUnderlayISISName).GetIsis()
+	if !isis.GetInterface("Loopback0").GetPassive() || !isis.GetInterface("Ethernet1").GetEnabled() {
+		t.Errorf("ISIS underlay interfaces got %v, want passive Loopback0 and Ethernet1", slices.Sorted(maps.Keys(isis.Interface)))
+	}
+
+	multihop := bgp.GetNeighbor("203.0.113.12")
+	if got := multihop.GetPeerAs(); got != AteAS2 {
+		t.Errorf("Multihop neighbor peer AS got %d, want %d", got, AteAS2)
+	}
+	if mh := multihop.GetEbgpMultihop(); !mh.GetEnabled() || mh.GetMultihopTtl() != 2 {
+		t.Errorf("Multihop neighbor multihop got %+v, want TTL 2", mh)
+	}
+	static := ni.GetProtocol(oc.PolicyTypes_INSTALL_PROTOCOL_TYPE_STATIC, "DEFAULT").GetStatic("203.0.113.12/32")
+	if got := static.GetNextHop("0").GetNextHop(); got != oc.UnionString(atePort2.IPv4) {
+		t.Errorf("Static underlay next hop got %v, want %s", got, atePort2.IPv4)
+	}
+	wantCLI := []string{"router bgp 65501\n   neighbor 203.0.113.12 ttl maximum-hops 2\n"}
+	if !slices.Equal(bs.dutCLI, wantCLI) {
+		t.Errorf("DUT CLI got %q, want %q", bs.dutCLI, wantCLI)
+	}
+
+	devices := bs.otgDevices()
+	peer1 := devices[0].Bgp().Ipv4Interfaces().Items()[0].Peers().Items()[0]
+	if peer1.PeerAddress() != dutLoopback.IPv4 || peer1.AsType() != gosnappi.BgpV4PeerAsType.IBGP || !peer1.Capability().Ipv4UnicastAddPath() {
+		t.Errorf("ATE iBGP peer got %s, want iBGP peer of %s with add-paths", peer1, dutLoopback.IPv4)
+	}
+	if got := len(devices[0].Isis().V4Routes().Items()); got != 1 {
+		t.Errorf("ATE ISIS routes got %d, want the loopback", got)
+	}
+	peer2 := devices[1].Bgp().Ipv4Interfaces().Items()[0].Peers().Items()[0]
+	if peer2.AsNumber() != AteAS2 || peer2.Advanced().TimeToLive() != 255 {
+		t.Errorf("ATE multihop peer got %s, want AS %d with TTL 255", peer2, AteAS2)
+	}
+}
@@ -0,0 +1,253 @@
+// Copyright 2026 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cfgplugins
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/openconfig/featureprofiles/internal/metadata"
+	"github.com/openconfig/ondatra"
+	"github.com/openconfig/ondatra/binding"
+	"github.com/openconfig/ondatra/fakebind"
+	"github.com/openconfig/ondatra/gnmi"
+	"github.com/openconfig/ygot/ygot"
+	"google.golang.org/grpc"
+
+	mpb "github.com/openconfig/featureprofiles/proto/metadata_go_proto"
+	gpb "github.com/openconfig/gnmi/proto/gnmi"
+	opb "github.com/openconfig/ondatra/proto"
+)
+
+// dryRunPorts is the number of ports of a fake DUT without explicit ports.
+const dryRunPorts = 8
+
+// Platform identifies the fake DUT a configuration is compiled for offline.
+type Platform struct {
+	Vendor  ondatra.Vendor
+	Model   string
+	Version string
+	// Ports maps the port IDs of the DUT to their interface names. If empty, the DUT has
+	// ports port1 to port8 named Ethernet1 to Ethernet8.
+	Ports map[string]string
+	// Metadata, if set, replaces the metadata the deviations of the DUT are looked up in
+	// during the dry run.
+	Metadata *mpb.Metadata
+}
+
+// String returns the vendor, model and version of the platform.
+func (p Platform) String() string {
+	return strings.Join([]string{p.Vendor.String(), p.Model, p.Version}, "/")
+}
+
+// dryRunGNMI is a gNMI client of a fake DUT recording the SetRequests. Other RPCs fail, so
+// builders reading state from the DUT cannot be dry run.
+type dryRunGNMI struct {
+	gpb.GNMIClient
+	mu   sync.Mutex
+	sets []*gpb.SetRequest
+}
+
+func (c *dryRunGNMI) Set(_ context.Context, req *gpb.SetRequest, _ ...grpc.CallOption) (*gpb.SetResponse, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.sets = append(c.sets, req)
+	return &gpb.SetResponse{}, nil
+}
+
+func (c *dryRunGNMI) Get(context.Context, *gpb.GetRequest, ...grpc.CallOption) (*gpb.GetResponse, error) {
+	return nil, errors.New("dry run: Get is not supported")
+}
+
+func (c *dryRunGNMI) Subscribe(context.Context, ...grpc.CallOption) (gpb.GNMI_SubscribeClient, error) {
+	return nil, errors.New("dry run: Subscribe is not supported")
+}
+
+// DryRun runs fn against a fake DUT of the platform, and returns the gNMI SetRequests fn sends
+// to it, such as those of a gnmi.SetBatch. It replaces the ondatra testbed, so it must not be
+// used in tests with a reserved testbed.
+func DryRun(t *testing.T, p Platform, fn func(t *testing.T, dut *ondatra.DUTDevice)) []*gpb.SetRequest {
+	t.Helper()
+	ports := map[string]*binding.Port{}
+	for id, name := range p.Ports {
+		ports[id] = &binding.Port{Name: name}
+	}
+	if len(ports) == 0 {
+		for i := 1; i <= dryRunPorts; i++ {
+			ports[fmt.Sprintf("port%d", i)] = &binding.Port{Name: fmt.Sprintf("Ethernet%d", i)}
+		}
+	}
+	c := &dryRunGNMI{}
+	dut := &fakebind.DUT{
+		AbstractDUT: &binding.AbstractDUT{Dims: &binding.Dims{
+			Name:            "dut",
+			Vendor:          opb.Device_Vendor(p.Vendor),
+			HardwareModel:   p.Model,
+			SoftwareVersion: p.Version,
+			Ports:           ports,
+		}},
+		DialGNMIFn: func(context.Context, ...grpc.DialOption) (gpb.GNMIClient, error) { return c, nil },
+	}
+	fakebind.Setup().WithReservation(&binding.Reservation{
+		ID:   "dryrun",
+		DUTs: map[string]binding.DUT{"dut": dut},
+	})
+	if p.Metadata != nil {
+		defer metadata.Set(metadata.Set(p.Metadata))
+	}
+	fn(t, ondatra.DUT(t, "dut"))
+	return c.sets
+}
+
+// DryRunIntent compiles in for a fake DUT of the platform and returns the SetRequests that
+// would configure it.
+func DryRunIntent(t *testing.T, in *Intent, p Platform) []*gpb.SetRequest {
+	t.Helper()
+	return DryRun(t, p, func(t *testing.T, dut *ondatra.DUTDevice) {
+		batch := &gnmi.SetBatch{}
+		CompileIntent(t, dut, batch, in)
+		batch.Set(t, dut)
+	})
+}
+
+// FormatSetRequests renders SetRequests as text, one operation per block: the operation and
+// path, followed by the indented JSON_IETF value or the CLI text.
+func FormatSetRequests(reqs []*gpb.SetRequest) (string, error) {
+	var b strings.Builder
+	for i, req := range reqs {
+		fmt.Fprintf(&b, "# SetRequest %d\n", i+1)
+		ops, err := setOperations(req)
+		if err != nil {
+			return "", err
+		}
+		for _, op := range ops {
+			fmt.Fprintf(&b, "%s %s\n", op.Op, op.Path)
+			if op.Op == "delete" {
+				continue
+			}
+			switch {
+			case op.Value != nil:
+				if err := writeIndentedJSON(&b, op.Value); err != nil {
+					return "", err
+				}
+			case op.CLI != "":
+				b.WriteString(strings.TrimRight(op.CLI, "\n"))
+				b.WriteString("\n")
+			default:
+				fmt.Fprintf(&b, "%v\n", op.val)
+			}
+		}
+	}
+	return b.String(), nil
+}
+
+// setOperation is the JSON form of an operation of a SetRequest.
+type setOperation struct {
+	Op    string          `json:"op"`
+	Path  string          `json:"path"`
+	Value json.RawMessage `json:"value,omitempty"`
+	CLI   string          `json:"cli,omitempty"`
+
+	// val is the value of a replace, update or union_replace.
+	val *gpb.TypedValue
+}
+
+// MarshalSetRequests renders SetRequests as an indented JSON list of SetRequests, each a list
+// of operations with their path and JSON_IETF value or CLI text, e.g. to compare the
+// configuration of a dry run with a golden file.
+func MarshalSetRequests(reqs []*gpb.SetRequest) ([]byte, error) {
+	out := [][]setOperation{}
+	for _, req := range reqs {
+		ops, err := setOperations(req)
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, ops)
+	}
+	return json.MarshalIndent(out, "", "  ")
+}
+
+// setOperations lists the operations of a SetRequest in the order the target applies them:
+// deletes, then replaces, updates and union replaces.
+func setOperations(req *gpb.SetRequest) ([]setOperation, error) {
+	ops := []setOperation{}
+	for _, d := range req.GetDelete() {
+		p, err := formatPath(req.GetPrefix(), d)
+		if err != nil {
+			return nil, err
+		}
+		ops = append(ops, setOperation{Op: "delete", Path: p})
+	}
+	for _, op := range []struct {
+		name    string
+		updates []*gpb.Update
+	}{
+		{"replace", req.GetReplace()},
+		{"update", req.GetUpdate()},
+		{"union_replace", req.GetUnionReplace()},
+	} {
+		for _, u := range op.updates {
+			p, err := formatPath(req.GetPrefix(), u.GetPath())
+			if err != nil {
+				return nil, err
+			}
+			v := u.GetVal()
+			o := setOperation{Op: op.name, Path: p, CLI: v.GetAsciiVal(), val: v}
+			if j := v.GetJsonIetfVal(); j != nil {
+				o.Value = j
+			} else if j := v.GetJsonVal(); j != nil {
+				o.Value = j
+			}
+			ops = append(ops, o)
+		}
+	}
+	return ops, nil
+}
+
+func formatPath(prefix, p *gpb.Path) (string, error) {
+	elems := append(append([]*gpb.PathElem{}, prefix.GetElem()...), p.GetElem()...)
+	s, err := ygot.PathToString(&gpb.Path{Elem: elems})
+	if err != nil {
+		return "", err
+	}
+	if origin := p.GetOrigin(); origin != "" {
+		s = origin + ":" + s
+	}
+	return s, nil
+}
+
+func writeIndentedJSON(b *strings.Builder, j []byte) error {
+	var out bytes.Buffer
+	if err := json.Indent(&out, j, "", "  "); err != nil {
+		return err
+	}
+	b.Write(out.Bytes())
+	b.WriteString("\n")
+	return nil
+}
+
+// DryRunVendors are the platforms of the vendors commonly used in dry runs.
+var DryRunVendors = []Platform{
+	{Vendor: ondatra.ARISTA},
+	{Vendor: ondatra.CISCO},
+	{Vendor: ondatra.JUNIPER},
+	{Vendor: ondatra.NOKIA},
+}
@@ -0,0 +1,122 @@
+// Copyright 2026 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cfgplugins
+
+import (
+	"flag"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/openconfig/featureprofiles/internal/deviations"
+	"github.com/openconfig/ondatra"
+	"github.com/openconfig/ondatra/gnmi"
+	"github.com/openconfig/ondatra/gnmi/oc"
+	"google.golang.org/protobuf/encoding/prototext"
+
+	mpb "github.com/openconfig/featureprofiles/proto/metadata_go_proto"
+)
+
+var updateGolden = flag.Bool("update_golden", false, "Update the golden configurations in testdata/golden instead of comparing with them.")
+
+// goldenMetadata reads the deviation profiles of the vendors of the golden configurations.
+func goldenMetadata(t *testing.T) *mpb.Metadata {
+	t.Helper()
+	b, err := os.ReadFile("testdata/golden/metadata.textproto")
+	if err != nil {
+		t.Fatalf("Cannot read golden metadata: %v", err)
+	}
+	md := &mpb.Metadata{}
+	if err := prototext.Unmarshal(b, md); err != nil {
+		t.Fatalf("Cannot parse golden metadata: %v", err)
+	}
+	return md
+}
+
+// checkGolden dry runs build for each vendor of DryRunVendors with the deviations of the
+// golden metadata, and compares the configuration it sets with the golden file
+// testdata/golden/<name>/<vendor>.json, or updates the golden file with --update_golden.
+func checkGolden(t *testing.T, name string, build func(t *testing.T, dut *ondatra.DUTDevice, batch *gnmi.SetBatch)) {
+	t.Helper()
+	md := goldenMetadata(t)
+	for _, p := range DryRunVendors {
+		p.Metadata = md
+		t.Run(p.Vendor.String(), func(t *testing.T) {
+			reqs := DryRun(t, p, func(t *testing.T, dut *ondatra.DUTDevice) {
+				batch := &gnmi.SetBatch{}
+				build(t, dut, batch)
+				batch.Set(t, dut)
+			})
+			got, err := MarshalSetRequests(reqs)
+			if err != nil {
+				t.Fatalf("MarshalSetRequests() got error: %v", err)
+			}
+			got = append(got, '\n')
+			path := filepath.Join("testdata", "golden", name, strings.ToLower(p.Vendor.String())+".json")
+			if *updateGolden {
+				if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+					t.Fatalf("Cannot create golden directory: %v", err)
+				}
+				if err := os.WriteFile(path, got, 0o644); err != nil {
+					t.Fatalf("Cannot update golden file: %v", err)
+				}
+				return
+			}
+			want, err := os.ReadFile(path)
+			if err != nil {
+				t.Fatalf("Cannot read golden file, run with --update_golden to create it: %v", err)
+			}
+			if diff := cmp.Diff(string(want), string(got)); diff != "" {
+				t.Errorf("Configuration differs from %s (-want +got), run with --update_golden if expected:\n%s", path, diff)
+			}
+		})
+	}
+}
+
+func TestGoldenConfigureDUTBGP(t *testing.T) {
+	checkGolden(t, "configure_dut_bgp", func(t *testing.T, dut *ondatra.DUTDevice, batch *gnmi.SetBatch) {
+		ConfigureDUTBGP(t, dut, batch, BGPConfig{DutAS: 65000, RouterID: "192.0.2.1", ECMPMaxPath: 4})
+	})
+}
+
+func TestGoldenNewQoSSchedulerPolicy(t *testing.T) {
+	checkGolden(t, "new_qos_scheduler_policy", func(t *testing.T, dut *ondatra.DUTDevice, batch *gnmi.SetBatch) {
+		q := &oc.Qos{}
+		NewQoSSchedulerPolicy(t, dut, q, []SchedulerPolicy{
+			{Sequence: 0, SetPriority: true, Priority: oc.Scheduler_Priority_STRICT, InputID: "NC1", InputType: oc.Input_InputType_QUEUE, QueueName: "NC1"},
+			{Sequence: 1, InputID: "BE1", InputType: oc.Input_InputType_QUEUE, QueueName: "BE1"},
+		})
+		NewQoSSchedulerInterface(t, dut, q, []QoSSchedulerInterface{
+			{QueueName: "NC1", Scheduler: "scheduler"},
+			{QueueName: "BE1", Scheduler: "scheduler"},
+		}, "port1")
+		gnmi.BatchReplace(batch, gnmi.OC().Qos().Config(), q)
+	})
+}
+
+func TestGoldenPolicyForwardingConfig(t *testing.T) {
+	checkGolden(t, "policy_forwarding_config", func(t *testing.T, dut *ondatra.DUTDevice, batch *gnmi.SetBatch) {
+		pf := &oc.NetworkInstance_PolicyForwarding{}
+		PolicyForwardingConfig(t, dut, "v4", pf, OcPolicyForwardingParams{
+			InnerDstIPv4: "198.51.100.1/32",
+			InnerDstIPv6: "2001:db8:1::1/128",
+		})
+		if len(pf.Policy) > 0 {
+			gnmi.BatchReplace(batch, gnmi.OC().NetworkInstance(deviations.DefaultNetworkInstance(dut)).PolicyForwarding().Config(), pf)
+		}
+	})
+}
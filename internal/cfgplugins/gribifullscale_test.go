@@ -0,0 +1,88 @@
+// Copyright 2026 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cfgplugins
+
+import (
+	"context"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/openconfig/featureprofiles/internal/gribi"
+	"github.com/openconfig/featureprofiles/internal/gribi/fakegribi"
+	"github.com/openconfig/gribigo/client"
+	"github.com/openconfig/gribigo/fluent"
+
+	gribipb "github.com/openconfig/gribi/v1/proto/service"
+)
+
+// programFake programs a next hop, two next hop groups and two IPv4 entries into s with FIB
+// acknowledgements, and returns the results.
+func programFake(t *testing.T, s *fakegribi.Server) []*client.OpResult {
+	t.Helper()
+	c := &gribi.Client{Stub: s.Stub(), FIBACK: true, Persistence: true}
+	if err := c.Start(t); err != nil {
+		t.Fatalf("gRIBI connection could not be established: %v", err)
+	}
+	defer c.Close(t)
+	c.BecomeLeader(t)
+	c.AddEntries(t, []fluent.GRIBIEntry{
+		fluent.NextHopEntry().WithNetworkInstance("DEFAULT").WithIndex(1).WithIPAddress("192.0.2.1"),
+		fluent.NextHopGroupEntry().WithNetworkInstance("DEFAULT").WithID(1).AddNextHop(1, 1),
+		fluent.NextHopGroupEntry().WithNetworkInstance("DEFAULT").WithID(2).AddNextHop(1, 1),
+		fluent.IPv4Entry().WithNetworkInstance("DEFAULT").WithPrefix("203.0.113.0/24").WithNextHopGroup(1),
+		fluent.IPv4Entry().WithNetworkInstance("DEFAULT").WithPrefix("198.51.100.0/24").WithNextHopGroup(1),
+	}, nil)
+	if err := c.AwaitTimeout(context.Background(), t, time.Minute); err != nil {
+		t.Fatalf("gRIBI programming timeout: %v", err)
+	}
+	return c.Fluent(t).Results(t)
+}
+
+func TestValidateGRIBIResults(t *testing.T) {
+	t.Run("success", func(t *testing.T) {
+		if ValidateGRIBIResults(t, programFake(t, fakegribi.Start(t))) {
+			t.Errorf("ValidateGRIBIResults() got true, want false")
+		}
+	})
+	t.Run("FIB failures", func(t *testing.T) {
+		s := fakegribi.Start(t)
+		s.FailFIB(func(op *gribipb.AFTOperation) bool {
+			return op.GetNextHopGroup().GetId() == 2 || op.GetIpv4().GetPrefix() == "198.51.100.0/24"
+		})
+		// The results that ValidateGRIBIResults reports as one next hop group and one IP entry
+		// failure.
+		var failed []string
+		for _, res := range programFake(t, s) {
+			if res.ProgrammingResult != gribipb.AFTResult_FIB_FAILED {
+				continue
+			}
+			switch {
+			case res.Details == nil:
+				failed = append(failed, "unknown")
+			case res.Details.NextHopIndex != 0:
+				failed = append(failed, "NH")
+			case res.Details.NextHopGroupID != 0:
+				failed = append(failed, "NHG")
+			default:
+				failed = append(failed, res.Details.IPv4Prefix)
+			}
+		}
+		slices.Sort(failed)
+		if want := []string{"198.51.100.0/24", "NHG"}; !slices.Equal(failed, want) {
+			t.Errorf("FIB_FAILED results got %v, want %v", failed, want)
+		}
+	})
+}
@@ -0,0 +1,509 @@
+// Copyright 2026 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cfgplugins
+
+import (
+	"bytes"
+	"cmp"
+	"fmt"
+	"net"
+	"os"
+	"strconv"
+	"testing"
+
+	"github.com/openconfig/featureprofiles/internal/attrs"
+	"github.com/openconfig/ondatra"
+	"github.com/openconfig/ondatra/gnmi"
+	"github.com/openconfig/ondatra/gnmi/oc"
+	"gopkg.in/yaml.v3"
+)
+
+// Intent is a declarative description of the configuration of a DUT. It is compiled into a
+// gnmi.SetBatch by CompileIntent with the cfgplugins builders, which apply the deviations of
+// the DUT, so that tests can share topologies by file.
+//
+// Interfaces are referenced either by ondatra port ID, e.g. "port1", or by interface name,
+// e.g. "Loopback0".
+type Intent struct {
+	Interfaces   []InterfaceIntent   `yaml:"interfaces"`
+	LAGs         []LAGIntent         `yaml:"lags"`
+	VRFs         []VRFIntent         `yaml:"vrfs"`
+	QoS          *QoSIntent          `yaml:"qos"`
+	ACLs         []ACLIntent         `yaml:"acls"`
+	StaticRoutes []StaticRouteIntent `yaml:"static_routes"`
+	ISIS         *ISISIntent         `yaml:"isis"`
+	BGP          *BGPIntent          `yaml:"bgp"`
+}
+
+// AddressIntent is the addressing of an interface or subinterface.
+type AddressIntent struct {
+	IPv4    string `yaml:"ipv4"`
+	IPv4Len uint8  `yaml:"ipv4_len"`
+	IPv6    string `yaml:"ipv6"`
+	IPv6Len uint8  `yaml:"ipv6_len"`
+}
+
+// SubinterfaceIntent is a VLAN subinterface.
+type SubinterfaceIntent struct {
+	AddressIntent `yaml:",inline"`
+	VlanID        int `yaml:"vlan_id"`
+}
+
+// InterfaceIntent is a routed interface.
+type InterfaceIntent struct {
+	AddressIntent `yaml:",inline"`
+	Interface     string               `yaml:"interface"`
+	Description   string               `yaml:"description"`
+	MTU           uint16               `yaml:"mtu"`
+	Loopback      bool                 `yaml:"loopback"`
+	Subinterfaces []SubinterfaceIntent `yaml:"subinterfaces"`
+}
+
+// LAGIntent is an aggregate interface. LACP is "active" or "passive", or empty for a static
+// LAG.
+type LAGIntent struct {
+	AddressIntent `yaml:",inline"`
+	Name          string               `yaml:"name"`
+	Description   string               `yaml:"description"`
+	Members       []string             `yaml:"members"`
+	LACP          string               `yaml:"lacp"`
+	Subinterfaces []SubinterfaceIntent `yaml:"subinterfaces"`
+}
+
+// VRFInterfaceIntent is a subinterface assigned to a VRF.
+type VRFInterfaceIntent struct {
+	Interface    string `yaml:"interface"`
+	Subinterface uint32 `yaml:"subinterface"`
+}
+
+// VRFIntent is a network instance. The default network instance is named by the deviations
+// of the DUT.
+type VRFIntent struct {
+	Name       string               `yaml:"name"`
+	Default    bool                 `yaml:"default"`
+	Interfaces []VRFInterfaceIntent `yaml:"interfaces"`
+}
+
+// QoSClassifierIntent is a DSCP classifier term sending packets to a forwarding group.
+type QoSClassifierIntent struct {
+	Name        string  `yaml:"name"`
+	Type        string  `yaml:"type"`
+	Term        string  `yaml:"term"`
+	TargetGroup string  `yaml:"target_group"`
+	DSCP        []uint8 `yaml:"dscp"`
+	RemarkDSCP  uint8   `yaml:"remark_dscp"`
+}
+
+// QoSForwardingGroupIntent maps a forwarding group to an output queue.
+type QoSForwardingGroupIntent struct {
+	Name  string `yaml:"name"`
+	Queue string `yaml:"queue"`
+}
+
+// QoSSchedulerIntent is a scheduler of the scheduler policy. Priority is "strict" or empty.
+type QoSSchedulerIntent struct {
+	Sequence uint32 `yaml:"sequence"`
+	Priority string `yaml:"priority"`
+	Input    string `yaml:"input"`
+	Queue    string `yaml:"queue"`
+}
+
+// QoSOutputIntent applies the scheduler policy to the queues of a port. Interface must be a
+// port ID.
+type QoSOutputIntent struct {
+	Interface string   `yaml:"interface"`
+	Queues    []string `yaml:"queues"`
+}
+
+// QoSIntent is the QoS configuration.
+type QoSIntent struct {
+	Classifiers      []QoSClassifierIntent      `yaml:"classifiers"`
+	ForwardingGroups []QoSForwardingGroupIntent `yaml:"forwarding_groups"`
+	Schedulers       []QoSSchedulerIntent       `yaml:"schedulers"`
+	Outputs          []QoSOutputIntent          `yaml:"outputs"`
+}
+
+// ACLTermIntent is an ACL entry.
+type ACLTermIntent struct {
+	Sequence     uint32 `yaml:"sequence"`
+	Description  string `yaml:"description"`
+	Permit       bool   `yaml:"permit"`
+	Source       string `yaml:"source"`
+	Destination  string `yaml:"destination"`
+	Protocol     uint8  `yaml:"protocol"`
+	SrcPort      uint32 `yaml:"src_port"`
+	SrcPortRange string `yaml:"src_port_range"`
+	DstPort      uint32 `yaml:"dst_port"`
+	DstPortRange string `yaml:"dst_port_range"`
+	Log          bool   `yaml:"log"`
+}
+
+// ACLIntent is an ACL applied to an interface. Type is "ipv4" or "ipv6".
+type ACLIntent struct {
+	Name          string          `yaml:"name"`
+	Type          string          `yaml:"type"`
+	Interface     string          `yaml:"interface"`
+	Egress        bool            `yaml:"egress"`
+	DefaultPermit bool            `yaml:"default_permit"`
+	Terms         []ACLTermIntent `yaml:"terms"`
+}
+
+// StaticRouteIntent is a static route to next hop addresses or an interface.
+type StaticRouteIntent struct {
+	NetworkInstance string   `yaml:"network_instance"`
+	Prefix          string   `yaml:"prefix"`
+	NextHops        []string `yaml:"next_hops"`
+	Interface       string   `yaml:"interface"`
+	Metric          uint32   `yaml:"metric"`
+}
+
+// ISISIntent is a level 2 ISIS instance in the default network instance.
+type ISISIntent struct {
+	Instance   string   `yaml:"instance"`
+	Area       string   `yaml:"area"`
+	SystemID   string   `yaml:"system_id"`
+	Interfaces []string `yaml:"interfaces"`
+	LAG        string   `yaml:"lag"`
+	Loopback   string   `yaml:"loopback"`
+}
+
+// BGPNeighborIntent is a dual stack BGP peering, with a peer group per address family named
+// after Interface.
+type BGPNeighborIntent struct {
+	Interface string `yaml:"interface"`
+	PeerAS    uint32 `yaml:"peer_as"`
+	IPv4      string `yaml:"ipv4"`
+	IPv6      string `yaml:"ipv6"`
+	Policy    string `yaml:"policy"`
+	Multipath bool   `yaml:"multipath"`
+	LAG       bool   `yaml:"lag"`
+}
+
+// BGPIntent is BGP in the default network instance.
+type BGPIntent struct {
+	AS          uint32              `yaml:"as"`
+	RouterID    string              `yaml:"router_id"`
+	ECMPMaxPath uint32              `yaml:"ecmp_max_path"`
+	Neighbors   []BGPNeighborIntent `yaml:"neighbors"`
+}
+
+// ParseIntent parses an Intent from YAML. Unknown fields are rejected.
+func ParseIntent(b []byte) (*Intent, error) {
+	dec := yaml.NewDecoder(bytes.NewReader(b))
+	dec.KnownFields(true)
+	in := &Intent{}
+	if err := dec.Decode(in); err != nil {
+		return nil, fmt.Errorf("cannot parse intent: %w", err)
+	}
+	return in, nil
+}
+
+// LoadIntent reads an Intent from a YAML file.
+func LoadIntent(path string) (*Intent, error) {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	return ParseIntent(b)
+}
+
+// CompileIntent adds the configuration described by in to batch, in dependency order:
+// interfaces and LAGs, VRFs, QoS, ACLs, static routes, ISIS and BGP. Builders that require
+// CLI configuration for the DUT send it directly, as they do when called from tests.
+func CompileIntent(t *testing.T, dut *ondatra.DUTDevice, batch *gnmi.SetBatch, in *Intent) {
+	t.Helper()
+	for _, i := range in.Interfaces {
+		compileInterface(t, dut, batch, i)
+	}
+	for _, l := range in.LAGs {
+		compileLAG(t, dut, batch, l)
+	}
+	for _, v := range in.VRFs {
+		nip := &NetworkInstanceParams{Name: v.Name, Default: v.Default}
+		NewNetworkInstance(t, dut, batch, nip)
+		for _, vi := range v.Interfaces {
+			AssignInterfaceToNetworkInstance(t, batch, dut, intentInterfaceName(dut, vi.Interface), nip, vi.Subinterface)
+		}
+	}
+	if in.QoS != nil {
+		compileQoS(t, dut, batch, in.QoS)
+	}
+	for _, a := range in.ACLs {
+		compileACL(t, dut, batch, a)
+	}
+	for _, r := range in.StaticRoutes {
+		compileStaticRoute(t, dut, batch, r)
+	}
+	if in.ISIS != nil {
+		compileISIS(t, dut, batch, in.ISIS)
+	}
+	if in.BGP != nil {
+		compileBGP(t, dut, batch, in.BGP)
+	}
+}
+
+// intentInterfaceName returns the name of the interface referenced by ref, a port ID of the
+// DUT or an interface name.
+func intentInterfaceName(dut *ondatra.DUTDevice, ref string) string {
+	for _, p := range dut.Ports() {
+		if p.ID() == ref {
+			return p.Name()
+		}
+	}
+	return ref
+}
+
+// intentPort returns the port of the DUT with the ID ref.
+func intentPort(t *testing.T, dut *ondatra.DUTDevice, ref string) *ondatra.Port {
+	t.Helper()
+	for _, p := range dut.Ports() {
+		if p.ID() == ref {
+			return p
+		}
+	}
+	t.Fatalf("Intent references port %q, which is not a port of %s", ref, dut.Name())
+	return nil
+}
+
+func (a AddressIntent) attributes(desc string, mtu uint16) *attrs.Attributes {
+	return &attrs.Attributes{
+		Desc:    desc,
+		IPv4:    a.IPv4,
+		IPv4Len: a.IPv4Len,
+		IPv6:    a.IPv6,
+		IPv6Len: a.IPv6Len,
+		MTU:     mtu,
+	}
+}
+
+func (s SubinterfaceIntent) data() *DUTSubInterfaceData {
+	return &DUTSubInterfaceData{
+		VlanID:        s.VlanID,
+		IPv4Address:   net.ParseIP(s.IPv4),
+		IPv6Address:   net.ParseIP(s.IPv6),
+		IPv4PrefixLen: int(s.IPv4Len),
+		IPv6PrefixLen: int(s.IPv6Len),
+	}
+}
+
+func compileInterface(t *testing.T, dut *ondatra.DUTDevice, batch *gnmi.SetBatch, in InterfaceIntent) {
+	t.Helper()
+	name := intentInterfaceName(dut, in.Interface)
+	i := in.attributes(in.Description, in.MTU).NewOCInterface(name, dut)
+	if in.Loopback {
+		i.Type = oc.IETFInterfaces_InterfaceType_softwareLoopback
+		i.Ethernet = nil
+	}
+	gnmi.BatchReplace(batch, gnmi.OC().Interface(name).Config(), i)
+	for _, s := range in.Subinterfaces {
+		AddSubInterface(t, dut, batch, i, s.data())
+	}
+}
+
+func compileLAG(t *testing.T, dut *ondatra.DUTDevice, batch *gnmi.SetBatch, in LAGIntent) {
+	t.Helper()
+	l := &DUTAggData{
+		Attributes: *in.attributes(in.Description, 0),
+		LagName:    in.Name,
+		AggType:    oc.IfAggregate_AggregationType_STATIC,
+	}
+	switch in.LACP {
+	case "":
+	case "active", "passive":
+		activity := oc.Lacp_LacpActivityType_ACTIVE
+		if in.LACP == "passive" {
+			activity = oc.Lacp_LacpActivityType_PASSIVE
+		}
+		period := oc.Lacp_LacpPeriodType_FAST
+		l.LacpParams = &LACPParams{Activity: &activity, Period: &period}
+		l.AggType = oc.IfAggregate_AggregationType_LACP
+	default:
+		t.Fatalf("LAG %s has LACP mode %q, want active, passive or empty", in.Name, in.LACP)
+	}
+	for _, m := range in.Members {
+		l.OndatraPorts = append(l.OndatraPorts, intentPort(t, dut, m))
+	}
+	for _, s := range in.Subinterfaces {
+		l.SubInterfaces = append(l.SubInterfaces, s.data())
+	}
+	NewAggregateInterface(t, dut, batch, l)
+}
+
+func intentAFIType(t *testing.T, kind, name, typ string) bool {
+	t.Helper()
+	switch typ {
+	case "ipv4":
+		return true
+	case "ipv6":
+		return false
+	}
+	t.Fatalf("%s %s has type %q, want ipv4 or ipv6", kind, name, typ)
+	return false
+}
+
+func compileQoS(t *testing.T, dut *ondatra.DUTDevice, batch *gnmi.SetBatch, in *QoSIntent) {
+	t.Helper()
+	q := &oc.Qos{}
+	NewQoSQueue(t, dut, q)
+
+	var fgs []ForwardingGroup
+	for _, fg := range in.ForwardingGroups {
+		fgs = append(fgs, ForwardingGroup{TargetGroup: fg.Name, QueueName: fg.Queue})
+	}
+	NewQoSForwardingGroup(t, dut, q, fgs)
+
+	var classifiers []QosClassifier
+	for _, c := range in.Classifiers {
+		classType := oc.Qos_Classifier_Type_IPV6
+		if intentAFIType(t, "QoS classifier", c.Name, c.Type) {
+			classType = oc.Qos_Classifier_Type_IPV4
+		}
+		classifiers = append(classifiers, QosClassifier{
+			Desc:        fmt.Sprintf("classifier %s term %s", c.Name, c.Term),
+			Name:        c.Name,
+			ClassType:   classType,
+			TermID:      c.Term,
+			TargetGroup: c.TargetGroup,
+			DscpSet:     c.DSCP,
+			RemarkDscp:  c.RemarkDSCP,
+		})
+	}
+	NewQoSClassifierConfiguration(t, dut, q, classifiers)
+
+	var policies []SchedulerPolicy
+	for _, s := range in.Schedulers {
+		p := SchedulerPolicy{
+			Sequence:  s.Sequence,
+			InputID:   cmp.Or(s.Input, s.Queue),
+			InputType: oc.Input_InputType_QUEUE,
+			QueueName: s.Queue,
+		}
+		switch s.Priority {
+		case "":
+		case "strict":
+			p.SetPriority = true
+			p.Priority = oc.Scheduler_Priority_STRICT
+		default:
+			t.Fatalf("QoS scheduler %d has priority %q, want strict or empty", s.Sequence, s.Priority)
+		}
+		policies = append(policies, p)
+	}
+	if len(policies) > 0 {
+		NewQoSSchedulerPolicy(t, dut, q, policies)
+	}
+
+	for _, o := range in.Outputs {
+		intentPort(t, dut, o.Interface)
+		var intfs []QoSSchedulerInterface
+		for _, queue := range o.Queues {
+			intfs = append(intfs, QoSSchedulerInterface{QueueName: queue, Scheduler: "scheduler"})
+		}
+		NewQoSSchedulerInterface(t, dut, q, intfs, o.Interface)
+	}
+	gnmi.BatchReplace(batch, gnmi.OC().Qos().Config(), q)
+}
+
+func compileACL(t *testing.T, dut *ondatra.DUTDevice, batch *gnmi.SetBatch, in ACLIntent) {
+	t.Helper()
+	params := AclParams{
+		Name:          in.Name,
+		ACLType:       oc.Acl_ACL_TYPE_ACL_IPV6,
+		Intf:          intentInterfaceName(dut, in.Interface),
+		Ingress:       !in.Egress,
+		DefaultPermit: in.DefaultPermit,
+	}
+	if intentAFIType(t, "ACL", in.Name, in.Type) {
+		params.ACLType = oc.Acl_ACL_TYPE_ACL_IPV4
+	}
+	for _, term := range in.Terms {
+		params.Terms = append(params.Terms, AclTerm{
+			SeqID:          term.Sequence,
+			Description:    term.Description,
+			Permit:         term.Permit,
+			IPSrc:          term.Source,
+			IPDst:          term.Destination,
+			Protocol:       term.Protocol,
+			L4SrcPort:      term.SrcPort,
+			L4SrcPortRange: term.SrcPortRange,
+			L4DstPort:      term.DstPort,
+			L4DstPortRange: term.DstPortRange,
+			Log:            term.Log,
+		})
+	}
+	ConfigureACL(t, dut, batch, params)
+}
+
+func compileStaticRoute(t *testing.T, dut *ondatra.DUTDevice, batch *gnmi.SetBatch, in StaticRouteIntent) {
+	t.Helper()
+	cfg := &StaticRouteCfg{
+		NetworkInstance: in.NetworkInstance,
+		Prefix:          in.Prefix,
+		Metric:          in.Metric,
+		T:               t,
+	}
+	if in.Interface != "" {
+		cfg.NextHopIntf = intentInterfaceName(dut, in.Interface)
+	}
+	if len(in.NextHops) > 0 {
+		cfg.NextHops = map[string]oc.NetworkInstance_Protocol_Static_NextHop_NextHop_Union{}
+		for i, nh := range in.NextHops {
+			cfg.NextHops[strconv.Itoa(i)] = oc.UnionString(nh)
+		}
+	}
+	if _, err := NewStaticRouteCfg(batch, cfg, dut); err != nil {
+		t.Fatalf("Cannot configure static route %s: %v", in.Prefix, err)
+	}
+}
+
+func compileISIS(t *testing.T, dut *ondatra.DUTDevice, batch *gnmi.SetBatch, in *ISISIntent) {
+	t.Helper()
+	cfg := ISISConfigBasic{
+		InstanceName: in.Instance,
+		AreaAddress:  in.Area,
+		SystemID:     in.SystemID,
+		AggID:        in.LAG,
+		LoopbackIntf: intentInterfaceName(dut, in.Loopback),
+	}
+	for _, i := range in.Interfaces {
+		cfg.Ports = append(cfg.Ports, intentPort(t, dut, i))
+	}
+	NewISISBasic(t, batch, dut, cfg)
+}
+
+func compileBGP(t *testing.T, dut *ondatra.DUTDevice, batch *gnmi.SetBatch, in *BGPIntent) {
+	t.Helper()
+	p := ConfigureDUTBGP(t, dut, batch, BGPConfig{
+		DutAS:       in.AS,
+		RouterID:    in.RouterID,
+		ECMPMaxPath: in.ECMPMaxPath,
+	})
+	for _, n := range in.Neighbors {
+		if n.IPv4 == "" || n.IPv6 == "" {
+			t.Fatalf("BGP neighbor on %s must have both ipv4 and ipv6 addresses", n.Interface)
+		}
+		cfg := BGPNeighborConfig{
+			AteAS:            n.PeerAS,
+			PortName:         n.Interface,
+			NeighborIPv4:     n.IPv4,
+			NeighborIPv6:     n.IPv6,
+			IsLag:            n.LAG,
+			MultiPathEnabled: n.Multipath,
+		}
+		if n.Policy != "" {
+			cfg.PolicyName = &n.Policy
+		}
+		AppendBGPNeighbor(t, dut, batch, p.GetBgp(), cfg)
+	}
+}
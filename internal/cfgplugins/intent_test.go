@@ -0,0 +1,82 @@
+// Copyright 2026 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cfgplugins
+
+import (
+	"flag"
+	"strings"
+	"testing"
+)
+
+var intentFile = flag.String("intent_file", "", "Intent file to dry run for each vendor; the resulting SetRequests are logged.")
+
+func TestParseIntent(t *testing.T) {
+	in, err := LoadIntent("testdata/intent/example.yaml")
+	if err != nil {
+		t.Fatalf("LoadIntent() got error: %v", err)
+	}
+	if got, want := len(in.Interfaces), 3; got != want {
+		t.Errorf("LoadIntent() got %d interfaces, want %d", got, want)
+	}
+	if got, want := in.BGP.Neighbors[0].PeerAS, uint32(65001); got != want {
+		t.Errorf("LoadIntent() got BGP peer AS %d, want %d", got, want)
+	}
+
+	if _, err := ParseIntent([]byte("interfaces:\n  - interface: port1\n    ipv4_address: 192.0.2.1\n")); err == nil {
+		t.Errorf("ParseIntent() with an unknown field got no error, want error")
+	}
+}
+
+func TestDryRunIntent(t *testing.T) {
+	path := "testdata/intent/example.yaml"
+	if *intentFile != "" {
+		path = *intentFile
+	}
+	in, err := LoadIntent(path)
+	if err != nil {
+		t.Fatalf("LoadIntent(%q) got error: %v", path, err)
+	}
+	for _, p := range DryRunVendors {
+		t.Run(p.Vendor.String(), func(t *testing.T) {
+			reqs := DryRunIntent(t, in, p)
+			if len(reqs) == 0 {
+				t.Fatalf("DryRunIntent() sent no SetRequest")
+			}
+			got, err := FormatSetRequests(reqs)
+			if err != nil {
+				t.Fatalf("FormatSetRequests() got error: %v", err)
+			}
+			if *intentFile != "" {
+				t.Logf("Dry run of %s for %s:\n%s", path, p, got)
+				return
+			}
+			for _, want := range []string{
+				"/interfaces/interface[name=Ethernet1]",
+				"/interfaces/interface[name=Port-Channel1]",
+				"/interfaces/interface[name=Loopback0]",
+				"/network-instances/network-instance[name=VRF-A]",
+				"/acl/acl-sets/acl-set[name=deny-telnet][type=ACL_IPV4]",
+				"/static-routes/static[prefix=198.51.100.0/24]",
+				"/protocols/protocol[identifier=ISIS]",
+				"/protocols/protocol[identifier=BGP]",
+				"/qos",
+			} {
+				if !strings.Contains(got, want) {
+					t.Errorf("DryRunIntent() has no operation on %s, got:\n%s", want, got)
+				}
+			}
+		})
+	}
+}
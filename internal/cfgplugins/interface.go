@@ -30,6 +30,7 @@ import (
 	"github.com/openconfig/featureprofiles/internal/deviations"
 	"github.com/openconfig/featureprofiles/internal/fptest"
 	"github.com/openconfig/featureprofiles/internal/helpers"
+	"github.com/openconfig/featureprofiles/internal/vendorcli"
 	"github.com/openconfig/ondatra"
 	"github.com/openconfig/ondatra/gnmi"
 	"github.com/openconfig/ondatra/gnmi/oc"
@@ -46,10 +47,6 @@ const (
 	vlanInterfaceCLITemplate = `interface vlan %d
    ip address %s/%d
    ipv6 address %s/%d
-`
-	interfaceFallbackCLITemplate = `interface %s
-   port-channel lacp fallback individual
-   port-channel lacp fallback timeout %d
 `
 )
 
@@ -1492,11 +1489,5 @@ func ConfigureVlanInterfaceFromOC(t *testing.T, dut *ondatra.DUTDevice, vlanBatc
 
 func ConfigureLACPFallbackCLI(t *testing.T, dut *ondatra.DUTDevice, lagIntfName string, timeoutSecs uint16) {
 	t.Helper()
-	switch dut.Vendor() {
-	case ondatra.ARISTA:
-		cli := fmt.Sprintf(interfaceFallbackCLITemplate, lagIntfName, timeoutSecs)
-		helpers.GnmiCLIConfig(t, dut, cli)
-	default:
-		t.Fatalf("configureLACPFallbackCLI: unsupported vendor %s", dut.Vendor())
-	}
+	vendorcli.LACPFallback.Config(t, dut, vendorcli.LACPFallbackParams{Interface: lagIntfName, TimeoutSecs: timeoutSecs})
 }
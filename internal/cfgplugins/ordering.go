@@ -0,0 +1,647 @@
+// Copyright 2026 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cfgplugins
+
+import (
+	"context"
+	"fmt"
+	"maps"
+	"reflect"
+	"slices"
+	"sort"
+	"strings"
+	"testing"
+
+	"github.com/openconfig/featureprofiles/internal/deviations"
+	"github.com/openconfig/goyang/pkg/yang"
+	"github.com/openconfig/ondatra"
+	"github.com/openconfig/ondatra/gnmi/oc"
+	"github.com/openconfig/ygot/util"
+	"github.com/openconfig/ygot/ygot"
+
+	gpb "github.com/openconfig/gnmi/proto/gnmi"
+)
+
+// ConfigObject is a unit of configuration ordered by OrderConfig: an entry of an outermost
+// list of the schema, e.g. an interface, a network instance or a routing policy, or a leaf
+// outside of any list.
+type ConfigObject struct {
+	// Path is the path of the object, e.g. /interfaces/interface[name=Ethernet1].
+	Path *gpb.Path
+	// DependsOn are the paths of the objects of the configuration the object references by
+	// leafref.
+	DependsOn []string
+
+	// list is the schema path of the list of the object, or empty for a leaf.
+	list string
+	// key is the value of the single key of the list entry.
+	key   string
+	value any
+}
+
+// String returns the path of the object.
+func (o *ConfigObject) String() string {
+	s, err := ygot.PathToString(o.Path)
+	if err != nil {
+		return fmt.Sprintf("<invalid path %v: %v>", o.Path, err)
+	}
+	return s
+}
+
+// typedValue returns the JSON_IETF value of the object, as ygnmi encodes configuration.
+func (o *ConfigObject) typedValue() (*gpb.TypedValue, error) {
+	if s, ok := o.value.(ygot.GoStruct); ok {
+		j, err := ygot.Marshal7951(s, &ygot.RFC7951JSONConfig{AppendModuleName: true, PreferShadowPath: true})
+		if err != nil {
+			return nil, err
+		}
+		return &gpb.TypedValue{Value: &gpb.TypedValue_JsonIetfVal{JsonIetfVal: j}}, nil
+	}
+	return ygot.EncodeTypedValue(o.value, gpb.Encoding_JSON_IETF)
+}
+
+// OrderConfig splits root into configuration objects and orders them in steps, each step
+// holding the objects whose references are to objects of earlier steps. Objects referencing
+// each other, directly or not, are in the same step. Within a step, objects are sorted by path.
+func OrderConfig(root *oc.Root) ([][]*ConfigObject, error) {
+	return orderConfig(root, OrderedSetOptions{})
+}
+
+// orderConfig orders the configuration objects of root as OrderConfig, splitting and ordering
+// them further as required by opts.AddressesAfterVRF and opts.DefaultNetworkInstance.
+func orderConfig(root *oc.Root, opts OrderedSetOptions) ([][]*ConfigObject, error) {
+	schema := oc.SchemaTree["Root"]
+	var objs []*ConfigObject
+	if err := collectObjects(schema, reflect.ValueOf(root), nil, &objs); err != nil {
+		return nil, err
+	}
+	if opts.AddressesAfterVRF {
+		var err error
+		if objs, err = splitAddresses(objs); err != nil {
+			return nil, err
+		}
+	}
+	byList := map[string][]*ConfigObject{}
+	byKey := map[string]*ConfigObject{}
+	for _, o := range objs {
+		if o.list != "" {
+			byList[o.list] = append(byList[o.list], o)
+			byKey[o.list+"\x00"+o.key] = o
+		}
+	}
+
+	deps := map[*ConfigObject][]*ConfigObject{}
+	for _, o := range objs {
+		seen := map[*ConfigObject]bool{o: true}
+		add := func(d *ConfigObject) {
+			if d != nil && !seen[d] {
+				seen[d] = true
+				deps[o] = append(deps[o], d)
+			}
+		}
+		var err error
+		visitLeafRefs(schema, o, func(e *yang.Entry, vals []string) {
+			list, keyed, rerr := leafRefTarget(schema, e)
+			if rerr != nil {
+				err = rerr
+				return
+			}
+			switch {
+			case list == "":
+			case keyed:
+				for _, v := range vals {
+					add(byKey[list+"\x00"+v])
+				}
+			case list != o.list:
+				// The referenced entry cannot be told from the value alone.
+				for _, d := range byList[list] {
+					add(d)
+				}
+			}
+		})
+		if err != nil {
+			return nil, err
+		}
+		if opts.AddressesAfterVRF && o.list == addressList {
+			// An address is configured after its interface is bound to its network instance.
+			intf := o.Path.GetElem()[1].GetKey()["name"]
+			add(byKey[interfaceList+"\x00"+intf])
+			for _, ni := range byList[networkInstanceList] {
+				if bindsInterface(ni.value.(*oc.NetworkInstance), intf) {
+					add(ni)
+				}
+			}
+		}
+		if def := opts.DefaultNetworkInstance; def != "" && o.list == networkInstanceList && o.key != def {
+			add(byKey[networkInstanceList+"\x00"+def])
+		}
+		for _, d := range deps[o] {
+			o.DependsOn = append(o.DependsOn, d.String())
+		}
+		sort.Strings(o.DependsOn)
+	}
+	return orderSteps(objs, deps), nil
+}
+
+const (
+	interfaceList       = "/interfaces/interface"
+	networkInstanceList = "/network-instances/network-instance"
+	// addressList marks the objects splitAddresses splits out of interfaces.
+	addressList = "/interfaces/interface/subinterfaces/subinterface/address"
+)
+
+// splitAddresses replaces the interface objects of objs with copies without IPv4 and IPv6
+// addresses, followed by an object per address.
+func splitAddresses(objs []*ConfigObject) ([]*ConfigObject, error) {
+	var split []*ConfigObject
+	for _, o := range objs {
+		intf, ok := o.value.(*oc.Interface)
+		if o.list != interfaceList || !ok {
+			split = append(split, o)
+			continue
+		}
+		c, err := ygot.DeepCopy(intf)
+		if err != nil {
+			return nil, fmt.Errorf("cannot copy %s: %w", o, err)
+		}
+		intf = c.(*oc.Interface)
+		split = append(split, &ConfigObject{Path: o.Path, list: o.list, key: o.key, value: intf})
+		for _, idx := range slices.Sorted(maps.Keys(intf.Subinterface)) {
+			sub := intf.Subinterface[idx]
+			subElems := append(slices.Clone(o.Path.GetElem()),
+				&gpb.PathElem{Name: "subinterfaces"},
+				&gpb.PathElem{Name: "subinterface", Key: map[string]string{"index": fmt.Sprint(idx)}})
+			addr := func(family, ip string, value any) {
+				elems := append(slices.Clone(subElems),
+					&gpb.PathElem{Name: family},
+					&gpb.PathElem{Name: "addresses"},
+					&gpb.PathElem{Name: "address", Key: map[string]string{"ip": ip}})
+				split = append(split, &ConfigObject{Path: &gpb.Path{Elem: elems}, list: addressList, value: value})
+			}
+			if v4 := sub.GetIpv4(); v4 != nil {
+				for _, ip := range slices.Sorted(maps.Keys(v4.Address)) {
+					addr("ipv4", ip, v4.Address[ip])
+				}
+				v4.Address = nil
+			}
+			if v6 := sub.GetIpv6(); v6 != nil {
+				for _, ip := range slices.Sorted(maps.Keys(v6.Address)) {
+					addr("ipv6", ip, v6.Address[ip])
+				}
+				v6.Address = nil
+			}
+		}
+	}
+	return split, nil
+}
+
+// bindsInterface returns whether the network instance ni has the interface intf.
+func bindsInterface(ni *oc.NetworkInstance, intf string) bool {
+	for _, i := range ni.Interface {
+		if i.GetInterface() == intf {
+			return true
+		}
+	}
+	return false
+}
+
+// collectObjects appends the configuration objects of the GoStruct v with the given schema
+// and path to objs.
+func collectObjects(schema *yang.Entry, v reflect.Value, elems []*gpb.PathElem, objs *[]*ConfigObject) error {
+	if util.IsValueNil(v.Interface()) {
+		return nil
+	}
+	sv := v.Elem()
+	for i := 0; i < sv.NumField(); i++ {
+		ft := sv.Type().Field(i)
+		fv := sv.Field(i)
+		if util.IsYgotAnnotation(ft) || util.IsValueNil(fv.Interface()) {
+			continue
+		}
+		cs, err := util.ChildSchema(schema, ft)
+		if err != nil {
+			return err
+		}
+		if cs == nil {
+			return fmt.Errorf("no schema for field %s of %s", ft.Name, sv.Type())
+		}
+		names, err := configPath(ft)
+		if err != nil {
+			return err
+		}
+		var celems []*gpb.PathElem
+		for _, n := range names {
+			celems = append(celems, &gpb.PathElem{Name: n})
+		}
+		celems = append(slices.Clone(elems), celems...)
+		switch {
+		case cs.IsList():
+			for _, ev := range listEntries(fv) {
+				elem := ev.Interface()
+				kh, ok := elem.(ygot.KeyHelperGoStruct)
+				if !ok {
+					return fmt.Errorf("list %s has no keys", ft.Name)
+				}
+				km, err := kh.ΛListKeyMap()
+				if err != nil {
+					return err
+				}
+				keyStrs := map[string]string{}
+				for kn, kv := range km {
+					if keyStrs[kn], err = ygot.KeyValueAsString(kv); err != nil {
+						return err
+					}
+				}
+				pelems := slices.Clone(celems)
+				pelems[len(pelems)-1] = &gpb.PathElem{Name: pelems[len(pelems)-1].Name, Key: keyStrs}
+				o := &ConfigObject{Path: &gpb.Path{Elem: pelems}, list: schemaPath(cs), value: elem}
+				if len(km) == 1 {
+					for _, s := range keyStrs {
+						o.key = s
+					}
+				}
+				*objs = append(*objs, o)
+			}
+		case cs.IsContainer():
+			if err := collectObjects(cs, fv, celems, objs); err != nil {
+				return err
+			}
+		default:
+			*objs = append(*objs, &ConfigObject{Path: &gpb.Path{Elem: celems}, value: fv.Interface()})
+		}
+	}
+	return nil
+}
+
+// listEntries returns the entries of the map or ordered map of a list, in key order for maps.
+func listEntries(v reflect.Value) []reflect.Value {
+	if om, ok := v.Interface().(ygot.GoOrderedMap); ok {
+		if util.IsValueNil(om) {
+			return nil
+		}
+		vals := v.MethodByName("Values").Call(nil)[0]
+		var entries []reflect.Value
+		for i := 0; i < vals.Len(); i++ {
+			entries = append(entries, vals.Index(i))
+		}
+		return entries
+	}
+	keys := v.MapKeys()
+	sort.Slice(keys, func(i, j int) bool { return fmt.Sprint(keys[i].Interface()) < fmt.Sprint(keys[j].Interface()) })
+	var entries []reflect.Value
+	for _, k := range keys {
+		entries = append(entries, v.MapIndex(k))
+	}
+	return entries
+}
+
+// configPath returns the path of a GoStruct field to its configuration.
+func configPath(ft reflect.StructField) ([]string, error) {
+	p, ok := ft.Tag.Lookup("shadow-path")
+	if !ok {
+		if p, ok = ft.Tag.Lookup("path"); !ok {
+			return nil, fmt.Errorf("field %s has no path", ft.Name)
+		}
+	}
+	p, _, _ = strings.Cut(p, "|")
+	return strings.Split(strings.TrimPrefix(p, "/"), "/"), nil
+}
+
+// schemaPath returns the path of a schema entry, without the root.
+func schemaPath(e *yang.Entry) string {
+	p := e.Path()
+	if i := strings.Index(p[1:], "/"); i >= 0 {
+		return p[i+1:]
+	}
+	return "/"
+}
+
+// visitLeafRefs calls fn with each leafref leaf of the object and its values.
+func visitLeafRefs(root *yang.Entry, o *ConfigObject, fn func(e *yang.Entry, vals []string)) {
+	if o.list == "" {
+		e, err := util.FindLeafRefSchema(root, "/"+strings.Join(pathNames(o.Path), "/"))
+		if err == nil && e.Type.Kind == yang.Yleafref {
+			fn(e, leafValues(reflect.ValueOf(o.value)))
+		}
+		return
+	}
+	e := root
+	for _, n := range strings.Split(strings.TrimPrefix(o.list, "/"), "/") {
+		if e = e.Dir[n]; e == nil {
+			return
+		}
+	}
+	visitStructLeafRefs(e, reflect.ValueOf(o.value), fn)
+}
+
+func visitStructLeafRefs(schema *yang.Entry, v reflect.Value, fn func(e *yang.Entry, vals []string)) {
+	if util.IsValueNil(v.Interface()) {
+		return
+	}
+	sv := v.Elem()
+	for i := 0; i < sv.NumField(); i++ {
+		ft := sv.Type().Field(i)
+		fv := sv.Field(i)
+		if util.IsYgotAnnotation(ft) || util.IsValueNil(fv.Interface()) {
+			continue
+		}
+		cs, err := util.ChildSchema(schema, ft)
+		if err != nil || cs == nil {
+			continue
+		}
+		switch {
+		case cs.IsList():
+			for _, ev := range listEntries(fv) {
+				visitStructLeafRefs(cs, ev, fn)
+			}
+		case cs.IsContainer():
+			visitStructLeafRefs(cs, fv, fn)
+		case cs.Type != nil && cs.Type.Kind == yang.Yleafref:
+			fn(cs, leafValues(fv))
+		}
+	}
+}
+
+func pathNames(p *gpb.Path) []string {
+	var names []string
+	for _, e := range p.GetElem() {
+		names = append(names, e.GetName())
+	}
+	return names
+}
+
+// leafValues returns the values of a leaf or leaf-list as strings.
+func leafValues(v reflect.Value) []string {
+	for v.Kind() == reflect.Ptr || v.Kind() == reflect.Interface {
+		if v.IsNil() {
+			return nil
+		}
+		v = v.Elem()
+	}
+	if v.Kind() == reflect.Slice {
+		var vals []string
+		for i := 0; i < v.Len(); i++ {
+			vals = append(vals, leafValues(v.Index(i))...)
+		}
+		return vals
+	}
+	if s, err := ygot.KeyValueAsString(v.Interface()); err == nil {
+		return []string{s}
+	}
+	return []string{fmt.Sprint(v.Interface())}
+}
+
+// leafRefTarget returns the schema path of the outermost list the leafref e references an
+// entry of, if any, and whether the leafref is to the single key of that list, in which case
+// the values of the leafref are keys of the list.
+func leafRefTarget(root, e *yang.Entry) (string, bool, error) {
+	target := leafRefPath(e)
+	cur := root
+	for i, n := range target {
+		if cur = cur.Dir[n]; cur == nil {
+			return "", false, fmt.Errorf("leafref %s of %s is not in the schema", e.Type.Path, schemaPath(e))
+		}
+		if !cur.IsList() {
+			continue
+		}
+		rest := target[i+1:]
+		keyed := !strings.Contains(cur.Key, " ") && (len(rest) == 1 && rest[0] == cur.Key ||
+			len(rest) == 2 && (rest[0] == "config" || rest[0] == "state") && rest[1] == cur.Key)
+		return "/" + strings.Join(target[:i+1], "/"), keyed, nil
+	}
+	return "", false, nil
+}
+
+// leafRefPath returns the absolute schema path of the leaf the leafref e references, without
+// module prefixes and predicates.
+func leafRefPath(e *yang.Entry) []string {
+	var b strings.Builder
+	depth := 0
+	for _, r := range e.Type.Path {
+		switch {
+		case r == '[':
+			depth++
+		case r == ']':
+			depth--
+		case depth == 0:
+			b.WriteRune(r)
+		}
+	}
+	ref := b.String()
+	var path []string
+	if !strings.HasPrefix(ref, "/") {
+		path = strings.Split(strings.TrimPrefix(schemaPath(e), "/"), "/")
+	}
+	for _, n := range strings.Split(strings.TrimPrefix(ref, "/"), "/") {
+		switch n {
+		case "", ".":
+		case "..":
+			path = path[:max(len(path)-1, 0)]
+		default:
+			if _, name, ok := strings.Cut(n, ":"); ok {
+				n = name
+			}
+			path = append(path, n)
+		}
+	}
+	return path
+}
+
+// orderSteps groups the objects in steps after the objects they depend on, merging the
+// strongly connected components of the dependencies.
+func orderSteps(objs []*ConfigObject, deps map[*ConfigObject][]*ConfigObject) [][]*ConfigObject {
+	// Tarjan's algorithm emits a component after the components it depends on.
+	index := map[*ConfigObject]int{}
+	low := map[*ConfigObject]int{}
+	onStack := map[*ConfigObject]bool{}
+	comp := map[*ConfigObject]int{}
+	var stack []*ConfigObject
+	var comps [][]*ConfigObject
+	var connect func(o *ConfigObject)
+	connect = func(o *ConfigObject) {
+		index[o] = len(index)
+		low[o] = index[o]
+		stack = append(stack, o)
+		onStack[o] = true
+		for _, d := range deps[o] {
+			if _, ok := index[d]; !ok {
+				connect(d)
+				low[o] = min(low[o], low[d])
+			} else if onStack[d] {
+				low[o] = min(low[o], index[d])
+			}
+		}
+		if low[o] != index[o] {
+			return
+		}
+		var c []*ConfigObject
+		for {
+			n := stack[len(stack)-1]
+			stack = stack[:len(stack)-1]
+			onStack[n] = false
+			comp[n] = len(comps)
+			c = append(c, n)
+			if n == o {
+				break
+			}
+		}
+		comps = append(comps, c)
+	}
+	for _, o := range objs {
+		if _, ok := index[o]; !ok {
+			connect(o)
+		}
+	}
+
+	level := make([]int, len(comps))
+	var steps [][]*ConfigObject
+	for i, c := range comps {
+		for _, o := range c {
+			for _, d := range deps[o] {
+				if dc := comp[d]; dc != i {
+					level[i] = max(level[i], level[dc]+1)
+				}
+			}
+		}
+		for len(steps) <= level[i] {
+			steps = append(steps, nil)
+		}
+		steps[level[i]] = append(steps[level[i]], c...)
+	}
+	for _, s := range steps {
+		sort.Slice(s, func(i, j int) bool { return s[i].String() < s[j].String() })
+	}
+	return steps
+}
+
+// OrderedSetOptions are the options of OrderedSetRequests and OrderedDeleteRequests.
+type OrderedSetOptions struct {
+	// Replace replaces the configuration objects instead of updating them.
+	Replace bool
+	// MultiStep sends the objects of each step in their own SetRequest, instead of a single
+	// SetRequest with the objects in order.
+	MultiStep bool
+	// AddressesAfterVRF configures the IPv4 and IPv6 addresses of subinterfaces as objects of
+	// their own, after the network instances the interfaces are bound to.
+	AddressesAfterVRF bool
+	// DefaultNetworkInstance, if set, is the name of the default network instance, configured
+	// before the other network instances.
+	DefaultNetworkInstance string
+}
+
+// OrderedSetRequests returns SetRequests setting the configuration of root, ordered by
+// OrderConfig and opts.
+func OrderedSetRequests(root *oc.Root, opts OrderedSetOptions) ([]*gpb.SetRequest, error) {
+	steps, err := orderConfig(root, opts)
+	if err != nil {
+		return nil, err
+	}
+	var reqs []*gpb.SetRequest
+	req := &gpb.SetRequest{}
+	for _, step := range steps {
+		for _, o := range step {
+			val, err := o.typedValue()
+			if err != nil {
+				return nil, fmt.Errorf("cannot encode %s: %w", o, err)
+			}
+			u := &gpb.Update{Path: &gpb.Path{Origin: "openconfig", Elem: o.Path.GetElem()}, Val: val}
+			if opts.Replace {
+				req.Replace = append(req.Replace, u)
+			} else {
+				req.Update = append(req.Update, u)
+			}
+		}
+		if opts.MultiStep {
+			reqs = append(reqs, req)
+			req = &gpb.SetRequest{}
+		}
+	}
+	if !opts.MultiStep && len(steps) > 0 {
+		reqs = append(reqs, req)
+	}
+	return reqs, nil
+}
+
+// OrderedDeleteRequests returns SetRequests deleting the configuration objects of root, in the
+// reverse order of OrderedSetRequests so that objects are deleted before the objects they
+// reference. opts.Replace is ignored.
+func OrderedDeleteRequests(root *oc.Root, opts OrderedSetOptions) ([]*gpb.SetRequest, error) {
+	steps, err := orderConfig(root, opts)
+	if err != nil {
+		return nil, err
+	}
+	var reqs []*gpb.SetRequest
+	req := &gpb.SetRequest{}
+	for i := len(steps) - 1; i >= 0; i-- {
+		for _, o := range steps[i] {
+			req.Delete = append(req.Delete, &gpb.Path{Origin: "openconfig", Elem: o.Path.GetElem()})
+		}
+		if opts.MultiStep {
+			reqs = append(reqs, req)
+			req = &gpb.SetRequest{}
+		}
+	}
+	if !opts.MultiStep && len(steps) > 0 {
+		reqs = append(reqs, req)
+	}
+	return reqs, nil
+}
+
+// orderedSetOptions returns the options ordering configuration as required by the deviations
+// of the DUT: interfaces are bound to their VRF before their addresses are configured, and the
+// default network instance, with its BGP AFI-SAFIs, is configured before the other network
+// instances. Either requires the objects to be pushed in steps.
+func orderedSetOptions(dut *ondatra.DUTDevice, replace bool) OrderedSetOptions {
+	opts := OrderedSetOptions{Replace: replace, AddressesAfterVRF: deviations.InterfaceConfigVRFBeforeAddress(dut)}
+	if deviations.BgpAfiSafiInDefaultNiBeforeOtherNi(dut) {
+		opts.DefaultNetworkInstance = deviations.DefaultNetworkInstance(dut)
+	}
+	opts.MultiStep = opts.AddressesAfterVRF || opts.DefaultNetworkInstance != ""
+	return opts
+}
+
+// PushOrderedConfig sets the configuration of root on the DUT in dependency order: in a
+// SetRequest per step if the deviations of the DUT require objects to be configured first, or
+// in a single ordered SetRequest otherwise.
+func PushOrderedConfig(t *testing.T, dut *ondatra.DUTDevice, root *oc.Root, replace bool) {
+	t.Helper()
+	reqs, err := OrderedSetRequests(root, orderedSetOptions(dut, replace))
+	if err != nil {
+		t.Fatalf("Cannot order configuration: %v", err)
+	}
+	setOrdered(t, dut, reqs)
+}
+
+// DeleteOrderedConfig deletes the configuration objects of root from the DUT in the reverse
+// order of PushOrderedConfig.
+func DeleteOrderedConfig(t *testing.T, dut *ondatra.DUTDevice, root *oc.Root) {
+	t.Helper()
+	reqs, err := OrderedDeleteRequests(root, orderedSetOptions(dut, false))
+	if err != nil {
+		t.Fatalf("Cannot order configuration: %v", err)
+	}
+	setOrdered(t, dut, reqs)
+}
+
+func setOrdered(t *testing.T, dut *ondatra.DUTDevice, reqs []*gpb.SetRequest) {
+	t.Helper()
+	c := dut.RawAPIs().GNMI(t)
+	for i, req := range reqs {
+		t.Logf("Setting ordered configuration step %d of %d", i+1, len(reqs))
+		if _, err := c.Set(context.Background(), req); err != nil {
+			t.Fatalf("gnmiClient.Set() of step %d with unexpected error: %v", i+1, err)
+		}
+	}
+}
@@ -0,0 +1,327 @@
+// Copyright 2026 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cfgplugins
+
+import (
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/openconfig/ondatra"
+	"github.com/openconfig/ondatra/gnmi/oc"
+	"github.com/openconfig/ygot/ygot"
+
+	mpb "github.com/openconfig/featureprofiles/proto/metadata_go_proto"
+	gpb "github.com/openconfig/gnmi/proto/gnmi"
+	opb "github.com/openconfig/ondatra/proto"
+)
+
+// orderedRoot returns configuration with a member of a LAG in a VRF whose BGP neighbor imports
+// a policy matching a prefix set, and a QoS classifier.
+func orderedRoot(t *testing.T) *oc.Root {
+	t.Helper()
+	root := &oc.Root{}
+	root.GetOrCreateSystem().SetHostname("dut1")
+	root.GetOrCreateInterface("Port-Channel1").SetType(oc.IETFInterfaces_InterfaceType_ieee8023adLag)
+	root.GetOrCreateInterface("Ethernet1").GetOrCreateEthernet().SetAggregateId("Port-Channel1")
+
+	ni := root.GetOrCreateNetworkInstance("VRF-A")
+	ni.SetType(oc.NetworkInstanceTypes_NETWORK_INSTANCE_TYPE_L3VRF)
+	nii := ni.GetOrCreateInterface("Ethernet1")
+	nii.SetInterface("Ethernet1")
+	nii.SetSubinterface(0)
+	bgp := ni.GetOrCreateProtocol(oc.PolicyTypes_INSTALL_PROTOCOL_TYPE_BGP, "BGP").GetOrCreateBgp()
+	bgp.GetOrCreateNeighbor("192.0.2.1").GetOrCreateApplyPolicy().SetImportPolicy([]string{"P1"})
+
+	rp := root.GetOrCreateRoutingPolicy()
+	rp.GetOrCreateDefinedSets().GetOrCreatePrefixSet("PS1").SetMode(oc.PrefixSet_Mode_IPV4)
+	stmt, err := rp.GetOrCreatePolicyDefinition("P1").AppendNewStatement("10")
+	if err != nil {
+		t.Fatalf("AppendNewStatement() got error: %v", err)
+	}
+	stmt.GetOrCreateConditions().GetOrCreateMatchPrefixSet().SetPrefixSet("PS1")
+	stmt.GetOrCreateActions().SetPolicyResult(oc.RoutingPolicy_PolicyResultType_ACCEPT_ROUTE)
+
+	qos := root.GetOrCreateQos()
+	qos.GetOrCreateQueue("q1")
+	qos.GetOrCreateForwardingGroup("fg1").SetOutputQueue("q1")
+	qos.GetOrCreateClassifier("c1").GetOrCreateTerm("t1").GetOrCreateActions().SetTargetGroup("fg1")
+	return root
+}
+
+func stepStrings(steps [][]*ConfigObject) [][]string {
+	var out [][]string
+	for _, step := range steps {
+		var s []string
+		for _, o := range step {
+			s = append(s, o.String())
+		}
+		out = append(out, s)
+	}
+	return out
+}
+
+func TestOrderConfig(t *testing.T) {
+	steps, err := OrderConfig(orderedRoot(t))
+	if err != nil {
+		t.Fatalf("OrderConfig() got error: %v", err)
+	}
+	want := [][]string{{
+		"/interfaces/interface[name=Port-Channel1]",
+		"/qos/queues/queue[name=q1]",
+		"/routing-policy/defined-sets/prefix-sets/prefix-set[name=PS1]",
+		"/system/config/hostname",
+	}, {
+		"/interfaces/interface[name=Ethernet1]",
+		"/qos/forwarding-groups/forwarding-group[name=fg1]",
+		"/routing-policy/policy-definitions/policy-definition[name=P1]",
+	}, {
+		"/network-instances/network-instance[name=VRF-A]",
+		"/qos/classifiers/classifier[name=c1]",
+	}}
+	if diff := cmp.Diff(want, stepStrings(steps)); diff != "" {
+		t.Errorf("OrderConfig() steps diff (-want +got):\n%s", diff)
+	}
+
+	// The subinterface of the network instance interface may be of any interface.
+	wantDeps := []string{
+		"/interfaces/interface[name=Ethernet1]",
+		"/interfaces/interface[name=Port-Channel1]",
+		"/routing-policy/policy-definitions/policy-definition[name=P1]",
+	}
+	if got := steps[2][0].DependsOn; !slices.Equal(got, wantDeps) {
+		t.Errorf("DependsOn of %s got %v, want %v", steps[2][0], got, wantDeps)
+	}
+}
+
+func TestOrderConfigCycle(t *testing.T) {
+	root := &oc.Root{}
+	root.GetOrCreateInterface("Ethernet1").GetOrCreateEthernet().SetAggregateId("Ethernet2")
+	root.GetOrCreateInterface("Ethernet2").GetOrCreateEthernet().SetAggregateId("Ethernet1")
+	root.GetOrCreateNetworkInstance("VRF-A").GetOrCreateInterface("Ethernet1").SetInterface("Ethernet1")
+	steps, err := OrderConfig(root)
+	if err != nil {
+		t.Fatalf("OrderConfig() got error: %v", err)
+	}
+	want := [][]string{
+		{"/interfaces/interface[name=Ethernet1]", "/interfaces/interface[name=Ethernet2]"},
+		{"/network-instances/network-instance[name=VRF-A]"},
+	}
+	if diff := cmp.Diff(want, stepStrings(steps)); diff != "" {
+		t.Errorf("OrderConfig() steps diff (-want +got):\n%s", diff)
+	}
+}
+
+func pathString(t *testing.T, p *gpb.Path) string {
+	t.Helper()
+	s, err := ygot.PathToString(p)
+	if err != nil {
+		t.Fatalf("PathToString(%v) got error: %v", p, err)
+	}
+	return s
+}
+
+func requestPaths(t *testing.T, reqs []*gpb.SetRequest) []string {
+	t.Helper()
+	var out []string
+	for _, req := range reqs {
+		var ps []string
+		for _, u := range append(slices.Clone(req.GetReplace()), req.GetUpdate()...) {
+			ps = append(ps, pathString(t, u.GetPath()))
+		}
+		for _, p := range req.GetDelete() {
+			ps = append(ps, "-"+pathString(t, p))
+		}
+		out = append(out, strings.Join(ps, " "))
+	}
+	return out
+}
+
+func TestOrderedRequests(t *testing.T) {
+	root := &oc.Root{}
+	root.GetOrCreateInterface("Port-Channel1").SetType(oc.IETFInterfaces_InterfaceType_ieee8023adLag)
+	root.GetOrCreateInterface("Ethernet1").GetOrCreateEthernet().SetAggregateId("Port-Channel1")
+	root.GetOrCreateNetworkInstance("VRF-A").GetOrCreateInterface("Ethernet1").SetInterface("Ethernet1")
+
+	tests := []struct {
+		desc   string
+		delete bool
+		opts   OrderedSetOptions
+		want   []string
+	}{{
+		desc: "single",
+		want: []string{"/interfaces/interface[name=Port-Channel1] /interfaces/interface[name=Ethernet1] /network-instances/network-instance[name=VRF-A]"},
+	}, {
+		desc: "multi-step",
+		opts: OrderedSetOptions{MultiStep: true},
+		want: []string{
+			"/interfaces/interface[name=Port-Channel1]",
+			"/interfaces/interface[name=Ethernet1]",
+			"/network-instances/network-instance[name=VRF-A]",
+		},
+	}, {
+		desc:   "delete",
+		delete: true,
+		want:   []string{"-/network-instances/network-instance[name=VRF-A] -/interfaces/interface[name=Ethernet1] -/interfaces/interface[name=Port-Channel1]"},
+	}, {
+		desc:   "multi-step delete",
+		delete: true,
+		opts:   OrderedSetOptions{MultiStep: true},
+		want: []string{
+			"-/network-instances/network-instance[name=VRF-A]",
+			"-/interfaces/interface[name=Ethernet1]",
+			"-/interfaces/interface[name=Port-Channel1]",
+		},
+	}}
+	for _, tc := range tests {
+		t.Run(tc.desc, func(t *testing.T) {
+			orderedRequests := OrderedSetRequests
+			if tc.delete {
+				orderedRequests = OrderedDeleteRequests
+			}
+			reqs, err := orderedRequests(root, tc.opts)
+			if err != nil {
+				t.Fatalf("got error: %v", err)
+			}
+			if diff := cmp.Diff(tc.want, requestPaths(t, reqs)); diff != "" {
+				t.Errorf("request paths diff (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+// vrfRoot returns configuration with an interface with addresses in a VRF, and BGP in the
+// default network instance.
+func vrfRoot() *oc.Root {
+	root := &oc.Root{}
+	sub := root.GetOrCreateInterface("Ethernet1").GetOrCreateSubinterface(0)
+	sub.GetOrCreateIpv4().GetOrCreateAddress("192.0.2.0").SetPrefixLength(31)
+	sub.GetOrCreateIpv6().GetOrCreateAddress("2001:db8::").SetPrefixLength(127)
+
+	def := root.GetOrCreateNetworkInstance("DEFAULT")
+	def.SetType(oc.NetworkInstanceTypes_NETWORK_INSTANCE_TYPE_DEFAULT_INSTANCE)
+	def.GetOrCreateProtocol(oc.PolicyTypes_INSTALL_PROTOCOL_TYPE_BGP, "BGP").GetOrCreateBgp().
+		GetOrCreateGlobal().GetOrCreateAfiSafi(oc.BgpTypes_AFI_SAFI_TYPE_IPV4_UNICAST).SetEnabled(true)
+	vrf := root.GetOrCreateNetworkInstance("VRF-A")
+	vrf.SetType(oc.NetworkInstanceTypes_NETWORK_INSTANCE_TYPE_L3VRF)
+	nii := vrf.GetOrCreateInterface("Ethernet1.0")
+	nii.SetInterface("Ethernet1")
+	nii.SetSubinterface(0)
+	return root
+}
+
+const (
+	vrfRootIntf = "/interfaces/interface[name=Ethernet1]"
+	vrfRootDef  = "/network-instances/network-instance[name=DEFAULT]"
+	vrfRootVRF  = "/network-instances/network-instance[name=VRF-A]"
+	vrfRootAddr = "/interfaces/interface[name=Ethernet1]/subinterfaces/subinterface[index=0]/ipv4/addresses/address[ip=192.0.2.0] " +
+		"/interfaces/interface[name=Ethernet1]/subinterfaces/subinterface[index=0]/ipv6/addresses/address[ip=2001:db8::]"
+)
+
+func TestOrderedRequestsDeviations(t *testing.T) {
+	tests := []struct {
+		desc   string
+		delete bool
+		opts   OrderedSetOptions
+		want   []string
+	}{{
+		desc: "no deviations",
+		opts: OrderedSetOptions{MultiStep: true},
+		want: []string{vrfRootIntf + " " + vrfRootDef, vrfRootVRF},
+	}, {
+		desc: "addresses after VRF",
+		opts: OrderedSetOptions{MultiStep: true, AddressesAfterVRF: true},
+		want: []string{vrfRootIntf + " " + vrfRootDef, vrfRootVRF, vrfRootAddr},
+	}, {
+		desc: "default network instance first",
+		opts: OrderedSetOptions{MultiStep: true, DefaultNetworkInstance: "DEFAULT"},
+		want: []string{vrfRootIntf + " " + vrfRootDef, vrfRootVRF},
+	}, {
+		desc:   "delete addresses first",
+		delete: true,
+		opts:   OrderedSetOptions{MultiStep: true, AddressesAfterVRF: true},
+		want:   []string{"-" + strings.ReplaceAll(vrfRootAddr, " ", " -"), "-" + vrfRootVRF, "-" + vrfRootIntf + " -" + vrfRootDef},
+	}}
+	for _, tc := range tests {
+		t.Run(tc.desc, func(t *testing.T) {
+			orderedRequests := OrderedSetRequests
+			if tc.delete {
+				orderedRequests = OrderedDeleteRequests
+			}
+			root := vrfRoot()
+			reqs, err := orderedRequests(root, tc.opts)
+			if err != nil {
+				t.Fatalf("got error: %v", err)
+			}
+			if diff := cmp.Diff(tc.want, requestPaths(t, reqs)); diff != "" {
+				t.Errorf("request paths diff (-want +got):\n%s", diff)
+			}
+			if got := len(root.GetInterface("Ethernet1").GetSubinterface(0).GetIpv4().Address); got != 1 {
+				t.Errorf("root has %d IPv4 addresses after ordering, want 1", got)
+			}
+		})
+	}
+
+	// Without its interface, the VRF is only ordered after the default network instance by
+	// DefaultNetworkInstance.
+	root := vrfRoot()
+	root.GetNetworkInstance("VRF-A").DeleteInterface("Ethernet1.0")
+	for _, tc := range []struct {
+		def  string
+		want []string
+	}{
+		{"", []string{vrfRootIntf + " " + vrfRootDef + " " + vrfRootVRF}},
+		{"DEFAULT", []string{vrfRootIntf + " " + vrfRootDef, vrfRootVRF}},
+	} {
+		reqs, err := OrderedSetRequests(root, OrderedSetOptions{MultiStep: true, DefaultNetworkInstance: tc.def})
+		if err != nil {
+			t.Fatalf("OrderedSetRequests() got error: %v", err)
+		}
+		if diff := cmp.Diff(tc.want, requestPaths(t, reqs)); diff != "" {
+			t.Errorf("OrderedSetRequests() with default network instance %q request paths diff (-want +got):\n%s", tc.def, diff)
+		}
+	}
+}
+
+func TestPushOrderedConfig(t *testing.T) {
+	md := &mpb.Metadata{PlatformExceptions: []*mpb.Metadata_PlatformExceptions{{
+		Platform:   &mpb.Metadata_Platform{Vendor: opb.Device_CISCO},
+		Deviations: &mpb.Metadata_Deviations{InterfaceConfigVrfBeforeAddress: true},
+	}, {
+		Platform:   &mpb.Metadata_Platform{Vendor: opb.Device_JUNIPER},
+		Deviations: &mpb.Metadata_Deviations{BgpAfiSafiInDefaultNiBeforeOtherNi: true},
+	}}}
+	for _, tc := range []struct {
+		vendor ondatra.Vendor
+		want   []string
+	}{
+		{ondatra.ARISTA, []string{vrfRootIntf + " " + vrfRootDef + " " + vrfRootVRF}},
+		{ondatra.CISCO, []string{vrfRootIntf + " " + vrfRootDef, vrfRootVRF, vrfRootAddr}},
+		{ondatra.JUNIPER, []string{vrfRootIntf + " " + vrfRootDef, vrfRootVRF}},
+	} {
+		reqs := DryRun(t, Platform{Vendor: tc.vendor, Metadata: md}, func(t *testing.T, dut *ondatra.DUTDevice) {
+			PushOrderedConfig(t, dut, vrfRoot(), true)
+		})
+		if diff := cmp.Diff(tc.want, requestPaths(t, reqs)); diff != "" {
+			t.Errorf("PushOrderedConfig() on %v request paths diff (-want +got):\n%s", tc.vendor, diff)
+		}
+		if tc.vendor == ondatra.CISCO && len(reqs) > 0 {
+			if j := string(reqs[0].GetReplace()[0].GetVal().GetJsonIetfVal()); strings.Contains(j, "192.0.2.0") {
+				t.Errorf("PushOrderedConfig() on %v set the interface with its addresses before its VRF: %s", tc.vendor, j)
+			}
+		}
+	}
+}
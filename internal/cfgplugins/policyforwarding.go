@@ -11,6 +11,7 @@ import (
 	"github.com/openconfig/featureprofiles/internal/attrs"
 	"github.com/openconfig/featureprofiles/internal/deviations"
 	"github.com/openconfig/featureprofiles/internal/helpers"
+	"github.com/openconfig/featureprofiles/internal/vendorcli"
 	"github.com/openconfig/ondatra"
 	"github.com/openconfig/ondatra/gnmi"
 	"github.com/openconfig/ondatra/gnmi/oc"
@@ -1614,14 +1615,9 @@ func ConfigureVRFSelectionPolicyOC(t *testing.T, dut *ondatra.DUTDevice, encapVR
 // ConfigureCLIDecapVRFMode enables next-hop decapsulation VRF mode required for VRF selection policy decapsulation forwarding.
 func ConfigureCLIDecapVRFMode(t *testing.T, dut *ondatra.DUTDevice) {
 	t.Helper()
-	if dut.Vendor() != ondatra.ARISTA {
+	if !vendorcli.DecapVRFMode.Supported(dut) {
 		return
 	}
-	cliConfig := `
-		vrf selection policy
-		next-hop decapsulation vrf
-		!
-		`
 	t.Log("Enabling next-hop decapsulation VRF mode")
-	helpers.GnmiCLIConfig(t, dut, cliConfig)
+	vendorcli.DecapVRFMode.Config(t, dut, struct{}{})
 }
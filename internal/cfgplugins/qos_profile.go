@@ -0,0 +1,445 @@
+// Copyright 2026 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cfgplugins
+
+import (
+	"fmt"
+	"maps"
+	"slices"
+	"sort"
+	"testing"
+
+	"github.com/open-traffic-generator/snappi/gosnappi"
+	"github.com/openconfig/featureprofiles/internal/attrs"
+	"github.com/openconfig/featureprofiles/internal/deviations"
+	"github.com/openconfig/featureprofiles/internal/fptest"
+	"github.com/openconfig/ondatra"
+	"github.com/openconfig/ondatra/gnmi"
+	"github.com/openconfig/ondatra/gnmi/oc"
+)
+
+// defaultQoSTolerance is the tolerance of flow throughput in percent when a QoSProfile does not
+// set one.
+const defaultQoSTolerance = 3.0
+
+// QoSProfileClass is a traffic class of a QoSProfile, classified by DSCP to a queue.
+type QoSProfileClass struct {
+	// Name is the name of the scheduler input and the forwarding group target-group-<Name>.
+	Name  string
+	Queue string
+	// DSCP are the DSCP values classified to the class. Flows of the class use the first.
+	DSCP           []uint8
+	StrictPriority bool
+	// Weight shares the bandwidth left by strict priority classes between WRR classes, and
+	// orders strict priority classes with the highest weight served first.
+	Weight uint64
+}
+
+// QoSProfileTraffic is traffic of a class that the ATE sends from an ingress port to the
+// egress port of a QoSProfile.
+type QoSProfileTraffic struct {
+	Class       string
+	IngressPort string
+	// RatePct is the rate of the traffic in percent of the line rate of the ingress port.
+	RatePct   float64
+	FrameSize uint32
+	IPv6      bool
+}
+
+// flowName returns the name of the OTG flow of the traffic.
+func (tr *QoSProfileTraffic) flowName() string {
+	name := tr.IngressPort + "-" + tr.Class
+	if tr.IPv6 {
+		name += "-v6"
+	}
+	return name
+}
+
+// QoSProfile is the QoS configuration of traffic classes scheduled on an egress port of the
+// DUT, with the traffic that the ATE sends through it. It generates the DUT configuration and
+// OTG flows, and predicts and validates the throughput of each class.
+type QoSProfile struct {
+	// Name is the name of the classifiers and scheduler policy.
+	Name       string
+	Classes    []*QoSProfileClass
+	EgressPort string
+	Traffic    []*QoSProfileTraffic
+	// Tolerance is the tolerance of flow throughput in percent, 3 if unset.
+	Tolerance float64
+}
+
+func (p *QoSProfile) class(name string) *QoSProfileClass {
+	for _, c := range p.Classes {
+		if c.Name == name {
+			return c
+		}
+	}
+	return nil
+}
+
+func (p *QoSProfile) ingressPorts() []string {
+	var ports []string
+	for _, tr := range p.Traffic {
+		if !slices.Contains(ports, tr.IngressPort) {
+			ports = append(ports, tr.IngressPort)
+		}
+	}
+	return ports
+}
+
+// Config returns the QoS configuration of the DUT for the profile: queues, forwarding groups,
+// DSCP classifiers on the ingress ports of the traffic, and a scheduler policy with the strict
+// priority classes in sequence 0 and the WRR classes in sequence 1 on the egress port.
+func (p *QoSProfile) Config(t *testing.T, dut *ondatra.DUTDevice) *oc.Qos {
+	t.Helper()
+	q := (&oc.Root{}).GetOrCreateQos()
+	weights := p.schedulerWeights(dut)
+	for i, c := range p.Classes {
+		queue := q.GetOrCreateQueue(c.Queue)
+		if deviations.QOSQueueRequiresID(dut) {
+			queue.SetQueueId(uint8(len(p.Classes) - i))
+		}
+		q.GetOrCreateForwardingGroup("target-group-" + c.Name).SetOutputQueue(c.Queue)
+	}
+
+	for _, family := range []struct {
+		name      string
+		classType oc.E_Qos_Classifier_Type
+		inputType oc.E_Input_Classifier_Type
+	}{
+		{p.Name + "_ipv4", oc.Qos_Classifier_Type_IPV4, oc.Input_Classifier_Type_IPV4},
+		{p.Name + "_ipv6", oc.Qos_Classifier_Type_IPV6, oc.Input_Classifier_Type_IPV6},
+	} {
+		classifier := q.GetOrCreateClassifier(family.name)
+		classifier.SetType(family.classType)
+		for i, c := range p.Classes {
+			term := classifier.GetOrCreateTerm(fmt.Sprint(i))
+			term.GetOrCreateActions().SetTargetGroup("target-group-" + c.Name)
+			if family.classType == oc.Qos_Classifier_Type_IPV4 {
+				term.GetOrCreateConditions().GetOrCreateIpv4().SetDscpSet(c.DSCP)
+			} else {
+				term.GetOrCreateConditions().GetOrCreateIpv6().SetDscpSet(c.DSCP)
+			}
+		}
+		for _, port := range p.ingressPorts() {
+			intfName := dut.Port(t, port).Name()
+			qosIntfID := intfName
+			if deviations.InterfaceRefInterfaceIDFormat(dut) {
+				qosIntfID += ".0"
+			}
+			intf := q.GetOrCreateInterface(qosIntfID)
+			intf.GetOrCreateInterfaceRef().SetInterface(intfName)
+			if dut.Vendor() != ondatra.CISCO {
+				intf.GetOrCreateInterfaceRef().SetSubinterface(0)
+			}
+			if deviations.InterfaceRefConfigUnsupported(dut) {
+				intf.InterfaceRef = nil
+			}
+			intf.GetOrCreateInput().GetOrCreateClassifier(family.inputType).SetName(family.name)
+		}
+	}
+
+	sp := q.GetOrCreateSchedulerPolicy(p.Name)
+	wrrSequence := uint32(0)
+	if slices.ContainsFunc(p.Classes, func(c *QoSProfileClass) bool { return c.StrictPriority }) {
+		wrrSequence = 1
+	}
+	for _, c := range p.Classes {
+		s := sp.GetOrCreateScheduler(wrrSequence)
+		if c.StrictPriority {
+			s = sp.GetOrCreateScheduler(0)
+			s.SetPriority(oc.Scheduler_Priority_STRICT)
+		}
+		input := s.GetOrCreateInput(c.Name)
+		input.SetInputType(oc.Input_InputType_QUEUE)
+		input.SetQueue(c.Queue)
+		input.SetWeight(weights[c.Name])
+	}
+
+	egress := dut.Port(t, p.EgressPort).Name()
+	intf := q.GetOrCreateInterface(egress)
+	intf.GetOrCreateInterfaceRef().SetInterface(egress)
+	if deviations.InterfaceRefConfigUnsupported(dut) {
+		intf.InterfaceRef = nil
+	}
+	output := intf.GetOrCreateOutput()
+	output.GetOrCreateSchedulerPolicy().SetName(p.Name)
+	for _, c := range p.Classes {
+		output.GetOrCreateQueue(c.Queue)
+	}
+	return q
+}
+
+// schedulerWeights returns the weights of the classes, scaled down to at most 100 on DUTs
+// that do not support larger weights.
+func (p *QoSProfile) schedulerWeights(dut *ondatra.DUTDevice) map[string]uint64 {
+	weights := map[string]uint64{}
+	var highest uint64
+	for _, c := range p.Classes {
+		weights[c.Name] = c.Weight
+		highest = max(highest, c.Weight)
+	}
+	if highest > 100 && deviations.SchedulerInputWeightLimit(dut) {
+		for name, w := range weights {
+			weights[name] = max(1, w*100/highest)
+		}
+	}
+	return weights
+}
+
+// ConfigureDUT replaces the QoS configuration of the DUT with the configuration of the profile.
+func (p *QoSProfile) ConfigureDUT(t *testing.T, dut *ondatra.DUTDevice) {
+	t.Helper()
+	gnmi.Replace(t, dut, gnmi.OC().Qos().Config(), p.Config(t, dut))
+}
+
+// AddFlows adds a flow per traffic of the profile to top, from the ATE device of the ingress
+// port to the ATE device of the egress port. atePorts are the attributes of the ATE ports by
+// port ID, whose devices are named as attrs.Attributes.AddToOTG names them.
+func (p *QoSProfile) AddFlows(t *testing.T, top gosnappi.Config, atePorts map[string]*attrs.Attributes) {
+	t.Helper()
+	dst, ok := atePorts[p.EgressPort]
+	if !ok {
+		t.Fatalf("No ATE attributes for egress port %s", p.EgressPort)
+	}
+	for _, tr := range p.Traffic {
+		src, ok := atePorts[tr.IngressPort]
+		if !ok {
+			t.Fatalf("No ATE attributes for ingress port %s", tr.IngressPort)
+		}
+		c := p.class(tr.Class)
+		if c == nil || len(c.DSCP) == 0 {
+			t.Fatalf("Traffic of port %s has class %q without DSCP values", tr.IngressPort, tr.Class)
+		}
+		flow := top.Flows().Add().SetName(tr.flowName())
+		flow.Metrics().SetEnable(true)
+		flow.Packet().Add().Ethernet().Src().SetValue(src.MAC)
+		if tr.IPv6 {
+			flow.TxRx().Device().SetTxNames([]string{src.Name + ".IPv6"}).SetRxNames([]string{dst.Name + ".IPv6"})
+			ip := flow.Packet().Add().Ipv6()
+			ip.Src().SetValue(src.IPv6)
+			ip.Dst().SetValue(dst.IPv6)
+			ip.TrafficClass().SetValue(uint32(c.DSCP[0]) << 2)
+		} else {
+			flow.TxRx().Device().SetTxNames([]string{src.Name + ".IPv4"}).SetRxNames([]string{dst.Name + ".IPv4"})
+			ip := flow.Packet().Add().Ipv4()
+			ip.Src().SetValue(src.IPv4)
+			ip.Dst().SetValue(dst.IPv4)
+			ip.Priority().Dscp().Phb().SetValue(uint32(c.DSCP[0]))
+		}
+		flow.Size().SetFixed(tr.FrameSize)
+		flow.Rate().SetPercentage(float32(tr.RatePct))
+	}
+}
+
+// QoSQueueExpectation is the predicted load of a queue of the egress port in bits per second
+// of line rate.
+type QoSQueueExpectation struct {
+	OfferedBps    float64
+	ThroughputBps float64
+}
+
+// ThroughputPct returns the percentage of the offered load that the queue transmits.
+func (e *QoSQueueExpectation) ThroughputPct() float64 {
+	if e.OfferedBps == 0 {
+		return 100
+	}
+	return 100 * e.ThroughputBps / e.OfferedBps
+}
+
+// QoSExpectation is the predicted throughput of the queues and flows of a QoSProfile.
+type QoSExpectation struct {
+	// Queues are keyed by queue name.
+	Queues map[string]*QoSQueueExpectation
+	// FlowThroughputPct is the percentage of the packets of each flow that the ATE receives.
+	FlowThroughputPct map[string]float64
+}
+
+// Expect returns the throughput of the profile predicted for the speeds of the DUT ports.
+func (p *QoSProfile) Expect(t *testing.T, dut *ondatra.DUTDevice) *QoSExpectation {
+	t.Helper()
+	speeds := map[string]uint64{}
+	for _, port := range append(p.ingressPorts(), p.EgressPort) {
+		speed := fptest.EthernetSpeedToUint64(fptest.GetIfSpeed(t, dut.Port(t, port)))
+		if speed == 0 {
+			t.Fatalf("Speed of port %s is unknown", port)
+		}
+		speeds[port] = speed
+	}
+	exp, err := p.Expectation(speeds)
+	if err != nil {
+		t.Fatalf("Failed to predict QoS throughput: %v", err)
+	}
+	return exp
+}
+
+// Expectation returns the throughput of the profile predicted for the port speeds in bits per
+// second by port ID. Strict priority classes are served in order up to the egress line rate,
+// and WRR classes share the rest in proportion to their weights, with the share a class does
+// not use going to the other classes.
+func (p *QoSProfile) Expectation(portSpeeds map[string]uint64) (*QoSExpectation, error) {
+	exp := &QoSExpectation{Queues: map[string]*QoSQueueExpectation{}, FlowThroughputPct: map[string]float64{}}
+	offered := map[string]float64{}
+	for _, tr := range p.Traffic {
+		if p.class(tr.Class) == nil {
+			return nil, fmt.Errorf("traffic of port %s has unknown class %q", tr.IngressPort, tr.Class)
+		}
+		speed, ok := portSpeeds[tr.IngressPort]
+		if !ok {
+			return nil, fmt.Errorf("no speed of ingress port %s", tr.IngressPort)
+		}
+		offered[tr.Class] += tr.RatePct / 100 * float64(speed)
+	}
+	capacity, ok := portSpeeds[p.EgressPort]
+	if !ok {
+		return nil, fmt.Errorf("no speed of egress port %s", p.EgressPort)
+	}
+
+	served := map[string]float64{}
+	remaining := float64(capacity)
+	var strict, wrr []*QoSProfileClass
+	for _, c := range p.Classes {
+		if c.StrictPriority {
+			strict = append(strict, c)
+		} else {
+			if c.Weight == 0 {
+				return nil, fmt.Errorf("WRR class %s has no weight", c.Name)
+			}
+			wrr = append(wrr, c)
+		}
+	}
+	sort.SliceStable(strict, func(i, j int) bool { return strict[i].Weight > strict[j].Weight })
+	for _, c := range strict {
+		served[c.Name] = min(offered[c.Name], remaining)
+		remaining -= served[c.Name]
+	}
+	for len(wrr) > 0 && remaining > 0 {
+		var total uint64
+		for _, c := range wrr {
+			total += c.Weight
+		}
+		var unsatisfied []*QoSProfileClass
+		used := 0.0
+		for _, c := range wrr {
+			if share := remaining * float64(c.Weight) / float64(total); offered[c.Name] <= share {
+				served[c.Name] = offered[c.Name]
+				used += offered[c.Name]
+			} else {
+				unsatisfied = append(unsatisfied, c)
+			}
+		}
+		if len(unsatisfied) == len(wrr) {
+			for _, c := range wrr {
+				served[c.Name] = remaining * float64(c.Weight) / float64(total)
+			}
+			break
+		}
+		remaining -= used
+		wrr = unsatisfied
+	}
+
+	for _, c := range p.Classes {
+		q, ok := exp.Queues[c.Queue]
+		if !ok {
+			q = &QoSQueueExpectation{}
+			exp.Queues[c.Queue] = q
+		}
+		q.OfferedBps += offered[c.Name]
+		q.ThroughputBps += served[c.Name]
+	}
+	for _, tr := range p.Traffic {
+		pct := 100.0
+		if o := offered[tr.Class]; o > 0 {
+			pct = 100 * served[tr.Class] / o
+		}
+		exp.FlowThroughputPct[tr.flowName()] = pct
+	}
+	return exp, nil
+}
+
+// QueueCounters returns the counters of the queues of the profile on the egress port of the
+// DUT by queue name, to take before the traffic and pass to Validate.
+func (p *QoSProfile) QueueCounters(t *testing.T, dut *ondatra.DUTDevice) map[string]*oc.Qos_Interface_Output_Queue {
+	t.Helper()
+	egress := dut.Port(t, p.EgressPort).Name()
+	counters := map[string]*oc.Qos_Interface_Output_Queue{}
+	for _, c := range p.Classes {
+		if _, ok := counters[c.Queue]; !ok {
+			counters[c.Queue] = gnmi.Get(t, dut, gnmi.OC().Qos().Interface(egress).Output().Queue(c.Queue).State())
+		}
+	}
+	return counters
+}
+
+// Validate checks the throughput of the flows of the profile against exp after the ATE sent
+// the traffic, and that the queue counters of the egress port grew at least by the packets and
+// octets that the ATE received and lost since before.
+func (p *QoSProfile) Validate(t *testing.T, dut *ondatra.DUTDevice, ate *ondatra.ATEDevice, exp *QoSExpectation, before map[string]*oc.Qos_Interface_Output_Queue) {
+	t.Helper()
+	tolerance := p.Tolerance
+	if tolerance == 0 {
+		tolerance = defaultQoSTolerance
+	}
+	type queueTraffic struct{ inPkts, lostPkts, inOctets, lostOctets uint64 }
+	ateQueues := map[string]*queueTraffic{}
+	for _, tr := range p.Traffic {
+		name := tr.flowName()
+		outPkts := gnmi.Get(t, ate.OTG(), gnmi.OTG().Flow(name).Counters().OutPkts().State())
+		inPkts := gnmi.Get(t, ate.OTG(), gnmi.OTG().Flow(name).Counters().InPkts().State())
+		if outPkts == 0 {
+			t.Errorf("Flow %s sent no packets", name)
+			continue
+		}
+		got := 100 * float64(inPkts) / float64(outPkts)
+		want := exp.FlowThroughputPct[name]
+		if got < want-tolerance || got > want+tolerance {
+			t.Errorf("Throughput of flow %s got %.2f%%, want %.2f%% +/- %.1f%%", name, got, want, tolerance)
+		} else {
+			t.Logf("Throughput of flow %s got %.2f%%, want %.2f%%", name, got, want)
+		}
+		queue := p.class(tr.Class).Queue
+		if ateQueues[queue] == nil {
+			ateQueues[queue] = &queueTraffic{}
+		}
+		q := ateQueues[queue]
+		lost := outPkts - min(inPkts, outPkts)
+		q.inPkts += inPkts
+		q.lostPkts += lost
+		q.inOctets += inPkts * uint64(tr.FrameSize)
+		q.lostOctets += lost * uint64(tr.FrameSize)
+	}
+
+	after := p.QueueCounters(t, dut)
+	for _, queue := range slices.Sorted(maps.Keys(ateQueues)) {
+		ateQ, b, a := ateQueues[queue], before[queue], after[queue]
+		if got := a.GetTransmitPkts() - b.GetTransmitPkts(); got < ateQ.inPkts {
+			t.Errorf("Transmitted packets of queue %s got %d, want at least %d", queue, got, ateQ.inPkts)
+		}
+		if !deviations.QOSOctets(dut) {
+			if got := a.GetTransmitOctets() - b.GetTransmitOctets(); got < ateQ.inOctets {
+				t.Errorf("Transmitted octets of queue %s got %d, want at least %d", queue, got, ateQ.inOctets)
+			}
+		}
+		if deviations.DequeueDeleteNotCountedAsDrops(dut) {
+			continue
+		}
+		if got := a.GetDroppedPkts() - b.GetDroppedPkts(); got < ateQ.lostPkts {
+			t.Errorf("Dropped packets of queue %s got %d, want at least %d", queue, got, ateQ.lostPkts)
+		}
+		if got := a.GetDroppedOctets() - b.GetDroppedOctets(); got < ateQ.lostOctets {
+			t.Errorf("Dropped octets of queue %s got %d, want at least %d", queue, got, ateQ.lostOctets)
+		}
+	}
+}
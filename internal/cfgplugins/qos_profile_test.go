@@ -0,0 +1,140 @@
+// Copyright 2026 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cfgplugins
+
+import (
+	"math"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/google/go-cmp/cmp/cmpopts"
+	"github.com/openconfig/ondatra"
+	"github.com/openconfig/ondatra/gnmi/oc"
+
+	mpb "github.com/openconfig/featureprofiles/proto/metadata_go_proto"
+	opb "github.com/openconfig/ondatra/proto"
+)
+
+// testQoSProfile returns a profile with two strict priority classes and two WRR classes, with
+// traffic from port1 and port2 oversubscribing port3.
+func testQoSProfile() *QoSProfile {
+	return &QoSProfile{
+		Name: "scheduler",
+		Classes: []*QoSProfileClass{
+			{Name: "NC1", Queue: "NC1", DSCP: []uint8{48, 49, 50, 51, 52, 53, 54, 55}, StrictPriority: true, Weight: 200},
+			{Name: "AF4", Queue: "AF4", DSCP: []uint8{32, 33, 34, 35, 36, 37, 38, 39}, StrictPriority: true, Weight: 100},
+			{Name: "AF3", Queue: "AF3", DSCP: []uint8{24, 25, 26, 27, 28, 29, 30, 31}, Weight: 12},
+			{Name: "BE1", Queue: "BE1", DSCP: []uint8{0, 1, 2, 3, 4, 5, 6, 7}, Weight: 4},
+		},
+		EgressPort: "port3",
+		Traffic: []*QoSProfileTraffic{
+			{Class: "NC1", IngressPort: "port1", RatePct: 10, FrameSize: 512},
+			{Class: "AF4", IngressPort: "port1", RatePct: 50, FrameSize: 512},
+			{Class: "AF4", IngressPort: "port2", RatePct: 20, FrameSize: 512, IPv6: true},
+			{Class: "AF3", IngressPort: "port2", RatePct: 40, FrameSize: 512},
+			{Class: "BE1", IngressPort: "port2", RatePct: 5, FrameSize: 512},
+		},
+	}
+}
+
+func TestQoSProfileExpectation(t *testing.T) {
+	speeds := map[string]uint64{"port1": 100e9, "port2": 100e9, "port3": 100e9}
+	exp, err := testQoSProfile().Expectation(speeds)
+	if err != nil {
+		t.Fatalf("Expectation() got error: %v", err)
+	}
+	// Strict classes take 80G of 100G. BE1 offers 5G, less than its 5G share of the remaining
+	// 20G, so AF3 gets the 15G left of its 40G.
+	want := &QoSExpectation{
+		Queues: map[string]*QoSQueueExpectation{
+			"NC1": {OfferedBps: 10e9, ThroughputBps: 10e9},
+			"AF4": {OfferedBps: 70e9, ThroughputBps: 70e9},
+			"AF3": {OfferedBps: 40e9, ThroughputBps: 15e9},
+			"BE1": {OfferedBps: 5e9, ThroughputBps: 5e9},
+		},
+		FlowThroughputPct: map[string]float64{
+			"port1-NC1":    100,
+			"port1-AF4":    100,
+			"port2-AF4-v6": 100,
+			"port2-AF3":    37.5,
+			"port2-BE1":    100,
+		},
+	}
+	if diff := cmp.Diff(want, exp, cmpopts.EquateApprox(0, 1)); diff != "" {
+		t.Errorf("Expectation() diff (-want +got):\n%s", diff)
+	}
+
+	speeds["port3"] = 50e9
+	exp, err = testQoSProfile().Expectation(speeds)
+	if err != nil {
+		t.Fatalf("Expectation() got error: %v", err)
+	}
+	if got := exp.Queues["AF4"].ThroughputPct(); math.Abs(got-100*40/70.0) > 0.01 {
+		t.Errorf("AF4 throughput on a 50G egress port got %.2f%%, want %.2f%%", got, 100*40/70.0)
+	}
+	if got := exp.Queues["AF3"].ThroughputBps + exp.Queues["BE1"].ThroughputBps; got != 0 {
+		t.Errorf("WRR throughput on a 50G egress port got %v, want 0", got)
+	}
+
+	p := testQoSProfile()
+	p.Classes[3].Weight = 0
+	if _, err := p.Expectation(speeds); err == nil {
+		t.Errorf("Expectation() of a WRR class without weight got no error")
+	}
+	delete(speeds, "port2")
+	if _, err := testQoSProfile().Expectation(speeds); err == nil {
+		t.Errorf("Expectation() without the speed of an ingress port got no error")
+	}
+}
+
+func TestQoSProfileConfig(t *testing.T) {
+	md := &mpb.Metadata{PlatformExceptions: []*mpb.Metadata_PlatformExceptions{{
+		Platform:   &mpb.Metadata_Platform{Vendor: opb.Device_CISCO},
+		Deviations: &mpb.Metadata_Deviations{SchedulerInputWeightLimit: true, InterfaceRefInterfaceIdFormat: true},
+	}}}
+	var q *oc.Qos
+	DryRun(t, Platform{Vendor: ondatra.CISCO, Metadata: md}, func(t *testing.T, dut *ondatra.DUTDevice) {
+		q = testQoSProfile().Config(t, dut)
+	})
+
+	sp := q.GetSchedulerPolicy("scheduler")
+	if got := sp.GetScheduler(0).GetPriority(); got != oc.Scheduler_Priority_STRICT {
+		t.Errorf("Scheduler 0 priority got %v, want STRICT", got)
+	}
+	weights := map[string]uint64{}
+	for seq, names := range map[uint32][]string{0: {"NC1", "AF4"}, 1: {"AF3", "BE1"}} {
+		for _, name := range names {
+			input := sp.GetScheduler(seq).GetInput(name)
+			if input == nil {
+				t.Fatalf("Scheduler %d has no input %s", seq, name)
+			}
+			weights[name] = input.GetWeight()
+		}
+	}
+	if diff := cmp.Diff(map[string]uint64{"NC1": 100, "AF4": 50, "AF3": 6, "BE1": 2}, weights); diff != "" {
+		t.Errorf("Scheduler input weights diff (-want +got):\n%s", diff)
+	}
+	for _, intf := range []string{"Ethernet1.0", "Ethernet2.0"} {
+		if got := q.GetInterface(intf).GetInput().GetClassifier(oc.Input_Classifier_Type_IPV4).GetName(); got != "scheduler_ipv4" {
+			t.Errorf("IPv4 classifier of %s got %q, want scheduler_ipv4", intf, got)
+		}
+	}
+	if got := q.GetInterface("Ethernet3").GetOutput().GetSchedulerPolicy().GetName(); got != "scheduler" {
+		t.Errorf("Scheduler policy of Ethernet3 got %q, want scheduler", got)
+	}
+	if got := q.GetForwardingGroup("target-group-AF3").GetOutputQueue(); got != "AF3" {
+		t.Errorf("Output queue of target-group-AF3 got %q, want AF3", got)
+	}
+}
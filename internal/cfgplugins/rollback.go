@@ -0,0 +1,196 @@
+// Copyright 2026 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cfgplugins
+
+import (
+	"context"
+	"reflect"
+	"sync"
+	"testing"
+
+	"github.com/openconfig/featureprofiles/internal/confirm"
+	"github.com/openconfig/ondatra"
+	"github.com/openconfig/ondatra/gnmi"
+	"github.com/openconfig/ygnmi/ygnmi"
+	"github.com/openconfig/ygot/ygot"
+)
+
+// ConfigGuard records the configuration operations a test adds to batches through
+// GuardReplace, GuardUpdate and GuardDelete, together with the configuration of their paths
+// before the operations. When the test ends, it rolls the configuration back: in reverse
+// order, it deletes what the operations added and restores the values they replaced, updated
+// or deleted.
+//
+// The previous configuration of a path is read when its operation is added to a batch, so a
+// batch must be set before the next guarded operation on the same paths is added.
+type ConfigGuard struct {
+	dut    *ondatra.DUTDevice
+	verify bool
+
+	mu  sync.Mutex
+	ops []*guardOp
+}
+
+// guardOp is an operation recorded by a ConfigGuard.
+type guardOp struct {
+	op   string
+	path string
+	// restore sets the configuration of the path before the operation.
+	restore func(ctx context.Context, c *ygnmi.Client) error
+	// check verifies that the path has its configuration before the operation.
+	check func(t testing.TB)
+}
+
+// GuardOption is an option of NewConfigGuard.
+type GuardOption func(*ConfigGuard)
+
+// WithRollbackVerification makes the guard verify, after rolling back, that the paths of the
+// recorded operations have their configuration from before the operations again.
+func WithRollbackVerification() GuardOption {
+	return func(g *ConfigGuard) { g.verify = true }
+}
+
+// NewConfigGuard returns a guard of the configuration of dut, which rolls back the operations
+// it records when t ends.
+func NewConfigGuard(t *testing.T, dut *ondatra.DUTDevice, opts ...GuardOption) *ConfigGuard {
+	t.Helper()
+	g := &ConfigGuard{dut: dut}
+	for _, opt := range opts {
+		opt(g)
+	}
+	t.Cleanup(func() { g.Rollback(t) })
+	return g
+}
+
+// Operations returns the recorded operations, e.g. "replace /interfaces/interface[name=Ethernet1]",
+// in the order they were added.
+func (g *ConfigGuard) Operations() []string {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	var ops []string
+	for _, op := range g.ops {
+		ops = append(ops, op.op+" "+op.path)
+	}
+	return ops
+}
+
+// Rollback restores the configuration the recorded operations changed, in reverse order, and
+// forgets them. It is called when the test of the guard ends. Operations that cannot be
+// restored are reported and the remaining operations are still restored.
+func (g *ConfigGuard) Rollback(t testing.TB) {
+	t.Helper()
+	g.mu.Lock()
+	ops := g.ops
+	g.ops = nil
+	g.mu.Unlock()
+	if len(ops) == 0 {
+		return
+	}
+	t.Logf("Rolling back %d configuration operations on %s", len(ops), g.dut.Name())
+	c, err := ygnmi.NewClient(g.dut.RawAPIs().GNMI(t), ygnmi.WithTarget(g.dut.ID()))
+	if err != nil {
+		t.Errorf("Cannot roll back the configuration of %s: %v", g.dut.Name(), err)
+		return
+	}
+	for i := len(ops) - 1; i >= 0; i-- {
+		if err := ops[i].restore(context.Background(), c); err != nil {
+			t.Errorf("Cannot roll back %s %s: %v", ops[i].op, ops[i].path, err)
+		}
+	}
+	if !g.verify {
+		return
+	}
+	// The earliest operation on a path has the configuration of the path before the test.
+	verified := map[string]bool{}
+	for _, op := range ops {
+		if verified[op.path] {
+			continue
+		}
+		verified[op.path] = true
+		op.check(t)
+	}
+}
+
+// GuardReplace adds a replace of q with val to batch, recording the configuration it replaces.
+func GuardReplace[T any](t testing.TB, g *ConfigGuard, batch *gnmi.SetBatch, q ygnmi.ConfigQuery[T], val T) {
+	t.Helper()
+	guardQuery(t, g, "replace", q)
+	gnmi.BatchReplace(batch, q, val)
+}
+
+// GuardUpdate adds an update of q with val to batch, recording the configuration it updates.
+func GuardUpdate[T any](t testing.TB, g *ConfigGuard, batch *gnmi.SetBatch, q ygnmi.ConfigQuery[T], val T) {
+	t.Helper()
+	guardQuery(t, g, "update", q)
+	gnmi.BatchUpdate(batch, q, val)
+}
+
+// GuardDelete adds a delete of q to batch, recording the configuration it deletes.
+func GuardDelete[T any](t testing.TB, g *ConfigGuard, batch *gnmi.SetBatch, q ygnmi.ConfigQuery[T]) {
+	t.Helper()
+	guardQuery(t, g, "delete", q)
+	gnmi.BatchDelete(batch, q)
+}
+
+// guardQuery reads the configuration at q and records an operation restoring it.
+func guardQuery[T any](t testing.TB, g *ConfigGuard, op string, q ygnmi.ConfigQuery[T]) {
+	t.Helper()
+	p, _, err := ygnmi.ResolvePath(q.PathStruct())
+	if err != nil {
+		t.Fatalf("Cannot resolve path of %s operation: %v", op, err)
+	}
+	path := confirm.PathLabel(p)
+	prev, present := gnmi.LookupConfig(t, g.dut, q).Val()
+	gop := &guardOp{
+		op:   op,
+		path: path,
+		restore: func(ctx context.Context, c *ygnmi.Client) error {
+			var err error
+			if present {
+				_, err = ygnmi.Replace(ctx, c, q, prev)
+			} else {
+				_, err = ygnmi.Delete(ctx, c, q)
+			}
+			return err
+		},
+		check: func(t testing.TB) {
+			t.Helper()
+			got, ok := gnmi.LookupConfig(t, g.dut, q).Val()
+			switch {
+			case !present && ok:
+				t.Errorf("%s: got %s after rollback, want unset", path, confirm.Readable(got))
+			case present && !ok:
+				t.Errorf("%s: got unset after rollback, want %s", path, confirm.Readable(prev))
+			case present:
+				checkRestored(t, path, prev, got)
+			}
+		},
+	}
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	g.ops = append(g.ops, gop)
+}
+
+// checkRestored checks that the configuration got of path is want.
+func checkRestored(t testing.TB, path string, want, got any) {
+	t.Helper()
+	if w, ok := want.(ygot.ValidatedGoStruct); ok {
+		confirm.Config(t, w, got.(ygot.ValidatedGoStruct))
+		return
+	}
+	if !reflect.DeepEqual(want, got) {
+		t.Errorf("%s: got %s after rollback, want %s", path, confirm.Readable(got), confirm.Readable(want))
+	}
+}
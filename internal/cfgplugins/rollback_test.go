@@ -0,0 +1,255 @@
+// Copyright 2026 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cfgplugins
+
+import (
+	"context"
+	"io"
+	"slices"
+	"sync"
+	"testing"
+
+	"github.com/openconfig/ondatra"
+	"github.com/openconfig/ondatra/binding"
+	"github.com/openconfig/ondatra/fakebind"
+	"github.com/openconfig/ondatra/gnmi"
+	"github.com/openconfig/ondatra/gnmi/oc"
+	"github.com/openconfig/testt"
+	"github.com/openconfig/ygot/util"
+	"github.com/openconfig/ygot/ygot"
+	"github.com/openconfig/ygot/ytypes"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	gpb "github.com/openconfig/gnmi/proto/gnmi"
+	opb "github.com/openconfig/ondatra/proto"
+)
+
+// fakeConfigGNMI is a gNMI client of a fake DUT keeping its configuration in an oc.Root.
+// Sets are ignored while frozen, and Sets with deletes fail with failDeletes.
+type fakeConfigGNMI struct {
+	gpb.GNMIClient
+	mu          sync.Mutex
+	root        *oc.Root
+	frozen      bool
+	failDeletes bool
+}
+
+func (c *fakeConfigGNMI) Set(_ context.Context, req *gpb.SetRequest, _ ...grpc.CallOption) (*gpb.SetResponse, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.frozen {
+		return &gpb.SetResponse{}, nil
+	}
+	if c.failDeletes && len(req.GetDelete()) > 0 {
+		return nil, status.Errorf(codes.Unavailable, "delete failed")
+	}
+	schema := oc.SchemaTree["Root"]
+	for _, p := range req.GetDelete() {
+		if err := ytypes.DeleteNode(schema, c.root, p, &ytypes.PreferShadowPath{}); err != nil {
+			return nil, err
+		}
+	}
+	for _, u := range req.GetReplace() {
+		if err := ytypes.DeleteNode(schema, c.root, u.GetPath(), &ytypes.PreferShadowPath{}); err != nil {
+			return nil, err
+		}
+	}
+	for _, u := range append(slices.Clone(req.GetReplace()), req.GetUpdate()...) {
+		if err := ytypes.SetNode(schema, c.root, u.GetPath(), u.GetVal(), &ytypes.InitMissingElements{}, &ytypes.PreferShadowPath{}); err != nil {
+			return nil, err
+		}
+	}
+	return &gpb.SetResponse{}, nil
+}
+
+// notification returns the configuration at p, or nil if there is none.
+func (c *fakeConfigGNMI) notification(p *gpb.Path) (*gpb.Notification, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	nodes, err := ytypes.GetNode(oc.SchemaTree["Root"], c.root, p, &ytypes.PreferShadowPath{})
+	if status.Code(err) == codes.NotFound || len(nodes) == 0 || util.IsValueNil(nodes[0].Data) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var val *gpb.TypedValue
+	if s, ok := nodes[0].Data.(ygot.GoStruct); ok {
+		j, err := ygot.Marshal7951(s, &ygot.RFC7951JSONConfig{AppendModuleName: true, PreferShadowPath: true})
+		if err != nil {
+			return nil, err
+		}
+		val = &gpb.TypedValue{Value: &gpb.TypedValue_JsonIetfVal{JsonIetfVal: j}}
+	} else if val, err = ygot.EncodeTypedValue(nodes[0].Data, gpb.Encoding_JSON_IETF); err != nil {
+		return nil, err
+	}
+	return &gpb.Notification{Timestamp: 1, Update: []*gpb.Update{{Path: p, Val: val}}}, nil
+}
+
+func (c *fakeConfigGNMI) Get(_ context.Context, req *gpb.GetRequest, _ ...grpc.CallOption) (*gpb.GetResponse, error) {
+	resp := &gpb.GetResponse{}
+	for _, p := range req.GetPath() {
+		n, err := c.notification(p)
+		if err != nil {
+			return nil, err
+		}
+		if n == nil {
+			return nil, status.Errorf(codes.NotFound, "no configuration at %v", p)
+		}
+		resp.Notification = append(resp.Notification, n)
+	}
+	return resp, nil
+}
+
+func (c *fakeConfigGNMI) Subscribe(context.Context, ...grpc.CallOption) (gpb.GNMI_SubscribeClient, error) {
+	return &fakeOnceSubscription{c: c}, nil
+}
+
+// fakeOnceSubscription answers a ONCE subscription with the configuration at its paths.
+type fakeOnceSubscription struct {
+	grpc.ClientStream
+	c    *fakeConfigGNMI
+	resp []*gpb.SubscribeResponse
+}
+
+func (s *fakeOnceSubscription) Send(req *gpb.SubscribeRequest) error {
+	for _, sub := range req.GetSubscribe().GetSubscription() {
+		p := &gpb.Path{Origin: req.GetSubscribe().GetPrefix().GetOrigin(), Elem: append(slices.Clone(req.GetSubscribe().GetPrefix().GetElem()), sub.GetPath().GetElem()...)}
+		n, err := s.c.notification(p)
+		if err != nil {
+			return err
+		}
+		if n != nil {
+			s.resp = append(s.resp, &gpb.SubscribeResponse{Response: &gpb.SubscribeResponse_Update{Update: n}})
+		}
+	}
+	s.resp = append(s.resp, &gpb.SubscribeResponse{Response: &gpb.SubscribeResponse_SyncResponse{SyncResponse: true}})
+	return nil
+}
+
+func (s *fakeOnceSubscription) Recv() (*gpb.SubscribeResponse, error) {
+	if len(s.resp) == 0 {
+		return nil, io.EOF
+	}
+	r := s.resp[0]
+	s.resp = s.resp[1:]
+	return r, nil
+}
+
+func (s *fakeOnceSubscription) CloseSend() error { return nil }
+
+// guardedDUT returns a fake DUT with interface Ethernet1 and hostname dut1 configured.
+func guardedDUT(t *testing.T) (*ondatra.DUTDevice, *fakeConfigGNMI) {
+	t.Helper()
+	root := &oc.Root{}
+	i := root.GetOrCreateInterface("Ethernet1")
+	i.SetDescription("before")
+	i.SetMtu(1500)
+	root.GetOrCreateSystem().SetHostname("dut1")
+	c := &fakeConfigGNMI{root: root}
+	dut := &fakebind.DUT{
+		AbstractDUT: &binding.AbstractDUT{Dims: &binding.Dims{
+			Name:   "dut",
+			Vendor: opb.Device_ARISTA,
+			Ports:  map[string]*binding.Port{"port1": {Name: "Ethernet1"}, "port2": {Name: "Ethernet2"}},
+		}},
+		DialGNMIFn: func(context.Context, ...grpc.DialOption) (gpb.GNMIClient, error) { return c, nil },
+	}
+	fakebind.Setup().WithReservation(&binding.Reservation{
+		ID:   "guarded",
+		DUTs: map[string]binding.DUT{"dut": dut},
+	})
+	return ondatra.DUT(t, "dut"), c
+}
+
+// guardOps adds guarded operations replacing Ethernet1, adding Ethernet2 and deleting the
+// hostname.
+func guardOps(t *testing.T, dut *ondatra.DUTDevice, g *ConfigGuard) {
+	t.Helper()
+	batch := &gnmi.SetBatch{}
+	GuardReplace(t, g, batch, gnmi.OC().Interface("Ethernet1").Config(), &oc.Interface{Name: ygot.String("Ethernet1"), Description: ygot.String("after")})
+	GuardUpdate(t, g, batch, gnmi.OC().Interface("Ethernet2").Description().Config(), "added")
+	GuardDelete(t, g, batch, gnmi.OC().System().Hostname().Config())
+	batch.Set(t, dut)
+}
+
+func TestConfigGuard(t *testing.T) {
+	dut, c := guardedDUT(t)
+	g := NewConfigGuard(t, dut, WithRollbackVerification())
+	guardOps(t, dut, g)
+
+	wantOps := []string{
+		"replace /interfaces/interface[name=Ethernet1]",
+		"update /interfaces/interface[name=Ethernet2]/config/description",
+		"delete /system/config/hostname",
+	}
+	if got := g.Operations(); !slices.Equal(got, wantOps) {
+		t.Errorf("Operations() got %v, want %v", got, wantOps)
+	}
+	if got := c.root.GetInterface("Ethernet1").GetMtu(); got != 0 {
+		t.Errorf("MTU of Ethernet1 before rollback got %d, want unset", got)
+	}
+
+	g.Rollback(t)
+	if got := c.root.GetInterface("Ethernet1"); got.GetDescription() != "before" || got.GetMtu() != 1500 {
+		t.Errorf("Ethernet1 after rollback got description %q and MTU %d, want before and 1500", got.GetDescription(), got.GetMtu())
+	}
+	if got := c.root.GetInterface("Ethernet2").GetDescription(); got != "" {
+		t.Errorf("Description of Ethernet2 after rollback got %q, want unset", got)
+	}
+	if got := c.root.GetSystem().GetHostname(); got != "dut1" {
+		t.Errorf("Hostname after rollback got %q, want dut1", got)
+	}
+	if got := g.Operations(); len(got) != 0 {
+		t.Errorf("Operations() after rollback got %v, want none", got)
+	}
+}
+
+func TestConfigGuardVerification(t *testing.T) {
+	dut, c := guardedDUT(t)
+	g := NewConfigGuard(t, dut, WithRollbackVerification())
+	guardOps(t, dut, g)
+
+	c.frozen = true
+	errs := testt.ExpectError(t, func(t testing.TB) {
+		g.Rollback(t)
+	})
+	if len(errs) != 4 {
+		t.Errorf("Rollback() of an unchanged DUT got errors %v, want 4 errors", errs)
+	}
+}
+
+func TestConfigGuardRestoreError(t *testing.T) {
+	dut, c := guardedDUT(t)
+	g := NewConfigGuard(t, dut)
+	guardOps(t, dut, g)
+
+	// Restoring Ethernet2 deletes it and fails, and the other operations are still restored.
+	c.failDeletes = true
+	errs := testt.ExpectError(t, func(t testing.TB) {
+		g.Rollback(t)
+	})
+	if len(errs) != 1 {
+		t.Errorf("Rollback() with a failing delete got errors %v, want 1 error", errs)
+	}
+	if got := c.root.GetInterface("Ethernet1").GetDescription(); got != "before" {
+		t.Errorf("Description of Ethernet1 after rollback got %q, want before", got)
+	}
+	if got := c.root.GetSystem().GetHostname(); got != "dut1" {
+		t.Errorf("Hostname after rollback got %q, want dut1", got)
+	}
+}
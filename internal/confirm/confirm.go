@@ -131,3 +131,28 @@ func State(t testing.TB, want, got ygot.ValidatedGoStruct) {
 		t.Errorf("%v: got %v, want %v", PathLabel(change.Path), Readable(change.Got), Readable(change.Want))
 	}
 }
+
+// Config checks that got is exactly want: like State, it reports the values of want missing or
+// different in got, and it also reports the values set in got but not in want.
+func Config(t testing.TB, want, got ygot.ValidatedGoStruct) {
+	t.Helper()
+	State(t, want, got)
+	diff, err := ygot.Diff(got, want, &ygot.IgnoreAdditions{})
+	if err != nil {
+		t.Errorf("ygot.Diff failure: %v", err)
+		return
+	}
+	schema, err := getSchema(got)
+	if err != nil {
+		t.Errorf("Failed to compare states: schema lookup failure: %v", err)
+		return
+	}
+	for _, pth := range diff.GetDelete() {
+		gotVal, err := getSingleValue(schema, got, pth)
+		if err != nil {
+			t.Errorf("Failed to compare states: faild to parse received value at path %v: %v", pth, err)
+			continue
+		}
+		t.Errorf("%v: got %v, want unset", PathLabel(pth), Readable(gotVal))
+	}
+}
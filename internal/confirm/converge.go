@@ -0,0 +1,418 @@
+// Copyright 2026 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package confirm
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/openconfig/featureprofiles/internal/deviations"
+	"github.com/openconfig/featureprofiles/internal/telemetry/schema"
+	"github.com/openconfig/gnmi/value"
+	"github.com/openconfig/goyang/pkg/yang"
+	"github.com/openconfig/ondatra"
+	"github.com/openconfig/ygnmi/ygnmi"
+	"github.com/openconfig/ygot/ygot"
+	"github.com/openconfig/ygot/ytypes"
+
+	gnmipb "github.com/openconfig/gnmi/proto/gnmi"
+)
+
+// deviationSkips maps deviations which mark state leaves as unsupported to the
+// schema path prefixes that should not be checked for convergence.
+var deviationSkips = []struct {
+	name     string
+	deviated func(*ondatra.DUTDevice) bool
+	prefixes []string
+}{
+	{
+		name:     "IPv4MissingEnabled",
+		deviated: deviations.IPv4MissingEnabled,
+		prefixes: []string{"/interfaces/interface/subinterfaces/subinterface/ipv4/state/enabled"},
+	},
+	{
+		name:     "IPNeighborMissing",
+		deviated: deviations.IPNeighborMissing,
+		prefixes: []string{
+			"/interfaces/interface/subinterfaces/subinterface/ipv4/neighbors",
+			"/interfaces/interface/subinterfaces/subinterface/ipv6/neighbors",
+		},
+	},
+	{
+		name:     "QosGetStatePathUnsupported",
+		deviated: deviations.QosGetStatePathUnsupported,
+		prefixes: []string{"/qos"},
+	},
+	{
+		name:     "ISISMetricStyleTelemetryUnsupported",
+		deviated: deviations.ISISMetricStyleTelemetryUnsupported,
+		prefixes: []string{"/network-instances/network-instance/protocols/protocol/isis/levels/level/state/metric-style"},
+	},
+	{
+		name:     "MissingStaticRouteNextHopMetricTelemetry",
+		deviated: deviations.MissingStaticRouteNextHopMetricTelemetry,
+		prefixes: []string{"/network-instances/network-instance/protocols/protocol/static-routes/static/next-hops/next-hop/state/metric"},
+	},
+	{
+		name:     "StaticRouteNexthopInterfaceStateOcUnsupported",
+		deviated: deviations.StaticRouteNexthopInterfaceStateOcUnsupported,
+		prefixes: []string{"/network-instances/network-instance/protocols/protocol/static-routes/static/next-hops/next-hop/interface-ref/state"},
+	},
+}
+
+// LeafConvergence describes how a single state leaf converged to its configured value.
+type LeafConvergence struct {
+	// Path is the state path of the leaf.
+	Path *gnmipb.Path
+	// Want is the configured value of the leaf.
+	Want any
+	// Got is the last value received for the leaf, or nil if none was received.
+	Got any
+	// Converged is true if the state value matched the configured value when the check ended.
+	Converged bool
+	// Elapsed is the time from the start of the check until the leaf converged.
+	Elapsed time.Duration
+	// Skipped is non-empty if the leaf was not checked, and describes why.
+	Skipped string
+}
+
+// ConvergenceReport is the result of AwaitStateConvergence.
+type ConvergenceReport struct {
+	// Leaves contains one entry per state leaf derived from the configuration, sorted by path.
+	Leaves []*LeafConvergence
+	// Duration is the total time spent waiting for convergence.
+	Duration time.Duration
+}
+
+// Unconverged returns the leaves that were checked but never matched their configured value.
+func (r *ConvergenceReport) Unconverged() []*LeafConvergence {
+	var out []*LeafConvergence
+	for _, l := range r.Leaves {
+		if l.Skipped == "" && !l.Converged {
+			out = append(out, l)
+		}
+	}
+	return out
+}
+
+// String returns a human readable per-leaf summary of the report.
+func (r *ConvergenceReport) String() string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "state convergence after %v: %d leaves, %d unconverged\n", r.Duration.Truncate(time.Millisecond), len(r.Leaves), len(r.Unconverged()))
+	for _, l := range r.Leaves {
+		switch {
+		case l.Skipped != "":
+			fmt.Fprintf(&b, "  SKIP %s: %s\n", PathLabel(l.Path), l.Skipped)
+		case l.Converged:
+			fmt.Fprintf(&b, "  OK   %s: %v after %v\n", PathLabel(l.Path), Readable(l.Want), l.Elapsed.Truncate(time.Millisecond))
+		default:
+			fmt.Fprintf(&b, "  FAIL %s: got %v, want %v\n", PathLabel(l.Path), readableOrNil(l.Got), Readable(l.Want))
+		}
+	}
+	return b.String()
+}
+
+func readableOrNil(v any) string {
+	if v == nil {
+		return "<nil>"
+	}
+	return Readable(v)
+}
+
+// ConvergenceOption is an optional parameter to AwaitStateConvergence.
+type ConvergenceOption func(*convergenceOpts)
+
+type convergenceOpts struct {
+	skipPrefixes []string
+}
+
+// WithSkippedPaths excludes state leaves whose schema path starts with any of the given
+// prefixes, e.g. "/interfaces/interface/state/description".
+func WithSkippedPaths(prefixes ...string) ConvergenceOption {
+	return func(o *convergenceOpts) {
+		o.skipPrefixes = append(o.skipPrefixes, prefixes...)
+	}
+}
+
+// convergenceOptions returns the options of AwaitStateConvergence on dut, skipping the paths
+// of the deviations of dut as well as those of opts.
+func convergenceOptions(dut *ondatra.DUTDevice, opts []ConvergenceOption) *convergenceOpts {
+	o := &convergenceOpts{}
+	for _, opt := range opts {
+		opt(o)
+	}
+	for _, s := range deviationSkips {
+		if s.deviated(dut) {
+			o.skipPrefixes = append(o.skipPrefixes, s.prefixes...)
+		}
+	}
+	return o
+}
+
+// skipReason returns why the state leaf at path is not checked, or "" if its schema path
+// starts with none of the skipped prefixes.
+func skipReason(path *gnmipb.Path, skipPrefixes []string) (string, error) {
+	schemaPath, err := ygot.PathToSchemaPath(path)
+	if err != nil {
+		return "", err
+	}
+	for _, p := range skipPrefixes {
+		if strings.HasPrefix(schemaPath, p) {
+			return fmt.Sprintf("matches skipped path %s", p), nil
+		}
+	}
+	return "", nil
+}
+
+// stateLeaf tracks the convergence of a single leaf while streaming.
+type stateLeaf struct {
+	result  *LeafConvergence
+	relPath *gnmipb.Path
+	val     *gnmipb.TypedValue
+	entry   *yang.Entry
+}
+
+// stateLeaves derives the state leaves corresponding to every configured leaf in cfg.
+// The returned paths are relative to cfg.
+func stateLeaves(cfg ygot.ValidatedGoStruct) ([]*stateLeaf, error) {
+	sch, err := getSchema(cfg)
+	if err != nil {
+		return nil, err
+	}
+	notifs, err := ygot.TogNMINotifications(cfg, 0, ygot.GNMINotificationsConfig{UsePathElem: true})
+	if err != nil {
+		return nil, fmt.Errorf("cannot render %T to notifications: %v", cfg, err)
+	}
+	var leaves []*stateLeaf
+	for _, n := range notifs {
+		for _, pt := range schema.NotificationToPoints(n) {
+			elems := pt.Path.GetElem()
+			// Compressed structs map list keys to both the key leaf and state/<key>; only the
+			// leaves under a state container are operational state.
+			if len(elems) < 2 || elems[len(elems)-2].GetName() != "state" {
+				continue
+			}
+			nodes, err := ytypes.GetNode(sch, cfg, pt.Path)
+			if err != nil {
+				return nil, fmt.Errorf("cannot find schema for %s: %v", PathLabel(pt.Path), err)
+			}
+			if len(nodes) != 1 {
+				return nil, fmt.Errorf("expected exactly one node at %s, found %d", PathLabel(pt.Path), len(nodes))
+			}
+			leaves = append(leaves, &stateLeaf{
+				result:  &LeafConvergence{Want: nodes[0].Data},
+				relPath: pt.Path,
+				val:     pt.Val,
+				entry:   nodes[0].Schema,
+			})
+		}
+	}
+	return leaves, nil
+}
+
+// decodeLeaf unmarshals a received value at a path relative to a struct of type typ, and
+// returns the Go value as it would be stored in the struct.
+func decodeLeaf(typ reflect.Type, sch *yang.Entry, relPath *gnmipb.Path, val *gnmipb.TypedValue) (any, error) {
+	got, ok := reflect.New(typ).Interface().(ygot.ValidatedGoStruct)
+	if !ok {
+		return nil, fmt.Errorf("%v is not a ValidatedGoStruct", typ)
+	}
+	if err := ytypes.SetNode(sch, got, relPath, val, &ytypes.InitMissingElements{}); err != nil {
+		return nil, err
+	}
+	return getSingleValue(sch, got, relPath)
+}
+
+// equalsDefault reports whether the leaf is configured to its schema default value.
+func (l *stateLeaf) equalsDefault() bool {
+	defaults := l.entry.DefaultValues()
+	if len(defaults) == 0 {
+		return false
+	}
+	s, err := value.ToScalar(l.val)
+	if err != nil {
+		return false
+	}
+	return fmt.Sprint(s) == defaults[0]
+}
+
+func relativePath(prefix, full *gnmipb.Path) (*gnmipb.Path, bool) {
+	pe, fe := prefix.GetElem(), full.GetElem()
+	if len(fe) < len(pe) {
+		return nil, false
+	}
+	for i, e := range pe {
+		if e.GetName() != fe[i].GetName() {
+			return nil, false
+		}
+		for k, v := range e.GetKey() {
+			if fe[i].GetKey()[k] != v {
+				return nil, false
+			}
+		}
+	}
+	return &gnmipb.Path{Elem: fe[len(pe):]}, true
+}
+
+// AwaitStateConvergence derives the state leaves that correspond to the configuration
+// in cfg, subscribes to them on the DUT and waits until every leaf reports its configured
+// value or the timeout expires.
+//
+// cfg may be the root of the tree or any subtree; pathStruct must point at the node
+// cfg was pushed to, e.g. gnmi.OC() for *oc.Root or gnmi.OC().Interface("port1") for
+// *oc.Interface. Leaves marked unsupported by a DUT deviation are reported as skipped.
+func AwaitStateConvergence(t testing.TB, dut *ondatra.DUTDevice, pathStruct ygnmi.PathStruct, cfg ygot.ValidatedGoStruct, timeout time.Duration, opts ...ConvergenceOption) (*ConvergenceReport, error) {
+	t.Helper()
+	o := convergenceOptions(dut, opts)
+	for _, s := range deviationSkips {
+		if s.deviated(dut) {
+			t.Logf("Skipping state convergence of %v due to deviation %s", s.prefixes, s.name)
+		}
+	}
+	sch, err := getSchema(cfg)
+	if err != nil {
+		return nil, fmt.Errorf("schema lookup failure: %v", err)
+	}
+	prefix, _, err := ygnmi.ResolvePath(pathStruct)
+	if err != nil {
+		return nil, fmt.Errorf("cannot resolve path %v: %v", pathStruct, err)
+	}
+	leaves, err := stateLeaves(cfg)
+	if err != nil {
+		return nil, err
+	}
+
+	report := &ConvergenceReport{}
+	pending := map[string]*stateLeaf{}
+	subReq := &gnmipb.SubscriptionList{
+		Prefix:   &gnmipb.Path{Origin: "openconfig", Target: dut.Name(), Elem: prefix.GetElem()},
+		Mode:     gnmipb.SubscriptionList_STREAM,
+		Encoding: gnmipb.Encoding_PROTO,
+	}
+	for _, l := range leaves {
+		l.result.Path = &gnmipb.Path{Elem: append(append([]*gnmipb.PathElem{}, prefix.GetElem()...), l.relPath.GetElem()...)}
+		report.Leaves = append(report.Leaves, l.result)
+		if l.result.Skipped, err = skipReason(l.result.Path, o.skipPrefixes); err != nil {
+			return nil, err
+		}
+		if l.result.Skipped != "" {
+			continue
+		}
+		pending[PathLabel(l.relPath)] = l
+		subReq.Subscription = append(subReq.Subscription, &gnmipb.Subscription{Path: l.relPath, Mode: gnmipb.SubscriptionMode_ON_CHANGE})
+	}
+	sort.Slice(report.Leaves, func(i, j int) bool {
+		return PathLabel(report.Leaves[i].Path) < PathLabel(report.Leaves[j].Path)
+	})
+	if len(pending) == 0 {
+		return report, nil
+	}
+
+	start := time.Now()
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+	sub, err := dut.RawAPIs().GNMI(t).Subscribe(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("error in Subscribe(): %v", err)
+	}
+	if err := sub.Send(&gnmipb.SubscribeRequest{Request: &gnmipb.SubscribeRequest_Subscribe{Subscribe: subReq}}); err != nil {
+		return nil, fmt.Errorf("error sending subscribe request %v: %v", subReq, err)
+	}
+	typ := reflect.TypeOf(cfg).Elem()
+	remaining := len(pending)
+	for remaining > 0 {
+		resp, err := sub.Recv()
+		if err != nil {
+			if ctx.Err() != nil {
+				break
+			}
+			return nil, fmt.Errorf("error from gNMI stream: %v", err)
+		}
+		n := resp.GetUpdate()
+		if n == nil {
+			continue
+		}
+		for _, del := range n.GetDelete() {
+			full := &gnmipb.Path{Elem: append(append([]*gnmipb.PathElem{}, n.GetPrefix().GetElem()...), del.GetElem()...)}
+			rel, ok := relativePath(prefix, full)
+			if !ok {
+				continue
+			}
+			if l, ok := pending[PathLabel(rel)]; ok {
+				if l.result.Converged {
+					remaining++
+				}
+				l.result.Got, l.result.Converged = nil, false
+			}
+		}
+		for _, pt := range schema.NotificationToPoints(n) {
+			rel, ok := relativePath(prefix, pt.Path)
+			if !ok {
+				continue
+			}
+			l, ok := pending[PathLabel(rel)]
+			if !ok {
+				continue
+			}
+			got, err := decodeLeaf(typ, sch, l.relPath, pt.Val)
+			if err != nil {
+				t.Logf("Cannot decode value %v at %s: %v", pt.Val, PathLabel(l.result.Path), err)
+				continue
+			}
+			l.result.Got = got
+			match := reflect.DeepEqual(got, l.result.Want)
+			switch {
+			case match && !l.result.Converged:
+				l.result.Converged = true
+				l.result.Elapsed = time.Since(start)
+				remaining--
+			case !match && l.result.Converged:
+				l.result.Converged = false
+				remaining++
+			}
+		}
+	}
+	report.Duration = time.Since(start)
+
+	if deviations.MissingValueForDefaults(dut) {
+		for _, l := range pending {
+			if !l.result.Converged && l.result.Got == nil && l.equalsDefault() {
+				l.result.Skipped = "value equals schema default and DUT has deviation MissingValueForDefaults"
+			}
+		}
+	}
+	return report, nil
+}
+
+// StateConverged calls AwaitStateConvergence and reports an error for every state leaf
+// that did not converge to its configured value within the timeout.
+func StateConverged(t testing.TB, dut *ondatra.DUTDevice, pathStruct ygnmi.PathStruct, cfg ygot.ValidatedGoStruct, timeout time.Duration, opts ...ConvergenceOption) *ConvergenceReport {
+	t.Helper()
+	report, err := AwaitStateConvergence(t, dut, pathStruct, cfg, timeout, opts...)
+	if err != nil {
+		t.Errorf("Failed to check state convergence: %v", err)
+		return nil
+	}
+	t.Log(report)
+	for _, l := range report.Unconverged() {
+		t.Errorf("%v did not converge within %v: got %v, want %v", PathLabel(l.Path), timeout, readableOrNil(l.Got), Readable(l.Want))
+	}
+	return report
+}
@@ -0,0 +1,241 @@
+// Copyright 2026 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package confirm
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/openconfig/ondatra/gnmi/oc"
+	"github.com/openconfig/ygot/ygot"
+
+	gnmipb "github.com/openconfig/gnmi/proto/gnmi"
+)
+
+func mustPath(t *testing.T, s string) *gnmipb.Path {
+	t.Helper()
+	p, err := ygot.StringToStructuredPath(s)
+	if err != nil {
+		t.Fatalf("StringToStructuredPath(%q) got error: %v", s, err)
+	}
+	return p
+}
+
+// convergeIntf returns an interface configuration with an IPv4 address.
+func convergeIntf(enabled bool) *oc.Interface {
+	i := &oc.Interface{Name: ygot.String("Ethernet1"), Description: ygot.String("uplink"), Mtu: ygot.Uint16(9000), Enabled: ygot.Bool(enabled)}
+	i.GetOrCreateSubinterface(0).GetOrCreateIpv4().GetOrCreateAddress("192.0.2.1").SetPrefixLength(31)
+	return i
+}
+
+// leavesByPath returns the state leaves of cfg keyed by their relative path.
+func leavesByPath(t *testing.T, cfg ygot.ValidatedGoStruct) map[string]*stateLeaf {
+	t.Helper()
+	leaves, err := stateLeaves(cfg)
+	if err != nil {
+		t.Fatalf("stateLeaves() got error: %v", err)
+	}
+	byPath := map[string]*stateLeaf{}
+	for _, l := range leaves {
+		byPath[PathLabel(l.relPath)] = l
+	}
+	return byPath
+}
+
+func TestStateLeaves(t *testing.T) {
+	got := map[string]any{}
+	for p, l := range leavesByPath(t, convergeIntf(true)) {
+		got[p] = l.result.Want
+	}
+	// Only the state leaves are derived, not the list keys outside of state containers.
+	want := map[string]any{
+		"/state/name":        ygot.String("Ethernet1"),
+		"/state/description": ygot.String("uplink"),
+		"/state/mtu":         ygot.Uint16(9000),
+		"/state/enabled":     ygot.Bool(true),
+		"/subinterfaces/subinterface[index=0]/state/index":                                              ygot.Uint32(0),
+		"/subinterfaces/subinterface[index=0]/ipv4/addresses/address[ip=192.0.2.1]/state/ip":            ygot.String("192.0.2.1"),
+		"/subinterfaces/subinterface[index=0]/ipv4/addresses/address[ip=192.0.2.1]/state/prefix-length": ygot.Uint8(31),
+	}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("stateLeaves() diff (-want +got):\n%s", diff)
+	}
+}
+
+func TestRelativePath(t *testing.T) {
+	tests := []struct {
+		desc, prefix, full string
+		want               string
+		ok                 bool
+	}{{
+		desc:   "root prefix",
+		prefix: "/",
+		full:   "/interfaces/interface[name=Ethernet1]/state/mtu",
+		want:   "/interfaces/interface[name=Ethernet1]/state/mtu",
+		ok:     true,
+	}, {
+		desc:   "keyed prefix",
+		prefix: "/interfaces/interface[name=Ethernet1]",
+		full:   "/interfaces/interface[name=Ethernet1]/state/mtu",
+		want:   "/state/mtu",
+		ok:     true,
+	}, {
+		desc:   "other key",
+		prefix: "/interfaces/interface[name=Ethernet1]",
+		full:   "/interfaces/interface[name=Ethernet2]/state/mtu",
+	}, {
+		desc:   "other element",
+		prefix: "/interfaces/interface[name=Ethernet1]",
+		full:   "/system/state/hostname",
+	}, {
+		desc:   "shorter path",
+		prefix: "/interfaces/interface[name=Ethernet1]/state",
+		full:   "/interfaces",
+	}}
+	for _, tc := range tests {
+		t.Run(tc.desc, func(t *testing.T) {
+			got, ok := relativePath(mustPath(t, tc.prefix), mustPath(t, tc.full))
+			if ok != tc.ok {
+				t.Fatalf("relativePath() got ok %v, want %v", ok, tc.ok)
+			}
+			if ok && PathLabel(got) != tc.want {
+				t.Errorf("relativePath() got %s, want %s", PathLabel(got), tc.want)
+			}
+		})
+	}
+}
+
+func TestDecodeLeaf(t *testing.T) {
+	sch, err := getSchema(&oc.Interface{})
+	if err != nil {
+		t.Fatalf("getSchema() got error: %v", err)
+	}
+	typ := reflect.TypeOf(oc.Interface{})
+	tests := []struct {
+		desc    string
+		path    string
+		val     *gnmipb.TypedValue
+		want    any
+		wantErr bool
+	}{{
+		desc: "uint16",
+		path: "/state/mtu",
+		val:  &gnmipb.TypedValue{Value: &gnmipb.TypedValue_UintVal{UintVal: 9000}},
+		want: ygot.Uint16(9000),
+	}, {
+		desc: "enumeration",
+		path: "/state/oper-status",
+		val:  &gnmipb.TypedValue{Value: &gnmipb.TypedValue_StringVal{StringVal: "UP"}},
+		want: oc.Interface_OperStatus_UP,
+	}, {
+		desc: "leaf of list entry",
+		path: "/subinterfaces/subinterface[index=0]/ipv4/addresses/address[ip=192.0.2.1]/state/prefix-length",
+		val:  &gnmipb.TypedValue{Value: &gnmipb.TypedValue_UintVal{UintVal: 31}},
+		want: ygot.Uint8(31),
+	}, {
+		desc:    "wrong type",
+		path:    "/state/mtu",
+		val:     &gnmipb.TypedValue{Value: &gnmipb.TypedValue_StringVal{StringVal: "jumbo"}},
+		wantErr: true,
+	}}
+	for _, tc := range tests {
+		t.Run(tc.desc, func(t *testing.T) {
+			got, err := decodeLeaf(typ, sch, mustPath(t, tc.path), tc.val)
+			if (err != nil) != tc.wantErr {
+				t.Fatalf("decodeLeaf() got error %v, want error %v", err, tc.wantErr)
+			}
+			if diff := cmp.Diff(tc.want, got); !tc.wantErr && diff != "" {
+				t.Errorf("decodeLeaf() diff (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestEqualsDefault(t *testing.T) {
+	enabled := leavesByPath(t, convergeIntf(true))
+	disabled := leavesByPath(t, convergeIntf(false))
+	tests := []struct {
+		desc string
+		leaf *stateLeaf
+		want bool
+	}{
+		{"enabled by default", enabled["/state/enabled"], true},
+		{"disabled", disabled["/state/enabled"], false},
+		{"no default", enabled["/state/mtu"], false},
+		{"default index", enabled["/subinterfaces/subinterface[index=0]/state/index"], true},
+	}
+	for _, tc := range tests {
+		if got := tc.leaf.equalsDefault(); got != tc.want {
+			t.Errorf("equalsDefault() of %s got %v, want %v", tc.desc, got, tc.want)
+		}
+	}
+}
+
+func TestSkippedPaths(t *testing.T) {
+	o := &convergenceOpts{}
+	WithSkippedPaths("/interfaces/interface/state/description")(o)
+	WithSkippedPaths("/interfaces/interface/subinterfaces")(o)
+	prefix := "/interfaces/interface[name=Ethernet1]"
+	got := map[string]string{}
+	for p := range leavesByPath(t, convergeIntf(true)) {
+		reason, err := skipReason(mustPath(t, prefix+p), o.skipPrefixes)
+		if err != nil {
+			t.Fatalf("skipReason(%s) got error: %v", p, err)
+		}
+		got[p] = reason
+	}
+	want := map[string]string{
+		"/state/name":        "",
+		"/state/description": "matches skipped path /interfaces/interface/state/description",
+		"/state/mtu":         "",
+		"/state/enabled":     "",
+		"/subinterfaces/subinterface[index=0]/state/index":                                              "matches skipped path /interfaces/interface/subinterfaces",
+		"/subinterfaces/subinterface[index=0]/ipv4/addresses/address[ip=192.0.2.1]/state/ip":            "matches skipped path /interfaces/interface/subinterfaces",
+		"/subinterfaces/subinterface[index=0]/ipv4/addresses/address[ip=192.0.2.1]/state/prefix-length": "matches skipped path /interfaces/interface/subinterfaces",
+	}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("skipReason() diff (-want +got):\n%s", diff)
+	}
+}
+
+func TestDeviationSkips(t *testing.T) {
+	root := oc.SchemaTree["Root"]
+	for _, s := range deviationSkips {
+		for _, p := range s.prefixes {
+			// The prefix is in the schema, and a path under it, with list keys, is skipped.
+			path := &gnmipb.Path{}
+			e := root
+			for _, n := range strings.Split(strings.TrimPrefix(p, "/"), "/") {
+				if e = e.Dir[n]; e == nil {
+					t.Fatalf("Skipped path %s of deviation %s is not in the schema", p, s.name)
+				}
+				elem := &gnmipb.PathElem{Name: n}
+				if e.IsList() {
+					elem.Key = map[string]string{}
+					for _, k := range strings.Fields(e.Key) {
+						elem.Key[k] = "k"
+					}
+				}
+				path.Elem = append(path.Elem, elem)
+			}
+			reason, err := skipReason(path, s.prefixes)
+			if err != nil || reason == "" {
+				t.Errorf("skipReason() of %s with deviation %s got %q, %v, want skipped", p, s.name, reason, err)
+			}
+		}
+	}
+}
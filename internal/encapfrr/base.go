@@ -23,6 +23,7 @@ import (
 
 	"github.com/openconfig/featureprofiles/internal/attrs"
 	"github.com/openconfig/featureprofiles/internal/deviations"
+	"github.com/openconfig/featureprofiles/internal/gribi"
 	"github.com/openconfig/gribigo/chk"
 	"github.com/openconfig/gribigo/constants"
 	"github.com/openconfig/gribigo/fluent"
@@ -238,64 +239,39 @@ func ConfigureBaseGribiRoutes(ctx context.Context, t *testing.T, dut *ondatra.DU
 	t.Helper()
 
 	// Programming AFT entries for prefixes in DEFAULT VRF
-	if deviations.GRIBIMACOverrideStaticARPStaticRoute(dut) {
-		client.Modify().AddEntry(t,
-			fluent.NextHopEntry().WithNetworkInstance(deviations.DefaultNetworkInstance(dut)).
-				WithIndex(11).WithMacAddress(magicMAC).WithInterfaceRef(dut.Port(t, "port2").Name()).WithIPAddress(magicIP),
-			fluent.NextHopEntry().WithNetworkInstance(deviations.DefaultNetworkInstance(dut)).
-				WithIndex(12).WithMacAddress(magicMAC).WithInterfaceRef(dut.Port(t, "port3").Name()).WithIPAddress(magicIP),
-			fluent.NextHopGroupEntry().WithNetworkInstance(deviations.DefaultNetworkInstance(dut)).
-				WithID(11).AddNextHop(11, 1).AddNextHop(12, 3),
-
-			fluent.NextHopEntry().WithNetworkInstance(deviations.DefaultNetworkInstance(dut)).
-				WithIndex(13).WithMacAddress(magicMAC).WithInterfaceRef(dut.Port(t, "port4").Name()).WithIPAddress(magicIP),
-			fluent.NextHopGroupEntry().WithNetworkInstance(deviations.DefaultNetworkInstance(dut)).
-				WithID(12).AddNextHop(13, 2),
-
-			fluent.NextHopEntry().WithNetworkInstance(deviations.DefaultNetworkInstance(dut)).
-				WithIndex(14).WithMacAddress(magicMAC).WithInterfaceRef(dut.Port(t, "port5").Name()).WithIPAddress(magicIP),
-			fluent.NextHopGroupEntry().WithNetworkInstance(deviations.DefaultNetworkInstance(dut)).
-				WithID(13).AddNextHop(14, 1),
-
-			fluent.NextHopEntry().WithNetworkInstance(deviations.DefaultNetworkInstance(dut)).
-				WithIndex(15).WithMacAddress(magicMAC).WithInterfaceRef(dut.Port(t, "port6").Name()).WithIPAddress(magicIP),
-			fluent.NextHopGroupEntry().WithNetworkInstance(deviations.DefaultNetworkInstance(dut)).
-				WithID(14).AddNextHop(15, 1),
-
-			fluent.NextHopEntry().WithNetworkInstance(deviations.DefaultNetworkInstance(dut)).
-				WithIndex(16).WithMacAddress(magicMAC).WithInterfaceRef(dut.Port(t, "port7").Name()).WithIPAddress(magicIP),
-			fluent.NextHopGroupEntry().WithNetworkInstance(deviations.DefaultNetworkInstance(dut)).
-				WithID(15).AddNextHop(16, 1),
-		)
-	} else if deviations.GRIBIMACOverrideWithStaticARP(dut) {
+	if deviations.GRIBIMACOverrideWithStaticARP(dut) && !deviations.GRIBIMACOverrideStaticARPStaticRoute(dut) {
 		programAftWithDummyIP(t, dut, client)
 	} else {
+		nhIP := ""
+		if deviations.GRIBIMACOverrideStaticARPStaticRoute(dut) {
+			nhIP = magicIP
+		}
+		ni := deviations.DefaultNetworkInstance(dut)
+		macNH := func(index uint64, port string) fluent.GRIBIEntry {
+			nh, _ := gribi.NHEntry(index, "MACwithInterface", ni, fluent.InstalledInFIB,
+				&gribi.NHOptions{Interface: dut.Port(t, port).Name(), Mac: magicMAC, Dest: nhIP})
+			return nh
+		}
 		client.Modify().AddEntry(t,
-			fluent.NextHopEntry().WithNetworkInstance(deviations.DefaultNetworkInstance(dut)).
-				WithIndex(11).WithMacAddress(magicMAC).WithInterfaceRef(dut.Port(t, "port2").Name()),
-			fluent.NextHopEntry().WithNetworkInstance(deviations.DefaultNetworkInstance(dut)).
-				WithIndex(12).WithMacAddress(magicMAC).WithInterfaceRef(dut.Port(t, "port3").Name()),
-			fluent.NextHopGroupEntry().WithNetworkInstance(deviations.DefaultNetworkInstance(dut)).
+			macNH(11, "port2"),
+			macNH(12, "port3"),
+			fluent.NextHopGroupEntry().WithNetworkInstance(ni).
 				WithID(11).AddNextHop(11, 1).AddNextHop(12, 3),
 
-			fluent.NextHopEntry().WithNetworkInstance(deviations.DefaultNetworkInstance(dut)).
-				WithIndex(13).WithMacAddress(magicMAC).WithInterfaceRef(dut.Port(t, "port4").Name()),
-			fluent.NextHopGroupEntry().WithNetworkInstance(deviations.DefaultNetworkInstance(dut)).
+			macNH(13, "port4"),
+			fluent.NextHopGroupEntry().WithNetworkInstance(ni).
 				WithID(12).AddNextHop(13, 2),
 
-			fluent.NextHopEntry().WithNetworkInstance(deviations.DefaultNetworkInstance(dut)).
-				WithIndex(14).WithMacAddress(magicMAC).WithInterfaceRef(dut.Port(t, "port5").Name()),
-			fluent.NextHopGroupEntry().WithNetworkInstance(deviations.DefaultNetworkInstance(dut)).
+			macNH(14, "port5"),
+			fluent.NextHopGroupEntry().WithNetworkInstance(ni).
 				WithID(13).AddNextHop(14, 1),
 
-			fluent.NextHopEntry().WithNetworkInstance(deviations.DefaultNetworkInstance(dut)).
-				WithIndex(15).WithMacAddress(magicMAC).WithInterfaceRef(dut.Port(t, "port6").Name()),
-			fluent.NextHopGroupEntry().WithNetworkInstance(deviations.DefaultNetworkInstance(dut)).
+			macNH(15, "port6"),
+			fluent.NextHopGroupEntry().WithNetworkInstance(ni).
 				WithID(14).AddNextHop(15, 1),
 
-			fluent.NextHopEntry().WithNetworkInstance(deviations.DefaultNetworkInstance(dut)).
-				WithIndex(16).WithMacAddress(magicMAC).WithInterfaceRef(dut.Port(t, "port7").Name()),
-			fluent.NextHopGroupEntry().WithNetworkInstance(deviations.DefaultNetworkInstance(dut)).
+			macNH(16, "port7"),
+			fluent.NextHopGroupEntry().WithNetworkInstance(ni).
 				WithID(15).AddNextHop(16, 1),
 		)
 	}
@@ -0,0 +1,456 @@
+// Copyright 2026 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package fptest
+
+import (
+	"flag"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	log "github.com/golang/glog"
+	"github.com/openconfig/goyang/pkg/yang"
+	"github.com/openconfig/ondatra/gnmi/oc"
+	"github.com/openconfig/ygnmi/ygnmi"
+	"github.com/openconfig/ygot/ygot"
+
+	gpb "github.com/openconfig/gnmi/proto/gnmi"
+)
+
+var (
+	datapointChecksFlag = flag.String("datapoint_checks", "", "Comma separated list of datapoint checks to enable for all tests, e.g. \"schema-type,stale-timestamp\". Violations fail the run at its end unless -datapoint_checks_soft is set.")
+	datapointChecksSoft = flag.Bool("datapoint_checks_soft", false, "Log the violations of -datapoint_checks instead of failing the run.")
+	datapointMaxAge     = flag.Duration("datapoint_max_age", time.Minute, "Maximum age of a datapoint timestamp relative to its receive time before the stale-timestamp check reports it.")
+)
+
+// DatapointCheck checks a single gNMI datapoint and returns an error describing the
+// violation if the datapoint is not conformant.
+type DatapointCheck func(dp *ygnmi.DataPoint) error
+
+// Names of the built-in datapoint checks.
+const (
+	// CheckSchemaType checks that the TypedValue type matches the type of the OC leaf.
+	CheckSchemaType = "schema-type"
+	// CheckDuplicateUpdate checks that a notification does not update a path twice.
+	CheckDuplicateUpdate = "duplicate-update"
+	// CheckStaleTimestamp checks that a datapoint is not older than -datapoint_max_age.
+	CheckStaleTimestamp = "stale-timestamp"
+	// CheckEnumValue checks that enum and identityref values are defined in the model.
+	CheckEnumValue = "enum-value"
+	// CheckNegativeCounter checks that unsigned leaves are not sent as negative integers.
+	CheckNegativeCounter = "negative-counter"
+)
+
+// maxDatapointViolations bounds the violations kept by a datapoint checker. Further
+// violations are only counted.
+const maxDatapointViolations = 10000
+
+var (
+	datapointMu sync.Mutex
+	// registeredChecks holds a constructor of every check that can be enabled, keyed by
+	// name. Every checker constructs its own checks, so stateful checks such as
+	// duplicate-update do not share state between checkers.
+	registeredChecks = map[string]func() DatapointCheck{
+		CheckSchemaType:      statelessCheck(checkSchemaType),
+		CheckDuplicateUpdate: newDuplicateUpdateCheck,
+		CheckStaleTimestamp:  statelessCheck(checkStaleTimestamp),
+		CheckEnumValue:       statelessCheck(checkEnumValue),
+		CheckNegativeCounter: statelessCheck(checkNegativeCounter),
+	}
+	// activeCheckers holds the checkers that received datapoints are run through.
+	activeCheckers = map[*datapointChecker]bool{}
+	// flagChecker is the checker of the -datapoint_checks flag, or nil if the flag is unset.
+	flagChecker *datapointChecker
+)
+
+func statelessCheck(check DatapointCheck) func() DatapointCheck {
+	return func() DatapointCheck { return check }
+}
+
+// DatapointViolation is a datapoint that failed a check.
+type DatapointViolation struct {
+	Check string
+	Path  string
+	Err   error
+}
+
+// RegisterDatapointCheck registers a named check that can then be enabled with
+// EnableDatapointChecks or the -datapoint_checks flag. It panics if the name is taken.
+// The check is shared by all checkers that enable it, so it should be stateless.
+func RegisterDatapointCheck(name string, check DatapointCheck) {
+	datapointMu.Lock()
+	defer datapointMu.Unlock()
+	if _, ok := registeredChecks[name]; ok {
+		panic(fmt.Sprintf("datapoint check %q is already registered", name))
+	}
+	registeredChecks[name] = statelessCheck(check)
+}
+
+// datapointChecker runs a set of checks against received datapoints and keeps up to
+// maxDatapointViolations of their violations.
+type datapointChecker struct {
+	checks     map[string]DatapointCheck
+	violations []*DatapointViolation
+	// dropped counts the violations beyond maxDatapointViolations.
+	dropped int
+}
+
+// startChecker returns a checker of the named checks that runs on every received datapoint
+// until it is stopped.
+func startChecker(names []string) (*datapointChecker, error) {
+	datapointMu.Lock()
+	defer datapointMu.Unlock()
+	c := &datapointChecker{checks: map[string]DatapointCheck{}}
+	for _, name := range names {
+		newCheck, ok := registeredChecks[name]
+		if !ok {
+			return nil, fmt.Errorf("unknown datapoint check %q", name)
+		}
+		c.checks[name] = newCheck()
+	}
+	activeCheckers[c] = true
+	return c, nil
+}
+
+// stopChecker stops the checker and returns the summary of its violations.
+func stopChecker(c *datapointChecker) []string {
+	datapointMu.Lock()
+	defer datapointMu.Unlock()
+	delete(activeCheckers, c)
+	out := summarizeViolations(c.violations)
+	if c.dropped > 0 {
+		out = append(out, fmt.Sprintf("%d further datapoint check violations were not kept", c.dropped))
+	}
+	return out
+}
+
+// run runs the checks of the checker against the datapoint and records violations.
+func (c *datapointChecker) run(dp *ygnmi.DataPoint) {
+	for name, check := range c.checks {
+		err := check(dp)
+		switch {
+		case err == nil:
+		case len(c.violations) < maxDatapointViolations:
+			c.violations = append(c.violations, &DatapointViolation{Check: name, Path: schemaPath(dp.Path), Err: err})
+		default:
+			c.dropped++
+		}
+	}
+}
+
+// EnableDatapointChecks enables the named checks for every gNMI datapoint received
+// until the test ends. Violations do not fail the query that received the datapoint.
+// Instead they are aggregated and reported as test errors when the test ends, one per
+// check and schema path. Violations seen by parallel tests are attributed to all of them.
+func EnableDatapointChecks(t testing.TB, names ...string) {
+	t.Helper()
+	c, err := startChecker(names)
+	if err != nil {
+		t.Fatalf("Cannot enable datapoint checks: %v", err)
+	}
+	t.Cleanup(func() {
+		for _, s := range stopChecker(c) {
+			t.Error(s)
+		}
+	})
+}
+
+// runDatapointChecks runs the datapoint through all active checkers.
+func runDatapointChecks(dp *ygnmi.DataPoint) {
+	datapointMu.Lock()
+	defer datapointMu.Unlock()
+	for c := range activeCheckers {
+		c.run(dp)
+	}
+}
+
+// summarizeViolations returns one line per check and schema path with the number of
+// violations and the first error.
+func summarizeViolations(vs []*DatapointViolation) []string {
+	type key struct{ check, path string }
+	first := map[key]*DatapointViolation{}
+	count := map[key]int{}
+	for _, v := range vs {
+		k := key{v.Check, v.Path}
+		if _, ok := first[k]; !ok {
+			first[k] = v
+		}
+		count[k]++
+	}
+	var out []string
+	for k, v := range first {
+		out = append(out, fmt.Sprintf("datapoint check %s failed %d times at %s, first: %v", k.check, count[k], k.path, v.Err))
+	}
+	sort.Strings(out)
+	return out
+}
+
+// enableFlagChecks enables the checks in the -datapoint_checks flag for the whole run.
+func enableFlagChecks() error {
+	if !flag.Parsed() {
+		flag.Parse()
+	}
+	if *datapointChecksFlag == "" {
+		return nil
+	}
+	c, err := startChecker(strings.Split(*datapointChecksFlag, ","))
+	if err != nil {
+		return err
+	}
+	flagChecker = c
+	return nil
+}
+
+// flagViolations stops the checks enabled by -datapoint_checks and returns an error listing
+// their violations. With -datapoint_checks_soft, the violations are only logged.
+func flagViolations() error {
+	if flagChecker == nil {
+		return nil
+	}
+	out := stopChecker(flagChecker)
+	flagChecker = nil
+	if len(out) == 0 {
+		return nil
+	}
+	if *datapointChecksSoft {
+		for _, s := range out {
+			log.Warning(s)
+		}
+		return nil
+	}
+	return fmt.Errorf("%d datapoint check failures:\n%s", len(out), strings.Join(out, "\n"))
+}
+
+// schemaPath returns the path without keys, which is used to aggregate violations.
+func schemaPath(p *gpb.Path) string {
+	var b strings.Builder
+	for _, e := range p.GetElem() {
+		b.WriteString("/" + e.GetName())
+	}
+	return b.String()
+}
+
+var ocSchema = sync.OnceValue(func() *yang.Entry {
+	s, err := oc.Schema()
+	if err != nil {
+		log.Errorf("Cannot load OC schema for datapoint checks: %v", err)
+		return nil
+	}
+	return s.RootSchema()
+})
+
+// findChild returns the child of e with the given name, looking through choice and case
+// statements, which do not appear in data paths.
+func findChild(e *yang.Entry, name string) *yang.Entry {
+	if _, after, ok := strings.Cut(name, ":"); ok {
+		name = after
+	}
+	if c, ok := e.Dir[name]; ok {
+		return c
+	}
+	for _, c := range e.Dir {
+		if c.IsChoice() || c.IsCase() {
+			if found := findChild(c, name); found != nil {
+				return found
+			}
+		}
+	}
+	return nil
+}
+
+// leafEntry returns the schema entry of the leaf or leaf-list at the path, or nil if the
+// path is not a leaf in the OC schema.
+func leafEntry(p *gpb.Path) *yang.Entry {
+	e := ocSchema()
+	for _, elem := range p.GetElem() {
+		if e == nil {
+			return nil
+		}
+		e = findChild(e, elem.GetName())
+	}
+	if e == nil || (!e.IsLeaf() && !e.IsLeafList()) || e.Type == nil {
+		return nil
+	}
+	return e
+}
+
+// scalarValues returns the values to check, flattening leaf-lists.
+func scalarValues(tv *gpb.TypedValue) []*gpb.TypedValue {
+	if ll := tv.GetLeaflistVal(); ll != nil {
+		return ll.GetElement()
+	}
+	return []*gpb.TypedValue{tv}
+}
+
+func isUnsigned(k yang.TypeKind) bool {
+	switch k {
+	case yang.Yuint8, yang.Yuint16, yang.Yuint32, yang.Yuint64:
+		return true
+	}
+	return false
+}
+
+func isSigned(k yang.TypeKind) bool {
+	switch k {
+	case yang.Yint8, yang.Yint16, yang.Yint32, yang.Yint64:
+		return true
+	}
+	return false
+}
+
+// isIEEEFloat32 returns whether the type is the OC ieeefloat32 typedef. It is defined as
+// binary, but devices stream it as a float, double or bytes value.
+func isIEEEFloat32(t *yang.YangType) bool {
+	return t.Name == "ieeefloat32"
+}
+
+// checkSchemaType checks that the type of the value matches the type of the OC leaf.
+// Unions, leafrefs and JSON encoded values are not checked.
+func checkSchemaType(dp *ygnmi.DataPoint) error {
+	if dp.Value == nil {
+		return nil
+	}
+	e := leafEntry(dp.Path)
+	if e == nil {
+		return nil
+	}
+	k := e.Type.Kind
+	for _, tv := range scalarValues(dp.Value) {
+		var ok bool
+		switch v := tv.GetValue().(type) {
+		case *gpb.TypedValue_JsonIetfVal, *gpb.TypedValue_JsonVal:
+			ok = true
+		case *gpb.TypedValue_UintVal:
+			ok = isUnsigned(k) || (isSigned(k) && v.UintVal <= 1<<63-1)
+		case *gpb.TypedValue_IntVal:
+			ok = isSigned(k)
+		case *gpb.TypedValue_StringVal:
+			ok = k == yang.Ystring || k == yang.Yenum || k == yang.Yidentityref
+		case *gpb.TypedValue_BoolVal:
+			ok = k == yang.Ybool || k == yang.Yempty
+		case *gpb.TypedValue_DoubleVal, *gpb.TypedValue_FloatVal:
+			ok = k == yang.Ydecimal64 || isIEEEFloat32(e.Type)
+		case *gpb.TypedValue_DecimalVal:
+			ok = k == yang.Ydecimal64
+		case *gpb.TypedValue_BytesVal:
+			ok = k == yang.Ybinary
+		}
+		if k == yang.Yunion || k == yang.Yleafref {
+			ok = true
+		}
+		if !ok {
+			return fmt.Errorf("value %v of type %T does not match leaf type %v", tv, tv.GetValue(), k)
+		}
+	}
+	return nil
+}
+
+// checkEnumValue checks that values of enum and identityref leaves are defined.
+func checkEnumValue(dp *ygnmi.DataPoint) error {
+	if dp.Value == nil {
+		return nil
+	}
+	e := leafEntry(dp.Path)
+	if e == nil {
+		return nil
+	}
+	for _, tv := range scalarValues(dp.Value) {
+		s, ok := tv.GetValue().(*gpb.TypedValue_StringVal)
+		if !ok {
+			continue
+		}
+		name := s.StringVal
+		if _, after, ok := strings.Cut(name, ":"); ok {
+			name = after
+		}
+		switch e.Type.Kind {
+		case yang.Yenum:
+			if e.Type.Enum != nil && !e.Type.Enum.IsDefined(name) {
+				return fmt.Errorf("enum value %q is not defined for %s", s.StringVal, e.Type.Name)
+			}
+		case yang.Yidentityref:
+			if base := e.Type.IdentityBase; base != nil && !identityDefined(base, name) {
+				return fmt.Errorf("identity %q is not derived from %s", s.StringVal, base.Name)
+			}
+		}
+	}
+	return nil
+}
+
+func identityDefined(base *yang.Identity, name string) bool {
+	for _, v := range base.Values {
+		if v.Name == name {
+			return true
+		}
+	}
+	return false
+}
+
+// checkNegativeCounter checks that unsigned leaves, such as counters, are not sent as
+// negative integers.
+func checkNegativeCounter(dp *ygnmi.DataPoint) error {
+	if dp.Value == nil {
+		return nil
+	}
+	e := leafEntry(dp.Path)
+	if e == nil || !isUnsigned(e.Type.Kind) {
+		return nil
+	}
+	for _, tv := range scalarValues(dp.Value) {
+		if v, ok := tv.GetValue().(*gpb.TypedValue_IntVal); ok && v.IntVal < 0 {
+			return fmt.Errorf("unsigned leaf has negative value %d", v.IntVal)
+		}
+	}
+	return nil
+}
+
+// checkStaleTimestamp checks that the notification timestamp is no older than
+// -datapoint_max_age when the datapoint is received.
+func checkStaleTimestamp(dp *ygnmi.DataPoint) error {
+	if dp.Timestamp.IsZero() || dp.RecvTimestamp.IsZero() {
+		return nil
+	}
+	if age := dp.RecvTimestamp.Sub(dp.Timestamp); age > *datapointMaxAge {
+		return fmt.Errorf("datapoint timestamp %v is %v older than its receive time", dp.Timestamp, age)
+	}
+	return nil
+}
+
+// newDuplicateUpdateCheck returns a check that reports paths updated twice within one
+// notification. Datapoints of a notification share the notification and receive
+// timestamps and are validated consecutively, so only the paths of the most recent
+// notification are kept. A datapoint validated again is not a duplicate of itself.
+func newDuplicateUpdateCheck() DatapointCheck {
+	var lastTS, lastRecv time.Time
+	seen := map[string]*ygnmi.DataPoint{}
+	return func(dp *ygnmi.DataPoint) error {
+		if !dp.Timestamp.Equal(lastTS) || !dp.RecvTimestamp.Equal(lastRecv) {
+			lastTS, lastRecv = dp.Timestamp, dp.RecvTimestamp
+			clear(seen)
+		}
+		p, err := ygot.PathToString(dp.Path)
+		if err != nil {
+			return nil
+		}
+		if prev, ok := seen[p]; ok && prev != dp {
+			return fmt.Errorf("path %s is updated more than once in the notification at %v", p, dp.Timestamp)
+		}
+		seen[p] = dp
+		return nil
+	}
+}
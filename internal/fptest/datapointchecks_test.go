@@ -0,0 +1,258 @@
+// Copyright 2026 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package fptest
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/openconfig/ygnmi/ygnmi"
+	"github.com/openconfig/ygot/ygot"
+
+	gpb "github.com/openconfig/gnmi/proto/gnmi"
+)
+
+func mustPath(t *testing.T, s string) *gpb.Path {
+	t.Helper()
+	p, err := ygot.StringToStructuredPath(s)
+	if err != nil {
+		t.Fatalf("StringToStructuredPath(%q) failed: %v", s, err)
+	}
+	return p
+}
+
+// TestDatapointChecks confirms behavior of the built-in datapoint checks.
+func TestDatapointChecks(t *testing.T) {
+	ts := time.Unix(1707215426, 123456789)
+	const (
+		counter  = "/interfaces/interface[name=eth0]/state/counters/in-octets"
+		operStat = "/interfaces/interface[name=eth0]/state/oper-status"
+		ifType   = "/interfaces/interface[name=eth0]/state/type"
+		// psuCapacity is an ieeefloat32 leaf.
+		psuCapacity = "/components/component[name=PSU0]/power-supply/state/capacity"
+	)
+	tests := []struct {
+		name    string
+		check   DatapointCheck
+		path    string
+		val     *gpb.TypedValue
+		recv    time.Time
+		wantErr bool
+	}{{
+		name:  "schema type uint",
+		check: checkSchemaType,
+		path:  counter,
+		val:   &gpb.TypedValue{Value: &gpb.TypedValue_UintVal{UintVal: 1}},
+	}, {
+		name:    "schema type string for uint",
+		check:   checkSchemaType,
+		path:    counter,
+		val:     &gpb.TypedValue{Value: &gpb.TypedValue_StringVal{StringVal: "1"}},
+		wantErr: true,
+	}, {
+		name:  "schema type unknown path",
+		check: checkSchemaType,
+		path:  "/not/a/path",
+		val:   &gpb.TypedValue{Value: &gpb.TypedValue_StringVal{StringVal: "1"}},
+	}, {
+		name:  "schema type float for ieeefloat32",
+		check: checkSchemaType,
+		path:  psuCapacity,
+		val:   &gpb.TypedValue{Value: &gpb.TypedValue_FloatVal{FloatVal: 1500}},
+	}, {
+		name:  "schema type double for ieeefloat32",
+		check: checkSchemaType,
+		path:  psuCapacity,
+		val:   &gpb.TypedValue{Value: &gpb.TypedValue_DoubleVal{DoubleVal: 1500}},
+	}, {
+		name:  "schema type bytes for ieeefloat32",
+		check: checkSchemaType,
+		path:  psuCapacity,
+		val:   &gpb.TypedValue{Value: &gpb.TypedValue_BytesVal{BytesVal: []byte{0x44, 0xbb, 0x80, 0x00}}},
+	}, {
+		name:    "schema type string for ieeefloat32",
+		check:   checkSchemaType,
+		path:    psuCapacity,
+		val:     &gpb.TypedValue{Value: &gpb.TypedValue_StringVal{StringVal: "1500"}},
+		wantErr: true,
+	}, {
+		name:    "negative counter",
+		check:   checkNegativeCounter,
+		path:    counter,
+		val:     &gpb.TypedValue{Value: &gpb.TypedValue_IntVal{IntVal: -1}},
+		wantErr: true,
+	}, {
+		name:  "defined enum",
+		check: checkEnumValue,
+		path:  operStat,
+		val:   &gpb.TypedValue{Value: &gpb.TypedValue_StringVal{StringVal: "UP"}},
+	}, {
+		name:    "undefined enum",
+		check:   checkEnumValue,
+		path:    operStat,
+		val:     &gpb.TypedValue{Value: &gpb.TypedValue_StringVal{StringVal: "SIDEWAYS"}},
+		wantErr: true,
+	}, {
+		name:  "defined identity with module prefix",
+		check: checkEnumValue,
+		path:  ifType,
+		val:   &gpb.TypedValue{Value: &gpb.TypedValue_StringVal{StringVal: "iana-if-type:ethernetCsmacd"}},
+	}, {
+		name:    "undefined identity",
+		check:   checkEnumValue,
+		path:    ifType,
+		val:     &gpb.TypedValue{Value: &gpb.TypedValue_StringVal{StringVal: "carrierPigeon"}},
+		wantErr: true,
+	}, {
+		name:  "fresh timestamp",
+		check: checkStaleTimestamp,
+		path:  counter,
+		recv:  ts.Add(time.Second),
+	}, {
+		name:    "stale timestamp",
+		check:   checkStaleTimestamp,
+		path:    counter,
+		recv:    ts.Add(time.Hour),
+		wantErr: true,
+	}}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			dp := &ygnmi.DataPoint{Path: mustPath(t, tc.path), Value: tc.val, Timestamp: ts, RecvTimestamp: tc.recv}
+			if err := tc.check(dp); (err != nil) != tc.wantErr {
+				t.Errorf("check(%v) got error %v, want error %v", dp, err, tc.wantErr)
+			}
+		})
+	}
+}
+
+// TestDuplicateUpdateCheck confirms that only repeated paths within one notification are reported.
+func TestDuplicateUpdateCheck(t *testing.T) {
+	check := newDuplicateUpdateCheck()
+	ts := time.Unix(1707215426, 123456789)
+	dp := func(path string, ts time.Time) *ygnmi.DataPoint {
+		return &ygnmi.DataPoint{Path: mustPath(t, path), Timestamp: ts, RecvTimestamp: ts}
+	}
+	a := dp("/system/state/hostname", ts)
+	if err := check(a); err != nil {
+		t.Errorf("first update got error %v", err)
+	}
+	if err := check(a); err != nil {
+		t.Errorf("revalidated update got error %v", err)
+	}
+	if err := check(dp("/system/state/hostname", ts)); err == nil {
+		t.Errorf("duplicate update got no error")
+	}
+	if err := check(dp("/system/state/hostname", ts.Add(time.Second))); err != nil {
+		t.Errorf("update in next notification got error %v", err)
+	}
+}
+
+// TestDatapointChecker confirms that violations are aggregated per check and path, and kept
+// per checker up to maxDatapointViolations.
+func TestDatapointChecker(t *testing.T) {
+	negative := &ygnmi.DataPoint{
+		Path:  mustPath(t, "/interfaces/interface[name=eth0]/state/counters/in-octets"),
+		Value: &gpb.TypedValue{Value: &gpb.TypedValue_IntVal{IntVal: -1}},
+	}
+	c, err := startChecker([]string{CheckNegativeCounter})
+	if err != nil {
+		t.Fatalf("startChecker() failed: %v", err)
+	}
+	other, err := startChecker([]string{CheckSchemaType})
+	if err != nil {
+		t.Fatalf("startChecker() failed: %v", err)
+	}
+	for range 3 {
+		runDatapointChecks(negative)
+	}
+	got := stopChecker(c)
+	runDatapointChecks(negative)
+	if len(got) != 1 {
+		t.Fatalf("stopChecker() got %q, want one summary", got)
+	}
+	if want := "datapoint check negative-counter failed 3 times at /interfaces/interface/state/counters/in-octets, first: unsigned leaf has negative value -1"; got[0] != want {
+		t.Errorf("stopChecker() got %q, want %q", got[0], want)
+	}
+	if got := stopChecker(other); len(got) != 1 || !strings.Contains(got[0], "schema-type failed 4 times") {
+		t.Errorf("stopChecker() of a second checker got %q, want its own schema-type violations", got)
+	}
+	if _, err := startChecker([]string{"no-such-check"}); err == nil {
+		t.Errorf("startChecker() of unknown check got no error")
+	}
+
+	bounded := &datapointChecker{checks: map[string]DatapointCheck{CheckNegativeCounter: checkNegativeCounter}}
+	for range maxDatapointViolations + 2 {
+		bounded.run(negative)
+	}
+	if len(bounded.violations) != maxDatapointViolations || bounded.dropped != 2 {
+		t.Errorf("Checker kept %d violations and dropped %d, want %d and 2", len(bounded.violations), bounded.dropped, maxDatapointViolations)
+	}
+}
+
+// TestFlagViolations confirms that violations of the -datapoint_checks checks are returned
+// as an error, or only logged with -datapoint_checks_soft.
+func TestFlagViolations(t *testing.T) {
+	defer func(soft bool) { *datapointChecksSoft = soft }(*datapointChecksSoft)
+	negative := &ygnmi.DataPoint{
+		Path:  mustPath(t, "/interfaces/interface[name=eth0]/state/counters/in-octets"),
+		Value: &gpb.TypedValue{Value: &gpb.TypedValue_IntVal{IntVal: -1}},
+	}
+	for _, soft := range []bool{false, true} {
+		c, err := startChecker([]string{CheckNegativeCounter})
+		if err != nil {
+			t.Fatalf("startChecker() failed: %v", err)
+		}
+		flagChecker = c
+		*datapointChecksSoft = soft
+		runDatapointChecks(negative)
+		err = flagViolations()
+		if gotErr := err != nil; gotErr == soft {
+			t.Errorf("flagViolations() with soft %v got error %v, want error %v", soft, err, !soft)
+		}
+		if err != nil && !strings.Contains(err.Error(), "negative-counter failed 1 times") {
+			t.Errorf("flagViolations() got error %v, want the negative-counter violation", err)
+		}
+		if flagChecker != nil {
+			t.Errorf("flagViolations() did not stop the flag checker")
+		}
+	}
+	if err := flagViolations(); err != nil {
+		t.Errorf("flagViolations() without checks got error %v", err)
+	}
+}
+
+// TestDuplicateUpdateCheckPerChecker confirms that checkers do not share duplicate-update state.
+func TestDuplicateUpdateCheckPerChecker(t *testing.T) {
+	a, err := startChecker([]string{CheckDuplicateUpdate})
+	if err != nil {
+		t.Fatalf("startChecker() failed: %v", err)
+	}
+	defer stopChecker(a)
+	b, err := startChecker([]string{CheckDuplicateUpdate})
+	if err != nil {
+		t.Fatalf("startChecker() failed: %v", err)
+	}
+	defer stopChecker(b)
+	ts := time.Unix(1707215426, 0)
+	dp := &ygnmi.DataPoint{Path: mustPath(t, "/system/state/hostname"), Timestamp: ts, RecvTimestamp: ts}
+	if err := a.checks[CheckDuplicateUpdate](dp); err != nil {
+		t.Errorf("first update got error %v", err)
+	}
+	dup := &ygnmi.DataPoint{Path: dp.Path, Timestamp: ts, RecvTimestamp: ts}
+	if err := b.checks[CheckDuplicateUpdate](dup); err != nil {
+		t.Errorf("update seen only by another checker got error %v", err)
+	}
+}
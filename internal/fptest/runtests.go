@@ -15,6 +15,7 @@
 package fptest
 
 import (
+	"errors"
 	"flag"
 	"fmt"
 	"path/filepath"
@@ -46,8 +47,21 @@ func RunTests(m *testing.M) {
 	if err := initMetadata(); err != nil {
 		log.Errorf("Unable to initialize test metadata: %v", err)
 	}
+	if err := enableFlagChecks(); err != nil {
+		log.Exitf("Invalid -datapoint_checks: %v", err)
+	}
 	ygnmi.WithDatapointValidator(datapointValidator)
-	ondatra.RunTests(m, binding.New)
+	err := ondatra.ExecuteTests(m, binding.New)
+	if verr := flagViolations(); verr != nil {
+		// Report the violations as a failed test case, as ondatra does for setup errors.
+		fmt.Println("=== RUN   TestDatapointChecks")
+		fmt.Printf("    %v\n", verr)
+		fmt.Println("--- FAIL: TestDatapointChecks (0.00s)")
+		err = errors.Join(err, verr)
+	}
+	if err != nil {
+		log.Exit(err)
+	}
 }
 
 func initMetadata() error {
@@ -102,8 +116,11 @@ func testbedPathFromMetadata() (string, error) {
 
 // datapointValidator is a ygnmi.ValidateFn that validates the timestamp of an input datapoint.
 // It is called for each gNMI datapoint (<timestamp, path, value> tuple) received by any test that
-// uses the ONDATRA gnmi library.
+// uses the ONDATRA gnmi library. It also runs the enabled datapoint checks, whose violations are
+// recorded rather than returned (see EnableDatapointChecks).
 func datapointValidator(dp *ygnmi.DataPoint) error {
+	runDatapointChecks(dp)
+
 	// Validate the timestamp
 	if !dp.Timestamp.IsZero() {
 		ns := dp.Timestamp.UnixNano()
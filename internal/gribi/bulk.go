@@ -0,0 +1,348 @@
+// Copyright 2026 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package gribi
+
+import (
+	"cmp"
+	"context"
+	"fmt"
+	"iter"
+	"math"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/openconfig/featureprofiles/internal/fptest"
+	"github.com/openconfig/gribigo/client"
+	"github.com/openconfig/gribigo/fluent"
+
+	gpb "github.com/openconfig/gribi/v1/proto/service"
+)
+
+// DefaultBulkBatchSize is the default number of operations per ModifyRequest of
+// BulkProgram.
+const DefaultBulkBatchSize = 1000
+
+// latencyBuckets are the upper bounds of the batch latency histogram of BulkStats.
+var latencyBuckets = []time.Duration{
+	time.Millisecond, 2 * time.Millisecond, 5 * time.Millisecond,
+	10 * time.Millisecond, 20 * time.Millisecond, 50 * time.Millisecond,
+	100 * time.Millisecond, 200 * time.Millisecond, 500 * time.Millisecond,
+	time.Second, 2 * time.Second, 5 * time.Second, 10 * time.Second, 30 * time.Second,
+}
+
+// BulkOptions control how BulkProgram paces operations.
+type BulkOptions struct {
+	// Op is the operation applied to every entry. The default is ADD.
+	Op gpb.AFTOperation_Operation
+	// BatchSize is the number of operations per ModifyRequest. The default is
+	// DefaultBulkBatchSize.
+	BatchSize int
+	// MaxInFlight is the maximum number of operations sent without a final result. A batch
+	// is only sent when it fits in the window, and otherwise after the whole window has
+	// drained. The default is one batch, so that each batch waits for the results of the
+	// previous one.
+	MaxInFlight int
+	// OpsPerSecond limits the rate at which operations are sent. Zero is unlimited.
+	OpsPerSecond float64
+	// Timeout is how long to wait for the window to drain before failing. The default is 30
+	// minutes.
+	Timeout time.Duration
+	// SkipRIB stops the entries from being recorded in the RIB model of the client, for
+	// scales where the model does not fit in memory.
+	SkipRIB bool
+}
+
+// BatchResult contains the results of a batch of BulkProgram.
+type BatchResult struct {
+	// Ops is the number of operations of the batch.
+	Ops int
+	// Failed is the number of operations with a FAILED or FIB_FAILED result.
+	Failed int
+	// Sent is the time the batch was sent.
+	Sent time.Time
+	// Latency is the time from Sent to when the final results of the batch were read.
+	// Results are read when the window drains, so the batches of a window share the same
+	// completion time and latencies are upper bounds, the tightest with the default
+	// MaxInFlight.
+	Latency time.Duration
+}
+
+// BulkStats contains the results of BulkProgram.
+type BulkStats struct {
+	Batches []BatchResult
+	// PeakInFlight is the largest number of operations sent without a final result.
+	PeakInFlight int
+	// Elapsed is the time from the first batch sent to the last result received.
+	Elapsed time.Duration
+}
+
+// Ops returns the total number of operations.
+func (s *BulkStats) Ops() int {
+	var n int
+	for _, b := range s.Batches {
+		n += b.Ops
+	}
+	return n
+}
+
+// Failed returns the total number of failed operations.
+func (s *BulkStats) Failed() int {
+	var n int
+	for _, b := range s.Batches {
+		n += b.Failed
+	}
+	return n
+}
+
+// OpsPerSecond returns the overall programming rate.
+func (s *BulkStats) OpsPerSecond() float64 {
+	if s.Elapsed <= 0 {
+		return 0
+	}
+	return float64(s.Ops()) / s.Elapsed.Seconds()
+}
+
+// Percentile returns the p-th percentile (0 <= p <= 100) of the batch latencies using the
+// nearest-rank method, or 0 without batches.
+func (s *BulkStats) Percentile(p float64) time.Duration {
+	if len(s.Batches) == 0 {
+		return 0
+	}
+	sorted := make([]time.Duration, 0, len(s.Batches))
+	for _, b := range s.Batches {
+		sorted = append(sorted, b.Latency)
+	}
+	slices.Sort(sorted)
+	rank := min(max(int(math.Ceil(p/100*float64(len(sorted)))), 1), len(sorted))
+	return sorted[rank-1]
+}
+
+// Histogram returns the number of batches per latency bucket. Bucket i counts the latencies
+// up to latencyBuckets[i], and the last bucket the larger ones.
+func (s *BulkStats) Histogram() []int {
+	h := make([]int, len(latencyBuckets)+1)
+	for _, b := range s.Batches {
+		i, _ := slices.BinarySearch(latencyBuckets, b.Latency)
+		h[i]++
+	}
+	return h
+}
+
+// String returns a summary of the stats with the batch latency histogram.
+func (s *BulkStats) String() string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "%d operations (%d failed) in %d batches in %v: %.0f ops/s, batch latency p50=%v p99=%v max=%v\n",
+		s.Ops(), s.Failed(), len(s.Batches), s.Elapsed, s.OpsPerSecond(), s.Percentile(50), s.Percentile(99), s.Percentile(100))
+	for i, n := range s.Histogram() {
+		if n == 0 {
+			continue
+		}
+		if i < len(latencyBuckets) {
+			fmt.Fprintf(&b, "  <= %-8v %d\n", latencyBuckets[i], n)
+		} else {
+			fmt.Fprintf(&b, "  >  %-8v %d\n", latencyBuckets[i-1], n)
+		}
+	}
+	return b.String()
+}
+
+// WriteCSV writes the per batch results to a CSV file in the test outputs directory and
+// returns its name.
+func (s *BulkStats) WriteCSV(name string) (string, error) {
+	var b strings.Builder
+	b.WriteString("batch,ops,failed,sent_unix_ns,latency_us\n")
+	for i, r := range s.Batches {
+		fmt.Fprintf(&b, "%d,%d,%d,%d,%d\n", i, r.Ops, r.Failed, r.Sent.UnixNano(), r.Latency.Microseconds())
+	}
+	return fptest.WriteOutput(name, ".csv", b.String())
+}
+
+// Log logs the summary of the stats and writes the per batch results to the test outputs
+// directory.
+func (s *BulkStats) Log(t testing.TB, name string) {
+	t.Helper()
+	t.Logf("gRIBI bulk programming %s: %s", name, s)
+	if _, err := s.WriteCSV(name); err != nil {
+		t.Errorf("Error writing gRIBI bulk programming stats: %v", err)
+	}
+}
+
+// bulkBatch is a batch sent by BulkProgram.
+type bulkBatch struct {
+	firstID uint64
+	result  BatchResult
+}
+
+// bulkTracker matches results to batches.
+type bulkTracker struct {
+	fibACK      bool
+	batches     []*bulkBatch
+	done        []bool // Final result seen, indexed by operation ID - firstID of the run.
+	firstID     uint64
+	inFlight    int
+	peak        int
+	resultsSeen int
+	lastResult  time.Time
+}
+
+// final reports whether a result is the last one expected for its operation.
+func (bt *bulkTracker) final(s gpb.AFTResult_Status) bool {
+	switch s {
+	case gpb.AFTResult_FAILED, gpb.AFTResult_FIB_PROGRAMMED, gpb.AFTResult_FIB_FAILED:
+		return true
+	case gpb.AFTResult_RIB_PROGRAMMED:
+		return !bt.fibACK
+	}
+	return false
+}
+
+// sent registers a batch of n operations with consecutive IDs from firstID.
+func (bt *bulkTracker) sent(firstID uint64, n int, at time.Time) {
+	if len(bt.batches) == 0 {
+		bt.firstID = firstID
+	}
+	bt.batches = append(bt.batches, &bulkBatch{firstID: firstID, result: BatchResult{Ops: n, Sent: at}})
+	bt.done = append(bt.done, make([]bool, n)...)
+	bt.inFlight += n
+	bt.peak = max(bt.peak, bt.inFlight)
+}
+
+// process accounts for the new results, observed at time now. Result timestamps are not used
+// since the gribigo client sets them all to its start time.
+func (bt *bulkTracker) process(results []*client.OpResult, now time.Time) {
+	for _, res := range results[bt.resultsSeen:] {
+		if res.OperationID < bt.firstID || res.OperationID >= bt.firstID+uint64(len(bt.done)) || !bt.final(res.ProgrammingResult) {
+			continue
+		}
+		idx := res.OperationID - bt.firstID
+		if bt.done[idx] {
+			continue
+		}
+		bt.done[idx] = true
+		i, _ := slices.BinarySearchFunc(bt.batches, res.OperationID, func(b *bulkBatch, id uint64) int {
+			switch {
+			case b.firstID+uint64(b.result.Ops) <= id:
+				return -1
+			case b.firstID > id:
+				return 1
+			}
+			return 0
+		})
+		b := bt.batches[i]
+		bt.inFlight--
+		if res.ProgrammingResult == gpb.AFTResult_FAILED || res.ProgrammingResult == gpb.AFTResult_FIB_FAILED {
+			b.result.Failed++
+		}
+		if l := now.Sub(b.result.Sent); l > b.result.Latency {
+			b.result.Latency = l
+		}
+		bt.lastResult = now
+	}
+	bt.resultsSeen = len(results)
+}
+
+// BulkProgram sends the entries in batches, keeping at most opts.MaxInFlight operations
+// without a final result and at most opts.OpsPerSecond operations per second, and returns
+// the results per batch. Entries are consumed from the iterator as they are sent, so that
+// they need not all be in memory. Use slices.Values to program a slice.
+//
+// When the window is full, BulkProgram awaits the convergence of the client before reading
+// its results once, since reading the results copies all the results of the session. The
+// window is therefore drained before the next batch is sent.
+func (c *Client) BulkProgram(t testing.TB, entries iter.Seq[fluent.GRIBIEntry], opts BulkOptions) *BulkStats {
+	t.Helper()
+	op := opts.Op
+	if op == gpb.AFTOperation_INVALID {
+		op = gpb.AFTOperation_ADD
+	}
+	batchSize := cmp.Or(opts.BatchSize, DefaultBulkBatchSize)
+	maxInFlight := max(cmp.Or(opts.MaxInFlight, batchSize), batchSize)
+	waitTimeout := cmp.Or(opts.Timeout, timeout)
+
+	bt := &bulkTracker{fibACK: c.FIBACK}
+	var start time.Time
+	sentOps := 0
+	// waitInFlight waits until at most limit operations are in flight.
+	waitInFlight := func(limit int) {
+		if bt.inFlight <= limit {
+			return
+		}
+		if err := c.AwaitTimeout(context.Background(), t, waitTimeout); err != nil {
+			t.Fatalf("Error waiting for gRIBI results of %d operations in flight: %v", bt.inFlight, err)
+		}
+		results := c.fluentC.Results(t)
+		c.rec.process(results)
+		bt.process(results, time.Now())
+		if bt.inFlight > limit {
+			t.Fatalf("gRIBI client converged with %d operations without final result", bt.inFlight)
+		}
+	}
+	send := func(batch []fluent.GRIBIEntry) {
+		waitInFlight(maxInFlight - len(batch))
+		now := time.Now()
+		if start.IsZero() {
+			start = now
+		}
+		if opts.OpsPerSecond > 0 {
+			next := start.Add(time.Duration(float64(sentOps) / opts.OpsPerSecond * float64(time.Second)))
+			if d := next.Sub(now); d > 0 {
+				time.Sleep(d)
+				now = next
+			}
+		}
+		firstID := c.rec.opCount + 1
+		if opts.SkipRIB {
+			c.rec.skip(len(batch))
+		} else {
+			c.rec.record(op, batch)
+		}
+		switch op {
+		case gpb.AFTOperation_ADD:
+			c.fluentC.Modify().AddEntry(t, batch...)
+		case gpb.AFTOperation_REPLACE:
+			c.fluentC.Modify().ReplaceEntry(t, batch...)
+		case gpb.AFTOperation_DELETE:
+			c.fluentC.Modify().DeleteEntry(t, batch...)
+		default:
+			t.Fatalf("Unsupported gRIBI operation %v", op)
+		}
+		bt.sent(firstID, len(batch), now)
+		sentOps += len(batch)
+	}
+
+	batch := make([]fluent.GRIBIEntry, 0, batchSize)
+	for e := range entries {
+		batch = append(batch, e)
+		if len(batch) == batchSize {
+			send(batch)
+			batch = batch[:0]
+		}
+	}
+	if len(batch) > 0 {
+		send(batch)
+	}
+	waitInFlight(0)
+
+	stats := &BulkStats{PeakInFlight: bt.peak}
+	for _, b := range bt.batches {
+		stats.Batches = append(stats.Batches, b.result)
+	}
+	if !start.IsZero() {
+		stats.Elapsed = bt.lastResult.Sub(start)
+	}
+	return stats
+}
@@ -0,0 +1,145 @@
+// Copyright 2026 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package gribi
+
+import (
+	"fmt"
+	"iter"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/openconfig/featureprofiles/internal/gribi/fakegribi"
+	"github.com/openconfig/gribigo/fluent"
+
+	gpb "github.com/openconfig/gribi/v1/proto/service"
+)
+
+// fakeClient returns a leader Client of a new fake server.
+func fakeClient(t *testing.T) *Client {
+	t.Helper()
+	c := startClient(t, fakegribi.Start(t))
+	c.BecomeLeader(t)
+	return c
+}
+
+// startClient returns a started Client of s.
+func startClient(t *testing.T, s *fakegribi.Server) *Client {
+	t.Helper()
+	c := &Client{Stub: s.Stub(), Persistence: true}
+	if err := c.Start(t); err != nil {
+		t.Fatalf("Start() failed: %v", err)
+	}
+	t.Cleanup(func() { c.Close(t) })
+	return c
+}
+
+// scaleEntries yields one next hop, one next hop group and n IPv4 entries using them.
+func scaleEntries(n int) iter.Seq[fluent.GRIBIEntry] {
+	return func(yield func(fluent.GRIBIEntry) bool) {
+		nh, _ := NHEntry(1, "192.0.2.1", "DEFAULT", fluent.InstalledInRIB)
+		nhg, _ := NHGEntry(1, map[uint64]uint64{1: 1}, "DEFAULT", fluent.InstalledInRIB)
+		if !yield(nh) || !yield(nhg) {
+			return
+		}
+		for i := range n {
+			p := fmt.Sprintf("10.%d.%d.0/24", i>>8&0xff, i&0xff)
+			if !yield(fluent.IPv4Entry().WithPrefix(p).WithNetworkInstance("DEFAULT").WithNextHopGroup(1)) {
+				return
+			}
+		}
+	}
+}
+
+func TestBulkProgram(t *testing.T) {
+	c := fakeClient(t)
+	stats := c.BulkProgram(t, scaleEntries(998), BulkOptions{BatchSize: 100, MaxInFlight: 300})
+	if got, want := stats.Ops(), 1000; got != want {
+		t.Errorf("BulkProgram() got %d operations, want %d", got, want)
+	}
+	if got := stats.Failed(); got != 0 {
+		t.Errorf("BulkProgram() got %d failed operations, want 0", got)
+	}
+	if got, want := len(stats.Batches), 10; got != want {
+		t.Errorf("BulkProgram() got %d batches, want %d", got, want)
+	}
+	if stats.OpsPerSecond() <= 0 {
+		t.Errorf("BulkProgram() got rate %v, want > 0", stats.OpsPerSecond())
+	}
+	if got, want := c.RIB(t).Len(), 1000; got != want {
+		t.Errorf("RIB() after BulkProgram() has %d entries, want %d", got, want)
+	}
+
+	// Rate limiting spaces batches: the last of 4 batches of 50 is sent 150 operations in.
+	stats = c.BulkProgram(t, scaleEntries(198), BulkOptions{BatchSize: 50, MaxInFlight: 200, OpsPerSecond: 2000, SkipRIB: true})
+	if got, want := stats.Batches[len(stats.Batches)-1].Sent.Sub(stats.Batches[0].Sent), 75*time.Millisecond; got < want {
+		t.Errorf("BulkProgram() with rate limit sent the last batch after %v, want at least %v", got, want)
+	}
+	if got, want := c.RIB(t).Len(), 1000; got != want {
+		t.Errorf("RIB() after BulkProgram() without recording has %d entries, want %d", got, want)
+	}
+}
+
+func TestBulkProgramFIBFailed(t *testing.T) {
+	s := fakegribi.Start(t)
+	// Fail routes 50, 306, 562 and 818, which are operations 52, 308, 564 and 820 after the
+	// NH and NHG, in batches 0, 3, 5 and 8.
+	s.FailFIB(func(op *gpb.AFTOperation) bool {
+		p := op.GetIpv4().GetPrefix()
+		return p != "" && strings.HasSuffix(p, ".50.0/24")
+	})
+	c := &Client{Stub: s.Stub(), FIBACK: true, Persistence: true}
+	if err := c.Start(t); err != nil {
+		t.Fatalf("Start() failed: %v", err)
+	}
+	t.Cleanup(func() { c.Close(t) })
+	c.BecomeLeader(t)
+
+	stats := c.BulkProgram(t, scaleEntries(998), BulkOptions{BatchSize: 100, MaxInFlight: 300})
+	if got, want := stats.PeakInFlight, 300; got != want {
+		t.Errorf("BulkProgram() got %d operations in flight at most, want %d", got, want)
+	}
+	if got, want := stats.Failed(), 4; got != want {
+		t.Errorf("BulkProgram() got %d failed operations, want %d", got, want)
+	}
+	var failed []int
+	for _, b := range stats.Batches {
+		failed = append(failed, b.Failed)
+	}
+	if diff := cmp.Diff([]int{1, 0, 0, 1, 0, 1, 0, 0, 1, 0}, failed); diff != "" {
+		t.Errorf("BulkProgram() failed operations per batch diff (-want +got):\n%s", diff)
+	}
+}
+
+func TestBulkStats(t *testing.T) {
+	s := &BulkStats{Elapsed: 2 * time.Second}
+	for _, l := range []time.Duration{time.Millisecond, 3 * time.Millisecond, 4 * time.Millisecond, time.Minute} {
+		s.Batches = append(s.Batches, BatchResult{Ops: 100, Failed: 1, Latency: l})
+	}
+	if got := s.OpsPerSecond(); got != 200 {
+		t.Errorf("OpsPerSecond() got %v, want 200", got)
+	}
+	if got := s.Failed(); got != 4 {
+		t.Errorf("Failed() got %d, want 4", got)
+	}
+	if got, want := s.Percentile(50), 3*time.Millisecond; got != want {
+		t.Errorf("Percentile(50) got %v, want %v", got, want)
+	}
+	h := s.Histogram()
+	if h[0] != 1 || h[2] != 2 || h[len(h)-1] != 1 {
+		t.Errorf("Histogram() got %v, want 1 batch <= 1ms, 2 <= 5ms and 1 over the last bucket", h)
+	}
+}
@@ -0,0 +1,377 @@
+// Copyright 2026 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package gribi
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"testing"
+
+	"github.com/openconfig/gribigo/fluent"
+	"github.com/openconfig/ondatra"
+	"google.golang.org/protobuf/proto"
+
+	gpb "github.com/openconfig/gribi/v1/proto/service"
+)
+
+// RedundancyMode is the gRIBI redundancy mode of the clients of a Harness.
+type RedundancyMode int
+
+const (
+	// SinglePrimary clients elect a primary with election IDs. Only the primary can modify
+	// entries.
+	SinglePrimary RedundancyMode = iota
+	// AllPrimary clients can all modify entries.
+	AllPrimary
+)
+
+// Cmp compares i and j and returns -1 if i < j, 0 if i == j and +1 if i > j.
+func (i Uint128) Cmp(j Uint128) int {
+	switch {
+	case i.High != j.High:
+		if i.High < j.High {
+			return -1
+		}
+		return 1
+	case i.Low < j.Low:
+		return -1
+	case i.Low > j.Low:
+		return 1
+	}
+	return 0
+}
+
+// HarnessConfig are the session parameters shared by the clients of a Harness. gRIBI
+// servers reject clients whose parameters differ from those of connected clients.
+type HarnessConfig struct {
+	// Clients is the number of clients.
+	Clients int
+	// Redundancy is the redundancy mode of the clients.
+	Redundancy RedundancyMode
+	// Persistence selects PRESERVE persistence, keeping the entries of a client after it
+	// disconnects. Entries are deleted with the client by default.
+	Persistence bool
+	// FIBACK requests FIB acknowledgements.
+	FIBACK bool
+}
+
+// harnessClient is a client of a Harness.
+type harnessClient struct {
+	fluentC    *fluent.GRIBIClient
+	cancel     context.CancelFunc
+	electionID Uint128
+	// rec records the entries programmed by the client. It is kept when the client
+	// disconnects so that the survival of the entries can be checked.
+	rec *recorder
+}
+
+// Harness manages several gRIBI clients of the same server to test election, reconnection
+// and persistence behavior of the server. Clients are identified by their index, from 0 to
+// Clients-1.
+//
+// Usage:
+//
+//	h := gribi.NewDUTHarness(t, dut, gribi.HarnessConfig{Clients: 2})
+//	defer h.Close(t)
+//	h.Connect(t, 0)
+//	h.Connect(t, 1)
+//	h.RunElection(t, gribi.ElectionStep{Client: 1, ElectionID: gribi.Uint128{Low: 10}, WantLeader: 1})
+//	h.Modify(t, 1, entries...)
+//	h.DisconnectDuringModify(t, 1, moreEntries...)
+//	h.CheckSurvivors(t)
+type Harness struct {
+	stub    gpb.GRIBIClient
+	cfg     HarnessConfig
+	clients []*harnessClient
+	// serverElectionID is the highest election ID announced to the server, and leader the
+	// last client to announce it.
+	serverElectionID Uint128
+	leader           int
+}
+
+// NewHarness returns a Harness of clients connecting to the server through stub.
+func NewHarness(stub gpb.GRIBIClient, cfg HarnessConfig) *Harness {
+	h := &Harness{stub: stub, cfg: cfg, leader: -1}
+	for range cfg.Clients {
+		h.clients = append(h.clients, &harnessClient{electionID: Uint128{Low: 1}, rec: newRecorder()})
+	}
+	return h
+}
+
+// NewDUTHarness returns a Harness of clients connecting to the gRIBI server of the DUT.
+func NewDUTHarness(t testing.TB, dut *ondatra.DUTDevice, cfg HarnessConfig) *Harness {
+	t.Helper()
+	return NewHarness(dut.RawAPIs().GRIBI(t), cfg)
+}
+
+func (h *Harness) client(t testing.TB, i int) *harnessClient {
+	t.Helper()
+	if i < 0 || i >= len(h.clients) {
+		t.Fatalf("gRIBI harness has no client %d, it has %d clients", i, len(h.clients))
+	}
+	return h.clients[i]
+}
+
+// Fluent returns the fluent client of client i, or nil if it is not connected.
+func (h *Harness) Fluent(t testing.TB, i int) *fluent.GRIBIClient {
+	return h.client(t, i).fluentC
+}
+
+// Connected reports whether client i is connected.
+func (h *Harness) Connected(i int) bool {
+	return i >= 0 && i < len(h.clients) && h.clients[i].fluentC != nil
+}
+
+// Connect opens the Modify stream of client i. In SinglePrimary mode the client announces its
+// current election ID, initially 1, and becomes the leader if it is not lower than the
+// server's. Entries programmed by the client in a previous session are dropped from its
+// record unless persistence is enabled.
+func (h *Harness) Connect(t testing.TB, i int) {
+	t.Helper()
+	c := h.client(t, i)
+	if c.fluentC != nil {
+		t.Fatalf("gRIBI harness client %d is already connected", i)
+	}
+	c.rec.reset(h.cfg.Persistence)
+	c.fluentC = fluent.NewClient()
+	conn := c.fluentC.Connection().WithStub(h.stub)
+	switch h.cfg.Redundancy {
+	case SinglePrimary:
+		conn.WithRedundancyMode(fluent.ElectedPrimaryClient).WithInitialElectionID(c.electionID.Low, c.electionID.High)
+	case AllPrimary:
+		conn.WithRedundancyMode(fluent.AllPrimaryClients)
+	}
+	if h.cfg.Persistence {
+		conn.WithPersistence()
+	}
+	if h.cfg.FIBACK {
+		conn.WithFIBACK()
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	c.cancel = cancel
+	c.fluentC.Start(ctx, t)
+	c.fluentC.StartSending(ctx, t)
+	if err := awaitTimeout(ctx, t, c.fluentC, timeout); err != nil {
+		t.Fatalf("Error waiting for gRIBI harness client %d to connect: %v", i, err)
+	}
+	if h.cfg.Redundancy == SinglePrimary {
+		h.announce(i, c.electionID)
+	}
+}
+
+// announce updates the leader after client i announced an election ID.
+func (h *Harness) announce(i int, id Uint128) {
+	if h.leader == -1 || id.Cmp(h.serverElectionID) >= 0 {
+		h.serverElectionID, h.leader = id, i
+	}
+}
+
+// SetElectionID sends a new election ID from client i, which becomes the leader if the ID is
+// not lower than any ID announced before.
+func (h *Harness) SetElectionID(t testing.TB, i int, id Uint128) {
+	t.Helper()
+	c := h.client(t, i)
+	if c.fluentC == nil {
+		t.Fatalf("gRIBI harness client %d is not connected", i)
+	}
+	UpdateElectionID(t, c.fluentC, id)
+	c.electionID = id
+	h.announce(i, id)
+	results := c.fluentC.Results(t)
+	if len(results) == 0 || results[len(results)-1].CurrentServerElectionID == nil {
+		t.Fatalf("gRIBI harness client %d got no election ID result", i)
+	}
+	got := results[len(results)-1].CurrentServerElectionID
+	if want := h.serverElectionID; got.GetLow() != want.Low || got.GetHigh() != want.High {
+		t.Errorf("gRIBI harness client %d sent election ID %+v and got server election ID %v, want %+v", i, id, got, want)
+	}
+}
+
+// Leader returns the index of the leader in SinglePrimary mode, or -1 if the leader is not
+// connected or the mode is AllPrimary.
+func (h *Harness) Leader() int {
+	if h.cfg.Redundancy != SinglePrimary || !h.Connected(h.leader) {
+		return -1
+	}
+	return h.leader
+}
+
+// ElectionStep is a step of a scripted election.
+type ElectionStep struct {
+	// Client is the index of the client sending the election ID.
+	Client int
+	// ElectionID is the election ID sent.
+	ElectionID Uint128
+	// WantLeader is the index of the leader expected after the step, or -1 if no
+	// connected client should be the leader.
+	WantLeader int
+}
+
+// RunElection sends the election IDs of the steps in order, connecting clients as needed, and
+// checks the leader after each step.
+func (h *Harness) RunElection(t testing.TB, steps ...ElectionStep) {
+	t.Helper()
+	for n, s := range steps {
+		if !h.Connected(s.Client) {
+			h.Connect(t, s.Client)
+		}
+		h.SetElectionID(t, s.Client, s.ElectionID)
+		if got := h.Leader(); got != s.WantLeader {
+			t.Errorf("After election step %d (client %d sends %+v) got leader %d, want %d", n, s.Client, s.ElectionID, got, s.WantLeader)
+		}
+	}
+}
+
+// Modify adds the entries with client i and waits for their results. The entries that are
+// acknowledged are recorded as programmed by the client.
+func (h *Harness) Modify(t testing.TB, i int, entries ...fluent.GRIBIEntry) {
+	t.Helper()
+	c := h.client(t, i)
+	if c.fluentC == nil {
+		t.Fatalf("gRIBI harness client %d is not connected", i)
+	}
+	c.rec.record(gpb.AFTOperation_ADD, entries)
+	c.fluentC.Modify().AddEntry(t, entries...)
+	if err := awaitTimeout(context.Background(), t, c.fluentC, timeout); err != nil {
+		t.Fatalf("Error waiting for gRIBI harness client %d to add entries: %v", i, err)
+	}
+	c.rec.process(c.fluentC.Results(t))
+}
+
+// Programmed returns the entries programmed by client i, including those of previous
+// sessions if persistence is enabled.
+func (h *Harness) Programmed(t testing.TB, i int) *RIB {
+	t.Helper()
+	c := h.client(t, i)
+	if c.fluentC != nil {
+		c.rec.process(c.fluentC.Results(t))
+	}
+	return c.rec.rib
+}
+
+// Disconnect cancels the Modify stream of client i.
+func (h *Harness) Disconnect(t testing.TB, i int) {
+	t.Helper()
+	c := h.client(t, i)
+	if c.fluentC == nil {
+		return
+	}
+	c.rec.process(c.fluentC.Results(t))
+	c.cancel()
+	c.fluentC.Stop(t)
+	c.fluentC, c.cancel = nil, nil
+}
+
+// DisconnectDuringModify adds the entries with client i and cancels its Modify stream without
+// waiting for the results. Entries acknowledged before the cancellation are recorded as
+// programmed, while the server may or may not have programmed the others. It returns the
+// number of operations without a result.
+func (h *Harness) DisconnectDuringModify(t testing.TB, i int, entries ...fluent.GRIBIEntry) int {
+	t.Helper()
+	c := h.client(t, i)
+	if c.fluentC == nil {
+		t.Fatalf("gRIBI harness client %d is not connected", i)
+	}
+	c.rec.record(gpb.AFTOperation_ADD, entries)
+	c.fluentC.Modify().AddEntry(t, entries...)
+	h.Disconnect(t, i)
+	inDoubt := len(c.rec.pending)
+	t.Logf("gRIBI harness client %d disconnected with %d of %d operations without result", i, inDoubt, len(entries))
+	return inDoubt
+}
+
+// ExpectedSurvivors returns the clients whose entries should be in the server RIB: all
+// connected clients, and disconnected ones if persistence is enabled.
+func (h *Harness) ExpectedSurvivors() []int {
+	var out []int
+	for i := range h.clients {
+		if h.cfg.Persistence || h.Connected(i) {
+			out = append(out, i)
+		}
+	}
+	return out
+}
+
+// ServerRIB gets all entries of all network instances from the server.
+func (h *Harness) ServerRIB(ctx context.Context) (*RIB, error) {
+	stream, err := h.stub.Get(ctx, &gpb.GetRequest{
+		NetworkInstance: &gpb.GetRequest_All{All: &gpb.Empty{}},
+		Aft:             gpb.AFTType_ALL,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("cannot open Get RPC: %w", err)
+	}
+	resp := &gpb.GetResponse{}
+	for {
+		r, err := stream.Recv()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("error receiving Get response: %w", err)
+		}
+		resp.Entry = append(resp.Entry, r.GetEntry()...)
+	}
+	return RIBFromGetResponse(resp)
+}
+
+// CheckSurvivors checks that the entries programmed by the survivors are in the server RIB,
+// and that the entries programmed only by other clients are not. If no survivors are given,
+// ExpectedSurvivors are used.
+func (h *Harness) CheckSurvivors(t testing.TB, survivors ...int) {
+	t.Helper()
+	if len(survivors) == 0 {
+		survivors = h.ExpectedSurvivors()
+	}
+	got, err := h.ServerRIB(context.Background())
+	if err != nil {
+		t.Fatalf("Error getting gRIBI server RIB: %v", err)
+	}
+	surviving := NewRIB()
+	for _, i := range survivors {
+		for k, e := range h.Programmed(t, i).entries {
+			surviving.entries[k] = e
+		}
+	}
+	for k, e := range surviving.entries {
+		g, ok := got.entries[k]
+		switch {
+		case !ok:
+			t.Errorf("Entry %v of a surviving client is missing from the server RIB", k)
+		case !proto.Equal(e, g):
+			t.Errorf("Entry %v of a surviving client differs in the server RIB: got %v, want %v", k, g, e)
+		}
+	}
+	for i := range h.clients {
+		for k := range h.Programmed(t, i).entries {
+			if _, ok := surviving.entries[k]; ok {
+				continue
+			}
+			if _, ok := got.entries[k]; ok {
+				t.Errorf("Entry %v of client %d should not survive but is in the server RIB", k, i)
+			}
+		}
+	}
+}
+
+// Close disconnects all clients.
+func (h *Harness) Close(t testing.TB) {
+	t.Helper()
+	for i := range h.clients {
+		h.Disconnect(t, i)
+	}
+}
@@ -0,0 +1,325 @@
+// Copyright 2026 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package gribi
+
+import (
+	"context"
+	"net"
+	"slices"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/openconfig/featureprofiles/internal/gribi/fakegribi"
+	"github.com/openconfig/gribigo/fluent"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
+
+	gpb "github.com/openconfig/gribi/v1/proto/service"
+)
+
+func TestUint128Cmp(t *testing.T) {
+	for _, tc := range []struct {
+		a, b Uint128
+		want int
+	}{
+		{a: Uint128{Low: 1}, b: Uint128{Low: 1}, want: 0},
+		{a: Uint128{Low: 1}, b: Uint128{Low: 2}, want: -1},
+		{a: Uint128{Low: 5, High: 1}, b: Uint128{Low: 9}, want: 1},
+		{a: Uint128{Low: ^uint64(0)}, b: Uint128{Low: ^uint64(0)}.Increment(), want: -1},
+	} {
+		if got := tc.a.Cmp(tc.b); got != tc.want {
+			t.Errorf("%+v.Cmp(%+v) got %d, want %d", tc.a, tc.b, got, tc.want)
+		}
+	}
+}
+
+func TestHarnessElectionAndPersistence(t *testing.T) {
+	// The reference server only supports SINGLE_PRIMARY clients with PRESERVE persistence.
+	h := NewHarness(fakegribi.Start(t).Stub(), HarnessConfig{Clients: 2, Persistence: true})
+	defer h.Close(t)
+
+	h.RunElection(t,
+		ElectionStep{Client: 0, ElectionID: Uint128{Low: 5}, WantLeader: 0},
+		ElectionStep{Client: 1, ElectionID: Uint128{Low: 3}, WantLeader: 0},
+		ElectionStep{Client: 1, ElectionID: Uint128{Low: 6}, WantLeader: 1},
+	)
+
+	nh, _ := NHEntry(1, "192.0.2.1", "DEFAULT", fluent.InstalledInRIB)
+	nhg, _ := NHGEntry(1, map[uint64]uint64{1: 1}, "DEFAULT", fluent.InstalledInRIB)
+	h.Modify(t, 1, nh, nhg, fluent.IPv4Entry().WithPrefix("198.51.100.0/24").WithNetworkInstance("DEFAULT").WithNextHopGroup(1))
+	if got := h.Programmed(t, 1).Len(); got != 3 {
+		t.Errorf("Leader programmed %d entries, want 3", got)
+	}
+
+	// The former leader cannot program entries.
+	h.Modify(t, 0, fluent.IPv4Entry().WithPrefix("203.0.113.0/24").WithNetworkInstance("DEFAULT").WithNextHopGroup(1))
+	if got := h.Programmed(t, 0).Len(); got != 0 {
+		t.Errorf("Non-leader programmed %d entries, want 0", got)
+	}
+
+	h.DisconnectDuringModify(t, 1, fluent.IPv4Entry().WithPrefix("198.51.100.128/25").WithNetworkInstance("DEFAULT").WithNextHopGroup(1))
+	if got := h.Leader(); got != -1 {
+		t.Errorf("Leader() after the leader disconnected got %d, want -1", got)
+	}
+	if got, want := h.ExpectedSurvivors(), []int{0, 1}; len(got) != len(want) {
+		t.Errorf("ExpectedSurvivors() got %v, want %v", got, want)
+	}
+	h.CheckSurvivors(t)
+
+	// Reconnecting with the same election ID restores leadership and keeps the entries.
+	h.Connect(t, 1)
+	if got := h.Leader(); got != 1 {
+		t.Errorf("Leader() after reconnection got %d, want 1", got)
+	}
+	if got := h.Programmed(t, 1).Len(); got < 3 {
+		t.Errorf("Reconnected client has %d programmed entries, want at least 3", got)
+	}
+	h.CheckSurvivors(t, 1)
+}
+
+// session is a Modify stream of a sessionServer.
+type session struct {
+	params     *gpb.SessionParameters
+	electionID Uint128
+}
+
+// sessionServer is a minimal gRIBI server implementing the redundancy and persistence modes
+// that the reference server lacks. It programs ADD operations without validating references
+// and acknowledges them immediately.
+type sessionServer struct {
+	gpb.UnimplementedGRIBIServer
+
+	mu         sync.Mutex
+	entries    map[ribKey]*gpb.AFTEntry
+	owners     map[ribKey]map[*session]bool
+	electionID Uint128
+	master     *session
+}
+
+// startSessionServer starts a sessionServer in-process and returns a client stub of it.
+func startSessionServer(t *testing.T) gpb.GRIBIClient {
+	t.Helper()
+	s := &sessionServer{entries: map[ribKey]*gpb.AFTEntry{}, owners: map[ribKey]map[*session]bool{}}
+	lis := bufconn.Listen(1 << 20)
+	srv := grpc.NewServer()
+	gpb.RegisterGRIBIServer(srv, s)
+	go srv.Serve(lis)
+	t.Cleanup(srv.Stop)
+	conn, err := grpc.NewClient("passthrough:///session",
+		grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) { return lis.DialContext(ctx) }),
+		grpc.WithTransportCredentials(insecure.NewCredentials()))
+	if err != nil {
+		t.Fatalf("Could not dial session gRIBI server: %v", err)
+	}
+	t.Cleanup(func() { conn.Close() })
+	return gpb.NewGRIBIClient(conn)
+}
+
+func (s *sessionServer) Modify(ms gpb.GRIBI_ModifyServer) error {
+	sess := &session{}
+	defer s.end(sess)
+	for {
+		req, err := ms.Recv()
+		if err != nil {
+			return nil
+		}
+		resp, err := s.handle(sess, req)
+		if err != nil {
+			return err
+		}
+		if err := ms.Send(resp); err != nil {
+			return err
+		}
+	}
+}
+
+func (s *sessionServer) handle(sess *session, req *gpb.ModifyRequest) (*gpb.ModifyResponse, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	allPrimary := sess.params.GetRedundancy() == gpb.SessionParameters_ALL_PRIMARY
+	switch {
+	case req.GetParams() != nil:
+		sess.params = req.GetParams()
+		return &gpb.ModifyResponse{SessionParamsResult: &gpb.SessionParametersResult{Status: gpb.SessionParametersResult_OK}}, nil
+	case req.GetElectionId() != nil:
+		if allPrimary {
+			return nil, status.Error(codes.FailedPrecondition, "election ID in ALL_PRIMARY mode")
+		}
+		sess.electionID = Uint128{Low: req.GetElectionId().GetLow(), High: req.GetElectionId().GetHigh()}
+		if sess.electionID.Cmp(s.electionID) >= 0 {
+			s.electionID, s.master = sess.electionID, sess
+		}
+		return &gpb.ModifyResponse{ElectionId: &gpb.Uint128{Low: s.electionID.Low, High: s.electionID.High}}, nil
+	}
+	resp := &gpb.ModifyResponse{}
+	for _, op := range req.GetOperation() {
+		id := Uint128{Low: op.GetElectionId().GetLow(), High: op.GetElectionId().GetHigh()}
+		if !allPrimary && (s.master != sess || id != s.electionID) {
+			resp.Result = append(resp.Result, &gpb.AFTResult{Id: op.GetId(), Status: gpb.AFTResult_FAILED})
+			continue
+		}
+		e, err := operationEntry(op)
+		if err != nil {
+			return nil, status.Error(codes.InvalidArgument, err.Error())
+		}
+		k, err := entryKey(e)
+		if err != nil {
+			return nil, status.Error(codes.InvalidArgument, err.Error())
+		}
+		s.entries[k] = e
+		if s.owners[k] == nil {
+			s.owners[k] = map[*session]bool{}
+		}
+		s.owners[k][sess] = true
+		resp.Result = append(resp.Result, &gpb.AFTResult{Id: op.GetId(), Status: gpb.AFTResult_RIB_PROGRAMMED})
+		if sess.params.GetAckType() == gpb.SessionParameters_RIB_AND_FIB_ACK {
+			resp.Result = append(resp.Result, &gpb.AFTResult{Id: op.GetId(), Status: gpb.AFTResult_FIB_PROGRAMMED})
+		}
+	}
+	return resp, nil
+}
+
+// end removes the entries of a session without persistence that no other session added.
+func (s *sessionServer) end(sess *session) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.master == sess {
+		s.master = nil
+	}
+	if sess.params.GetPersistence() == gpb.SessionParameters_PRESERVE {
+		return
+	}
+	for k, owners := range s.owners {
+		delete(owners, sess)
+		if len(owners) == 0 {
+			delete(s.owners, k)
+			delete(s.entries, k)
+		}
+	}
+}
+
+func (s *sessionServer) Get(_ *gpb.GetRequest, stream gpb.GRIBI_GetServer) error {
+	s.mu.Lock()
+	resp := &gpb.GetResponse{}
+	for _, e := range s.entries {
+		resp.Entry = append(resp.Entry, e)
+	}
+	s.mu.Unlock()
+	return stream.Send(resp)
+}
+
+func TestHarnessModes(t *testing.T) {
+	nh, _ := NHEntry(1, "192.0.2.1", "DEFAULT", fluent.InstalledInRIB)
+	nhg, _ := NHGEntry(1, map[uint64]uint64{1: 1}, "DEFAULT", fluent.InstalledInRIB)
+	prefix := fluent.IPv4Entry().WithPrefix("198.51.100.0/24").WithNetworkInstance("DEFAULT").WithNextHopGroup(1)
+	other, _ := NHEntry(2, "192.0.2.2", "DEFAULT", fluent.InstalledInRIB)
+
+	tests := []struct {
+		desc string
+		cfg  HarnessConfig
+		// wantOther is the number of entries client 0 programs while client 1 is the leader.
+		wantOther int
+	}{{
+		desc: "single primary, preserve",
+		cfg:  HarnessConfig{Clients: 2, Redundancy: SinglePrimary, Persistence: true, FIBACK: true},
+	}, {
+		desc: "single primary, delete",
+		cfg:  HarnessConfig{Clients: 2, Redundancy: SinglePrimary},
+	}, {
+		desc:      "all primary, preserve",
+		cfg:       HarnessConfig{Clients: 2, Redundancy: AllPrimary, Persistence: true},
+		wantOther: 1,
+	}, {
+		desc:      "all primary, delete",
+		cfg:       HarnessConfig{Clients: 2, Redundancy: AllPrimary, FIBACK: true},
+		wantOther: 1,
+	}}
+	for _, tc := range tests {
+		t.Run(tc.desc, func(t *testing.T) {
+			h := NewHarness(startSessionServer(t), tc.cfg)
+			defer h.Close(t)
+
+			wantLeader := -1
+			if tc.cfg.Redundancy == SinglePrimary {
+				wantLeader = 1
+				h.RunElection(t,
+					ElectionStep{Client: 0, ElectionID: Uint128{Low: 5}, WantLeader: 0},
+					ElectionStep{Client: 1, ElectionID: Uint128{Low: 6}, WantLeader: 1},
+				)
+			} else {
+				h.Connect(t, 0)
+				h.Connect(t, 1)
+			}
+			if got := h.Leader(); got != wantLeader {
+				t.Errorf("Leader() got %d, want %d", got, wantLeader)
+			}
+
+			h.Modify(t, 1, nh, nhg, prefix)
+			if got := h.Programmed(t, 1).Len(); got != 3 {
+				t.Errorf("Client 1 programmed %d entries, want 3", got)
+			}
+			h.Modify(t, 0, other)
+			if got := h.Programmed(t, 0).Len(); got != tc.wantOther {
+				t.Errorf("Client 0 programmed %d entries, want %d", got, tc.wantOther)
+			}
+
+			h.Disconnect(t, 1)
+			if got := h.Leader(); got != -1 {
+				t.Errorf("Leader() after client 1 disconnected got %d, want -1", got)
+			}
+			wantSurvivors := []int{0}
+			if tc.cfg.Persistence {
+				wantSurvivors = []int{0, 1}
+			}
+			if got := h.ExpectedSurvivors(); !slices.Equal(got, wantSurvivors) {
+				t.Errorf("ExpectedSurvivors() got %v, want %v", got, wantSurvivors)
+			}
+			// The server ends the session, and removes its entries, after the stream is
+			// cancelled.
+			var found bool
+			for deadline := time.Now().Add(5 * time.Second); time.Now().Before(deadline); time.Sleep(10 * time.Millisecond) {
+				rib, err := h.ServerRIB(context.Background())
+				if err != nil {
+					t.Fatalf("ServerRIB() failed: %v", err)
+				}
+				if _, found, _ = rib.Lookup(prefix); found == tc.cfg.Persistence {
+					break
+				}
+			}
+			if found != tc.cfg.Persistence {
+				t.Errorf("Prefix of the disconnected client in the server RIB got %t, want %t", found, tc.cfg.Persistence)
+			}
+			h.CheckSurvivors(t)
+
+			// The reconnected client keeps its entries only with persistence.
+			h.Connect(t, 1)
+			if got := h.Leader(); got != wantLeader {
+				t.Errorf("Leader() after reconnection got %d, want %d", got, wantLeader)
+			}
+			wantProgrammed := 0
+			if tc.cfg.Persistence {
+				wantProgrammed = 3
+			}
+			if got := h.Programmed(t, 1).Len(); got != wantProgrammed {
+				t.Errorf("Reconnected client has %d programmed entries, want %d", got, wantProgrammed)
+			}
+			h.CheckSurvivors(t)
+		})
+	}
+}
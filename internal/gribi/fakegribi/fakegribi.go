@@ -0,0 +1,240 @@
+// Copyright 2026 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package fakegribi provides an in-process gRIBI server to unit test gRIBI helpers without a
+// device. It serves the gribigo reference RIB implementation, which supports SINGLE_PRIMARY
+// clients with PRESERVE persistence, and can inject FIB failures, delayed acknowledgements
+// and dropped Modify streams.
+//
+// Usage:
+//
+//	s := fakegribi.Start(t)
+//	s.FailFIB(func(op *gpb.AFTOperation) bool { return op.GetNextHop().GetIndex() == 2 })
+//	c := &gribi.Client{Stub: s.Stub(), FIBACK: true, Persistence: true}
+package fakegribi
+
+import (
+	"context"
+	"net"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/openconfig/gribigo/server"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
+
+	gpb "github.com/openconfig/gribi/v1/proto/service"
+)
+
+// Server is a gRIBI server backed by the gribigo reference server. Its knobs can be changed
+// while clients are connected.
+type Server struct {
+	// The reference server embeds a nil UnimplementedGRIBIServer, which RegisterGRIBIServer
+	// rejects, so it is wrapped rather than registered directly.
+	gpb.UnimplementedGRIBIServer
+	ref  *server.Server
+	stub gpb.GRIBIClient
+
+	mu        sync.Mutex
+	failFIB   func(*gpb.AFTOperation) bool
+	ackDelay  time.Duration
+	dropAfter int
+	streams   map[*modifyStream]bool
+}
+
+// Start starts a Server with the reference server options in-process, and stops it at the
+// end of the test.
+func Start(t testing.TB, opts ...server.ServerOpt) *Server {
+	t.Helper()
+	ref, err := server.New(opts...)
+	if err != nil {
+		t.Fatalf("Could not create gRIBI reference server: %v", err)
+	}
+	s := &Server{ref: ref, streams: map[*modifyStream]bool{}}
+	lis := bufconn.Listen(1 << 20)
+	srv := grpc.NewServer()
+	gpb.RegisterGRIBIServer(srv, s)
+	go srv.Serve(lis)
+	t.Cleanup(srv.Stop)
+
+	conn, err := grpc.NewClient("passthrough:///fakegribi",
+		grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) { return lis.DialContext(ctx) }),
+		grpc.WithTransportCredentials(insecure.NewCredentials()))
+	if err != nil {
+		t.Fatalf("Could not dial fake gRIBI server: %v", err)
+	}
+	t.Cleanup(func() { conn.Close() })
+	s.stub = gpb.NewGRIBIClient(conn)
+	return s
+}
+
+// Stub returns a client stub of the server.
+func (s *Server) Stub() gpb.GRIBIClient {
+	return s.stub
+}
+
+// FailFIB reports FIB_FAILED instead of FIB_PROGRAMMED for the operations for which fn returns
+// true. The entries stay in the RIB, as they would on a device failing to program its FIB.
+// Only clients requesting RIB_AND_FIB_ACK see the failures. A nil fn stops the injection.
+func (s *Server) FailFIB(fn func(*gpb.AFTOperation) bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.failFIB = fn
+}
+
+// DelayAcks delays every Modify response carrying operation results by d. A zero d stops the
+// delay.
+func (s *Server) DelayAcks(d time.Duration) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.ackDelay = d
+}
+
+// DropStreamAfter drops each Modify stream when it receives its nth operation. The request
+// carrying that operation is discarded. A zero n stops dropping streams.
+func (s *Server) DropStreamAfter(n int) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.dropAfter = n
+}
+
+// DropStreams drops all the open Modify streams.
+func (s *Server) DropStreams() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for st := range s.streams {
+		st.dropOnce.Do(func() { close(st.dropped) })
+	}
+}
+
+// Modify serves a Modify stream through the reference server, applying the knobs.
+func (s *Server) Modify(ms gpb.GRIBI_ModifyServer) error {
+	st := &modifyStream{
+		GRIBI_ModifyServer: ms,
+		srv:                s,
+		ops:                map[uint64]*gpb.AFTOperation{},
+		dropped:            make(chan struct{}),
+	}
+	s.mu.Lock()
+	s.streams[st] = true
+	s.mu.Unlock()
+	defer func() {
+		s.mu.Lock()
+		delete(s.streams, st)
+		s.mu.Unlock()
+	}()
+
+	errCh := make(chan error, 1)
+	go func() { errCh <- s.ref.Modify(st) }()
+	select {
+	case err := <-errCh:
+		return err
+	case <-st.dropped:
+		return errDropped
+	}
+}
+
+// Get serves a Get request from the reference server.
+func (s *Server) Get(req *gpb.GetRequest, stream gpb.GRIBI_GetServer) error {
+	return s.ref.Get(req, stream)
+}
+
+// Flush serves a Flush request from the reference server.
+func (s *Server) Flush(ctx context.Context, req *gpb.FlushRequest) (*gpb.FlushResponse, error) {
+	return s.ref.Flush(ctx, req)
+}
+
+var errDropped = status.Error(codes.Unavailable, "fakegribi: Modify stream dropped")
+
+// modifyStream intercepts the requests and responses of a Modify stream.
+type modifyStream struct {
+	gpb.GRIBI_ModifyServer
+	srv *Server
+
+	mu       sync.Mutex
+	fibACK   bool
+	received int
+	ops      map[uint64]*gpb.AFTOperation
+
+	dropOnce sync.Once
+	dropped  chan struct{}
+}
+
+// Recv records the operations of the requests, for the results to be matched against them.
+func (st *modifyStream) Recv() (*gpb.ModifyRequest, error) {
+	req, err := st.GRIBI_ModifyServer.Recv()
+	if err != nil {
+		return nil, err
+	}
+	st.srv.mu.Lock()
+	dropAfter := st.srv.dropAfter
+	st.srv.mu.Unlock()
+
+	st.mu.Lock()
+	defer st.mu.Unlock()
+	if p := req.GetParams(); p != nil {
+		st.fibACK = p.GetAckType() == gpb.SessionParameters_RIB_AND_FIB_ACK
+	}
+	st.received += len(req.GetOperation())
+	if dropAfter > 0 && st.received >= dropAfter {
+		st.dropOnce.Do(func() { close(st.dropped) })
+		return nil, errDropped
+	}
+	for _, op := range req.GetOperation() {
+		st.ops[op.GetId()] = op
+	}
+	return req, nil
+}
+
+// Send delays and rewrites the results of the responses according to the knobs.
+func (st *modifyStream) Send(resp *gpb.ModifyResponse) error {
+	if len(resp.GetResult()) == 0 {
+		return st.GRIBI_ModifyServer.Send(resp)
+	}
+	st.srv.mu.Lock()
+	failFIB, delay := st.srv.failFIB, st.srv.ackDelay
+	st.srv.mu.Unlock()
+
+	if delay > 0 {
+		select {
+		case <-time.After(delay):
+		case <-st.Context().Done():
+			return st.Context().Err()
+		}
+	}
+
+	st.mu.Lock()
+	for _, r := range resp.GetResult() {
+		op := st.ops[r.GetId()]
+		switch r.GetStatus() {
+		case gpb.AFTResult_FIB_PROGRAMMED:
+			if failFIB != nil && op != nil && failFIB(op) {
+				r.Status = gpb.AFTResult_FIB_FAILED
+			}
+			delete(st.ops, r.GetId())
+		case gpb.AFTResult_RIB_PROGRAMMED:
+			if !st.fibACK {
+				delete(st.ops, r.GetId())
+			}
+		default:
+			delete(st.ops, r.GetId())
+		}
+	}
+	st.mu.Unlock()
+	return st.GRIBI_ModifyServer.Send(resp)
+}
@@ -0,0 +1,140 @@
+// Copyright 2026 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package fakegribi
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/openconfig/gribigo/client"
+	"github.com/openconfig/gribigo/fluent"
+
+	gpb "github.com/openconfig/gribi/v1/proto/service"
+)
+
+// leader returns a started fluent client of s, leader with FIB acknowledgements.
+func leader(t *testing.T, s *Server) *fluent.GRIBIClient {
+	t.Helper()
+	c := fluent.NewClient()
+	c.Connection().WithStub(s.Stub()).WithRedundancyMode(fluent.ElectedPrimaryClient).
+		WithInitialElectionID(1, 0).WithPersistence().WithFIBACK()
+	ctx, cancel := context.WithCancel(context.Background())
+	t.Cleanup(cancel)
+	c.Start(ctx, t)
+	c.StartSending(ctx, t)
+	t.Cleanup(func() { c.Stop(t) })
+	if err := await(c, t); err != nil {
+		t.Fatalf("Await() failed: %v", err)
+	}
+	return c
+}
+
+func await(c *fluent.GRIBIClient, t *testing.T) error {
+	ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+	defer cancel()
+	return c.Await(ctx, t)
+}
+
+func nh(index uint64) fluent.GRIBIEntry {
+	return fluent.NextHopEntry().WithNetworkInstance("DEFAULT").WithIndex(index).WithIPAddress("192.0.2.1")
+}
+
+// nhStatus returns the final status reported for the next hop index.
+func nhStatus(results []*client.OpResult, index uint64) gpb.AFTResult_Status {
+	var st gpb.AFTResult_Status
+	for _, r := range results {
+		if r.Details != nil && r.Details.NextHopIndex == index {
+			st = r.ProgrammingResult
+		}
+	}
+	return st
+}
+
+func TestFailFIB(t *testing.T) {
+	s := Start(t)
+	s.FailFIB(func(op *gpb.AFTOperation) bool { return op.GetNextHop().GetIndex() == 2 })
+	c := leader(t, s)
+	c.Modify().AddEntry(t, nh(1), nh(2))
+	if err := await(c, t); err != nil {
+		t.Fatalf("Await() failed: %v", err)
+	}
+	res := c.Results(t)
+	for _, tc := range []struct {
+		index uint64
+		want  gpb.AFTResult_Status
+	}{
+		{index: 1, want: gpb.AFTResult_FIB_PROGRAMMED},
+		{index: 2, want: gpb.AFTResult_FIB_FAILED},
+	} {
+		if got := nhStatus(res, tc.index); got != tc.want {
+			t.Errorf("Next hop %d got status %v, want %v", tc.index, got, tc.want)
+		}
+	}
+
+	s.FailFIB(nil)
+	c.Modify().AddEntry(t, nh(3))
+	if err := await(c, t); err != nil {
+		t.Fatalf("Await() failed: %v", err)
+	}
+	if got, want := nhStatus(c.Results(t), 3), gpb.AFTResult_FIB_PROGRAMMED; got != want {
+		t.Errorf("Next hop 3 after FailFIB(nil) got status %v, want %v", got, want)
+	}
+}
+
+func TestDelayAcks(t *testing.T) {
+	s := Start(t)
+	c := leader(t, s)
+	s.DelayAcks(100 * time.Millisecond)
+	start := time.Now()
+	c.Modify().AddEntry(t, nh(1))
+	if err := await(c, t); err != nil {
+		t.Fatalf("Await() failed: %v", err)
+	}
+	if got, want := time.Since(start), 100*time.Millisecond; got < want {
+		t.Errorf("Acknowledgement received after %v, want at least %v", got, want)
+	}
+	if got, want := nhStatus(c.Results(t), 1), gpb.AFTResult_FIB_PROGRAMMED; got != want {
+		t.Errorf("Delayed next hop got status %v, want %v", got, want)
+	}
+}
+
+func TestDropStreams(t *testing.T) {
+	t.Run("after operations", func(t *testing.T) {
+		s := Start(t)
+		s.DropStreamAfter(2)
+		c := leader(t, s)
+		c.Modify().AddEntry(t, nh(1))
+		if err := await(c, t); err != nil {
+			t.Fatalf("Await() failed: %v", err)
+		}
+		c.Modify().AddEntry(t, nh(2))
+		if err := await(c, t); err == nil {
+			t.Errorf("Await() after the stream was dropped got nil error, want error")
+		}
+		if got := nhStatus(c.Results(t), 2); got != gpb.AFTResult_UNSET {
+			t.Errorf("Next hop in the dropped request got status %v, want no result", got)
+		}
+	})
+	t.Run("immediately", func(t *testing.T) {
+		s := Start(t)
+		c := leader(t, s)
+		s.DropStreams()
+		c.Modify().AddEntry(t, nh(1))
+		if err := await(c, t); err == nil {
+			t.Errorf("Await() after the stream was dropped got nil error, want error")
+		}
+	})
+}
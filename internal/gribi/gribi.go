@@ -82,10 +82,14 @@ type Client struct {
 	DUT         *ondatra.DUTDevice
 	FIBACK      bool
 	Persistence bool
+	// Stub, if set, is used instead of the gRIBI API of the DUT, e.g. to connect to a
+	// fakegribi server in unit tests.
+	Stub gpb.GRIBIClient
 
 	// Unexport fields below.
 	fluentC    *fluent.GRIBIClient
 	electionID Uint128
+	rec        *recorder
 }
 
 // Fluent resturns the fluent client that can be used to directly call the gribi fluent APIs
@@ -114,10 +118,16 @@ type NHOptions struct {
 // needs to be called.
 func (c *Client) Start(t testing.TB) error {
 	t.Helper()
-	t.Logf("Starting GRIBI connection for dut: %s", c.DUT.Name())
-	gribiC := c.DUT.RawAPIs().GRIBI(t)
+	t.Logf("Starting GRIBI connection for dut: %s", c.name())
+	gribiC := c.Stub
+	if gribiC == nil {
+		gribiC = c.DUT.RawAPIs().GRIBI(t)
+	}
 	c.fluentC = fluent.NewClient()
 	c.electionID = Uint128{Low: 1, High: 0}
+	if c.rec == nil {
+		c.rec = newRecorder()
+	}
 
 	conn := c.fluentC.Connection().WithStub(gribiC).WithRedundancyMode(fluent.ElectedPrimaryClient)
 	conn.WithInitialElectionID(c.electionID.Low, c.electionID.High)
@@ -134,14 +144,26 @@ func (c *Client) Start(t testing.TB) error {
 	return err
 }
 
+// name returns the name of the DUT, or of the stub if the client is not connected to a DUT.
+func (c *Client) name() string {
+	if c.DUT == nil {
+		return "stub"
+	}
+	return c.DUT.Name()
+}
+
 // Close function closes the gribi session with the dut by stopping the fluent client.
 func (c *Client) Close(t testing.TB) {
 	t.Helper()
-	t.Logf("Closing GRIBI connection for dut: %s", c.DUT.Name())
+	t.Logf("Closing GRIBI connection for dut: %s", c.name())
 	if c.fluentC != nil {
+		c.syncRIB(t)
 		c.fluentC.Stop(t)
 		c.fluentC = nil
 	}
+	if c.rec != nil {
+		c.rec.reset(c.Persistence)
+	}
 }
 
 // AwaitTimeout calls a fluent client Await by adding a timeout to the context.
@@ -266,6 +288,7 @@ func NHGEntry(nhgIndex uint64, nhWeights map[uint64]uint64, instance string, exp
 // AddEntries adds the input gRIBI entries and checks the success of the input OperationResults.
 func (c *Client) AddEntries(t testing.TB, entries []fluent.GRIBIEntry, expectedResults []*client.OpResult) {
 	t.Helper()
+	c.rec.record(gpb.AFTOperation_ADD, entries)
 	c.fluentC.Modify().AddEntry(t, entries...)
 	if len(expectedResults) == 0 {
 		return
@@ -273,6 +296,7 @@ func (c *Client) AddEntries(t testing.TB, entries []fluent.GRIBIEntry, expectedR
 	if err := c.AwaitTimeout(context.Background(), t, timeout); err != nil {
 		t.Fatalf("Error waiting to add entries: %v", err)
 	}
+	c.syncRIB(t)
 	for _, result := range expectedResults {
 		chk.HasResult(t, c.fluentC.Results(t),
 			result,
@@ -284,10 +308,12 @@ func (c *Client) AddEntries(t testing.TB, entries []fluent.GRIBIEntry, expectedR
 // DeleteEntries deletes the input gRIBI entries and checks the success of the input OperationResults.
 func (c *Client) DeleteEntries(t testing.TB, entries []fluent.GRIBIEntry, expectedResults []*client.OpResult) {
 	t.Helper()
+	c.rec.record(gpb.AFTOperation_DELETE, entries)
 	c.fluentC.Modify().DeleteEntry(t, entries...)
 	if err := c.AwaitTimeout(context.Background(), t, timeout); err != nil {
 		t.Fatalf("Error waiting to delete entries: %v", err)
 	}
+	c.syncRIB(t)
 	for _, result := range expectedResults {
 		chk.HasResult(t, c.fluentC.Results(t),
 			result,
@@ -305,10 +331,12 @@ func (c *Client) AddIPv4(t testing.TB, prefix string, nhgIndex uint64, instance,
 	if nhgInstance != "" && nhgInstance != instance {
 		ipv4Entry.WithNextHopGroupNetworkInstance(nhgInstance)
 	}
+	c.rec.record(gpb.AFTOperation_ADD, []fluent.GRIBIEntry{ipv4Entry})
 	c.fluentC.Modify().AddEntry(t, ipv4Entry)
 	if err := c.AwaitTimeout(context.Background(), t, timeout); err != nil {
 		t.Fatalf("Error waiting to add IPv4: %v", err)
 	}
+	c.syncRIB(t)
 	chk.HasResult(t, c.fluentC.Results(t),
 		fluent.OperationResult().
 			WithIPv4Operation(prefix).
@@ -328,10 +356,12 @@ func (c *Client) AddIPv6(t testing.TB, prefix string, nhgIndex uint64, instance,
 	if nhgInstance != "" && nhgInstance != instance {
 		ipv6Entry.WithNextHopGroupNetworkInstance(nhgInstance)
 	}
+	c.rec.record(gpb.AFTOperation_ADD, []fluent.GRIBIEntry{ipv6Entry})
 	c.fluentC.Modify().AddEntry(t, ipv6Entry)
 	if err := c.AwaitTimeout(context.Background(), t, timeout); err != nil {
 		t.Fatalf("Error waiting to add IPv6: %v", err)
 	}
+	c.syncRIB(t)
 	chk.HasResult(t, c.fluentC.Results(t),
 		fluent.OperationResult().
 			WithIPv6Operation(prefix).
@@ -346,10 +376,12 @@ func (c *Client) AddIPv6(t testing.TB, prefix string, nhgIndex uint64, instance,
 func (c *Client) DeleteIPv4(t testing.TB, prefix string, instance string, expectedResult fluent.ProgrammingResult) {
 	t.Helper()
 	ipv4Entry := fluent.IPv4Entry().WithPrefix(prefix).WithNetworkInstance(instance)
+	c.rec.record(gpb.AFTOperation_DELETE, []fluent.GRIBIEntry{ipv4Entry})
 	c.fluentC.Modify().DeleteEntry(t, ipv4Entry)
 	if err := c.AwaitTimeout(context.Background(), t, timeout); err != nil {
 		t.Fatalf("Error waiting to delete IPv4: %v", err)
 	}
+	c.syncRIB(t)
 	chk.HasResult(t, c.fluentC.Results(t),
 		fluent.OperationResult().
 			WithIPv4Operation(prefix).
@@ -364,10 +396,12 @@ func (c *Client) DeleteIPv4(t testing.TB, prefix string, instance string, expect
 func (c *Client) DeleteIPv6(t testing.TB, prefix string, instance string, expectedResult fluent.ProgrammingResult) {
 	t.Helper()
 	ipv6Entry := fluent.IPv6Entry().WithPrefix(prefix).WithNetworkInstance(instance)
+	c.rec.record(gpb.AFTOperation_DELETE, []fluent.GRIBIEntry{ipv6Entry})
 	c.fluentC.Modify().DeleteEntry(t, ipv6Entry)
 	if err := c.AwaitTimeout(context.Background(), t, timeout); err != nil {
 		t.Fatalf("Error waiting to delete IPv6: %v", err)
 	}
+	c.syncRIB(t)
 	chk.HasResult(t, c.fluentC.Results(t),
 		fluent.OperationResult().
 			WithIPv6Operation(prefix).
@@ -383,6 +417,7 @@ func (c *Client) FlushAll(t testing.TB) {
 	if err := FlushAll(c.fluentC); err != nil {
 		t.Fatal(err)
 	}
+	c.rec.rib.flush("")
 }
 
 // Flush flushes gRIBI entries specific to the provided NetworkInstance end electionID
@@ -391,6 +426,50 @@ func (c *Client) Flush(t testing.TB, electionID Uint128, networkInstanceName str
 	if err != nil {
 		t.Fatal(err)
 	}
+	c.rec.rib.flush(networkInstanceName)
+}
+
+// syncRIB applies the operations acknowledged since the last call to the RIB model.
+func (c *Client) syncRIB(t testing.TB) {
+	if c.rec == nil || c.fluentC == nil {
+		return
+	}
+	c.rec.process(c.fluentC.Results(t))
+}
+
+// RIB returns the model of the RIB of the DUT built from the operations sent through the
+// client and acknowledged by the DUT. Operations sent directly with Fluent are not recorded,
+// and make the model unreliable when they are interleaved with client operations.
+func (c *Client) RIB(t testing.TB) *RIB {
+	t.Helper()
+	if c.rec == nil {
+		return NewRIB()
+	}
+	c.syncRIB(t)
+	if c.rec.err != nil {
+		t.Errorf("gRIBI RIB model of dut %s may be inaccurate: %v", c.name(), c.rec.err)
+	}
+	return c.rec.rib
+}
+
+// VerifyRIB gets all entries of all network instances from the DUT with the Get RPC and
+// compares them with the RIB model. Differences are reported as test errors and returned.
+func (c *Client) VerifyRIB(t testing.TB) *RIBDiff {
+	t.Helper()
+	want := c.RIB(t)
+	resp, err := c.fluentC.Get().AllNetworkInstances().WithAFT(fluent.AllAFTs).Send()
+	if err != nil {
+		t.Fatalf("Error getting gRIBI entries from dut %s: %v", c.name(), err)
+	}
+	got, err := RIBFromGetResponse(resp)
+	if err != nil {
+		t.Fatalf("Error parsing gRIBI entries from dut %s: %v", c.name(), err)
+	}
+	diff := DiffRIB(want, got)
+	if !diff.Empty() {
+		t.Errorf("gRIBI entries of dut %s differ from the RIB model:\n%s", c.name(), diff)
+	}
+	return diff
 }
 
 // LearnElectionID learns the current server election id by sending
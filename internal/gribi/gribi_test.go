@@ -0,0 +1,53 @@
+// Copyright 2026 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package gribi
+
+import (
+	"testing"
+
+	"github.com/openconfig/featureprofiles/internal/gribi/fakegribi"
+	"github.com/openconfig/gribigo/fluent"
+)
+
+func TestClientElection(t *testing.T) {
+	s := fakegribi.Start(t)
+	c1 := startClient(t, s)
+	e1 := c1.BecomeLeader(t)
+	c2 := startClient(t, s)
+	e2 := c2.BecomeLeader(t)
+	if e2.Cmp(e1) <= 0 {
+		t.Fatalf("BecomeLeader() of the second client got election ID %+v, want more than %+v", e2, e1)
+	}
+	if got := c1.LearnElectionID(t); got != e2 {
+		t.Errorf("LearnElectionID() got %+v, want %+v", got, e2)
+	}
+
+	c1.AddNH(t, 1, "192.0.2.1", "DEFAULT", fluent.ProgrammingFailed)
+	c2.AddNH(t, 2, "192.0.2.2", "DEFAULT", fluent.InstalledInRIB)
+
+	c1.UpdateElectionID(t, e2.Increment())
+	if got, want := c1.ElectionID(), e2.Increment(); got != want {
+		t.Errorf("ElectionID() got %+v, want %+v", got, want)
+	}
+	c1.AddNH(t, 3, "192.0.2.3", "DEFAULT", fluent.InstalledInRIB)
+	c2.AddNH(t, 4, "192.0.2.4", "DEFAULT", fluent.ProgrammingFailed)
+
+	// Each client models the entries it programmed while leader.
+	for i, c := range []*Client{c1, c2} {
+		if got := c.RIB(t).Len(); got != 1 {
+			t.Errorf("RIB() of client %d has %d entries, want 1", i+1, got)
+		}
+	}
+}
@@ -0,0 +1,254 @@
+// Copyright 2026 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package gribi
+
+import (
+	"context"
+	"fmt"
+	"net/netip"
+	"testing"
+
+	"github.com/openconfig/gribigo/chk"
+	"github.com/openconfig/gribigo/client"
+	"github.com/openconfig/gribigo/constants"
+	"github.com/openconfig/gribigo/fluent"
+
+	aftpb "github.com/openconfig/gribi/v1/proto/gribi_aft"
+	enumspb "github.com/openconfig/gribi/v1/proto/gribi_aft/enums"
+	gpb "github.com/openconfig/gribi/v1/proto/service"
+	wpb "github.com/openconfig/ygot/proto/ywrapper"
+)
+
+// LabelAction is the MPLS label stack operation of a next hop.
+type LabelAction struct {
+	// PopTop pops the top label of the stack.
+	PopTop bool
+	// Push is the label stack pushed, outermost label last.
+	Push []uint32
+}
+
+// Push returns the action pushing labels onto the stack.
+func Push(labels ...uint32) LabelAction {
+	return LabelAction{Push: labels}
+}
+
+// Pop returns the action popping the top label of the stack.
+func Pop() LabelAction {
+	return LabelAction{PopTop: true}
+}
+
+// Swap returns the action replacing the top label of the stack with label.
+func Swap(label uint32) LabelAction {
+	return LabelAction{PopTop: true, Push: []uint32{label}}
+}
+
+// LabelEntry returns a fluent LabelEntry forwarding label to a next hop group, popping
+// popLabels if any, and the gribigo client OpResult to expect. An empty nhgInstance is not
+// set.
+func LabelEntry(label uint32, nhgIndex uint64, instance, nhgInstance string, expectedResult fluent.ProgrammingResult, popLabels ...uint32) (fluent.GRIBIEntry, *client.OpResult) {
+	l := fluent.LabelEntry().WithLabel(label).
+		WithNetworkInstance(instance).
+		WithNextHopGroup(nhgIndex)
+	if nhgInstance != "" {
+		l.WithNextHopGroupNetworkInstance(nhgInstance)
+	}
+	if len(popLabels) > 0 {
+		l.WithPoppedLabelStack(popLabels...)
+	}
+	return l, fluent.OperationResult().
+		WithMPLSOperation(uint64(label)).
+		WithOperationType(constants.Add).
+		WithProgrammingResult(expectedResult).
+		AsResult()
+}
+
+// MPLSNHEntry returns a fluent NextHopEntry to an address applying a label stack action,
+// and the gribigo client OpResult to expect.
+func MPLSNHEntry(nhIndex uint64, address, instance string, action LabelAction, expectedResult fluent.ProgrammingResult) (fluent.GRIBIEntry, *client.OpResult) {
+	nh := fluent.NextHopEntry().
+		WithNetworkInstance(instance).
+		WithIndex(nhIndex).
+		WithIPAddress(address)
+	if action.PopTop {
+		nh.WithPopTopLabel()
+	}
+	if len(action.Push) > 0 {
+		nh.WithPushedLabelStack(action.Push...)
+	}
+	return nh, nhResult(nhIndex, expectedResult)
+}
+
+// UDPEncap are the parameters of the outer UDP header of an MPLS-in-UDP encapsulation. The
+// header is UDPv4 or UDPv6 according to the family of DstIP, which is required. Other zero
+// values are not set.
+type UDPEncap struct {
+	SrcIP, DstIP     string
+	SrcPort, DstPort uint64
+	DSCP, TTL        uint64
+}
+
+// encapHeader is an encapsulation header for fluent AddEncapHeader, for the header types
+// that fluent does not build.
+type encapHeader struct {
+	pb *aftpb.Afts_NextHop_EncapHeader
+}
+
+// EncapProto returns the encapsulation header.
+func (eh *encapHeader) EncapProto() *aftpb.Afts_NextHop_EncapHeader {
+	return eh.pb
+}
+
+func stringVal(s string) *wpb.StringValue {
+	if s == "" {
+		return nil
+	}
+	return &wpb.StringValue{Value: s}
+}
+
+func uintVal(u uint64) *wpb.UintValue {
+	if u == 0 {
+		return nil
+	}
+	return &wpb.UintValue{Value: u}
+}
+
+// MPLSInUDPNHEntry returns a fluent NextHopEntry encapsulating packets in an MPLS header
+// with labels and an outer UDP header, and the gribigo client OpResult to expect. It returns
+// an error if DstIP is not an IP address, or SrcIP is set and not an address of its family.
+func MPLSInUDPNHEntry(nhIndex uint64, instance string, labels []uint64, udp UDPEncap, expectedResult fluent.ProgrammingResult) (fluent.GRIBIEntry, *client.OpResult, error) {
+	dst, err := netip.ParseAddr(udp.DstIP)
+	if err != nil {
+		return nil, nil, fmt.Errorf("MPLS-in-UDP destination: %w", err)
+	}
+	if udp.SrcIP != "" {
+		src, err := netip.ParseAddr(udp.SrcIP)
+		if err != nil {
+			return nil, nil, fmt.Errorf("MPLS-in-UDP source: %w", err)
+		}
+		if src.Is4() != dst.Is4() {
+			return nil, nil, fmt.Errorf("MPLS-in-UDP source %v and destination %v are of different families", src, dst)
+		}
+	}
+	nh := fluent.NextHopEntry().
+		WithNetworkInstance(instance).
+		WithIndex(nhIndex)
+	eh := &aftpb.Afts_NextHop_EncapHeader{}
+	if dst.Is4() {
+		eh.Type = enumspb.OpenconfigAftTypesEncapsulationHeaderType_OPENCONFIGAFTTYPESENCAPSULATIONHEADERTYPE_UDPV4
+		eh.UdpV4 = &aftpb.Afts_NextHop_EncapHeader_UdpV4{
+			SrcIp:      stringVal(udp.SrcIP),
+			DstIp:      stringVal(udp.DstIP),
+			SrcUdpPort: uintVal(udp.SrcPort),
+			DstUdpPort: uintVal(udp.DstPort),
+			Dscp:       uintVal(udp.DSCP),
+			IpTtl:      uintVal(udp.TTL),
+		}
+	} else {
+		eh.Type = enumspb.OpenconfigAftTypesEncapsulationHeaderType_OPENCONFIGAFTTYPESENCAPSULATIONHEADERTYPE_UDPV6
+		eh.UdpV6 = &aftpb.Afts_NextHop_EncapHeader_UdpV6{
+			SrcIp:      stringVal(udp.SrcIP),
+			DstIp:      stringVal(udp.DstIP),
+			SrcUdpPort: uintVal(udp.SrcPort),
+			DstUdpPort: uintVal(udp.DstPort),
+			Dscp:       uintVal(udp.DSCP),
+			IpTtl:      uintVal(udp.TTL),
+		}
+	}
+	nh.AddEncapHeader(fluent.MPLSEncapHeader().WithLabels(labels...), &encapHeader{pb: eh})
+	return nh, nhResult(nhIndex, expectedResult), nil
+}
+
+// GRENHEntry returns a fluent NextHopEntry encapsulating packets in GRE from src to dst, and
+// the gribigo client OpResult to expect. A zero ttl is not set.
+func GRENHEntry(nhIndex uint64, src, dst, instance string, ttl uint64, expectedResult fluent.ProgrammingResult) (fluent.GRIBIEntry, *client.OpResult) {
+	nh := fluent.NextHopEntry().
+		WithNetworkInstance(instance).
+		WithIndex(nhIndex).
+		AddEncapHeader(&encapHeader{pb: &aftpb.Afts_NextHop_EncapHeader{
+			Type: enumspb.OpenconfigAftTypesEncapsulationHeaderType_OPENCONFIGAFTTYPESENCAPSULATIONHEADERTYPE_GRE,
+			Gre: &aftpb.Afts_NextHop_EncapHeader_Gre{
+				SrcIp: stringVal(src),
+				DstIp: stringVal(dst),
+				Ttl:   uintVal(ttl),
+			},
+		}})
+	return nh, nhResult(nhIndex, expectedResult)
+}
+
+// DecapLookupNHEntry returns a fluent NextHopEntry decapsulating the header and looking up
+// the inner packet in lookupInstance, and the gribigo client OpResult to expect.
+func DecapLookupNHEntry(nhIndex uint64, header fluent.Header, instance, lookupInstance string, expectedResult fluent.ProgrammingResult) (fluent.GRIBIEntry, *client.OpResult) {
+	nh := fluent.NextHopEntry().
+		WithNetworkInstance(instance).
+		WithIndex(nhIndex).
+		WithDecapsulateHeader(header).
+		WithNextHopNetworkInstance(lookupInstance)
+	return nh, nhResult(nhIndex, expectedResult)
+}
+
+func nhResult(nhIndex uint64, expectedResult fluent.ProgrammingResult) *client.OpResult {
+	return fluent.OperationResult().
+		WithNextHopOperation(nhIndex).
+		WithOperationType(constants.Add).
+		WithProgrammingResult(expectedResult).
+		AsResult()
+}
+
+// AddLabel adds a LabelEntry forwarding label to a given next hop group index within a given
+// network instance, popping popLabels if any.
+func (c *Client) AddLabel(t testing.TB, label uint32, nhgIndex uint64, instance, nhgInstance string, expectedResult fluent.ProgrammingResult, popLabels ...uint32) {
+	t.Helper()
+	l, opResult := LabelEntry(label, nhgIndex, instance, nhgInstance, expectedResult, popLabels...)
+	c.AddEntries(t, []fluent.GRIBIEntry{l}, []*client.OpResult{opResult})
+}
+
+// DeleteLabel deletes a LabelEntry within a network instance.
+func (c *Client) DeleteLabel(t testing.TB, label uint32, instance string, expectedResult fluent.ProgrammingResult) {
+	t.Helper()
+	l := fluent.LabelEntry().WithLabel(label).WithNetworkInstance(instance)
+	c.rec.record(gpb.AFTOperation_DELETE, []fluent.GRIBIEntry{l})
+	c.fluentC.Modify().DeleteEntry(t, l)
+	if err := c.AwaitTimeout(context.Background(), t, timeout); err != nil {
+		t.Fatalf("Error waiting to delete label: %v", err)
+	}
+	c.syncRIB(t)
+	chk.HasResult(t, c.fluentC.Results(t),
+		fluent.OperationResult().
+			WithMPLSOperation(uint64(label)).
+			WithOperationType(constants.Delete).
+			WithProgrammingResult(expectedResult).
+			AsResult(),
+		chk.IgnoreOperationID(),
+	)
+}
+
+// AddDecapLookup programs the decap-then-lookup pattern: packets to prefix in instance are
+// decapsulated from header by next hop nhIndex, through next hop group nhgIndex, and the
+// inner packets looked up in lookupInstance.
+func (c *Client) AddDecapLookup(t testing.TB, prefix string, nhIndex, nhgIndex uint64, header fluent.Header, instance, lookupInstance string, expectedResult fluent.ProgrammingResult) {
+	t.Helper()
+	pfx, err := netip.ParsePrefix(prefix)
+	if err != nil {
+		t.Fatalf("Invalid decap-then-lookup prefix: %v", err)
+	}
+	nh, nhRes := DecapLookupNHEntry(nhIndex, header, instance, lookupInstance, expectedResult)
+	nhg, nhgRes := NHGEntry(nhgIndex, map[uint64]uint64{nhIndex: 1}, instance, expectedResult)
+	c.AddEntries(t, []fluent.GRIBIEntry{nh, nhg}, []*client.OpResult{nhRes, nhgRes})
+	if pfx.Addr().Is4() {
+		c.AddIPv4(t, prefix, nhgIndex, instance, "", expectedResult)
+	} else {
+		c.AddIPv6(t, prefix, nhgIndex, instance, "", expectedResult)
+	}
+}
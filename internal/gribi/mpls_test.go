@@ -0,0 +1,142 @@
+// Copyright 2026 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package gribi
+
+import (
+	"testing"
+
+	"github.com/openconfig/gribigo/fluent"
+
+	enumspb "github.com/openconfig/gribi/v1/proto/gribi_aft/enums"
+)
+
+func TestMPLSNHEntry(t *testing.T) {
+	for _, tc := range []struct {
+		desc     string
+		action   LabelAction
+		wantPop  bool
+		wantPush []uint64
+	}{
+		{desc: "push", action: Push(100, 200), wantPush: []uint64{100, 200}},
+		{desc: "pop", action: Pop(), wantPop: true},
+		{desc: "swap", action: Swap(300), wantPop: true, wantPush: []uint64{300}},
+	} {
+		t.Run(tc.desc, func(t *testing.T) {
+			nh, _ := MPLSNHEntry(1, "192.0.2.1", "DEFAULT", tc.action, fluent.InstalledInRIB)
+			e, err := nh.EntryProto()
+			if err != nil {
+				t.Fatalf("EntryProto() failed: %v", err)
+			}
+			got := e.GetNextHop().GetNextHop()
+			if pop := got.GetPopTopLabel().GetValue(); pop != tc.wantPop {
+				t.Errorf("MPLSNHEntry() pop top label got %v, want %v", pop, tc.wantPop)
+			}
+			var push []uint64
+			for _, l := range got.GetPushedMplsLabelStack() {
+				push = append(push, l.GetPushedMplsLabelStackUint64())
+			}
+			if len(push) != len(tc.wantPush) {
+				t.Fatalf("MPLSNHEntry() pushed labels got %v, want %v", push, tc.wantPush)
+			}
+			for i := range push {
+				if push[i] != tc.wantPush[i] {
+					t.Errorf("MPLSNHEntry() pushed labels got %v, want %v", push, tc.wantPush)
+				}
+			}
+		})
+	}
+}
+
+func TestEncapNHEntries(t *testing.T) {
+	udp4, _, err := MPLSInUDPNHEntry(1, "DEFAULT", []uint64{100}, UDPEncap{SrcIP: "192.0.2.1", DstIP: "192.0.2.2", DstPort: 6635}, fluent.InstalledInRIB)
+	if err != nil {
+		t.Fatalf("MPLSInUDPNHEntry() of UDPv4 got error: %v", err)
+	}
+	udp6, _, err := MPLSInUDPNHEntry(2, "DEFAULT", []uint64{100}, UDPEncap{SrcIP: "2001:db8::1", DstIP: "2001:db8::2", DSCP: 10}, fluent.InstalledInRIB)
+	if err != nil {
+		t.Fatalf("MPLSInUDPNHEntry() of UDPv6 got error: %v", err)
+	}
+	gre, _ := GRENHEntry(3, "192.0.2.1", "198.51.100.1", "DEFAULT", 64, fluent.InstalledInRIB)
+	for _, tc := range []struct {
+		desc      string
+		entry     fluent.GRIBIEntry
+		wantTypes []enumspb.OpenconfigAftTypesEncapsulationHeaderType
+	}{{
+		desc:  "MPLS in UDPv4",
+		entry: udp4,
+		wantTypes: []enumspb.OpenconfigAftTypesEncapsulationHeaderType{
+			enumspb.OpenconfigAftTypesEncapsulationHeaderType_OPENCONFIGAFTTYPESENCAPSULATIONHEADERTYPE_MPLS,
+			enumspb.OpenconfigAftTypesEncapsulationHeaderType_OPENCONFIGAFTTYPESENCAPSULATIONHEADERTYPE_UDPV4,
+		},
+	}, {
+		desc:  "MPLS in UDPv6",
+		entry: udp6,
+		wantTypes: []enumspb.OpenconfigAftTypesEncapsulationHeaderType{
+			enumspb.OpenconfigAftTypesEncapsulationHeaderType_OPENCONFIGAFTTYPESENCAPSULATIONHEADERTYPE_MPLS,
+			enumspb.OpenconfigAftTypesEncapsulationHeaderType_OPENCONFIGAFTTYPESENCAPSULATIONHEADERTYPE_UDPV6,
+		},
+	}, {
+		desc:  "GRE",
+		entry: gre,
+		wantTypes: []enumspb.OpenconfigAftTypesEncapsulationHeaderType{
+			enumspb.OpenconfigAftTypesEncapsulationHeaderType_OPENCONFIGAFTTYPESENCAPSULATIONHEADERTYPE_GRE,
+		},
+	}} {
+		t.Run(tc.desc, func(t *testing.T) {
+			e, err := tc.entry.EntryProto()
+			if err != nil {
+				t.Fatalf("EntryProto() failed: %v", err)
+			}
+			headers := e.GetNextHop().GetNextHop().GetEncapHeader()
+			if len(headers) != len(tc.wantTypes) {
+				t.Fatalf("got %d encapsulation headers, want %d", len(headers), len(tc.wantTypes))
+			}
+			for i, h := range headers {
+				if got := h.GetEncapHeader().GetType(); got != tc.wantTypes[i] {
+					t.Errorf("encapsulation header %d got type %v, want %v", i, got, tc.wantTypes[i])
+				}
+			}
+		})
+	}
+}
+
+func TestMPLSInUDPNHEntryErrors(t *testing.T) {
+	for _, udp := range []UDPEncap{
+		{},
+		{DstIP: "192.0.2.256"},
+		{DstIP: "2001:db8::2/128"},
+		{SrcIP: "192.0.2", DstIP: "192.0.2.2"},
+		{SrcIP: "2001:db8::1", DstIP: "192.0.2.2"},
+	} {
+		if _, _, err := MPLSInUDPNHEntry(1, "DEFAULT", []uint64{100}, udp, fluent.InstalledInRIB); err == nil {
+			t.Errorf("MPLSInUDPNHEntry(%+v) got no error, want error", udp)
+		}
+	}
+}
+
+func TestLabelEntries(t *testing.T) {
+	c := fakeClient(t)
+	c.AddNH(t, 1, "192.0.2.1", "DEFAULT", fluent.InstalledInRIB)
+	c.AddNHG(t, 1, map[uint64]uint64{1: 1}, "DEFAULT", fluent.InstalledInRIB)
+	c.AddLabel(t, 100, 1, "DEFAULT", "", fluent.InstalledInRIB)
+	label := fluent.LabelEntry().WithLabel(100).WithNetworkInstance("DEFAULT")
+	if _, ok, err := c.RIB(t).Lookup(label); err != nil || !ok {
+		t.Errorf("RIB() after AddLabel() does not have label 100, err: %v", err)
+	}
+	c.DeleteLabel(t, 100, "DEFAULT", fluent.InstalledInRIB)
+	if got, ok, _ := c.RIB(t).Lookup(label); ok {
+		t.Errorf("RIB() after DeleteLabel() has label 100: %v", got)
+	}
+}
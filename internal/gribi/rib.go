@@ -0,0 +1,380 @@
+// Copyright 2026 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package gribi
+
+import (
+	"cmp"
+	"fmt"
+	"io"
+	"maps"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/openconfig/gribigo/client"
+	"github.com/openconfig/gribigo/constants"
+	"github.com/openconfig/gribigo/fluent"
+	"google.golang.org/protobuf/encoding/prototext"
+	"google.golang.org/protobuf/proto"
+
+	aftpb "github.com/openconfig/gribi/v1/proto/gribi_aft"
+	gpb "github.com/openconfig/gribi/v1/proto/service"
+)
+
+// entryKind is the AFT of a gRIBI entry.
+type entryKind string
+
+const (
+	kindNextHop      entryKind = "nh"
+	kindNextHopGroup entryKind = "nhg"
+	kindIPv4         entryKind = "ipv4"
+	kindIPv6         entryKind = "ipv6"
+	kindMPLS         entryKind = "mpls"
+)
+
+// ribKey identifies an entry of a RIB.
+type ribKey struct {
+	networkInstance string
+	kind            entryKind
+	key             string
+}
+
+func (k ribKey) String() string {
+	return fmt.Sprintf("%s/%s/%s", k.networkInstance, k.kind, k.key)
+}
+
+func compareKeys(a, b ribKey) int {
+	return cmp.Or(cmp.Compare(a.networkInstance, b.networkInstance), cmp.Compare(a.kind, b.kind), cmp.Compare(a.key, b.key))
+}
+
+// RIB is a model of the gRIBI RIB of a device, holding the next hop, next hop group, IPv4,
+// IPv6 and MPLS entries of every network instance.
+type RIB struct {
+	entries map[ribKey]*gpb.AFTEntry
+}
+
+// NewRIB returns an empty RIB.
+func NewRIB() *RIB {
+	return &RIB{entries: map[ribKey]*gpb.AFTEntry{}}
+}
+
+// RIBFromGetResponse returns the RIB of the entries of a Get RPC response. The RIB and FIB
+// status of the entries is dropped.
+func RIBFromGetResponse(resp *gpb.GetResponse) (*RIB, error) {
+	r := NewRIB()
+	for _, e := range resp.GetEntry() {
+		e = proto.Clone(e).(*gpb.AFTEntry)
+		e.RibStatus, e.FibStatus = gpb.AFTEntry_UNAVAILABLE, gpb.AFTEntry_UNAVAILABLE
+		k, err := entryKey(e)
+		if err != nil {
+			return nil, err
+		}
+		r.entries[k] = e
+	}
+	return r, nil
+}
+
+// entryKey returns the key of an entry.
+func entryKey(e *gpb.AFTEntry) (ribKey, error) {
+	k := ribKey{networkInstance: e.GetNetworkInstance()}
+	switch v := e.GetEntry().(type) {
+	case *gpb.AFTEntry_NextHop:
+		k.kind, k.key = kindNextHop, strconv.FormatUint(v.NextHop.GetIndex(), 10)
+	case *gpb.AFTEntry_NextHopGroup:
+		k.kind, k.key = kindNextHopGroup, strconv.FormatUint(v.NextHopGroup.GetId(), 10)
+	case *gpb.AFTEntry_Ipv4:
+		k.kind, k.key = kindIPv4, v.Ipv4.GetPrefix()
+	case *gpb.AFTEntry_Ipv6:
+		k.kind, k.key = kindIPv6, v.Ipv6.GetPrefix()
+	case *gpb.AFTEntry_Mpls:
+		k.kind, k.key = kindMPLS, labelKey(v.Mpls)
+	default:
+		return ribKey{}, fmt.Errorf("unsupported gRIBI entry %v", e)
+	}
+	return k, nil
+}
+
+func labelKey(l *aftpb.Afts_LabelEntryKey) string {
+	switch v := l.GetLabel().(type) {
+	case *aftpb.Afts_LabelEntryKey_LabelUint64:
+		return strconv.FormatUint(v.LabelUint64, 10)
+	case *aftpb.Afts_LabelEntryKey_LabelOpenconfigmplstypesmplslabelenum:
+		return v.LabelOpenconfigmplstypesmplslabelenum.String()
+	}
+	return ""
+}
+
+// operationEntry returns the entry an operation adds, replaces or deletes.
+func operationEntry(op *gpb.AFTOperation) (*gpb.AFTEntry, error) {
+	e := &gpb.AFTEntry{NetworkInstance: op.GetNetworkInstance()}
+	switch v := op.GetEntry().(type) {
+	case *gpb.AFTOperation_NextHop:
+		e.Entry = &gpb.AFTEntry_NextHop{NextHop: v.NextHop}
+	case *gpb.AFTOperation_NextHopGroup:
+		e.Entry = &gpb.AFTEntry_NextHopGroup{NextHopGroup: v.NextHopGroup}
+	case *gpb.AFTOperation_Ipv4:
+		e.Entry = &gpb.AFTEntry_Ipv4{Ipv4: v.Ipv4}
+	case *gpb.AFTOperation_Ipv6:
+		e.Entry = &gpb.AFTEntry_Ipv6{Ipv6: v.Ipv6}
+	case *gpb.AFTOperation_Mpls:
+		e.Entry = &gpb.AFTEntry_Mpls{Mpls: v.Mpls}
+	default:
+		return nil, fmt.Errorf("unsupported gRIBI operation %v", op)
+	}
+	return e, nil
+}
+
+// apply applies an acknowledged operation to the RIB.
+func (r *RIB) apply(op *gpb.AFTOperation) error {
+	e, err := operationEntry(op)
+	if err != nil {
+		return err
+	}
+	k, err := entryKey(e)
+	if err != nil {
+		return err
+	}
+	switch op.GetOp() {
+	case gpb.AFTOperation_ADD, gpb.AFTOperation_REPLACE:
+		r.entries[k] = e
+	case gpb.AFTOperation_DELETE:
+		delete(r.entries, k)
+	default:
+		return fmt.Errorf("unsupported operation type %v", op.GetOp())
+	}
+	return nil
+}
+
+// flush removes the entries of a network instance, or of all network instances if
+// networkInstance is empty.
+func (r *RIB) flush(networkInstance string) {
+	maps.DeleteFunc(r.entries, func(k ribKey, _ *gpb.AFTEntry) bool {
+		return networkInstance == "" || k.networkInstance == networkInstance
+	})
+}
+
+// Len returns the number of entries in the RIB.
+func (r *RIB) Len() int {
+	return len(r.entries)
+}
+
+// Lookup returns the entry of the RIB with the network instance and key of e.
+func (r *RIB) Lookup(e fluent.GRIBIEntry) (*gpb.AFTEntry, bool, error) {
+	ep, err := e.EntryProto()
+	if err != nil {
+		return nil, false, err
+	}
+	k, err := entryKey(ep)
+	if err != nil {
+		return nil, false, err
+	}
+	got, ok := r.entries[k]
+	return got, ok, nil
+}
+
+// Entries returns the entries of the RIB sorted by network instance, AFT and key.
+func (r *RIB) Entries() []*gpb.AFTEntry {
+	var out []*gpb.AFTEntry
+	for _, k := range slices.SortedFunc(maps.Keys(r.entries), compareKeys) {
+		out = append(out, r.entries[k])
+	}
+	return out
+}
+
+// Dump writes the entries of the RIB to w, one text proto per line.
+func (r *RIB) Dump(w io.Writer) error {
+	for _, e := range r.Entries() {
+		b, err := prototext.MarshalOptions{}.Marshal(e)
+		if err != nil {
+			return err
+		}
+		if _, err := fmt.Fprintf(w, "%s\n", b); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// EntryChange is an entry present in two RIBs with different contents.
+type EntryChange struct {
+	Want *gpb.AFTEntry
+	Got  *gpb.AFTEntry
+}
+
+// RIBDiff contains the differences between an expected and an actual RIB.
+type RIBDiff struct {
+	// Missing contains the expected entries not in the actual RIB.
+	Missing []*gpb.AFTEntry
+	// Extra contains the entries of the actual RIB that were not expected.
+	Extra []*gpb.AFTEntry
+	// Changed contains the entries with different contents.
+	Changed []EntryChange
+}
+
+// Empty reports whether the RIBs are identical.
+func (d *RIBDiff) Empty() bool {
+	return len(d.Missing)+len(d.Extra)+len(d.Changed) == 0
+}
+
+// String returns a summary of the differences with up to 10 examples of each.
+func (d *RIBDiff) String() string {
+	const maxSample = 10
+	if d.Empty() {
+		return "RIBs are identical"
+	}
+	var b strings.Builder
+	sample := func(name string, entries []*gpb.AFTEntry) {
+		if len(entries) == 0 {
+			return
+		}
+		fmt.Fprintf(&b, "%d %s entries:\n", len(entries), name)
+		for _, e := range entries[:min(len(entries), maxSample)] {
+			fmt.Fprintf(&b, "  %s\n", prototext.MarshalOptions{}.Format(e))
+		}
+	}
+	sample("missing", d.Missing)
+	sample("extra", d.Extra)
+	if len(d.Changed) > 0 {
+		fmt.Fprintf(&b, "%d changed entries:\n", len(d.Changed))
+		for _, c := range d.Changed[:min(len(d.Changed), maxSample)] {
+			fmt.Fprintf(&b, "  want %s\n  got  %s\n", prototext.MarshalOptions{}.Format(c.Want), prototext.MarshalOptions{}.Format(c.Got))
+		}
+	}
+	return b.String()
+}
+
+// DiffRIB returns the differences of the got RIB from the want RIB.
+func DiffRIB(want, got *RIB) *RIBDiff {
+	d := &RIBDiff{}
+	for _, k := range slices.SortedFunc(maps.Keys(want.entries), compareKeys) {
+		g, ok := got.entries[k]
+		switch {
+		case !ok:
+			d.Missing = append(d.Missing, want.entries[k])
+		case !proto.Equal(want.entries[k], g):
+			d.Changed = append(d.Changed, EntryChange{Want: want.entries[k], Got: g})
+		}
+	}
+	for _, k := range slices.SortedFunc(maps.Keys(got.entries), compareKeys) {
+		if _, ok := want.entries[k]; !ok {
+			d.Extra = append(d.Extra, got.entries[k])
+		}
+	}
+	return d
+}
+
+// recorder tracks the operations a Client sends and applies them to its RIB model once the
+// server acknowledges them.
+type recorder struct {
+	rib         *RIB
+	opCount     uint64                       // Mirror of the operation IDs assigned by the fluent client.
+	pending     map[uint64]*gpb.AFTOperation // Operations without a result, keyed by ID.
+	resultsSeen int                          // Number of results of the fluent client processed.
+	err         error                        // First inconsistency between operations and results.
+}
+
+func newRecorder() *recorder {
+	return &recorder{rib: NewRIB(), pending: map[uint64]*gpb.AFTOperation{}}
+}
+
+// reset prepares the recorder for a new fluent client, whose operation IDs restart at 1.
+// Entries of a session without persistence are removed by the server when it ends.
+func (r *recorder) reset(persistence bool) {
+	if !persistence {
+		r.rib.flush("")
+	}
+	r.opCount, r.resultsSeen = 0, 0
+	clear(r.pending)
+}
+
+// record records operations sent with the fluent client in the same order.
+func (r *recorder) record(op gpb.AFTOperation_Operation, entries []fluent.GRIBIEntry) {
+	for _, e := range entries {
+		ep, err := e.OpProto()
+		if err != nil {
+			// The fluent client fails the test on the same error.
+			continue
+		}
+		ep.Op = op
+		r.opCount++
+		ep.Id = r.opCount
+		r.pending[ep.Id] = ep
+	}
+}
+
+// skip accounts for n operations sent with the fluent client that are not recorded.
+func (r *recorder) skip(n int) {
+	r.opCount += uint64(n)
+}
+
+// process applies the operations acknowledged by new results to the RIB. An operation is
+// applied on its first RIB_PROGRAMMED, FIB_PROGRAMMED or FIB_FAILED result, since in all of
+// them the entry is in the RIB, and dropped on FAILED.
+func (r *recorder) process(results []*client.OpResult) {
+	if len(results) < r.resultsSeen {
+		r.resultsSeen = 0
+	}
+	for _, res := range results[r.resultsSeen:] {
+		op, ok := r.pending[res.OperationID]
+		if res.OperationID == 0 || !ok {
+			continue
+		}
+		if !detailsMatch(op, res.Details) {
+			if r.err == nil {
+				r.err = fmt.Errorf("result %v does not match recorded operation %v; operations were sent outside of gribi.Client", res, op)
+			}
+			continue
+		}
+		switch res.ProgrammingResult {
+		case gpb.AFTResult_RIB_PROGRAMMED, gpb.AFTResult_FIB_PROGRAMMED, gpb.AFTResult_FIB_FAILED:
+			if err := r.rib.apply(op); err != nil && r.err == nil {
+				r.err = err
+			}
+		case gpb.AFTResult_FAILED:
+		default:
+			continue
+		}
+		delete(r.pending, res.OperationID)
+	}
+	r.resultsSeen = len(results)
+}
+
+// detailsMatch reports whether the details of a result describe the operation.
+func detailsMatch(op *gpb.AFTOperation, d *client.OpDetailsResults) bool {
+	if d == nil {
+		return true
+	}
+	wantType := map[gpb.AFTOperation_Operation]constants.OpType{
+		gpb.AFTOperation_ADD:     constants.Add,
+		gpb.AFTOperation_REPLACE: constants.Replace,
+		gpb.AFTOperation_DELETE:  constants.Delete,
+	}[op.GetOp()]
+	if d.Type != wantType {
+		return false
+	}
+	switch v := op.GetEntry().(type) {
+	case *gpb.AFTOperation_NextHop:
+		return d.NextHopIndex == v.NextHop.GetIndex()
+	case *gpb.AFTOperation_NextHopGroup:
+		return d.NextHopGroupID == v.NextHopGroup.GetId()
+	case *gpb.AFTOperation_Ipv4:
+		return d.IPv4Prefix == v.Ipv4.GetPrefix()
+	case *gpb.AFTOperation_Ipv6:
+		return d.IPv6Prefix == v.Ipv6.GetPrefix()
+	case *gpb.AFTOperation_Mpls:
+		return labelKey(v.Mpls) == strconv.FormatUint(d.MPLSLabel, 10) || d.MPLSLabel == 0
+	}
+	return true
+}
@@ -0,0 +1,147 @@
+// Copyright 2026 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package gribi
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/openconfig/gribigo/client"
+	"github.com/openconfig/gribigo/constants"
+	"github.com/openconfig/gribigo/fluent"
+
+	gpb "github.com/openconfig/gribi/v1/proto/service"
+)
+
+func TestRecorder(t *testing.T) {
+	nh, _ := NHEntry(1, "192.0.2.1", "DEFAULT", fluent.InstalledInRIB)
+	nhg, _ := NHGEntry(10, map[uint64]uint64{1: 1}, "DEFAULT", fluent.InstalledInRIB)
+	v4 := fluent.IPv4Entry().WithPrefix("198.51.100.0/24").WithNetworkInstance("VRF-A").WithNextHopGroup(10).WithNextHopGroupNetworkInstance("DEFAULT")
+	failed := fluent.IPv4Entry().WithPrefix("203.0.113.0/24").WithNetworkInstance("VRF-A").WithNextHopGroup(11)
+
+	r := newRecorder()
+	r.record(gpb.AFTOperation_ADD, []fluent.GRIBIEntry{nh, nhg, v4, failed})
+	results := []*client.OpResult{
+		{OperationID: 1, ProgrammingResult: gpb.AFTResult_FIB_PROGRAMMED, Details: &client.OpDetailsResults{Type: constants.Add, NextHopIndex: 1}},
+		{OperationID: 2, ProgrammingResult: gpb.AFTResult_FIB_PROGRAMMED, Details: &client.OpDetailsResults{Type: constants.Add, NextHopGroupID: 10}},
+		{OperationID: 3, ProgrammingResult: gpb.AFTResult_RIB_PROGRAMMED, Details: &client.OpDetailsResults{Type: constants.Add, IPv4Prefix: "198.51.100.0/24"}},
+		{OperationID: 4, ProgrammingResult: gpb.AFTResult_FAILED, Details: &client.OpDetailsResults{Type: constants.Add, IPv4Prefix: "203.0.113.0/24"}},
+	}
+	r.process(results)
+	if r.err != nil {
+		t.Fatalf("process() got error: %v", r.err)
+	}
+	if got := r.rib.Len(); got != 3 {
+		t.Fatalf("RIB has %d entries after adds, want 3", got)
+	}
+	if _, ok, err := r.rib.Lookup(failed); err != nil || ok {
+		t.Errorf("Lookup() of failed entry got (%v, %v), want (false, nil)", ok, err)
+	}
+	if len(r.pending) != 0 {
+		t.Errorf("got %d pending operations, want 0", len(r.pending))
+	}
+
+	// The late FIB_PROGRAMMED result of operation 3 must not apply it twice.
+	r.record(gpb.AFTOperation_DELETE, []fluent.GRIBIEntry{v4})
+	results = append(results,
+		&client.OpResult{OperationID: 3, ProgrammingResult: gpb.AFTResult_FIB_PROGRAMMED, Details: &client.OpDetailsResults{Type: constants.Add, IPv4Prefix: "198.51.100.0/24"}},
+		&client.OpResult{OperationID: 5, ProgrammingResult: gpb.AFTResult_RIB_PROGRAMMED, Details: &client.OpDetailsResults{Type: constants.Delete, IPv4Prefix: "198.51.100.0/24"}},
+	)
+	r.process(results)
+	if _, ok, _ := r.rib.Lookup(v4); ok {
+		t.Errorf("Lookup() of deleted entry found it")
+	}
+
+	// A result that does not match the recorded operation means IDs were consumed elsewhere.
+	r.record(gpb.AFTOperation_ADD, []fluent.GRIBIEntry{v4})
+	r.process(append(results, &client.OpResult{OperationID: 6, ProgrammingResult: gpb.AFTResult_RIB_PROGRAMMED, Details: &client.OpDetailsResults{Type: constants.Add, NextHopIndex: 7}}))
+	if r.err == nil {
+		t.Errorf("process() of mismatched result got no error")
+	}
+
+	r.reset(false)
+	if r.rib.Len() != 0 || r.opCount != 0 {
+		t.Errorf("reset(false) got %d entries and op count %d, want 0 and 0", r.rib.Len(), r.opCount)
+	}
+}
+
+func TestDiffRIB(t *testing.T) {
+	add := func(r *RIB, entries ...fluent.GRIBIEntry) {
+		t.Helper()
+		for _, e := range entries {
+			op, err := e.OpProto()
+			if err != nil {
+				t.Fatalf("OpProto() failed: %v", err)
+			}
+			op.Op = gpb.AFTOperation_ADD
+			if err := r.apply(op); err != nil {
+				t.Fatalf("apply() failed: %v", err)
+			}
+		}
+	}
+	nh, _ := NHEntry(1, "192.0.2.1", "DEFAULT", fluent.InstalledInRIB)
+	nhg, _ := NHGEntry(10, map[uint64]uint64{1: 1}, "DEFAULT", fluent.InstalledInRIB)
+	v4 := fluent.IPv4Entry().WithPrefix("198.51.100.0/24").WithNetworkInstance("DEFAULT").WithNextHopGroup(10)
+	v6 := fluent.IPv6Entry().WithPrefix("2001:db8::/32").WithNetworkInstance("DEFAULT").WithNextHopGroup(10)
+	otherNH, _ := NHEntry(1, "192.0.2.2", "DEFAULT", fluent.InstalledInRIB)
+	label := fluent.LabelEntry().WithLabel(100).WithNetworkInstance("DEFAULT").WithNextHopGroup(10)
+
+	want := NewRIB()
+	add(want, nh, nhg, v4, label)
+
+	// Entries returned by Get carry their programming status, which is not compared.
+	resp := &gpb.GetResponse{}
+	for _, e := range []fluent.GRIBIEntry{otherNH, nhg, v6, label} {
+		ep, err := e.EntryProto()
+		if err != nil {
+			t.Fatalf("EntryProto() failed: %v", err)
+		}
+		ep.RibStatus, ep.FibStatus = gpb.AFTEntry_PROGRAMMED, gpb.AFTEntry_PROGRAMMED
+		resp.Entry = append(resp.Entry, ep)
+	}
+	got, err := RIBFromGetResponse(resp)
+	if err != nil {
+		t.Fatalf("RIBFromGetResponse() failed: %v", err)
+	}
+
+	d := DiffRIB(want, got)
+	if len(d.Missing) != 1 || d.Missing[0].GetIpv4().GetPrefix() != "198.51.100.0/24" {
+		t.Errorf("DiffRIB() got missing %v, want 198.51.100.0/24", d.Missing)
+	}
+	if len(d.Extra) != 1 || d.Extra[0].GetIpv6().GetPrefix() != "2001:db8::/32" {
+		t.Errorf("DiffRIB() got extra %v, want 2001:db8::/32", d.Extra)
+	}
+	if len(d.Changed) != 1 || d.Changed[0].Want.GetNextHop().GetIndex() != 1 {
+		t.Errorf("DiffRIB() got changed %v, want next hop 1", d.Changed)
+	}
+	if d.Empty() || !strings.Contains(d.String(), "1 missing entries") {
+		t.Errorf("DiffRIB().String() got %q", d.String())
+	}
+	if d := DiffRIB(want, want); !d.Empty() {
+		t.Errorf("DiffRIB() of identical RIBs got %v", d)
+	}
+
+	var b strings.Builder
+	if err := want.Dump(&b); err != nil {
+		t.Fatalf("Dump() failed: %v", err)
+	}
+	if lines := strings.Count(b.String(), "\n"); lines != 4 {
+		t.Errorf("Dump() wrote %d lines, want 4:\n%s", lines, b.String())
+	}
+	want.flush("DEFAULT")
+	if want.Len() != 0 {
+		t.Errorf("flush() left %d entries", want.Len())
+	}
+}
@@ -27,3 +27,12 @@ func Init() error {
 func Get() *mpb.Metadata {
 	return md
 }
+
+// Set replaces the metadata for the current test, e.g. so that deviations are looked up in a
+// chosen metadata when a test runs without a metadata file. It returns the metadata it
+// replaces.
+func Set(m *mpb.Metadata) *mpb.Metadata {
+	old := md
+	md = m
+	return old
+}
@@ -22,3 +22,15 @@ func TestInit(t *testing.T) {
 		t.Errorf("Init() got unexpected metadata diff: %s", diff)
 	}
 }
+
+func TestSet(t *testing.T) {
+	want := &mpb.Metadata{PlanId: "TestSet"}
+	old := Set(want)
+	defer Set(old)
+	if got := Get(); got != want {
+		t.Errorf("Get() after Set() got %v, want %v", got, want)
+	}
+	if got := Set(old); got != want {
+		t.Errorf("Set() got previous metadata %v, want %v", got, want)
+	}
+}
@@ -0,0 +1,319 @@
+// Copyright 2026 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package samplestream
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"sort"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/openconfig/featureprofiles/internal/telemetry/schema"
+	"github.com/openconfig/ondatra"
+	"github.com/openconfig/ygnmi/ygnmi"
+	"github.com/openconfig/ygot/ygot"
+
+	ocpb "github.com/openconfig/featureprofiles/proto/ocpaths_go_proto"
+	gpb "github.com/openconfig/gnmi/proto/gnmi"
+)
+
+// Expectation is the expected streaming behavior of a telemetry path.
+type Expectation struct {
+	// Path is the OpenConfig path to subscribe to, e.g.
+	// "/interfaces/interface/state/counters/in-octets".
+	Path string
+	// Mode is the stream mode of the subscription, either SAMPLE or ON_CHANGE.
+	Mode gpb.SubscriptionMode
+	// Interval is the requested sample interval for SAMPLE subscriptions.
+	Interval time.Duration
+}
+
+func (e *Expectation) String() string {
+	if e.Mode == gpb.SubscriptionMode_SAMPLE {
+		return fmt.Sprintf("%s (SAMPLE %v)", e.Path, e.Interval)
+	}
+	return fmt.Sprintf("%s (%v)", e.Path, e.Mode)
+}
+
+// ExpectationsFromOCPaths returns the expectations declared by the GNMIRpc of each
+// OCPath that requires STREAM subscriptions. A path requiring both SAMPLE and ON_CHANGE
+// results in two expectations. SAMPLE paths without sample_interval_nanoseconds use
+// defaultInterval.
+func ExpectationsFromOCPaths(paths []*ocpb.OCPath, defaultInterval time.Duration) []*Expectation {
+	var exps []*Expectation
+	for _, p := range paths {
+		rpc := p.GetGnmiRpc()
+		if !rpc.GetSubscribe() && !slices.Contains(rpc.GetSubMode(), ocpb.GNMIRpc_STREAM) {
+			continue
+		}
+		for _, mode := range rpc.GetStreamMode() {
+			switch mode {
+			case ocpb.GNMIRpc_SAMPLE:
+				interval := time.Duration(rpc.GetSampleIntervalNanoseconds())
+				if interval == 0 {
+					interval = defaultInterval
+				}
+				exps = append(exps, &Expectation{Path: p.GetName(), Mode: gpb.SubscriptionMode_SAMPLE, Interval: interval})
+			case ocpb.GNMIRpc_ON_CHANGE:
+				exps = append(exps, &Expectation{Path: p.GetName(), Mode: gpb.SubscriptionMode_ON_CHANGE})
+			}
+		}
+	}
+	return exps
+}
+
+// Bounds are the tolerances a DUT must meet for its updates to be compliant.
+type Bounds struct {
+	// Jitter is the allowed deviation of the time between two SAMPLE updates from the
+	// requested interval.
+	Jitter time.Duration
+	// MaxLatency is the maximum allowed time between the notification timestamp of an
+	// update and the time the test received it. Zero disables the latency check.
+	MaxLatency time.Duration
+}
+
+// DefaultBounds are the bounds used when CheckCompliance is called with nil bounds.
+var DefaultBounds = &Bounds{
+	Jitter:     intervalTolerance,
+	MaxLatency: 5 * time.Second,
+}
+
+// PathCompliance is the result of checking the updates received for one leaf.
+type PathCompliance struct {
+	// Expectation is the expectation whose subscription produced the updates.
+	Expectation *Expectation
+	// Path is the leaf path the updates were received for.
+	Path string
+	// Updates is the number of updates received after the initial synchronization.
+	Updates int
+	// Timing summarizes the intervals between updates. It is only set for SAMPLE.
+	Timing *Timing
+	// Latency summarizes the notification-to-receive latency of updates in seconds.
+	Latency *Stats
+	// Violations describes every way in which the updates were not compliant.
+	Violations []string
+}
+
+// ComplianceReport is the per-path result of CheckCompliance.
+type ComplianceReport struct {
+	// Duration is the time the subscriptions were observed for.
+	Duration time.Duration
+	// Paths contains the result for every leaf, sorted by path and mode.
+	Paths []*PathCompliance
+	// Missing contains expectations for which no update was received at all.
+	Missing []*Expectation
+}
+
+// Violations returns the results which are not compliant.
+func (r *ComplianceReport) Violations() []*PathCompliance {
+	var out []*PathCompliance
+	for _, p := range r.Paths {
+		if len(p.Violations) > 0 {
+			out = append(out, p)
+		}
+	}
+	return out
+}
+
+// String returns a table of the per-path results.
+func (r *ComplianceReport) String() string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "Telemetry compliance over %v: %d paths, %d with violations, %d missing\n", r.Duration, len(r.Paths), len(r.Violations()), len(r.Missing))
+	for _, p := range r.Paths {
+		status := "OK"
+		if len(p.Violations) > 0 {
+			status = strings.Join(p.Violations, "; ")
+		}
+		fmt.Fprintf(&b, "  %s %v: updates=%d", p.Path, p.Expectation.Mode, p.Updates)
+		if p.Timing != nil {
+			fmt.Fprintf(&b, " gap[min=%v max=%v mean=%v]", p.Timing.MinGap, p.Timing.MaxGap, p.Timing.MeanGap)
+		}
+		if p.Latency.Count > 0 {
+			fmt.Fprintf(&b, " latency[max=%.3fs p99=%.3fs]", p.Latency.Max, p.Latency.Percentile(99))
+		}
+		fmt.Fprintf(&b, " %s\n", status)
+	}
+	for _, e := range r.Missing {
+		fmt.Fprintf(&b, "  %v: no updates received\n", e)
+	}
+	return b.String()
+}
+
+// update is a single update received for a leaf.
+type update struct {
+	ts, recv time.Time
+	synced   bool // Whether the update was received after the sync_response.
+}
+
+// evaluate checks the updates received for one leaf against the expectation.
+func evaluate(exp *Expectation, path string, updates []update, bounds *Bounds) *PathCompliance {
+	pc := &PathCompliance{Expectation: exp, Path: path}
+	var vals []*ygnmi.Value[time.Duration]
+	for _, u := range updates {
+		// Initial ON_CHANGE updates carry the time of the last change, so only updates
+		// after the sync_response say anything about latency.
+		if exp.Mode == gpb.SubscriptionMode_ON_CHANGE && !u.synced {
+			continue
+		}
+		pc.Updates++
+		v := &ygnmi.Value[time.Duration]{Timestamp: u.ts, RecvTimestamp: u.recv}
+		vals = append(vals, v.SetVal(u.recv.Sub(u.ts)))
+	}
+	pc.Latency = computeStats(vals, func(d time.Duration) float64 { return d.Seconds() })
+	if bounds.MaxLatency > 0 && pc.Latency.Count > 0 && pc.Latency.Max > bounds.MaxLatency.Seconds() {
+		pc.Violations = append(pc.Violations, fmt.Sprintf("max latency %.3fs exceeds %v", pc.Latency.Max, bounds.MaxLatency))
+	}
+	if exp.Mode != gpb.SubscriptionMode_SAMPLE {
+		return pc
+	}
+	pc.Timing = computeTiming(vals, exp.Interval)
+	switch {
+	case len(pc.Timing.Gaps) == 0:
+		pc.Violations = append(pc.Violations, fmt.Sprintf("got %d updates, want at least 2 to measure the interval", pc.Updates))
+	case pc.Timing.MaxGap > exp.Interval+bounds.Jitter:
+		pc.Violations = append(pc.Violations, fmt.Sprintf("max interval %v exceeds %v+%v", pc.Timing.MaxGap, exp.Interval, bounds.Jitter))
+	}
+	if len(pc.Timing.Gaps) > 0 && pc.Timing.MinGap < exp.Interval-bounds.Jitter {
+		pc.Violations = append(pc.Violations, fmt.Sprintf("min interval %v is below %v-%v", pc.Timing.MinGap, exp.Interval, bounds.Jitter))
+	}
+	if n := len(pc.Timing.Regressions); n > 0 {
+		pc.Violations = append(pc.Violations, fmt.Sprintf("%d timestamp regressions", n))
+	}
+	return pc
+}
+
+// collect subscribes to the expectation for the duration and returns the updates
+// received, keyed by leaf path.
+func collect(ctx context.Context, gnmiClient gpb.GNMIClient, exp *Expectation) (map[string][]update, error) {
+	path, err := ygot.StringToStructuredPath(exp.Path)
+	if err != nil {
+		return nil, fmt.Errorf("invalid path %q: %v", exp.Path, err)
+	}
+	sub, err := gnmiClient.Subscribe(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("error in Subscribe(): %v", err)
+	}
+	req := &gpb.SubscribeRequest{Request: &gpb.SubscribeRequest_Subscribe{Subscribe: &gpb.SubscriptionList{
+		Prefix:   &gpb.Path{Origin: "openconfig"},
+		Mode:     gpb.SubscriptionList_STREAM,
+		Encoding: gpb.Encoding_PROTO,
+		Subscription: []*gpb.Subscription{{
+			Path:           path,
+			Mode:           exp.Mode,
+			SampleInterval: uint64(exp.Interval.Nanoseconds()),
+		}},
+	}}}
+	if err := sub.Send(req); err != nil {
+		return nil, fmt.Errorf("error sending subscribe request %v: %v", req, err)
+	}
+	updates := map[string][]update{}
+	synced := false
+	for {
+		resp, err := sub.Recv()
+		if err != nil {
+			if ctx.Err() != nil {
+				return updates, nil
+			}
+			return updates, fmt.Errorf("error from gNMI stream: %v", err)
+		}
+		recv := time.Now()
+		if resp.GetSyncResponse() {
+			synced = true
+			continue
+		}
+		n := resp.GetUpdate()
+		if n == nil {
+			continue
+		}
+		ts := time.Unix(0, n.GetTimestamp())
+		for _, pt := range schema.NotificationToPoints(n) {
+			p, err := ygot.PathToString(pt.Path)
+			if err != nil {
+				p = pt.Path.String()
+			}
+			updates[p] = append(updates[p], update{ts: ts, recv: recv, synced: synced})
+		}
+	}
+}
+
+// CheckCompliance subscribes to every expectation for the given duration, measures the
+// intervals between updates and their notification-to-receive latency, and returns a
+// per-path report of how the DUT complied with the expectations. All subscriptions are
+// observed concurrently. If bounds is nil, DefaultBounds is used.
+func CheckCompliance(t testing.TB, dut *ondatra.DUTDevice, exps []*Expectation, duration time.Duration, bounds *Bounds) (*ComplianceReport, error) {
+	t.Helper()
+	if bounds == nil {
+		bounds = DefaultBounds
+	}
+	gnmiClient := dut.RawAPIs().GNMI(t)
+	ctx, cancel := context.WithTimeout(context.Background(), duration)
+	defer cancel()
+
+	results := make([]map[string][]update, len(exps))
+	errs := make([]error, len(exps))
+	var wg sync.WaitGroup
+	for i, exp := range exps {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			results[i], errs[i] = collect(ctx, gnmiClient, exp)
+		}()
+	}
+	wg.Wait()
+
+	report := &ComplianceReport{Duration: duration}
+	for i, exp := range exps {
+		if errs[i] != nil {
+			return nil, fmt.Errorf("subscription to %v failed: %v", exp, errs[i])
+		}
+		if len(results[i]) == 0 {
+			report.Missing = append(report.Missing, exp)
+			continue
+		}
+		for path, updates := range results[i] {
+			report.Paths = append(report.Paths, evaluate(exp, path, updates, bounds))
+		}
+	}
+	sort.Slice(report.Paths, func(i, j int) bool {
+		if report.Paths[i].Path != report.Paths[j].Path {
+			return report.Paths[i].Path < report.Paths[j].Path
+		}
+		return report.Paths[i].Expectation.Mode < report.Paths[j].Expectation.Mode
+	})
+	return report, nil
+}
+
+// Compliant calls CheckCompliance, logs the report and reports an error for every
+// expectation without updates and every path whose updates violate the bounds.
+func Compliant(t testing.TB, dut *ondatra.DUTDevice, exps []*Expectation, duration time.Duration, bounds *Bounds) *ComplianceReport {
+	t.Helper()
+	report, err := CheckCompliance(t, dut, exps, duration, bounds)
+	if err != nil {
+		t.Errorf("Failed to check telemetry compliance: %v", err)
+		return nil
+	}
+	t.Log(report)
+	for _, e := range report.Missing {
+		t.Errorf("%v: no updates received within %v", e, duration)
+	}
+	for _, p := range report.Violations() {
+		t.Errorf("%s %v: %s", p.Path, p.Expectation.Mode, strings.Join(p.Violations, "; "))
+	}
+	return report
+}
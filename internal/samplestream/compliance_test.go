@@ -0,0 +1,108 @@
+// Copyright 2026 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package samplestream
+
+import (
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+
+	ocpb "github.com/openconfig/featureprofiles/proto/ocpaths_go_proto"
+	gpb "github.com/openconfig/gnmi/proto/gnmi"
+)
+
+func TestExpectationsFromOCPaths(t *testing.T) {
+	paths := []*ocpb.OCPath{{
+		Name: "/interfaces/interface/state/counters/in-octets",
+		GnmiRpc: &ocpb.GNMIRpc{
+			Subscribe:                 true,
+			StreamMode:                []ocpb.GNMIRpc_StreamMode{ocpb.GNMIRpc_SAMPLE, ocpb.GNMIRpc_ON_CHANGE},
+			SampleIntervalNanoseconds: uint64(10 * time.Second),
+		},
+	}, {
+		Name:    "/system/state/hostname",
+		GnmiRpc: &ocpb.GNMIRpc{Subscribe: true, StreamMode: []ocpb.GNMIRpc_StreamMode{ocpb.GNMIRpc_SAMPLE}},
+	}, {
+		Name:    "/system/config/hostname",
+		GnmiRpc: &ocpb.GNMIRpc{Get: true, Set: true},
+	}}
+	got := ExpectationsFromOCPaths(paths, 30*time.Second)
+	want := []*Expectation{
+		{Path: "/interfaces/interface/state/counters/in-octets", Mode: gpb.SubscriptionMode_SAMPLE, Interval: 10 * time.Second},
+		{Path: "/interfaces/interface/state/counters/in-octets", Mode: gpb.SubscriptionMode_ON_CHANGE},
+		{Path: "/system/state/hostname", Mode: gpb.SubscriptionMode_SAMPLE, Interval: 30 * time.Second},
+	}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("ExpectationsFromOCPaths() diff (-want +got):\n%s", diff)
+	}
+}
+
+func TestEvaluate(t *testing.T) {
+	start := time.Unix(1000, 0)
+	at := func(offset, latency time.Duration, synced bool) update {
+		return update{ts: start.Add(offset), recv: start.Add(offset + latency), synced: synced}
+	}
+	bounds := &Bounds{Jitter: time.Second, MaxLatency: 2 * time.Second}
+	sample := &Expectation{Path: "/p", Mode: gpb.SubscriptionMode_SAMPLE, Interval: 10 * time.Second}
+	onChange := &Expectation{Path: "/p", Mode: gpb.SubscriptionMode_ON_CHANGE}
+
+	tests := []struct {
+		desc           string
+		exp            *Expectation
+		updates        []update
+		wantUpdates    int
+		wantViolations int
+	}{{
+		desc:        "compliant sample",
+		exp:         sample,
+		updates:     []update{at(0, 0, false), at(10*time.Second, 0, true), at(20500*time.Millisecond, 0, true)},
+		wantUpdates: 3,
+	}, {
+		desc:           "late sample",
+		exp:            sample,
+		updates:        []update{at(0, 0, false), at(15*time.Second, 0, true)},
+		wantUpdates:    2,
+		wantViolations: 1,
+	}, {
+		desc:           "early sample with high latency",
+		exp:            sample,
+		updates:        []update{at(0, 0, false), at(5*time.Second, 3*time.Second, true)},
+		wantUpdates:    2,
+		wantViolations: 2,
+	}, {
+		desc:        "on change ignores initial updates",
+		exp:         onChange,
+		updates:     []update{at(0, time.Hour, false), at(time.Minute, time.Second, true)},
+		wantUpdates: 1,
+	}, {
+		desc:           "late on change",
+		exp:            onChange,
+		updates:        []update{at(0, 0, false), at(time.Minute, 3*time.Second, true)},
+		wantUpdates:    1,
+		wantViolations: 1,
+	}}
+	for _, tc := range tests {
+		t.Run(tc.desc, func(t *testing.T) {
+			pc := evaluate(tc.exp, "/p", tc.updates, bounds)
+			if pc.Updates != tc.wantUpdates {
+				t.Errorf("Updates got %d, want %d", pc.Updates, tc.wantUpdates)
+			}
+			if len(pc.Violations) != tc.wantViolations {
+				t.Errorf("Violations got %q, want %d violations", pc.Violations, tc.wantViolations)
+			}
+		})
+	}
+}
@@ -0,0 +1,126 @@
+// Copyright 2026 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package samplestream
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"testing"
+
+	"github.com/openconfig/featureprofiles/internal/fptest"
+	"github.com/openconfig/ygnmi/ygnmi"
+	"github.com/openconfig/ygot/ygot"
+)
+
+// exportedSample is the JSON representation of a sample.
+type exportedSample struct {
+	Path          string `json:"path"`
+	Timestamp     int64  `json:"timestamp"`
+	RecvTimestamp int64  `json:"recv_timestamp"`
+	Value         any    `json:"value"`
+}
+
+// exportValue converts a sample value to a JSON friendly representation. GoStructs are
+// rendered as IETF JSON and enums by name.
+func exportValue(v any) (any, error) {
+	switch val := v.(type) {
+	case ygot.GoStruct:
+		return ygot.ConstructIETFJSON(val, nil)
+	case ygot.GoEnum:
+		return val.String(), nil
+	}
+	return v, nil
+}
+
+func exportSamples[T any](vals []*ygnmi.Value[T]) ([]exportedSample, error) {
+	out := []exportedSample{}
+	for _, v := range vals {
+		val, _ := v.Val()
+		ev, err := exportValue(val)
+		if err != nil {
+			return nil, err
+		}
+		path, err := ygot.PathToString(v.Path)
+		if err != nil {
+			path = v.Path.String()
+		}
+		out = append(out, exportedSample{
+			Path:          path,
+			Timestamp:     v.Timestamp.UnixNano(),
+			RecvTimestamp: v.RecvTimestamp.UnixNano(),
+			Value:         ev,
+		})
+	}
+	return out, nil
+}
+
+func samplesCSV[T any](vals []*ygnmi.Value[T]) (string, error) {
+	samples, err := exportSamples(vals)
+	if err != nil {
+		return "", err
+	}
+	var buf bytes.Buffer
+	w := csv.NewWriter(&buf)
+	if err := w.Write([]string{"timestamp", "recv_timestamp", "path", "value"}); err != nil {
+		return "", err
+	}
+	for _, s := range samples {
+		val := fmt.Sprint(s.Value)
+		if m, ok := s.Value.(map[string]any); ok {
+			b, err := json.Marshal(m)
+			if err != nil {
+				return "", err
+			}
+			val = string(b)
+		}
+		rec := []string{strconv.FormatInt(s.Timestamp, 10), strconv.FormatInt(s.RecvTimestamp, 10), s.Path, val}
+		if err := w.Write(rec); err != nil {
+			return "", err
+		}
+	}
+	w.Flush()
+	return buf.String(), w.Error()
+}
+
+// WriteCSV writes all samples received thus far as CSV to a file in the test output
+// directory (see fptest.WriteOutput). Timestamps are in nanoseconds since the epoch.
+// It returns the filename relative to the output directory.
+func (s *SampleStream[T]) WriteCSV(t testing.TB, name string) (string, error) {
+	t.Helper()
+	content, err := samplesCSV(s.All())
+	if err != nil {
+		return "", err
+	}
+	return fptest.WriteOutput(t.Name()+" "+name, ".csv", content)
+}
+
+// WriteJSON writes all samples received thus far as a JSON array to a file in the test
+// output directory (see fptest.WriteOutput). It returns the filename relative to the
+// output directory.
+func (s *SampleStream[T]) WriteJSON(t testing.TB, name string) (string, error) {
+	t.Helper()
+	samples, err := exportSamples(s.All())
+	if err != nil {
+		return "", err
+	}
+	b, err := json.MarshalIndent(samples, "", "  ")
+	if err != nil {
+		return "", err
+	}
+	return fptest.WriteOutput(t.Name()+" "+name, ".json", string(b))
+}
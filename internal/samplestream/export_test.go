@@ -0,0 +1,137 @@
+// Copyright 2026 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package samplestream
+
+import (
+	"flag"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/openconfig/ondatra/gnmi/oc"
+	"github.com/openconfig/ygnmi/ygnmi"
+	"github.com/openconfig/ygot/ygot"
+
+	gpb "github.com/openconfig/gnmi/proto/gnmi"
+)
+
+// sample returns a sample of a state leaf of interface eth0 taken at offset after time.Unix(1000, 0)
+// and received a millisecond later.
+func sample(leaf string, offset time.Duration, val any) *ygnmi.Value[any] {
+	ts := time.Unix(1000, 0).Add(offset)
+	path := &gpb.Path{Elem: []*gpb.PathElem{
+		{Name: "interfaces"},
+		{Name: "interface", Key: map[string]string{"name": "eth0"}},
+		{Name: "state"},
+		{Name: leaf},
+	}}
+	v := &ygnmi.Value[any]{Path: path, Timestamp: ts, RecvTimestamp: ts.Add(time.Millisecond)}
+	return v.SetVal(val)
+}
+
+func TestExport(t *testing.T) {
+	outputs := t.TempDir()
+	if err := flag.Set("outputs_dir", outputs); err != nil {
+		t.Fatalf("Cannot set -outputs_dir: %v", err)
+	}
+	t.Cleanup(func() { flag.Set("outputs_dir", "") })
+
+	tests := []struct {
+		desc     string
+		vals     []*ygnmi.Value[any]
+		wantCSV  string
+		wantJSON string
+	}{{
+		desc:     "no samples",
+		wantCSV:  "timestamp,recv_timestamp,path,value\n",
+		wantJSON: `[]`,
+	}, {
+		desc: "numbers and enums",
+		vals: []*ygnmi.Value[any]{
+			sample("mtu", 0, uint16(1500)),
+			sample("oper-status", time.Second, oc.Interface_OperStatus_UP),
+		},
+		wantCSV: "timestamp,recv_timestamp,path,value\n" +
+			"1000000000000,1000001000000,/interfaces/interface[name=eth0]/state/mtu,1500\n" +
+			"1001000000000,1001001000000,/interfaces/interface[name=eth0]/state/oper-status,UP\n",
+		wantJSON: `[
+  {
+    "path": "/interfaces/interface[name=eth0]/state/mtu",
+    "timestamp": 1000000000000,
+    "recv_timestamp": 1000001000000,
+    "value": 1500
+  },
+  {
+    "path": "/interfaces/interface[name=eth0]/state/oper-status",
+    "timestamp": 1001000000000,
+    "recv_timestamp": 1001001000000,
+    "value": "UP"
+  }
+]`,
+	}, {
+		desc: "containers and quoting",
+		vals: []*ygnmi.Value[any]{
+			sample("counters", 0, &oc.Interface_Counters{InOctets: ygot.Uint64(5), OutOctets: ygot.Uint64(7)}),
+			sample("description", 0, `uplink, "to core"`),
+		},
+		wantCSV: "timestamp,recv_timestamp,path,value\n" +
+			`1000000000000,1000001000000,/interfaces/interface[name=eth0]/state/counters,"{""in-octets"":""5"",""out-octets"":""7""}"` + "\n" +
+			`1000000000000,1000001000000,/interfaces/interface[name=eth0]/state/description,"uplink, ""to core"""` + "\n",
+		wantJSON: `[
+  {
+    "path": "/interfaces/interface[name=eth0]/state/counters",
+    "timestamp": 1000000000000,
+    "recv_timestamp": 1000001000000,
+    "value": {
+      "in-octets": "5",
+      "out-octets": "7"
+    }
+  },
+  {
+    "path": "/interfaces/interface[name=eth0]/state/description",
+    "timestamp": 1000000000000,
+    "recv_timestamp": 1000001000000,
+    "value": "uplink, \"to core\""
+  }
+]`,
+	}}
+	for _, tc := range tests {
+		t.Run(tc.desc, func(t *testing.T) {
+			s := &SampleStream[any]{data: tc.vals}
+			for _, f := range []struct {
+				name  string
+				write func(testing.TB, string) (string, error)
+				want  string
+			}{
+				{"WriteCSV", s.WriteCSV, tc.wantCSV},
+				{"WriteJSON", s.WriteJSON, tc.wantJSON},
+			} {
+				name, err := f.write(t, "samples")
+				if err != nil {
+					t.Fatalf("%s() failed: %v", f.name, err)
+				}
+				got, err := os.ReadFile(filepath.Join(outputs, name))
+				if err != nil {
+					t.Fatalf("Cannot read %s() output: %v", f.name, err)
+				}
+				if diff := cmp.Diff(f.want, string(got)); diff != "" {
+					t.Errorf("%s() output diff (-want +got):\n%s", f.name, diff)
+				}
+			}
+		})
+	}
+}
@@ -19,11 +19,12 @@ const (
 
 // SampleStream represents a gNMI Subscription with SAMPLE mode.
 type SampleStream[T any] struct {
-	dataMu   sync.Mutex         // Lock that protects the received data and the next channel.
-	lastVal  *ygnmi.Value[T]    // Holds the last received sample.
-	data     []*ygnmi.Value[T]  // Data received from gNMI call.
-	cancel   context.CancelFunc // Cancels the subscription.
-	interval time.Duration      // Configured interval for the SAMPLE mode stream.
+	dataMu   sync.Mutex              // Lock that protects the received data and the next channel.
+	lastVal  *ygnmi.Value[T]         // Holds the last received sample.
+	data     []*ygnmi.Value[T]       // Data received from gNMI call.
+	cancel   context.CancelFunc      // Cancels the subscription.
+	interval time.Duration           // Configured interval for the SAMPLE mode stream.
+	views    []func(*ygnmi.Value[T]) // Called with each received sample to feed views sharing the subscription.
 }
 
 // New creates a new SampleStream.
@@ -45,13 +46,54 @@ func New[T any](t *testing.T, dut *ondatra.DUTDevice, q ygnmi.SingletonQuery[T],
 		if !v.IsPresent() {
 			return ygnmi.Continue
 		}
-		s.data = append(s.data, v)
-		s.lastVal = v
+		s.add(v)
 		return ygnmi.Continue
 	}, ygnmi.WithSubscriptionMode(gpb.SubscriptionMode_SAMPLE), ygnmi.WithSampleInterval(interval))
 	return s
 }
 
+// add records a received sample and forwards it to all views. The caller must hold dataMu.
+func (s *SampleStream[T]) add(v *ygnmi.Value[T]) {
+	s.data = append(s.data, v)
+	s.lastVal = v
+	for _, view := range s.views {
+		view(v)
+	}
+}
+
+// View returns a SampleStream that shares the subscription of s and contains the values
+// produced by extract for each sample of s. Samples for which extract returns false are
+// dropped. This allows multiple queries to be served by one subscription, for example by
+// subscribing to a container or a ygnmi.Batch query and extracting individual leaves.
+//
+// Closing s also ends the view; closing the view has no effect on s.
+func View[T, U any](s *SampleStream[T], extract func(T) (U, bool)) *SampleStream[U] {
+	view := &SampleStream[U]{
+		dataMu:   sync.Mutex{},
+		cancel:   func() {},
+		interval: s.interval,
+	}
+	s.dataMu.Lock()
+	defer s.dataMu.Unlock()
+	s.views = append(s.views, func(v *ygnmi.Value[T]) {
+		val, _ := v.Val()
+		u, ok := extract(val)
+		if !ok {
+			return
+		}
+		uv := (&ygnmi.Value[U]{
+			Path:             v.Path,
+			Timestamp:        v.Timestamp,
+			RecvTimestamp:    v.RecvTimestamp,
+			ComplianceErrors: v.ComplianceErrors,
+		}).SetVal(u)
+		view.dataMu.Lock()
+		defer view.dataMu.Unlock()
+		view.add(uv)
+	})
+	return view
+}
+
 // Next returns the next sample received within the sample interval.
 // If no sample is received within the interval, nil is returned.
 func (s *SampleStream[T]) Next() *ygnmi.Value[T] {
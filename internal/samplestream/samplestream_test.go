@@ -0,0 +1,78 @@
+// Copyright 2026 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package samplestream
+
+import (
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestView(t *testing.T) {
+	start := time.Unix(1000, 0)
+	offsets := []time.Duration{0, 10 * time.Second, 20 * time.Second, 30 * time.Second}
+
+	tests := []struct {
+		desc       string
+		extract    func(uint64) (uint64, bool)
+		vals       []uint64
+		wantVals   []uint64
+		wantOffset []time.Duration
+	}{{
+		desc:       "all samples",
+		extract:    func(v uint64) (uint64, bool) { return v * 2, true },
+		vals:       []uint64{1, 2, 3, 4},
+		wantVals:   []uint64{2, 4, 6, 8},
+		wantOffset: offsets,
+	}, {
+		desc:       "drops samples",
+		extract:    func(v uint64) (uint64, bool) { return v, v%2 == 0 },
+		vals:       []uint64{1, 2, 3, 4},
+		wantVals:   []uint64{2, 4},
+		wantOffset: []time.Duration{10 * time.Second, 30 * time.Second},
+	}, {
+		desc:    "drops all samples",
+		extract: func(uint64) (uint64, bool) { return 0, false },
+		vals:    []uint64{1, 2, 3, 4},
+	}}
+	for _, tc := range tests {
+		t.Run(tc.desc, func(t *testing.T) {
+			s := &SampleStream[uint64]{interval: 10 * time.Second}
+			view := View(s, tc.extract)
+			for _, v := range samplesAt(start, offsets, tc.vals) {
+				s.add(v)
+			}
+			if got := len(s.All()); got != len(tc.vals) {
+				t.Errorf("stream got %d samples, want %d", got, len(tc.vals))
+			}
+			var gotVals []uint64
+			for _, v := range view.All() {
+				val, _ := v.Val()
+				gotVals = append(gotVals, val)
+			}
+			if diff := cmp.Diff(tc.wantVals, gotVals); diff != "" {
+				t.Errorf("view values diff (-want +got):\n%s", diff)
+			}
+			if diff := cmp.Diff(tc.wantOffset, timestamps(start, view.All())); diff != "" {
+				t.Errorf("view timestamps diff (-want +got):\n%s", diff)
+			}
+			if view.interval != s.interval {
+				t.Errorf("view interval got %v, want %v", view.interval, s.interval)
+			}
+			view.Close()
+		})
+	}
+}
@@ -0,0 +1,192 @@
+// Copyright 2026 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package samplestream
+
+import (
+	"fmt"
+	"math"
+	"slices"
+	"time"
+
+	"github.com/openconfig/ygnmi/ygnmi"
+)
+
+// Numeric is the set of sample value types for which NumericStats can be computed.
+type Numeric interface {
+	~int | ~int8 | ~int16 | ~int32 | ~int64 |
+		~uint | ~uint8 | ~uint16 | ~uint32 | ~uint64 |
+		~float32 | ~float64
+}
+
+// Stats summarizes the values of a set of samples.
+type Stats struct {
+	Count int
+	Min   float64
+	Max   float64
+	Mean  float64
+
+	sorted []float64
+}
+
+// Percentile returns the p-th percentile (0 <= p <= 100) of the values using the
+// nearest-rank method. It returns NaN if there are no values.
+func (s *Stats) Percentile(p float64) float64 {
+	if len(s.sorted) == 0 {
+		return math.NaN()
+	}
+	rank := int(math.Ceil(p / 100 * float64(len(s.sorted))))
+	rank = min(max(rank, 1), len(s.sorted))
+	return s.sorted[rank-1]
+}
+
+// String returns a one line summary of the statistics.
+func (s *Stats) String() string {
+	return fmt.Sprintf("count=%d min=%v max=%v mean=%v p50=%v p99=%v", s.Count, s.Min, s.Max, s.Mean, s.Percentile(50), s.Percentile(99))
+}
+
+func computeStats[T any](vals []*ygnmi.Value[T], toFloat func(T) float64) *Stats {
+	s := &Stats{}
+	var sum float64
+	for _, v := range vals {
+		val, ok := v.Val()
+		if !ok {
+			continue
+		}
+		s.sorted = append(s.sorted, toFloat(val))
+	}
+	if len(s.sorted) == 0 {
+		return s
+	}
+	slices.Sort(s.sorted)
+	for _, f := range s.sorted {
+		sum += f
+	}
+	s.Count = len(s.sorted)
+	s.Min = s.sorted[0]
+	s.Max = s.sorted[len(s.sorted)-1]
+	s.Mean = sum / float64(s.Count)
+	return s
+}
+
+// Stats computes statistics over all samples received thus far, using toFloat to convert
+// each sample to a number. Use NumericStats for streams of numeric leaves.
+func (s *SampleStream[T]) Stats(toFloat func(T) float64) *Stats {
+	return computeStats(s.All(), toFloat)
+}
+
+// NumericStats computes statistics over all samples of a numeric stream received thus far.
+func NumericStats[T Numeric](s *SampleStream[T]) *Stats {
+	return s.Stats(func(v T) float64 { return float64(v) })
+}
+
+// Regression describes a sample whose timestamp is not after the timestamp of the
+// sample received before it.
+type Regression struct {
+	// Index is the position of the regressing sample in the stream.
+	Index int
+	// Prev is the timestamp of the preceding sample.
+	Prev time.Time
+	// Cur is the timestamp of the regressing sample.
+	Cur time.Time
+}
+
+// Timing summarizes the arrival of samples relative to the requested sample interval.
+type Timing struct {
+	// Interval is the requested sample interval.
+	Interval time.Duration
+	// Gaps contains the time between the timestamps of consecutive samples.
+	Gaps []time.Duration
+	// MinGap, MaxGap and MeanGap summarize Gaps.
+	MinGap  time.Duration
+	MaxGap  time.Duration
+	MeanGap time.Duration
+	// MissedIntervals is the number of sample intervals for which no sample was received.
+	MissedIntervals int
+	// Regressions contains all samples whose timestamp did not advance.
+	Regressions []Regression
+}
+
+// String returns a one line summary of the timing.
+func (tm *Timing) String() string {
+	return fmt.Sprintf("interval=%v gaps=%d min=%v max=%v mean=%v missed=%d regressions=%d",
+		tm.Interval, len(tm.Gaps), tm.MinGap, tm.MaxGap, tm.MeanGap, tm.MissedIntervals, len(tm.Regressions))
+}
+
+func computeTiming[T any](vals []*ygnmi.Value[T], interval time.Duration) *Timing {
+	tm := &Timing{Interval: interval}
+	var sum time.Duration
+	for i := 1; i < len(vals); i++ {
+		prev, cur := vals[i-1].Timestamp, vals[i].Timestamp
+		if !cur.After(prev) {
+			tm.Regressions = append(tm.Regressions, Regression{Index: i, Prev: prev, Cur: cur})
+			continue
+		}
+		gap := cur.Sub(prev)
+		tm.Gaps = append(tm.Gaps, gap)
+		sum += gap
+		if len(tm.Gaps) == 1 || gap < tm.MinGap {
+			tm.MinGap = gap
+		}
+		if gap > tm.MaxGap {
+			tm.MaxGap = gap
+		}
+		if interval > 0 && gap > interval+intervalTolerance {
+			tm.MissedIntervals += max(int((gap+interval/2)/interval)-1, 1)
+		}
+	}
+	if len(tm.Gaps) > 0 {
+		tm.MeanGap = sum / time.Duration(len(tm.Gaps))
+	}
+	return tm
+}
+
+// Timing computes the gaps between samples received thus far, the number of sample
+// intervals that were missed and any timestamp regressions.
+func (s *SampleStream[T]) Timing() *Timing {
+	return computeTiming(s.All(), s.interval)
+}
+
+// Between returns the samples with a timestamp in [start, end).
+func (s *SampleStream[T]) Between(start, end time.Time) []*ygnmi.Value[T] {
+	var out []*ygnmi.Value[T]
+	for _, v := range s.All() {
+		if !v.Timestamp.Before(start) && v.Timestamp.Before(end) {
+			out = append(out, v)
+		}
+	}
+	return out
+}
+
+// Window returns the samples received within the last d, based on the time the test
+// received them. Calling Window repeatedly gives a sliding window over the stream.
+func (s *SampleStream[T]) Window(d time.Duration) []*ygnmi.Value[T] {
+	return s.receivedSince(time.Now().Add(-d))
+}
+
+// receivedSince returns the samples received at or after cutoff.
+func (s *SampleStream[T]) receivedSince(cutoff time.Time) []*ygnmi.Value[T] {
+	var out []*ygnmi.Value[T]
+	for _, v := range s.All() {
+		if !v.RecvTimestamp.Before(cutoff) {
+			out = append(out, v)
+		}
+	}
+	return out
+}
+
+// WindowStats computes statistics over the samples received within the last d.
+func (s *SampleStream[T]) WindowStats(d time.Duration, toFloat func(T) float64) *Stats {
+	return computeStats(s.Window(d), toFloat)
+}
@@ -0,0 +1,168 @@
+// Copyright 2026 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package samplestream
+
+import (
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/openconfig/ygnmi/ygnmi"
+)
+
+func samplesAt(start time.Time, offsets []time.Duration, vals []uint64) []*ygnmi.Value[uint64] {
+	var out []*ygnmi.Value[uint64]
+	for i, off := range offsets {
+		v := &ygnmi.Value[uint64]{Timestamp: start.Add(off), RecvTimestamp: start.Add(off)}
+		out = append(out, v.SetVal(vals[i]))
+	}
+	return out
+}
+
+func TestComputeStats(t *testing.T) {
+	start := time.Unix(1000, 0)
+	vals := samplesAt(start, []time.Duration{0, time.Second, 2 * time.Second, 3 * time.Second}, []uint64{4, 1, 3, 2})
+	s := computeStats(vals, func(v uint64) float64 { return float64(v) })
+	if s.Count != 4 || s.Min != 1 || s.Max != 4 || s.Mean != 2.5 {
+		t.Errorf("computeStats got %v, want count=4 min=1 max=4 mean=2.5", s)
+	}
+	if got := s.Percentile(50); got != 2 {
+		t.Errorf("Percentile(50) got %v, want 2", got)
+	}
+	if got := s.Percentile(100); got != 4 {
+		t.Errorf("Percentile(100) got %v, want 4", got)
+	}
+}
+
+func TestComputeTiming(t *testing.T) {
+	start := time.Unix(1000, 0)
+	offsets := []time.Duration{0, 10 * time.Second, 40 * time.Second, 35 * time.Second, 45 * time.Second}
+	vals := samplesAt(start, offsets, []uint64{0, 0, 0, 0, 0})
+	tm := computeTiming(vals, 10*time.Second)
+	if got, want := len(tm.Gaps), 3; got != want {
+		t.Fatalf("len(Gaps) got %d, want %d", got, want)
+	}
+	if tm.MinGap != 10*time.Second || tm.MaxGap != 30*time.Second {
+		t.Errorf("gaps got min=%v max=%v, want min=10s max=30s", tm.MinGap, tm.MaxGap)
+	}
+	if got, want := tm.MissedIntervals, 2; got != want {
+		t.Errorf("MissedIntervals got %d, want %d", got, want)
+	}
+	if len(tm.Regressions) != 1 || tm.Regressions[0].Index != 3 {
+		t.Errorf("Regressions got %+v, want one regression at index 3", tm.Regressions)
+	}
+}
+
+// timestamps returns the offsets from start of the timestamps of vals.
+func timestamps(start time.Time, vals []*ygnmi.Value[uint64]) []time.Duration {
+	var out []time.Duration
+	for _, v := range vals {
+		out = append(out, v.Timestamp.Sub(start))
+	}
+	return out
+}
+
+func TestWindow(t *testing.T) {
+	start := time.Unix(1000, 0)
+	vals := samplesAt(start, []time.Duration{0, 10 * time.Second, 20 * time.Second}, []uint64{0, 1, 2})
+	// The last sample carries an old timestamp but was received last; windows go by
+	// receive time.
+	late := (&ygnmi.Value[uint64]{Timestamp: start, RecvTimestamp: start.Add(30 * time.Second)}).SetVal(3)
+	s := &SampleStream[uint64]{data: append(vals, late)}
+
+	tests := []struct {
+		desc   string
+		cutoff time.Duration
+		want   []time.Duration
+	}{{
+		desc:   "before all samples",
+		cutoff: -time.Second,
+		want:   []time.Duration{0, 10 * time.Second, 20 * time.Second, 0},
+	}, {
+		desc:   "at first sample",
+		cutoff: 0,
+		want:   []time.Duration{0, 10 * time.Second, 20 * time.Second, 0},
+	}, {
+		desc:   "just after first sample",
+		cutoff: time.Nanosecond,
+		want:   []time.Duration{10 * time.Second, 20 * time.Second, 0},
+	}, {
+		desc:   "at sample received with old timestamp",
+		cutoff: 30 * time.Second,
+		want:   []time.Duration{0},
+	}, {
+		desc:   "after all samples",
+		cutoff: 31 * time.Second,
+	}}
+	for _, tc := range tests {
+		t.Run(tc.desc, func(t *testing.T) {
+			got := timestamps(start, s.receivedSince(start.Add(tc.cutoff)))
+			if diff := cmp.Diff(tc.want, got); diff != "" {
+				t.Errorf("receivedSince(start+%v) diff (-want +got):\n%s", tc.cutoff, diff)
+			}
+		})
+	}
+
+	t.Run("empty stream", func(t *testing.T) {
+		if got := (&SampleStream[uint64]{}).Window(time.Hour); len(got) != 0 {
+			t.Errorf("Window(1h) on empty stream got %d samples, want 0", len(got))
+		}
+	})
+	t.Run("relative to now", func(t *testing.T) {
+		now := time.Now()
+		s := &SampleStream[uint64]{data: samplesAt(now, []time.Duration{-time.Hour, -time.Minute, 0}, []uint64{0, 1, 2})}
+		if got, want := timestamps(now, s.Window(10*time.Minute)), []time.Duration{-time.Minute, 0}; !cmp.Equal(got, want) {
+			t.Errorf("Window(10m) got samples at %v, want %v", got, want)
+		}
+	})
+}
+
+func TestBetween(t *testing.T) {
+	start := time.Unix(1000, 0)
+	s := &SampleStream[uint64]{data: samplesAt(start, []time.Duration{0, 10 * time.Second, 20 * time.Second}, []uint64{0, 1, 2})}
+
+	tests := []struct {
+		desc       string
+		start, end time.Duration
+		want       []time.Duration
+	}{{
+		desc:  "start is inclusive",
+		start: 0,
+		end:   10 * time.Second,
+		want:  []time.Duration{0},
+	}, {
+		desc:  "end is exclusive",
+		start: time.Nanosecond,
+		end:   20 * time.Second,
+		want:  []time.Duration{10 * time.Second},
+	}, {
+		desc:  "covers all samples",
+		start: -time.Second,
+		end:   21 * time.Second,
+		want:  []time.Duration{0, 10 * time.Second, 20 * time.Second},
+	}, {
+		desc:  "empty range",
+		start: 10 * time.Second,
+		end:   10 * time.Second,
+	}}
+	for _, tc := range tests {
+		t.Run(tc.desc, func(t *testing.T) {
+			got := timestamps(start, s.Between(start.Add(tc.start), start.Add(tc.end)))
+			if diff := cmp.Diff(tc.want, got); diff != "" {
+				t.Errorf("Between(start+%v, start+%v) diff (-want +got):\n%s", tc.start, tc.end, diff)
+			}
+		})
+	}
+}
@@ -26,6 +26,7 @@ import (
 	"slices"
 	"strconv"
 	"strings"
+	"sync/atomic"
 	"testing"
 	"time"
 
@@ -46,8 +47,11 @@ const (
 	prefixNHGPathV4           = "/network-instances/network-instance/afts/ipv4-unicast/ipv4-entry/state/next-hop-group"
 	prefixPathV6              = "/network-instances/network-instance/afts/ipv6-unicast/ipv6-entry/state/prefix"
 	prefixNHGPathV6           = "/network-instances/network-instance/afts/ipv6-unicast/ipv6-entry/state/next-hop-group"
+	prefixNHGNIPathV4         = "/network-instances/network-instance/afts/ipv4-unicast/ipv4-entry/state/next-hop-group-network-instance"
+	prefixNHGNIPathV6         = "/network-instances/network-instance/afts/ipv6-unicast/ipv6-entry/state/next-hop-group-network-instance"
 	nextHopWeightPath         = "/network-instances/network-instance/afts/next-hop-groups/next-hop-group/next-hops/next-hop/state/weight"
 	nextHopGroupConditionPath = "/network-instances/network-instance/afts/next-hop-groups/next-hop-group/condition"
+	nextHopGroupBackupPath    = "/network-instances/network-instance/afts/next-hop-groups/next-hop-group/state/backup-next-hop-group"
 	// periodicInterval is the time between execution of periodic hooks.
 	periodicInterval = 2 * time.Minute
 	// periodicDeadline is the deadline for all periodic hooks in a run. Should be < periodicInterval.
@@ -82,7 +86,6 @@ var unusedPaths = []string{
 	"/network-instances/network-instance/afts/ipv4-unicast/ipv4-entry/state/counters/packets-forwarded",
 	"/network-instances/network-instance/afts/ipv4-unicast/ipv4-entry/state/decapsulate-header",
 	"/network-instances/network-instance/afts/ipv4-unicast/ipv4-entry/state/entry-metadata",
-	"/network-instances/network-instance/afts/ipv4-unicast/ipv4-entry/state/next-hop-group-network-instance",
 	"/network-instances/network-instance/afts/ipv4-unicast/ipv4-entry/state/origin-network-instance",
 	"/network-instances/network-instance/afts/ipv4-unicast/ipv4-entry/state/origin-protocol",
 	"/network-instances/network-instance/afts/ipv6-unicast/ipv6-entry/prefix",
@@ -90,12 +93,10 @@ var unusedPaths = []string{
 	"/network-instances/network-instance/afts/ipv6-unicast/ipv6-entry/state/counters/packets-forwarded",
 	"/network-instances/network-instance/afts/ipv6-unicast/ipv6-entry/state/decapsulate-header",
 	"/network-instances/network-instance/afts/ipv6-unicast/ipv6-entry/state/entry-metadata",
-	"/network-instances/network-instance/afts/ipv6-unicast/ipv6-entry/state/next-hop-group-network-instance",
 	"/network-instances/network-instance/afts/ipv6-unicast/ipv6-entry/state/origin-network-instance",
 	"/network-instances/network-instance/afts/ipv6-unicast/ipv6-entry/state/origin-protocol",
 	"/network-instances/network-instance/afts/next-hop-groups/next-hop-group/id",
 	"/network-instances/network-instance/afts/next-hop-groups/next-hop-group/next-hops/next-hop/index",
-	"/network-instances/network-instance/afts/next-hop-groups/next-hop-group/state/backup-next-hop-group",
 	"/network-instances/network-instance/afts/next-hops/next-hop/index",
 	"/network-instances/network-instance/afts/next-hops/next-hop/interface-ref/state/subinterface",
 	"/network-instances/network-instance/afts/next-hops/next-hop/state/counters/octets-forwarded",
@@ -105,20 +106,48 @@ var unusedPaths = []string{
 	"/network-instances/network-instance/afts/next-hops/next-hop/state/origin-protocol",
 }
 
-func subscriptionPaths(dut *ondatra.DUTDevice) map[string][]string {
-	defaultNetworkInstance := deviations.DefaultNetworkInstance(dut)
-	return map[string][]string{
-		"prefix": {
-			fmt.Sprintf("network-instances/network-instance[name=%s]/afts/ipv4-unicast/ipv4-entry", defaultNetworkInstance),
-			fmt.Sprintf("network-instances/network-instance[name=%s]/afts/ipv6-unicast/ipv6-entry", defaultNetworkInstance),
-		},
+// Family is a family of AFT entries that can be streamed in addition to next hop groups and
+// next hops, which are always streamed since they are needed to resolve entries.
+type Family string
+
+const (
+	// FamilyIPv4 is the family of IPv4 unicast entries.
+	FamilyIPv4 Family = "ipv4-unicast"
+	// FamilyIPv6 is the family of IPv6 unicast entries.
+	FamilyIPv6 Family = "ipv6-unicast"
+	// FamilyMPLS is the family of MPLS label entries.
+	FamilyMPLS Family = "mpls"
+	// FamilyEthernet is the family of ethernet MAC entries.
+	FamilyEthernet Family = "ethernet"
+)
+
+// defaultFamilies are the families streamed when no families are given.
+var defaultFamilies = []Family{FamilyIPv4, FamilyIPv6}
+
+// subscriptionPaths returns the paths to subscribe to for the given families of a network
+// instance, keyed by the kind of entry found at the path.
+func subscriptionPaths(networkInstance string, families []Family) map[string][]string {
+	paths := map[string][]string{
 		"nhg": {
-			fmt.Sprintf("network-instances/network-instance[name=%s]/afts/next-hop-groups/next-hop-group", defaultNetworkInstance),
+			fmt.Sprintf("network-instances/network-instance[name=%s]/afts/next-hop-groups/next-hop-group", networkInstance),
 		},
 		"nh": {
-			fmt.Sprintf("network-instances/network-instance[name=%s]/afts/next-hops/next-hop", defaultNetworkInstance),
+			fmt.Sprintf("network-instances/network-instance[name=%s]/afts/next-hops/next-hop", networkInstance),
 		},
 	}
+	for _, f := range families {
+		switch f {
+		case FamilyIPv4:
+			paths["prefix"] = append(paths["prefix"], fmt.Sprintf("network-instances/network-instance[name=%s]/afts/ipv4-unicast/ipv4-entry", networkInstance))
+		case FamilyIPv6:
+			paths["prefix"] = append(paths["prefix"], fmt.Sprintf("network-instances/network-instance[name=%s]/afts/ipv6-unicast/ipv6-entry", networkInstance))
+		case FamilyMPLS:
+			paths["label"] = append(paths["label"], fmt.Sprintf("network-instances/network-instance[name=%s]/afts/mpls/label-entry", networkInstance))
+		case FamilyEthernet:
+			paths["mac"] = append(paths["mac"], fmt.Sprintf("network-instances/network-instance[name=%s]/afts/ethernet/mac-entry", networkInstance))
+		}
+	}
+	return paths
 }
 
 // AFTData represents an AFT and provides methods for resolving routes.
@@ -129,6 +158,69 @@ type AFTData struct {
 	NextHopGroups map[uint64]*aftNextHopGroup
 	// NextHops contains a map of next hop IDs to their corresponding next hop data.
 	NextHops map[uint64]*aftNextHop
+	// DefaultNetworkInstance is the name of the network instance whose entries are stored in
+	// Prefixes, NextHopGroups and NextHops.
+	DefaultNetworkInstance string
+	// NetworkInstances contains the AFT of every streamed network instance keyed by name. The
+	// entry of the default network instance shares its maps with the fields above.
+	NetworkInstances map[string]*NetworkInstanceAFT
+}
+
+// NetworkInstanceAFT contains the AFT entries of a single network instance.
+type NetworkInstanceAFT struct {
+	// Prefixes contains a map of prefixes to their corresponding next hop group IDs.
+	Prefixes map[string]uint64
+	// PrefixNHGNetworkInstances contains the next-hop-group-network-instance of prefixes whose
+	// next hop group is in another network instance.
+	PrefixNHGNetworkInstances map[string]string
+	// LabelEntries contains a map of MPLS labels to their corresponding next hop group IDs.
+	LabelEntries map[uint64]uint64
+	// LabelNHGNetworkInstances contains the next-hop-group-network-instance of labels whose
+	// next hop group is in another network instance.
+	LabelNHGNetworkInstances map[uint64]string
+	// MACEntries contains a map of MAC addresses to their corresponding next hop group IDs.
+	MACEntries map[string]uint64
+	// NextHopGroups contains a map of next hop group IDs to their corresponding next hop group data.
+	NextHopGroups map[uint64]*aftNextHopGroup
+	// NextHops contains a map of next hop IDs to their corresponding next hop data.
+	NextHops map[uint64]*aftNextHop
+}
+
+func newNetworkInstanceAFT() *NetworkInstanceAFT {
+	return &NetworkInstanceAFT{
+		Prefixes:                  map[string]uint64{},
+		PrefixNHGNetworkInstances: map[string]string{},
+		LabelEntries:              map[uint64]uint64{},
+		LabelNHGNetworkInstances:  map[uint64]string{},
+		MACEntries:                map[string]uint64{},
+		NextHopGroups:             map[uint64]*aftNextHopGroup{},
+		NextHops:                  map[uint64]*aftNextHop{},
+	}
+}
+
+// defaultNetworkInstanceAFT returns the AFT of the default network instance. AFTData built
+// without NetworkInstances, such as the result of FilterByPrefixes, is wrapped in a view.
+func (a *AFTData) defaultNetworkInstanceAFT() *NetworkInstanceAFT {
+	if ni, ok := a.NetworkInstances[a.DefaultNetworkInstance]; ok {
+		return ni
+	}
+	return &NetworkInstanceAFT{
+		Prefixes:      a.Prefixes,
+		NextHopGroups: a.NextHopGroups,
+		NextHops:      a.NextHops,
+	}
+}
+
+// networkInstanceAFT returns the AFT of the named network instance.
+func (a *AFTData) networkInstanceAFT(name string) (*NetworkInstanceAFT, error) {
+	if name == a.DefaultNetworkInstance {
+		return a.defaultNetworkInstanceAFT(), nil
+	}
+	ni, ok := a.NetworkInstances[name]
+	if !ok {
+		return nil, fmt.Errorf("network instance %s was not streamed: %w", name, ErrNotExist)
+	}
+	return ni, nil
 }
 
 // FilterByPrefixes returns a new AFTData containing only the specified prefixes
@@ -176,8 +268,9 @@ func (a *AFTData) FilterByPrefixes(wantPrefixes map[string]bool) *AFTData {
 
 // aftCache is the AFT streaming cache.
 type aftCache struct {
-	cache  *cache.Cache // Cache used to store AFT notifications during streaming.
-	target string
+	cache   *cache.Cache  // Cache used to store AFT notifications during streaming.
+	compact *compactStore // Store used instead of cache if WithCompactStore is set.
+	target  string
 }
 
 // aftNextHopGroup represents an AFT next hop group.
@@ -188,6 +281,8 @@ type aftNextHopGroup struct {
 	NHWeights map[uint64]uint64
 	// Conditionals contains the conditionals that are part of this next hop group.
 	Conditionals []*aftNextHopGroupConditional
+	// BackupNHGID contains the ID of the backup next hop group, or 0 if there is none.
+	BackupNHGID uint64
 }
 
 // aftNextHopGroupConditional represents a condition for an AFT next hop group.
@@ -206,6 +301,15 @@ type aftNextHop struct {
 	IP string
 	// LSPName contains the LSP name of the next hop.
 	LSPName string
+	// NetworkInstance contains the network instance in which the next hop is resolved.
+	NetworkInstance string
+	// EncapHeader and DecapHeader contain the header types, e.g. "IPV4", the next hop
+	// encapsulates or decapsulates packets with.
+	EncapHeader string
+	DecapHeader string
+	// TunnelSrcIP and TunnelDstIP contain the addresses of an IP-in-IP encapsulation.
+	TunnelSrcIP string
+	TunnelDstIP string
 }
 
 // generateCacheTraversalPaths converts a map of subscription paths to a map of cache traversal paths.
@@ -249,17 +353,63 @@ func generateCacheTraversalPaths(subscriptionPaths map[string][]string) (map[str
 }
 
 // ToAFT Creates AFT maps with cache information.
+// The entries of every network instance of the session are included.
 func (ss *AFTStreamSession) ToAFT(t *testing.T, dut *ondatra.DUTDevice) (*AFTData, error) {
+	a := newAFT(ss.defaultNetworkInstance, ss.networkInstances)
+	for _, name := range ss.networkInstances {
+		if ss.Cache.compact != nil {
+			if err := ss.Cache.compact.toNetworkInstanceAFT(name, a.NetworkInstances[name]); err != nil {
+				return nil, err
+			}
+			continue
+		}
+		if err := ss.toNetworkInstanceAFT(t, name, a.NetworkInstances[name]); err != nil {
+			return nil, err
+		}
+	}
+	return a, nil
+}
+
+// toNetworkInstanceAFT fills a with the cached entries of the named network instance.
+func (ss *AFTStreamSession) toNetworkInstanceAFT(t *testing.T, name string, a *NetworkInstanceAFT) error {
 	sessionPrefix := ss.sessionPrefix()
 	c := ss.Cache
-	a := newAFT()
 	prefixFunc := func(n *gnmipb.Notification) error {
-		p, nhg, err := parsePrefix(t, n, sessionPrefix)
+		p, nhg, nhgNI, err := parsePrefix(t, n, sessionPrefix)
 		if err != nil {
 			t.Logf("%s error in parsing prefix: %v", sessionPrefix, err)
 			return err
 		}
 		a.Prefixes[p] = nhg
+		if nhgNI != "" && nhgNI != name {
+			a.PrefixNHGNetworkInstances[p] = nhgNI
+		}
+		return nil
+	}
+	labelFunc := func(n *gnmipb.Notification) error {
+		key, nhg, nhgNI, err := parseEntry(n, "label")
+		if err != nil {
+			t.Logf("%s error parsing label entry: %v", sessionPrefix, err)
+			return nil
+		}
+		label, err := strconv.ParseUint(key, 10, 64)
+		if err != nil {
+			t.Logf("%s skipping label entry with non-numeric label %q: %v", sessionPrefix, key, ErrUnsupported)
+			return nil
+		}
+		a.LabelEntries[label] = nhg
+		if nhgNI != "" && nhgNI != name {
+			a.LabelNHGNetworkInstances[label] = nhgNI
+		}
+		return nil
+	}
+	macFunc := func(n *gnmipb.Notification) error {
+		mac, nhg, _, err := parseEntry(n, "mac-address")
+		if err != nil {
+			t.Logf("%s error parsing MAC entry: %v", sessionPrefix, err)
+			return nil
+		}
+		a.MACEntries[mac] = nhg
 		return nil
 	}
 	nhgFunc := func(n *gnmipb.Notification) error {
@@ -287,9 +437,9 @@ func (ss *AFTStreamSession) ToAFT(t *testing.T, dut *ondatra.DUTDevice) (*AFTDat
 		}
 		return nil
 	}
-	cacheTraversalPaths, err := generateCacheTraversalPaths(subscriptionPaths(dut))
+	cacheTraversalPaths, err := generateCacheTraversalPaths(subscriptionPaths(name, ss.families))
 	if err != nil {
-		return nil, err
+		return err
 	}
 	parsers := []struct {
 		paths []string
@@ -299,6 +449,14 @@ func (ss *AFTStreamSession) ToAFT(t *testing.T, dut *ondatra.DUTDevice) (*AFTDat
 			paths: cacheTraversalPaths["prefix"],
 			f:     prefixFunc,
 		},
+		{
+			paths: cacheTraversalPaths["label"],
+			f:     labelFunc,
+		},
+		{
+			paths: cacheTraversalPaths["mac"],
+			f:     macFunc,
+		},
 		{
 			paths: cacheTraversalPaths["nhg"],
 			f:     nhgFunc,
@@ -311,15 +469,19 @@ func (ss *AFTStreamSession) ToAFT(t *testing.T, dut *ondatra.DUTDevice) (*AFTDat
 	for _, p := range parsers {
 		for _, path := range p.paths {
 			if err := c.traverse(path, p.f); err != nil {
-				return nil, err
+				return err
 			}
 		}
 	}
-	return a, nil
+	return nil
 }
 
 // logMetadata sends cache metadata to testing log.
 func (c *aftCache) logMetadata(t *testing.T, start time.Time, prefix string) error {
+	if c.compact != nil {
+		c.compact.logMetadata(t, start, prefix)
+		return nil
+	}
 	m := c.cache.Metadata()[c.target]
 	msg := fmt.Sprintf("%s After %v: ", prefix, time.Since(start).Truncate(time.Millisecond))
 	fields := []string{metadata.LeafCount, metadata.AddCount, metadata.UpdateCount, metadata.DelCount}
@@ -355,7 +517,7 @@ func (a *AFTData) resolveRoute(prefix string) ([]*aftNextHop, error) {
 	return a.resolveRouteCBF(prefix, 0)
 }
 
-func (a *AFTData) isCNHG(nhgID uint64) (bool, error) {
+func (a *NetworkInstanceAFT) isCNHG(nhgID uint64) (bool, error) {
 	// Assume we've already checked the nhgID exists.
 	if len(a.NextHopGroups[nhgID].NHIDs) > 0 && len(a.NextHopGroups[nhgID].Conditionals) > 0 {
 		return false, fmt.Errorf("the NHG has both NHs and conditionals. not clear if CNHG or leaf NHG")
@@ -370,18 +532,82 @@ func (a *AFTData) isCNHG(nhgID uint64) (bool, error) {
 // ResolveRouteCBF gets the possible next hops for a specific route.
 // dscp is the DSCP bits.
 func (a *AFTData) resolveRouteCBF(prefix string, dscp uint8) ([]*aftNextHop, error) {
-	if _, ok := a.Prefixes[prefix]; !ok {
+	return a.resolvePrefix(a.DefaultNetworkInstance, a.defaultNetworkInstanceAFT(), prefix, dscp)
+}
+
+// ResolvePrefix gets the possible next hops for a prefix in the named network instance for
+// traffic with the given DSCP bits. A next-hop-group-network-instance of the prefix is
+// followed to resolve its next hop group in that network instance.
+func (a *AFTData) ResolvePrefix(networkInstance, prefix string, dscp uint8) ([]*aftNextHop, error) {
+	ni, err := a.networkInstanceAFT(networkInstance)
+	if err != nil {
+		return nil, err
+	}
+	return a.resolvePrefix(networkInstance, ni, prefix, dscp)
+}
+
+func (a *AFTData) resolvePrefix(name string, ni *NetworkInstanceAFT, prefix string, dscp uint8) ([]*aftNextHop, error) {
+	nhgID, ok := ni.Prefixes[prefix]
+	if !ok {
 		return nil, fmt.Errorf("missing prefix. want %s, %w", prefix, ErrNotExist)
 	}
-	nhgID := a.Prefixes[prefix]
+	if nhgNI, ok := ni.PrefixNHGNetworkInstances[prefix]; ok && nhgNI != name {
+		var err error
+		if ni, err = a.networkInstanceAFT(nhgNI); err != nil {
+			return nil, fmt.Errorf("missing reference for prefix %s: %w", prefix, err)
+		}
+	}
+	return ni.resolveNHG(nhgID, dscp, "prefix "+prefix)
+}
+
+// ResolveLabel gets the possible next hops for an MPLS label entry in the named network
+// instance. A next-hop-group-network-instance of the label entry is followed to resolve its
+// next hop group in that network instance.
+func (a *AFTData) ResolveLabel(networkInstance string, label uint64) ([]*aftNextHop, error) {
+	ni, err := a.networkInstanceAFT(networkInstance)
+	if err != nil {
+		return nil, err
+	}
+	nhgID, ok := ni.LabelEntries[label]
+	if !ok {
+		return nil, fmt.Errorf("missing label entry. want %d, %w", label, ErrNotExist)
+	}
+	if nhgNI, ok := ni.LabelNHGNetworkInstances[label]; ok && nhgNI != networkInstance {
+		if ni, err = a.networkInstanceAFT(nhgNI); err != nil {
+			return nil, fmt.Errorf("missing reference for label %d: %w", label, err)
+		}
+	}
+	return ni.resolveNHG(nhgID, 0, fmt.Sprintf("label %d", label))
+}
+
+// resolveNHG gets the possible next hops of a next hop group, following conditionals for the
+// DSCP bits. entry describes the AFT entry being resolved for error messages.
+func (a *NetworkInstanceAFT) resolveNHG(nhgID uint64, dscp uint8, entry string) ([]*aftNextHop, error) {
+	nhgID, ok, err := a.selectNHG(nhgID, dscp, entry)
+	if err != nil || !ok {
+		return nil, err // No conditionals matched. Return empty NH slice (nil).
+	}
+	var nhs []*aftNextHop
+	for _, nhID := range a.NextHopGroups[nhgID].NHIDs {
+		if _, ok := a.NextHops[nhID]; !ok {
+			return nil, fmt.Errorf("missing reference for %s, NH %d not found, %w", entry, nhID, ErrNotExist)
+		}
+		nhs = append(nhs, a.NextHops[nhID])
+	}
+	return nhs, nil
+}
+
+// selectNHG follows conditional next hop groups for the DSCP bits and returns the ID of the
+// resulting non-conditional next hop group. It returns false if no conditional matched.
+func (a *NetworkInstanceAFT) selectNHG(nhgID uint64, dscp uint8, entry string) (uint64, bool, error) {
 	visited := map[uint64]bool{} // Track NHGs we've seen in case of circular references.
 	for {
 		if _, ok := a.NextHopGroups[nhgID]; !ok {
-			return nil, fmt.Errorf("missing reference for prefix %s, NHG %d not found: %w", prefix, nhgID, ErrNotExist)
+			return 0, false, fmt.Errorf("missing reference for %s, NHG %d not found: %w", entry, nhgID, ErrNotExist)
 		}
 		isCNHG, err := a.isCNHG(nhgID)
 		if err != nil {
-			return nil, fmt.Errorf("error in prefix %s, error reading NHG %d: %v", prefix, nhgID, err)
+			return 0, false, fmt.Errorf("error in %s, error reading NHG %d: %v", entry, nhgID, err)
 		}
 		if !isCNHG {
 			// This is a leaf, non-conditional NHG node. Terminate.
@@ -389,7 +615,7 @@ func (a *AFTData) resolveRouteCBF(prefix string, dscp uint8) ([]*aftNextHop, err
 		}
 		// We look up each ID in visited and add all IDs to visited. This should always terminate.
 		if _, ok := visited[nhgID]; ok {
-			return nil, fmt.Errorf("circular reference for prefix %s, NHG %d already seen", prefix, nhgID)
+			return 0, false, fmt.Errorf("circular reference for %s, NHG %d already seen", entry, nhgID)
 		}
 		visited[nhgID] = true
 		match := false
@@ -398,7 +624,7 @@ func (a *AFTData) resolveRouteCBF(prefix string, dscp uint8) ([]*aftNextHop, err
 				if d == dscp {
 					if match {
 						// We already matched a different conditional. Undefined behavior.
-						return nil, fmt.Errorf("undefined behavior for prefix %s, multiple conditionals apply", prefix)
+						return 0, false, fmt.Errorf("undefined behavior for %s, multiple conditionals apply", entry)
 					}
 					match = true
 					nhgID = c.NHGID
@@ -407,17 +633,10 @@ func (a *AFTData) resolveRouteCBF(prefix string, dscp uint8) ([]*aftNextHop, err
 			}
 		}
 		if !match {
-			return nil, nil // No conditionals matched. Return empty NH slice (nil).
-		}
-	}
-	var nhs []*aftNextHop
-	for _, nhID := range a.NextHopGroups[nhgID].NHIDs {
-		if _, ok := a.NextHops[nhID]; !ok {
-			return nil, fmt.Errorf("missing reference for prefix %s, NH %d not found, %w", prefix, nhID, ErrNotExist)
+			return 0, false, nil
 		}
-		nhs = append(nhs, a.NextHops[nhID])
 	}
-	return nhs, nil
+	return nhgID, true, nil
 }
 
 func (c *aftCache) addAFTNotification(n *gnmipb.SubscribeResponse) error {
@@ -440,6 +659,9 @@ func (c *aftCache) addAFTNotification(n *gnmipb.SubscribeResponse) error {
 	if prefix.GetTarget() == "" {
 		prefix.Target = c.target
 	}
+	if c.compact != nil {
+		return c.compact.apply(update)
+	}
 	err := c.cache.GnmiUpdate(update)
 
 	if err != nil {
@@ -455,17 +677,32 @@ func newAFTCache(target string) *aftCache {
 	}
 }
 
-func newAFT() *AFTData {
-	return &AFTData{
-		Prefixes:      map[string]uint64{},
-		NextHopGroups: map[uint64]*aftNextHopGroup{},
-		NextHops:      map[uint64]*aftNextHop{},
+func newCompactAFTCache(target string) *aftCache {
+	return &aftCache{
+		compact: newCompactStore(),
+		target:  target,
 	}
 }
 
+// newAFT returns an empty AFT for the given network instances, whose top-level maps are those
+// of the default network instance.
+func newAFT(defaultNetworkInstance string, networkInstances []string) *AFTData {
+	a := &AFTData{
+		DefaultNetworkInstance: defaultNetworkInstance,
+		NetworkInstances:       map[string]*NetworkInstanceAFT{},
+	}
+	for _, name := range append([]string{defaultNetworkInstance}, networkInstances...) {
+		a.NetworkInstances[name] = newNetworkInstanceAFT()
+	}
+	def := a.NetworkInstances[defaultNetworkInstance]
+	a.Prefixes, a.NextHopGroups, a.NextHops = def.Prefixes, def.NextHopGroups, def.NextHops
+	return a
+}
+
 type aftSubscriptionResponse struct {
 	notification *gnmipb.SubscribeResponse
 	err          error
+	received     time.Time // When the response was read from the stream.
 }
 
 // aftSubscribe subscribes to a gNMI client and creates a channel to read from the subscription
@@ -474,12 +711,13 @@ type aftSubscriptionResponse struct {
 // This is somewhat bad practice. I was surprised that this function spawned a goroutine.
 // Functions should not return if they spawn goroutines. (Assume the caller will cancel the context
 // on return.)
-func aftSubscribe(ctx context.Context, t *testing.T, c gnmipb.GNMIClient, dut *ondatra.DUTDevice) <-chan *aftSubscriptionResponse {
+// The time spent waiting for space in a full buffer is added to blocked.
+func aftSubscribe(ctx context.Context, t *testing.T, c gnmipb.GNMIClient, dut *ondatra.DUTDevice, networkInstances []string, families []Family, bufferSize int, blocked *atomic.Int64) <-chan *aftSubscriptionResponse {
 	sub, err := c.Subscribe(ctx)
 	if err != nil {
 		t.Fatalf("error in Subscribe(): %v", err)
 	}
-	req, err := checkForRoutesRequest(dut)
+	req, err := checkForRoutesRequest(dut, networkInstances, families)
 	if err != nil {
 		t.Fatalf("error preparing subscribe request: %v", err)
 	}
@@ -488,14 +726,22 @@ func aftSubscribe(ctx context.Context, t *testing.T, c gnmipb.GNMIClient, dut *o
 		t.Fatalf("error sending subscribe request %v: %v", req, err)
 	}
 
-	buffer := make(chan *aftSubscriptionResponse, aftBufferSize)
+	buffer := make(chan *aftSubscriptionResponse, bufferSize)
 	// Don't need to close the buffer channel. We don't need that signal, the stream stopping logic is with the consumer.
 	go func() {
 		for {
 			n, err := sub.Recv()
-			resp := &aftSubscriptionResponse{n, err}
+			resp := &aftSubscriptionResponse{notification: n, err: err, received: time.Now()}
 			select {
 			case buffer <- resp:
+				continue
+			default:
+			}
+			// The buffer is full. Not reading from the stream pushes back on the DUT through gRPC flow control.
+			s := time.Now()
+			select {
+			case buffer <- resp:
+				blocked.Add(int64(time.Since(s)))
 			case <-ctx.Done():
 				// Context cancellation also makes sub.Recv() return an error. We rely on the out channel's
 				// buffer filling up or random chance (select picks a random available case) to hit
@@ -510,29 +756,83 @@ func aftSubscribe(ctx context.Context, t *testing.T, c gnmipb.GNMIClient, dut *o
 // AFTStreamSession represents a single gNMI AFT streaming session and cached AFT state. It contains
 // a subscription that can be used across multiple calls to ListenUntil().
 type AFTStreamSession struct {
-	buffer            <-chan *aftSubscriptionResponse
-	Cache             *aftCache
-	start             time.Time
-	notifications     []*gnmipb.SubscribeResponse
-	missingPrefixes   map[string]bool
-	failingNHPrefixes map[string]bool
-	debugMode         bool
+	buffer                 <-chan *aftSubscriptionResponse
+	Cache                  *aftCache
+	start                  time.Time
+	notifications          []*gnmipb.SubscribeResponse
+	missingPrefixes        map[string]bool
+	failingNHPrefixes      map[string]bool
+	debugMode              bool
+	defaultNetworkInstance string
+	networkInstances       []string                    // Network instances to stream AFTs of.
+	families               []Family                    // AFT families to stream in addition to NHGs and NHs.
+	replay                 []*gnmipb.SubscribeResponse // Recorded notifications not yet replayed.
+	replayNext             time.Time                   // Simulated time of the next periodic hook run during replay.
+	compact                bool                        // Whether the AFT is stored in a compactStore.
+	bufferSize             int                         // Capacity of buffer, or 0 for the default.
+	blocked                atomic.Int64                // Nanoseconds the stream was not read because buffer was full.
+	timeline               *Timeline                   // Prefix events, recorded only if enabled with WithTimeline.
 }
 
 func (ss *AFTStreamSession) sessionPrefix() string {
 	return fmt.Sprintf("[%s-%d]", ss.Cache.target, ss.start.UnixNano())
 }
 
+// SessionOption configures what an AFTStreamSession streams.
+type SessionOption func(*AFTStreamSession)
+
+// WithNetworkInstances streams the AFTs of the given network instances instead of only the
+// default network instance.
+func WithNetworkInstances(names ...string) SessionOption {
+	return func(ss *AFTStreamSession) {
+		ss.networkInstances = names
+	}
+}
+
+// WithFamilies streams the given AFT families instead of only IPv4 and IPv6 entries. Next
+// hop groups and next hops are always streamed.
+func WithFamilies(families ...Family) SessionOption {
+	return func(ss *AFTStreamSession) {
+		ss.families = families
+	}
+}
+
 // NewAFTStreamSession constructs an AFTStreamSession. It subscribes to a given gNMI client.
-func NewAFTStreamSession(ctx context.Context, t *testing.T, c gnmipb.GNMIClient, dut *ondatra.DUTDevice) *AFTStreamSession {
-	return &AFTStreamSession{
-		buffer:            aftSubscribe(ctx, t, c, dut),
-		Cache:             newAFTCache(dut.Name()),
-		notifications:     []*gnmipb.SubscribeResponse{},
-		missingPrefixes:   make(map[string]bool),
-		failingNHPrefixes: make(map[string]bool),
-		debugMode:         false,
+// By default, the IPv4 and IPv6 entries of the default network instance are streamed.
+func NewAFTStreamSession(ctx context.Context, t *testing.T, c gnmipb.GNMIClient, dut *ondatra.DUTDevice, opts ...SessionOption) *AFTStreamSession {
+	ss := &AFTStreamSession{
+		notifications:          []*gnmipb.SubscribeResponse{},
+		missingPrefixes:        make(map[string]bool),
+		failingNHPrefixes:      make(map[string]bool),
+		debugMode:              false,
+		defaultNetworkInstance: deviations.DefaultNetworkInstance(dut),
+		families:               defaultFamilies,
+	}
+	ss.networkInstances = []string{ss.defaultNetworkInstance}
+	for _, opt := range opts {
+		opt(ss)
+	}
+	ss.Cache = ss.newCache(dut.Name())
+	ss.buffer = aftSubscribe(ctx, t, c, dut, ss.networkInstances, ss.families, ss.bufferSizeOrDefault(), &ss.blocked)
+	return ss
+}
+
+// newCache returns the AFT cache selected by the session options.
+func (ss *AFTStreamSession) newCache(target string) *aftCache {
+	if ss.compact {
+		return newCompactAFTCache(target)
+	}
+	return newAFTCache(target)
+}
+
+func (ss *AFTStreamSession) bufferSizeOrDefault() int {
+	switch {
+	case ss.bufferSize > 0:
+		return ss.bufferSize
+	case ss.compact:
+		return compactBufferSize
 	}
+	return aftBufferSize
 }
 
 // WithDebug enables the storage of all gNMI notifications for debugging purposes.
@@ -571,6 +871,9 @@ func (ss *AFTStreamSession) loggingFinal(t *testing.T) {
 	prefix := ss.sessionPrefix()
 	ss.Cache.logMetadata(t, ss.start, prefix)
 	t.Logf("%s After %v: Finished streaming.", prefix, time.Since(ss.start).Truncate(time.Millisecond))
+	if blocked := time.Duration(ss.blocked.Load()); blocked > 0 {
+		t.Logf("%s Stream was not read for %v because the notification buffer was full.", prefix, blocked.Truncate(time.Millisecond))
+	}
 	if len(ss.missingPrefixes) > 0 {
 		filename, err := writeMissingPrefixes(t, ss.missingPrefixes, ss.Cache.target, ss.start)
 		if err != nil {
@@ -627,35 +930,17 @@ func (ss *AFTStreamSession) listenUntil(ctx context.Context, t *testing.T, timeo
 				// Context cancellation can hit this code path from the stream sending a context cancellation error.
 				t.Fatalf("error from gNMI stream: %v", resp.err)
 			}
-			// Only store notifications if debug mode is enabled.
-			if ss.debugMode {
-				ss.notifications = append(ss.notifications, resp.notification)
-			}
-			for _, hook := range preUpdateHooks {
-				err := hook.NotificationFunc(ss.Cache, resp.notification)
-				if err != nil {
-					t.Fatalf("error in notificationHook %q: %v", hook.Description, err)
-				}
-			}
-			err := ss.Cache.addAFTNotification(resp.notification)
-			switch {
-			case errors.Is(err, cache.ErrStale):
-				// TODO: The log line below is currently commented out to avoid stale log messages. Uncomment it later if the additional logging is required.
-				// t.Logf("Received stale notification with timestamp %v (current time: %v)", time.Unix(0, resp.notification.GetUpdate().GetTimestamp()), time.Now())
-				continue
-			case err != nil:
-				t.Fatalf("error updating AFT cache with response %v: %v", resp.notification, err)
+			if err := ss.handleNotification(resp.notification, resp.received, preUpdateHooks); err != nil {
+				t.Fatal(err)
 			}
 		case <-periodicTicker.C:
 			s := time.Now()
-			for _, hook := range periodicHooks {
-				done, err := hook.PeriodicFunc(ss)
-				if err != nil {
-					t.Fatalf("error in PeriodicHook %q: %v", hook.Description, err)
-				}
-				if done {
-					return
-				}
+			done, err := runPeriodicHooks(ss, periodicHooks)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if done {
+				return
 			}
 			d := time.Since(s)
 			if d > periodicDeadline {
@@ -671,6 +956,48 @@ func (ss *AFTStreamSession) listenUntil(ctx context.Context, t *testing.T, timeo
 	}
 }
 
+// handleNotification runs the preUpdateHooks on a notification received at recv and applies it
+// to the AFT cache.
+func (ss *AFTStreamSession) handleNotification(n *gnmipb.SubscribeResponse, recv time.Time, preUpdateHooks []NotificationHook) error {
+	// Only store notifications if debug mode is enabled.
+	if ss.debugMode {
+		ss.notifications = append(ss.notifications, n)
+	}
+	for _, hook := range preUpdateHooks {
+		err := hook.NotificationFunc(ss.Cache, n)
+		if err != nil {
+			return fmt.Errorf("error in notificationHook %q: %v", hook.Description, err)
+		}
+	}
+	err := ss.Cache.addAFTNotification(n)
+	switch {
+	case errors.Is(err, cache.ErrStale):
+		// TODO: The log line below is currently commented out to avoid stale log messages. Uncomment it later if the additional logging is required.
+		// t.Logf("Received stale notification with timestamp %v (current time: %v)", time.Unix(0, n.GetUpdate().GetTimestamp()), time.Now())
+		return nil
+	case err != nil:
+		return fmt.Errorf("error updating AFT cache with response %v: %v", n, err)
+	}
+	if ss.timeline != nil {
+		ss.timeline.record(n.GetUpdate(), recv)
+	}
+	return nil
+}
+
+// runPeriodicHooks runs the periodic hooks in order and reports whether any of them is done.
+func runPeriodicHooks(ss *AFTStreamSession, periodicHooks []PeriodicHook) (bool, error) {
+	for _, hook := range periodicHooks {
+		done, err := hook.PeriodicFunc(ss)
+		if err != nil {
+			return false, fmt.Errorf("error in PeriodicHook %q: %v", hook.Description, err)
+		}
+		if done {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
 // DeletionStoppingCondition returns a PeriodicHook which can be used to check if all given prefixes have been deleted.
 func DeletionStoppingCondition(t *testing.T, dut *ondatra.DUTDevice, wantDeletePrefixes map[string]bool) PeriodicHook {
 	return PeriodicHook{
@@ -940,10 +1267,23 @@ func parseNH(n *gnmipb.Notification) (uint64, *aftNextHop, error) {
 		case strings.HasSuffix(path, "interface-ref/state/interface"):
 			nh.IntfName = u.Val.GetStringVal()
 			found = true
+		case strings.HasSuffix(path, "next-hop/state/network-instance"):
+			nh.NetworkInstance = u.Val.GetStringVal()
+			found = true
+		case strings.HasSuffix(path, "state/encapsulate-header"):
+			nh.EncapHeader = u.Val.GetStringVal()
+			found = true
+		case strings.HasSuffix(path, "state/decapsulate-header"):
+			nh.DecapHeader = u.Val.GetStringVal()
+			found = true
+		case strings.HasSuffix(path, "ip-in-ip/state/src-ip"):
+			nh.TunnelSrcIP = u.Val.GetStringVal()
+		case strings.HasSuffix(path, "ip-in-ip/state/dst-ip"):
+			nh.TunnelDstIP = u.Val.GetStringVal()
 		}
 	}
 	if !found {
-		err = fmt.Errorf("ip-address, interface, nor lsp-name were found in notification %v. %w", n, ErrNotExist)
+		err = fmt.Errorf("ip-address, interface, lsp-name, network-instance nor encap/decap header were found in notification %v. %w", n, ErrNotExist)
 	}
 	return nhID, nh, err
 }
@@ -995,6 +1335,8 @@ func parseNHG(t *testing.T, n *gnmipb.Notification) (uint64, *aftNextHopGroup, e
 				nhidSeen[id] = struct{}{}
 				nhg.NHIDs = append(nhg.NHIDs, id)
 			}
+		case p == nextHopGroupBackupPath:
+			nhg.BackupNHGID = u.Val.GetUintVal()
 		case p == nextHopWeightPath:
 			nhID, err := strconv.ParseUint(u.Path.GetElem()[6].GetKey()["index"], 10, 64)
 			if err != nil {
@@ -1015,37 +1357,41 @@ func parseNHG(t *testing.T, n *gnmipb.Notification) (uint64, *aftNextHopGroup, e
 	return nhgID, nhg, err
 }
 
-// parsePrefix extracts the IP prefix and next-hop-group ID from an AFT prefix GNMI notification.
-func parsePrefix(t *testing.T, n *gnmipb.Notification, sessionPrefix string) (string, uint64, error) {
+// parsePrefix extracts the IP prefix, next-hop-group ID and next-hop-group-network-instance
+// from an AFT prefix GNMI notification.
+func parsePrefix(t *testing.T, n *gnmipb.Notification, sessionPrefix string) (string, uint64, string, error) {
 	// Normalizes paths for the "updates" in the gNMI notification.
 	updates := schema.NotificationToPoints(n)
 	if len(updates) == 0 {
 		t.Logf("no updates found in parsePrefix")
-		return "", 0, fmt.Errorf("missing updates")
+		return "", 0, "", fmt.Errorf("missing updates")
 	}
 	e := updates[0].Path.GetElem()
 	if len(e) < 5 {
-		return "", 0, fmt.Errorf("invalid prefix path in Notification: %v", n)
+		return "", 0, "", fmt.Errorf("invalid prefix path in Notification: %v", n)
 	}
 	prefix, ok := updates[0].Path.GetElem()[4].GetKey()["prefix"]
 	if !ok {
-		return "", 0, fmt.Errorf("invalid prefix path")
+		return "", 0, "", fmt.Errorf("invalid prefix path")
 	}
 	wantFields := map[string]bool{}
 	nhgID := uint64(0)
+	nhgNI := ""
 	for _, u := range updates {
 		path, err := ygot.PathToSchemaPath(u.Path)
 		if err != nil {
-			return "", 0, fmt.Errorf("error converting path to schema path: %v", err)
+			return "", 0, "", fmt.Errorf("error converting path to schema path: %v", err)
 		}
 		switch {
 		case path == prefixNHGPathV4 || path == prefixNHGPathV6:
 			wantFields[path] = true
 			nhgID = u.Val.GetUintVal()
+		case path == prefixNHGNIPathV4 || path == prefixNHGNIPathV6:
+			nhgNI = u.Val.GetStringVal()
 		case path == prefixPathV4 || path == prefixPathV6:
 			wantFields[path] = true
 			if u.Val.GetStringVal() != prefix {
-				return "", 0, fmt.Errorf("prefix mismatch")
+				return "", 0, "", fmt.Errorf("prefix mismatch")
 			}
 		// known unused paths
 		case slices.Contains(unusedPaths, path):
@@ -1054,12 +1400,49 @@ func parsePrefix(t *testing.T, n *gnmipb.Notification, sessionPrefix string) (st
 		}
 	}
 	if len(wantFields) < 2 {
-		return "", 0, fmt.Errorf("missing required fields %v from the response %v", wantFields, n)
+		return "", 0, "", fmt.Errorf("missing required fields %v from the response %v", wantFields, n)
+	}
+	return prefix, nhgID, nhgNI, nil
+}
+
+// parseEntry extracts the value of the given key, the next-hop-group ID and the
+// next-hop-group-network-instance from an AFT label or MAC entry notification.
+func parseEntry(n *gnmipb.Notification, key string) (string, uint64, string, error) {
+	updates := schema.NotificationToPoints(n)
+	if len(updates) == 0 {
+		return "", 0, "", fmt.Errorf("missing updates in notification %v", n)
+	}
+	e := updates[0].Path.GetElem()
+	if len(e) < 5 {
+		return "", 0, "", fmt.Errorf("invalid entry path in notification %v", n)
+	}
+	val, ok := e[4].GetKey()[key]
+	if !ok {
+		return "", 0, "", fmt.Errorf("%q not a key in element. Notification: %v", key, n)
 	}
-	return prefix, nhgID, nil
+	var nhgID uint64
+	var nhgNI string
+	found := false
+	for _, u := range updates {
+		path, err := ygot.PathToSchemaPath(u.Path)
+		if err != nil {
+			return "", 0, "", fmt.Errorf("error converting path to schema path: %v", err)
+		}
+		switch {
+		case strings.HasSuffix(path, "state/next-hop-group"):
+			nhgID = u.Val.GetUintVal()
+			found = true
+		case strings.HasSuffix(path, "state/next-hop-group-network-instance"):
+			nhgNI = u.Val.GetStringVal()
+		}
+	}
+	if !found {
+		return "", 0, "", fmt.Errorf("next-hop-group not found in notification %v: %w", n, ErrNotExist)
+	}
+	return val, nhgID, nhgNI, nil
 }
 
-func checkForRoutesRequest(dut *ondatra.DUTDevice) (*gnmipb.SubscribeRequest, error) {
+func checkForRoutesRequest(dut *ondatra.DUTDevice, networkInstances []string, families []Family) (*gnmipb.SubscribeRequest, error) {
 	subReq := &gnmipb.SubscribeRequest_Subscribe{
 		Subscribe: &gnmipb.SubscriptionList{
 			Mode:     gnmipb.SubscriptionList_STREAM,
@@ -1067,13 +1450,15 @@ func checkForRoutesRequest(dut *ondatra.DUTDevice) (*gnmipb.SubscribeRequest, er
 			Encoding: gnmipb.Encoding_PROTO,
 		},
 	}
-	for _, paths := range subscriptionPaths(dut) {
-		for _, p := range paths {
-			pp, err := ygot.StringToPath(p, ygot.StructuredPath)
-			if err != nil {
-				return nil, fmt.Errorf("failed to parse path: %v", err)
+	for _, ni := range networkInstances {
+		for _, paths := range subscriptionPaths(ni, families) {
+			for _, p := range paths {
+				pp, err := ygot.StringToPath(p, ygot.StructuredPath)
+				if err != nil {
+					return nil, fmt.Errorf("failed to parse path: %v", err)
+				}
+				subReq.Subscribe.Subscription = append(subReq.Subscribe.Subscription, &gnmipb.Subscription{Path: pp, Mode: gnmipb.SubscriptionMode_ON_CHANGE})
 			}
-			subReq.Subscribe.Subscription = append(subReq.Subscribe.Subscription, &gnmipb.Subscription{Path: pp, Mode: gnmipb.SubscriptionMode_ON_CHANGE})
 		}
 	}
 	return &gnmipb.SubscribeRequest{Request: subReq}, nil
@@ -0,0 +1,114 @@
+// Copyright 2026 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package aftcache
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+// testAFT returns an AFT with a VRF whose prefixes and labels resolve through next hop
+// groups in the default network instance.
+func testAFT() *AFTData {
+	a := newAFT("DEFAULT", []string{"VRF-A"})
+	a.NextHopGroups[1] = &aftNextHopGroup{NHIDs: []uint64{10}, NHWeights: map[uint64]uint64{10: 1}}
+	a.NextHops[10] = &aftNextHop{IP: "192.0.2.1"}
+	a.Prefixes["198.51.100.0/24"] = 1
+
+	vrf := a.NetworkInstances["VRF-A"]
+	vrf.NextHopGroups[1] = &aftNextHopGroup{NHIDs: []uint64{20}, NHWeights: map[uint64]uint64{20: 1}}
+	vrf.NextHops[20] = &aftNextHop{IP: "203.0.113.1"}
+	vrf.Prefixes["10.0.0.0/8"] = 1
+	vrf.Prefixes["10.1.0.0/16"] = 1
+	vrf.PrefixNHGNetworkInstances["10.1.0.0/16"] = "DEFAULT"
+	vrf.LabelEntries[100] = 1
+	vrf.LabelEntries[200] = 1
+	vrf.LabelNHGNetworkInstances[200] = "DEFAULT"
+	vrf.Prefixes["10.2.0.0/16"] = 1
+	vrf.PrefixNHGNetworkInstances["10.2.0.0/16"] = "VRF-B"
+	return a
+}
+
+func TestResolve(t *testing.T) {
+	a := testAFT()
+	tests := []struct {
+		desc    string
+		resolve func() ([]*aftNextHop, error)
+		wantIPs []string
+		wantErr error
+	}{{
+		desc:    "default network instance",
+		resolve: func() ([]*aftNextHop, error) { return a.resolveRoute("198.51.100.0/24") },
+		wantIPs: []string{"192.0.2.1"},
+	}, {
+		desc:    "vrf prefix",
+		resolve: func() ([]*aftNextHop, error) { return a.ResolvePrefix("VRF-A", "10.0.0.0/8", 0) },
+		wantIPs: []string{"203.0.113.1"},
+	}, {
+		desc:    "vrf prefix with nhg in default",
+		resolve: func() ([]*aftNextHop, error) { return a.ResolvePrefix("VRF-A", "10.1.0.0/16", 0) },
+		wantIPs: []string{"192.0.2.1"},
+	}, {
+		desc:    "vrf prefix with nhg in unstreamed network instance",
+		resolve: func() ([]*aftNextHop, error) { return a.ResolvePrefix("VRF-A", "10.2.0.0/16", 0) },
+		wantErr: ErrNotExist,
+	}, {
+		desc:    "missing prefix",
+		resolve: func() ([]*aftNextHop, error) { return a.ResolvePrefix("VRF-A", "192.0.2.0/24", 0) },
+		wantErr: ErrNotExist,
+	}, {
+		desc:    "label",
+		resolve: func() ([]*aftNextHop, error) { return a.ResolveLabel("VRF-A", 100) },
+		wantIPs: []string{"203.0.113.1"},
+	}, {
+		desc:    "label with nhg in default",
+		resolve: func() ([]*aftNextHop, error) { return a.ResolveLabel("VRF-A", 200) },
+		wantIPs: []string{"192.0.2.1"},
+	}, {
+		desc:    "unknown network instance",
+		resolve: func() ([]*aftNextHop, error) { return a.ResolveLabel("VRF-C", 100) },
+		wantErr: ErrNotExist,
+	}}
+	for _, tc := range tests {
+		t.Run(tc.desc, func(t *testing.T) {
+			nhs, err := tc.resolve()
+			if !errors.Is(err, tc.wantErr) {
+				t.Fatalf("got error %v, want %v", err, tc.wantErr)
+			}
+			var gotIPs []string
+			for _, nh := range nhs {
+				gotIPs = append(gotIPs, nh.IP)
+			}
+			if diff := cmp.Diff(tc.wantIPs, gotIPs); diff != "" {
+				t.Errorf("resolved next hops diff (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestSubscriptionPaths(t *testing.T) {
+	got := subscriptionPaths("VRF-A", []Family{FamilyIPv4, FamilyMPLS})
+	want := map[string][]string{
+		"prefix": {"network-instances/network-instance[name=VRF-A]/afts/ipv4-unicast/ipv4-entry"},
+		"label":  {"network-instances/network-instance[name=VRF-A]/afts/mpls/label-entry"},
+		"nhg":    {"network-instances/network-instance[name=VRF-A]/afts/next-hop-groups/next-hop-group"},
+		"nh":     {"network-instances/network-instance[name=VRF-A]/afts/next-hops/next-hop"},
+	}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("subscriptionPaths() diff (-want +got):\n%s", diff)
+	}
+}
@@ -0,0 +1,486 @@
+// Copyright 2026 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package aftcache
+
+import (
+	"fmt"
+	"maps"
+	"net/netip"
+	"slices"
+	"strconv"
+	"testing"
+	"time"
+
+	gnmipb "github.com/openconfig/gnmi/proto/gnmi"
+)
+
+// compactBufferSize is the capacity of the channel queueing notifications from the DUT when
+// the compact store is used. Applying a notification to the compact store is cheap, so the
+// channel only absorbs bursts and the periodic hooks; beyond that the receiving goroutine
+// blocks and gRPC flow control pushes back on the DUT.
+const compactBufferSize = 1 << 16
+
+// WithCompactStore stores AFT entries in a compact columnar store instead of a gNMI cache of
+// full notifications, for sessions streaming millions of prefixes. Notifications are applied
+// directly and not retained, and the notification buffer is bounded to compactBufferSize
+// unless WithBufferSize is also given.
+//
+// Prefixes are stored packed and reported by ToAFT in canonical form, e.g. "2001:db8::/32".
+// Unlike the gNMI cache, the compact store does not reject stale notifications.
+func WithCompactStore() SessionOption {
+	return func(ss *AFTStreamSession) {
+		ss.compact = true
+	}
+}
+
+// WithBufferSize sets the capacity of the channel queueing notifications from the DUT. When it
+// is full, the session stops reading from the stream until notifications are applied.
+func WithBufferSize(n int) SessionOption {
+	return func(ss *AFTStreamSession) {
+		ss.bufferSize = n
+	}
+}
+
+// v6Key is a packed IPv6 prefix.
+type v6Key struct {
+	hi, lo uint64
+	bits   uint8
+}
+
+// compactNHG is a next hop group in the compact store.
+type compactNHG struct {
+	nhg aftNextHopGroup
+	// conditional is set for conditional next hop groups, which are not supported and so are
+	// left out of ToAFT like in the gNMI cache.
+	conditional bool
+}
+
+// compactNetworkInstance holds the entries of one network instance. Prefix and label entries
+// reference next hop groups by their index in compactStore.nhgIDs.
+type compactNetworkInstance struct {
+	v4       map[uint64]uint32
+	v6       map[v6Key]uint32
+	labels   map[uint64]uint32
+	macs     map[string]uint32
+	entryNIs map[string]string // Keyed by prefix, label or MAC; only set for entries with a next-hop-group-network-instance.
+	nhgs     map[uint64]*compactNHG
+	nhs      map[uint64]aftNextHop
+}
+
+func newCompactNetworkInstance() *compactNetworkInstance {
+	return &compactNetworkInstance{
+		v4:       map[uint64]uint32{},
+		v6:       map[v6Key]uint32{},
+		labels:   map[uint64]uint32{},
+		macs:     map[string]uint32{},
+		entryNIs: map[string]string{},
+		nhgs:     map[uint64]*compactNHG{},
+		nhs:      map[uint64]aftNextHop{},
+	}
+}
+
+// compactStore is a memory-bounded AFT store which applies notifications as they arrive
+// instead of caching them.
+type compactStore struct {
+	networkInstances map[string]*compactNetworkInstance
+	nhgIDs           []uint64          // Interned next hop group IDs.
+	nhgIndex         map[uint64]uint32 // Index of a next hop group ID in nhgIDs.
+	strs             map[string]string // Interned interface names, LSP names and next hop IPs.
+	updates, deletes int
+}
+
+func newCompactStore() *compactStore {
+	return &compactStore{
+		networkInstances: map[string]*compactNetworkInstance{},
+		nhgIndex:         map[uint64]uint32{},
+		strs:             map[string]string{},
+	}
+}
+
+func (s *compactStore) networkInstance(name string) *compactNetworkInstance {
+	ni, ok := s.networkInstances[name]
+	if !ok {
+		ni = newCompactNetworkInstance()
+		s.networkInstances[name] = ni
+	}
+	return ni
+}
+
+func (s *compactStore) internNHG(id uint64) uint32 {
+	i, ok := s.nhgIndex[id]
+	if !ok {
+		i = uint32(len(s.nhgIDs))
+		s.nhgIDs = append(s.nhgIDs, id)
+		s.nhgIndex[id] = i
+	}
+	return i
+}
+
+func (s *compactStore) intern(v string) string {
+	if i, ok := s.strs[v]; ok {
+		return i
+	}
+	s.strs[v] = v
+	return v
+}
+
+// aftEntryPath splits a full AFT path into its network instance, the AFT container, e.g.
+// "ipv4-unicast" or "next-hops", and the entry element. It returns false for other paths.
+func aftEntryPath(elems []*gnmipb.PathElem) (ni, container string, entry *gnmipb.PathElem, ok bool) {
+	if len(elems) < 5 || elems[0].GetName() != "network-instances" || elems[2].GetName() != "afts" {
+		return "", "", nil, false
+	}
+	return elems[1].GetKey()["name"], elems[3].GetName(), elems[4], true
+}
+
+func packPrefix(s string) (v4 uint64, v6 v6Key, is4 bool, err error) {
+	p, err := netip.ParsePrefix(s)
+	if err != nil {
+		return 0, v6Key{}, false, err
+	}
+	p = p.Masked()
+	a := p.Addr()
+	if a.Is4() {
+		b := a.As4()
+		return uint64(b[0])<<32 | uint64(b[1])<<24 | uint64(b[2])<<16 | uint64(b[3])<<8 | uint64(p.Bits()), v6Key{}, true, nil
+	}
+	b := a.As16()
+	var k v6Key
+	for i := range 8 {
+		k.hi = k.hi<<8 | uint64(b[i])
+		k.lo = k.lo<<8 | uint64(b[i+8])
+	}
+	k.bits = uint8(p.Bits())
+	return 0, k, false, nil
+}
+
+func unpackV4(k uint64) string {
+	a := netip.AddrFrom4([4]byte{byte(k >> 32), byte(k >> 24), byte(k >> 16), byte(k >> 8)})
+	return netip.PrefixFrom(a, int(k&0xff)).String()
+}
+
+func unpackV6(k v6Key) string {
+	var b [16]byte
+	for i := range 8 {
+		b[i] = byte(k.hi >> (56 - 8*i))
+		b[i+8] = byte(k.lo >> (56 - 8*i))
+	}
+	return netip.PrefixFrom(netip.AddrFrom16(b), int(k.bits)).String()
+}
+
+// unpackPrefix returns the canonical form of a packed prefix.
+func unpackPrefix(v4 uint64, v6 v6Key, is4 bool) string {
+	if is4 {
+		return unpackV4(v4)
+	}
+	return unpackV6(v6)
+}
+
+// apply applies a notification to the store. Atomic notifications replace the entry at their
+// prefix; other notifications update the entries leaf by leaf.
+func (s *compactStore) apply(n *gnmipb.Notification) error {
+	if n.GetAtomic() {
+		if err := s.deleteEntries(n.GetPrefix().GetElem()); err != nil {
+			return err
+		}
+	}
+	var elems []*gnmipb.PathElem
+	for _, d := range n.GetDelete() {
+		elems = append(append(elems[:0], n.GetPrefix().GetElem()...), d.GetElem()...)
+		if err := s.deleteEntries(elems); err != nil {
+			return err
+		}
+		s.deletes++
+	}
+	for _, u := range n.GetUpdate() {
+		elems = append(append(elems[:0], n.GetPrefix().GetElem()...), u.GetPath().GetElem()...)
+		if err := s.update(elems, u.GetVal()); err != nil {
+			return fmt.Errorf("error applying update %v: %w", u, err)
+		}
+		s.updates++
+	}
+	return nil
+}
+
+// update sets a single leaf of an AFT entry.
+func (s *compactStore) update(elems []*gnmipb.PathElem, val *gnmipb.TypedValue) error {
+	name, container, entry, ok := aftEntryPath(elems)
+	if !ok {
+		return nil
+	}
+	leaf := make([]string, 0, len(elems)-5)
+	for _, e := range elems[5:] {
+		leaf = append(leaf, e.GetName())
+	}
+	ni := s.networkInstance(name)
+	switch container {
+	case "ipv4-unicast", "ipv6-unicast":
+		v4, v6, is4, err := packPrefix(entry.GetKey()["prefix"])
+		if err != nil {
+			return err
+		}
+		switch {
+		case slices.Equal(leaf, []string{"state", "next-hop-group"}):
+			i := s.internNHG(val.GetUintVal())
+			if is4 {
+				ni.v4[v4] = i
+			} else {
+				ni.v6[v6] = i
+			}
+		case slices.Equal(leaf, []string{"state", "next-hop-group-network-instance"}):
+			ni.entryNIs[unpackPrefix(v4, v6, is4)] = val.GetStringVal()
+		}
+	case "mpls":
+		label := entry.GetKey()["label"]
+		l, err := strconv.ParseUint(label, 10, 64)
+		if err != nil {
+			// Non-numeric labels, e.g. IPV4_EXPLICIT_NULL, are not supported.
+			return nil
+		}
+		switch {
+		case slices.Equal(leaf, []string{"state", "next-hop-group"}):
+			ni.labels[l] = s.internNHG(val.GetUintVal())
+		case slices.Equal(leaf, []string{"state", "next-hop-group-network-instance"}):
+			ni.entryNIs[label] = val.GetStringVal()
+		}
+	case "ethernet":
+		if slices.Equal(leaf, []string{"state", "next-hop-group"}) {
+			ni.macs[s.intern(entry.GetKey()["mac-address"])] = s.internNHG(val.GetUintVal())
+		}
+	case "next-hop-groups":
+		id, err := strconv.ParseUint(entry.GetKey()["id"], 10, 64)
+		if err != nil {
+			return err
+		}
+		g, ok := ni.nhgs[id]
+		if !ok {
+			g = &compactNHG{nhg: aftNextHopGroup{NHWeights: map[uint64]uint64{}}}
+			ni.nhgs[id] = g
+		}
+		switch {
+		case len(leaf) > 0 && leaf[0] == "condition":
+			g.conditional = true
+		case slices.Equal(leaf, []string{"state", "backup-next-hop-group"}):
+			g.nhg.BackupNHGID = val.GetUintVal()
+		case len(leaf) == 4 && leaf[0] == "next-hops" && leaf[2] == "state":
+			nhID, err := strconv.ParseUint(elems[6].GetKey()["index"], 10, 64)
+			if err != nil {
+				return err
+			}
+			if !slices.Contains(g.nhg.NHIDs, nhID) {
+				g.nhg.NHIDs = append(g.nhg.NHIDs, nhID)
+			}
+			if leaf[3] == "weight" {
+				g.nhg.NHWeights[nhID] = val.GetUintVal()
+			}
+		}
+	case "next-hops":
+		id, err := strconv.ParseUint(entry.GetKey()["index"], 10, 64)
+		if err != nil {
+			return err
+		}
+		nh := ni.nhs[id]
+		switch {
+		case slices.Equal(leaf, []string{"state", "ip-address"}):
+			nh.IP = s.intern(val.GetStringVal())
+		case slices.Equal(leaf, []string{"state", "lsp-name"}):
+			nh.LSPName = s.intern(val.GetStringVal())
+		case slices.Equal(leaf, []string{"interface-ref", "state", "interface"}):
+			nh.IntfName = s.intern(val.GetStringVal())
+		case slices.Equal(leaf, []string{"state", "network-instance"}):
+			nh.NetworkInstance = s.intern(val.GetStringVal())
+		case slices.Equal(leaf, []string{"state", "encapsulate-header"}):
+			nh.EncapHeader = s.intern(val.GetStringVal())
+		case slices.Equal(leaf, []string{"state", "decapsulate-header"}):
+			nh.DecapHeader = s.intern(val.GetStringVal())
+		case slices.Equal(leaf, []string{"ip-in-ip", "state", "src-ip"}):
+			nh.TunnelSrcIP = s.intern(val.GetStringVal())
+		case slices.Equal(leaf, []string{"ip-in-ip", "state", "dst-ip"}):
+			nh.TunnelDstIP = s.intern(val.GetStringVal())
+		}
+		ni.nhs[id] = nh
+	}
+	return nil
+}
+
+// deleteEntries deletes the entries at or below a path. Deleting the next hop of a next hop
+// group removes it from the group; deleting other leaves is ignored.
+func (s *compactStore) deleteEntries(elems []*gnmipb.PathElem) error {
+	switch {
+	case len(elems) == 0:
+		clear(s.networkInstances)
+		return nil
+	case elems[0].GetName() != "network-instances":
+		return nil
+	case len(elems) == 1:
+		clear(s.networkInstances)
+		return nil
+	}
+	name := elems[1].GetKey()["name"]
+	ni, ok := s.networkInstances[name]
+	if !ok {
+		return nil
+	}
+	if len(elems) <= 3 {
+		delete(s.networkInstances, name)
+		return nil
+	}
+	container := elems[3].GetName()
+	if len(elems) == 4 {
+		switch container {
+		case "ipv4-unicast":
+			clear(ni.v4)
+		case "ipv6-unicast":
+			clear(ni.v6)
+		case "mpls":
+			clear(ni.labels)
+		case "ethernet":
+			clear(ni.macs)
+		case "next-hop-groups":
+			clear(ni.nhgs)
+		case "next-hops":
+			clear(ni.nhs)
+		}
+		return nil
+	}
+	entry := elems[4]
+	switch container {
+	case "ipv4-unicast", "ipv6-unicast":
+		if len(elems) > 5 {
+			return nil
+		}
+		v4, v6, is4, err := packPrefix(entry.GetKey()["prefix"])
+		if err != nil {
+			return err
+		}
+		if is4 {
+			delete(ni.v4, v4)
+		} else {
+			delete(ni.v6, v6)
+		}
+		delete(ni.entryNIs, unpackPrefix(v4, v6, is4))
+	case "mpls":
+		if len(elems) > 5 {
+			return nil
+		}
+		label := entry.GetKey()["label"]
+		if l, err := strconv.ParseUint(label, 10, 64); err == nil {
+			delete(ni.labels, l)
+		}
+		delete(ni.entryNIs, label)
+	case "ethernet":
+		if len(elems) == 5 {
+			delete(ni.macs, entry.GetKey()["mac-address"])
+		}
+	case "next-hop-groups":
+		id, err := strconv.ParseUint(entry.GetKey()["id"], 10, 64)
+		if err != nil {
+			return err
+		}
+		switch {
+		case len(elems) == 5:
+			delete(ni.nhgs, id)
+		case len(elems) == 7 && elems[5].GetName() == "next-hops":
+			g, ok := ni.nhgs[id]
+			if !ok {
+				return nil
+			}
+			nhID, err := strconv.ParseUint(elems[6].GetKey()["index"], 10, 64)
+			if err != nil {
+				return err
+			}
+			g.nhg.NHIDs = slices.DeleteFunc(g.nhg.NHIDs, func(id uint64) bool { return id == nhID })
+			delete(g.nhg.NHWeights, nhID)
+		}
+	case "next-hops":
+		if len(elems) > 5 {
+			return nil
+		}
+		id, err := strconv.ParseUint(entry.GetKey()["index"], 10, 64)
+		if err != nil {
+			return err
+		}
+		delete(ni.nhs, id)
+	}
+	return nil
+}
+
+// toNetworkInstanceAFT fills a with the entries of the named network instance. Entries which
+// the gNMI cache based ToAFT would skip, such as next hops without attributes, are skipped.
+func (s *compactStore) toNetworkInstanceAFT(name string, a *NetworkInstanceAFT) error {
+	ni, ok := s.networkInstances[name]
+	if !ok {
+		return nil
+	}
+	for k, i := range ni.v4 {
+		p := unpackV4(k)
+		a.Prefixes[p] = s.nhgIDs[i]
+		if nhgNI := ni.entryNIs[p]; nhgNI != "" && nhgNI != name {
+			a.PrefixNHGNetworkInstances[p] = nhgNI
+		}
+	}
+	for k, i := range ni.v6 {
+		p := unpackV6(k)
+		a.Prefixes[p] = s.nhgIDs[i]
+		if nhgNI := ni.entryNIs[p]; nhgNI != "" && nhgNI != name {
+			a.PrefixNHGNetworkInstances[p] = nhgNI
+		}
+	}
+	for l, i := range ni.labels {
+		a.LabelEntries[l] = s.nhgIDs[i]
+		if nhgNI := ni.entryNIs[strconv.FormatUint(l, 10)]; nhgNI != "" && nhgNI != name {
+			a.LabelNHGNetworkInstances[l] = nhgNI
+		}
+	}
+	for mac, i := range ni.macs {
+		a.MACEntries[mac] = s.nhgIDs[i]
+	}
+	for id, g := range ni.nhgs {
+		if g.conditional {
+			continue
+		}
+		if len(g.nhg.NHIDs) != len(g.nhg.NHWeights) {
+			return fmt.Errorf("missing weights for a few NHIDs of NHG %d in network instance %s", id, name)
+		}
+		a.NextHopGroups[id] = &aftNextHopGroup{
+			NHIDs:       slices.Clone(g.nhg.NHIDs),
+			NHWeights:   maps.Clone(g.nhg.NHWeights),
+			BackupNHGID: g.nhg.BackupNHGID,
+		}
+	}
+	for id, nh := range ni.nhs {
+		if nh == (aftNextHop{}) {
+			continue
+		}
+		a.NextHops[id] = &nh
+	}
+	return nil
+}
+
+// counts returns the number of prefixes, next hop groups and next hops in the store.
+func (s *compactStore) counts() (prefixes, nhgs, nhs int) {
+	for _, ni := range s.networkInstances {
+		prefixes += len(ni.v4) + len(ni.v6)
+		nhgs += len(ni.nhgs)
+		nhs += len(ni.nhs)
+	}
+	return prefixes, nhgs, nhs
+}
+
+// logMetadata sends compact store statistics to testing log.
+func (s *compactStore) logMetadata(t *testing.T, start time.Time, prefix string) {
+	prefixes, nhgs, nhs := s.counts()
+	t.Logf("%s After %v: prefixes:%d nhgs:%d nhs:%d updates:%d deletes:%d ", prefix, time.Since(start).Truncate(time.Millisecond), prefixes, nhgs, nhs, s.updates, s.deletes)
+}
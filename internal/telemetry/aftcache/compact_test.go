@@ -0,0 +1,245 @@
+// Copyright 2026 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package aftcache
+
+import (
+	"fmt"
+	"net/netip"
+	"runtime"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/openconfig/ygot/ygot"
+
+	gnmipb "github.com/openconfig/gnmi/proto/gnmi"
+)
+
+func TestCompactStoreMatchesCache(t *testing.T) {
+	start := time.Unix(1700000000, 0)
+	const vrf = "/network-instances/network-instance[name=VRF-A]/afts"
+	notifications := append(recordedAFT(t),
+		aftNotification(t, start, vrf+"/next-hops/next-hop[index=20]", map[string]*gnmipb.TypedValue{
+			"state/index":      uintVal(20),
+			"state/ip-address": stringVal("2001:db8::1"),
+		}),
+		aftNotification(t, start, vrf+"/next-hop-groups/next-hop-group[id=2]", map[string]*gnmipb.TypedValue{
+			"state/id": uintVal(2),
+			"next-hops/next-hop[index=20]/state/index":  uintVal(20),
+			"next-hops/next-hop[index=20]/state/weight": uintVal(3),
+			"state/backup-next-hop-group":               uintVal(7),
+		}),
+		aftNotification(t, start, vrf+"/ipv6-unicast/ipv6-entry[prefix=2001:db8:1::/48]", map[string]*gnmipb.TypedValue{
+			"state/prefix":                          stringVal("2001:db8:1::/48"),
+			"state/next-hop-group":                  uintVal(2),
+			"state/next-hop-group-network-instance": stringVal("DEFAULT"),
+		}),
+		aftNotification(t, start, vrf+"/ipv6-unicast/ipv6-entry[prefix=2001:db8:2::/48]", map[string]*gnmipb.TypedValue{
+			"state/prefix":         stringVal("2001:db8:2::/48"),
+			"state/next-hop-group": uintVal(2),
+		}),
+		// Replacing the NHG atomically drops its backup NHG.
+		aftNotification(t, start.Add(time.Second), vrf+"/next-hop-groups/next-hop-group[id=2]", map[string]*gnmipb.TypedValue{
+			"state/id": uintVal(2),
+			"next-hops/next-hop[index=20]/state/index":  uintVal(20),
+			"next-hops/next-hop[index=20]/state/weight": uintVal(1),
+		}),
+	)
+	prefix, err := ygot.StringToStructuredPath(vrf + "/ipv6-unicast")
+	if err != nil {
+		t.Fatalf("StringToStructuredPath() failed: %v", err)
+	}
+	del, err := ygot.StringToStructuredPath("ipv6-entry[prefix=2001:db8:2::/48]")
+	if err != nil {
+		t.Fatalf("StringToStructuredPath() failed: %v", err)
+	}
+	notifications = append(notifications, &gnmipb.SubscribeResponse{Response: &gnmipb.SubscribeResponse_Update{Update: &gnmipb.Notification{
+		Timestamp: start.Add(2 * time.Second).UnixNano(),
+		Prefix:    prefix,
+		Delete:    []*gnmipb.Path{del},
+	}}})
+
+	never := PeriodicHook{Description: "never", PeriodicFunc: func(*AFTStreamSession) (bool, error) { return false, nil }}
+	toAFT := func(opts ...SessionOption) *AFTData {
+		t.Helper()
+		opts = append(opts, WithNetworkInstances("DEFAULT", "VRF-A"))
+		ss := NewReplaySession("dut", "DEFAULT", notifications, opts...)
+		if _, err := ss.Replay(nil, never, 0); err != nil {
+			t.Fatalf("Replay() failed: %v", err)
+		}
+		a, err := ss.ToAFT(t, nil)
+		if err != nil {
+			t.Fatalf("ToAFT() failed: %v", err)
+		}
+		return a
+	}
+	want := toAFT()
+	got := toAFT(WithCompactStore())
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("ToAFT() with compact store diff (-cache +compact):\n%s", diff)
+	}
+	if n := len(got.NetworkInstances["VRF-A"].Prefixes); n != 1 {
+		t.Errorf("ToAFT() with compact store got %d VRF prefixes, want 1", n)
+	}
+}
+
+func TestPackPrefix(t *testing.T) {
+	for _, tc := range []struct {
+		in, want string
+	}{
+		{in: "198.51.100.0/24", want: "198.51.100.0/24"},
+		{in: "0.0.0.0/0", want: "0.0.0.0/0"},
+		{in: "192.0.2.1/32", want: "192.0.2.1/32"},
+		{in: "2001:DB8:0:0::/64", want: "2001:db8::/64"},
+		{in: "2001:db8::1/128", want: "2001:db8::1/128"},
+	} {
+		v4, v6, is4, err := packPrefix(tc.in)
+		if err != nil {
+			t.Fatalf("packPrefix(%q) failed: %v", tc.in, err)
+		}
+		if got := unpackPrefix(v4, v6, is4); got != tc.want {
+			t.Errorf("unpackPrefix(packPrefix(%q)) got %q, want %q", tc.in, got, tc.want)
+		}
+	}
+	if _, _, _, err := packPrefix("not-a-prefix"); err == nil {
+		t.Errorf("packPrefix() of an invalid prefix got no error")
+	}
+}
+
+// syntheticAFT generates the notifications of an AFT with nPrefixes prefixes spread over
+// nNIs network instances, three quarters of them IPv4. Each network instance has 64 next hop
+// groups of 2 next hops each. Notifications are passed to yield in batches of batchSize so
+// that scale tests do not hold all of them in memory.
+func syntheticAFT(nPrefixes, nNIs, batchSize int, yield func([]*gnmipb.SubscribeResponse)) {
+	const nNHGs = 64
+	elem := func(name string, key ...string) *gnmipb.PathElem {
+		e := &gnmipb.PathElem{Name: name}
+		if len(key) == 2 {
+			e.Key = map[string]string{key[0]: key[1]}
+		}
+		return e
+	}
+	entryPath := func(ni, container string, entry *gnmipb.PathElem) *gnmipb.Path {
+		return &gnmipb.Path{Elem: []*gnmipb.PathElem{elem("network-instances"), elem("network-instance", "name", ni), elem("afts"), elem(container), entry}}
+	}
+	leaf := func(v *gnmipb.TypedValue, names ...string) *gnmipb.Update {
+		p := &gnmipb.Path{}
+		for _, n := range names {
+			p.Elem = append(p.Elem, elem(n))
+		}
+		return &gnmipb.Update{Path: p, Val: v}
+	}
+	notification := func(prefix *gnmipb.Path, updates ...*gnmipb.Update) *gnmipb.SubscribeResponse {
+		return &gnmipb.SubscribeResponse{Response: &gnmipb.SubscribeResponse_Update{Update: &gnmipb.Notification{
+			Timestamp: time.Now().UnixNano(), Prefix: prefix, Update: updates, Atomic: true,
+		}}}
+	}
+
+	var batch []*gnmipb.SubscribeResponse
+	emit := func(n *gnmipb.SubscribeResponse) {
+		batch = append(batch, n)
+		if len(batch) == batchSize {
+			yield(batch)
+			batch = nil
+		}
+	}
+	for i := range nNIs {
+		ni := fmt.Sprintf("VRF-%d", i)
+		for nh := uint64(1); nh <= 2*nNHGs; nh++ {
+			emit(notification(entryPath(ni, "next-hops", elem("next-hop", "index", strconv.FormatUint(nh, 10))),
+				leaf(uintVal(nh), "state", "index"),
+				leaf(stringVal(netip.AddrFrom4([4]byte{192, 0, byte(nh >> 8), byte(nh)}).String()), "state", "ip-address"),
+				leaf(stringVal(fmt.Sprintf("Ethernet%d", nh%8)), "interface-ref", "state", "interface"),
+			))
+		}
+		for nhg := uint64(1); nhg <= nNHGs; nhg++ {
+			id := strconv.FormatUint(nhg, 10)
+			u := []*gnmipb.Update{leaf(uintVal(nhg), "state", "id")}
+			for _, nh := range []uint64{2*nhg - 1, 2 * nhg} {
+				idx := strconv.FormatUint(nh, 10)
+				u = append(u,
+					&gnmipb.Update{Path: &gnmipb.Path{Elem: []*gnmipb.PathElem{elem("next-hops"), elem("next-hop", "index", idx), elem("state"), elem("index")}}, Val: uintVal(nh)},
+					&gnmipb.Update{Path: &gnmipb.Path{Elem: []*gnmipb.PathElem{elem("next-hops"), elem("next-hop", "index", idx), elem("state"), elem("weight")}}, Val: uintVal(1)},
+				)
+			}
+			emit(notification(entryPath(ni, "next-hop-groups", elem("next-hop-group", "id", id)), u...))
+		}
+	}
+	for i := range nPrefixes {
+		ni := fmt.Sprintf("VRF-%d", i%nNIs)
+		var container, entry, p string
+		if i%4 != 3 {
+			container, entry = "ipv4-unicast", "ipv4-entry"
+			p = fmt.Sprintf("%d.%d.%d.0/24", 1+i>>16&0xff, i>>8&0xff, i&0xff)
+		} else {
+			container, entry = "ipv6-unicast", "ipv6-entry"
+			p = fmt.Sprintf("2001:db8:%x:%x::/64", i>>16, i&0xffff)
+		}
+		emit(notification(entryPath(ni, container, elem(entry, "prefix", p)),
+			leaf(stringVal(p), "state", "prefix"),
+			leaf(uintVal(uint64(1+i%nNHGs)), "state", "next-hop-group"),
+		))
+	}
+	if len(batch) > 0 {
+		yield(batch)
+	}
+}
+
+// benchmarkApply applies a synthetic AFT of n prefixes over 4 network instances to a new
+// cache per iteration and reports the apply throughput and the heap retained per prefix.
+func benchmarkApply(b *testing.B, newCache func() *aftCache, n int) {
+	var bytesPerPrefix float64
+	for b.Loop() {
+		b.StopTimer()
+		runtime.GC()
+		var before, after runtime.MemStats
+		runtime.ReadMemStats(&before)
+		c := newCache()
+		syntheticAFT(n, 4, 1<<16, func(batch []*gnmipb.SubscribeResponse) {
+			b.StartTimer()
+			for _, resp := range batch {
+				if err := c.addAFTNotification(resp); err != nil {
+					b.Fatalf("addAFTNotification() failed: %v", err)
+				}
+			}
+			b.StopTimer()
+		})
+		runtime.GC()
+		runtime.ReadMemStats(&after)
+		bytesPerPrefix = float64(after.HeapAlloc-before.HeapAlloc) / float64(n)
+		runtime.KeepAlive(c)
+		b.StartTimer()
+	}
+	b.ReportMetric(bytesPerPrefix, "B/prefix")
+	b.ReportMetric(float64(n)*float64(b.N)/b.Elapsed().Seconds(), "prefixes/s")
+}
+
+func BenchmarkCompactStoreApply(b *testing.B) {
+	for _, n := range []int{1 << 20, 4 << 20, 8 << 20} {
+		b.Run(fmt.Sprintf("%dM", n>>20), func(b *testing.B) {
+			benchmarkApply(b, func() *aftCache { return newCompactAFTCache("dut") }, n)
+		})
+	}
+}
+
+// BenchmarkCacheApply is the gNMI cache baseline. Larger scales need tens of GB of memory.
+func BenchmarkCacheApply(b *testing.B) {
+	for _, n := range []int{1 << 20} {
+		b.Run(fmt.Sprintf("%dM", n>>20), func(b *testing.B) {
+			benchmarkApply(b, func() *aftCache { return newAFTCache("dut") }, n)
+		})
+	}
+}
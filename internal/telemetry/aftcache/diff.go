@@ -0,0 +1,202 @@
+// Copyright 2026 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package aftcache
+
+import (
+	"cmp"
+	"fmt"
+	"maps"
+	"slices"
+	"strings"
+)
+
+// PrefixChange is a prefix whose next hop group changed between two AFT snapshots.
+type PrefixChange struct {
+	Prefix string
+	OldNHG uint64
+	NewNHG uint64
+}
+
+// WeightChange is a next hop whose weight in a next hop group changed.
+type WeightChange struct {
+	NHID      uint64
+	OldWeight uint64
+	NewWeight uint64
+}
+
+// NHGChange describes how the membership and weights of a next hop group changed.
+type NHGChange struct {
+	ID            uint64
+	AddedNHs      []uint64
+	RemovedNHs    []uint64
+	WeightChanges []WeightChange
+	// OldBackupNHG and NewBackupNHG are set if the backup next hop group changed.
+	OldBackupNHG uint64
+	NewBackupNHG uint64
+}
+
+// NHChange is a next hop whose attributes changed.
+type NHChange struct {
+	ID  uint64
+	Old aftNextHop
+	New aftNextHop
+}
+
+// NetworkInstanceDiff contains the differences of the AFT of one network instance.
+type NetworkInstanceDiff struct {
+	AddedPrefixes   []string
+	RemovedPrefixes []string
+	ChangedPrefixes []PrefixChange
+	AddedNHGs       []uint64
+	RemovedNHGs     []uint64
+	ChangedNHGs     []NHGChange
+	AddedNHs        []uint64
+	RemovedNHs      []uint64
+	ChangedNHs      []NHChange
+}
+
+// Empty reports whether there are no differences.
+func (d *NetworkInstanceDiff) Empty() bool {
+	return len(d.AddedPrefixes)+len(d.RemovedPrefixes)+len(d.ChangedPrefixes)+
+		len(d.AddedNHGs)+len(d.RemovedNHGs)+len(d.ChangedNHGs)+
+		len(d.AddedNHs)+len(d.RemovedNHs)+len(d.ChangedNHs) == 0
+}
+
+// AFTDiff contains the differences between two AFT snapshots, keyed by network instance.
+// Network instances without differences are omitted.
+type AFTDiff struct {
+	NetworkInstances map[string]*NetworkInstanceDiff
+}
+
+// Empty reports whether the snapshots are identical.
+func (d *AFTDiff) Empty() bool {
+	return len(d.NetworkInstances) == 0
+}
+
+// String returns a summary of the differences per network instance.
+func (d *AFTDiff) String() string {
+	if d.Empty() {
+		return "AFTs are identical"
+	}
+	var b strings.Builder
+	for _, name := range slices.Sorted(maps.Keys(d.NetworkInstances)) {
+		ni := d.NetworkInstances[name]
+		fmt.Fprintf(&b, "%s: prefixes +%d -%d ~%d, NHGs +%d -%d ~%d, NHs +%d -%d ~%d\n", name,
+			len(ni.AddedPrefixes), len(ni.RemovedPrefixes), len(ni.ChangedPrefixes),
+			len(ni.AddedNHGs), len(ni.RemovedNHGs), len(ni.ChangedNHGs),
+			len(ni.AddedNHs), len(ni.RemovedNHs), len(ni.ChangedNHs))
+	}
+	return b.String()
+}
+
+// DiffAFT returns the differences from before to after for every network instance present in
+// either snapshot.
+func DiffAFT(before, after *AFTData) *AFTDiff {
+	names := map[string]bool{before.DefaultNetworkInstance: true, after.DefaultNetworkInstance: true}
+	for name := range before.NetworkInstances {
+		names[name] = true
+	}
+	for name := range after.NetworkInstances {
+		names[name] = true
+	}
+	d := &AFTDiff{NetworkInstances: map[string]*NetworkInstanceDiff{}}
+	for name := range names {
+		niDiff := diffNetworkInstance(networkInstanceOrEmpty(before, name), networkInstanceOrEmpty(after, name))
+		if !niDiff.Empty() {
+			d.NetworkInstances[name] = niDiff
+		}
+	}
+	return d
+}
+
+func networkInstanceOrEmpty(a *AFTData, name string) *NetworkInstanceAFT {
+	ni, err := a.networkInstanceAFT(name)
+	if err != nil {
+		return newNetworkInstanceAFT()
+	}
+	return ni
+}
+
+// diffKeys returns the sorted keys only in before, only in after, and in both.
+func diffKeys[K cmp.Ordered, V any](before, after map[K]V) (removed, added, common []K) {
+	for k := range before {
+		if _, ok := after[k]; ok {
+			common = append(common, k)
+		} else {
+			removed = append(removed, k)
+		}
+	}
+	for k := range after {
+		if _, ok := before[k]; !ok {
+			added = append(added, k)
+		}
+	}
+	slices.Sort(removed)
+	slices.Sort(added)
+	slices.Sort(common)
+	return removed, added, common
+}
+
+func diffNetworkInstance(before, after *NetworkInstanceAFT) *NetworkInstanceDiff {
+	d := &NetworkInstanceDiff{}
+	var common []string
+	d.RemovedPrefixes, d.AddedPrefixes, common = diffKeys(before.Prefixes, after.Prefixes)
+	for _, p := range common {
+		if before.Prefixes[p] != after.Prefixes[p] {
+			d.ChangedPrefixes = append(d.ChangedPrefixes, PrefixChange{Prefix: p, OldNHG: before.Prefixes[p], NewNHG: after.Prefixes[p]})
+		}
+	}
+
+	var commonNHGs []uint64
+	d.RemovedNHGs, d.AddedNHGs, commonNHGs = diffKeys(before.NextHopGroups, after.NextHopGroups)
+	for _, id := range commonNHGs {
+		if c, ok := diffNHG(id, before.NextHopGroups[id], after.NextHopGroups[id]); ok {
+			d.ChangedNHGs = append(d.ChangedNHGs, c)
+		}
+	}
+
+	var commonNHs []uint64
+	d.RemovedNHs, d.AddedNHs, commonNHs = diffKeys(before.NextHops, after.NextHops)
+	for _, id := range commonNHs {
+		if o, n := before.NextHops[id], after.NextHops[id]; *o != *n {
+			d.ChangedNHs = append(d.ChangedNHs, NHChange{ID: id, Old: *o, New: *n})
+		}
+	}
+	return d
+}
+
+func diffNHG(id uint64, before, after *aftNextHopGroup) (NHGChange, bool) {
+	c := NHGChange{ID: id}
+	removed, added, common := diffKeys(setOf(before.NHIDs), setOf(after.NHIDs))
+	c.RemovedNHs, c.AddedNHs = removed, added
+	for _, nh := range common {
+		if o, n := before.NHWeights[nh], after.NHWeights[nh]; o != n {
+			c.WeightChanges = append(c.WeightChanges, WeightChange{NHID: nh, OldWeight: o, NewWeight: n})
+		}
+	}
+	if before.BackupNHGID != after.BackupNHGID {
+		c.OldBackupNHG, c.NewBackupNHG = before.BackupNHGID, after.BackupNHGID
+	}
+	changed := len(c.AddedNHs)+len(c.RemovedNHs)+len(c.WeightChanges) > 0 || c.OldBackupNHG != c.NewBackupNHG
+	return c, changed
+}
+
+func setOf(ids []uint64) map[uint64]bool {
+	s := make(map[uint64]bool, len(ids))
+	for _, id := range ids {
+		s[id] = true
+	}
+	return s
+}
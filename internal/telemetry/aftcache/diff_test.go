@@ -0,0 +1,58 @@
+// Copyright 2026 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package aftcache
+
+import (
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestDiffAFT(t *testing.T) {
+	before := forwardingAFT()
+	if d := DiffAFT(before, forwardingAFT()); !d.Empty() {
+		t.Errorf("DiffAFT() of identical AFTs got %v, want empty", d)
+	}
+
+	after := forwardingAFT()
+	delete(after.Prefixes, "100.64.0.0/10")
+	after.Prefixes["192.0.2.128/25"] = 2
+	after.Prefixes["198.51.100.0/24"] = 2
+	after.NextHopGroups[1] = &aftNextHopGroup{NHIDs: []uint64{1, 3}, NHWeights: map[uint64]uint64{1: 1, 3: 1}}
+	after.NextHopGroups[3].BackupNHGID = 2
+	after.NextHops[4] = &aftNextHop{IP: "192.0.2.14", IntfName: "Ethernet5"}
+	delete(after.NextHops, 5)
+	vrf := newNetworkInstanceAFT()
+	vrf.Prefixes["10.0.0.0/8"] = 1
+	after.NetworkInstances["VRF-A"] = vrf
+
+	want := &AFTDiff{NetworkInstances: map[string]*NetworkInstanceDiff{
+		"DEFAULT": {
+			AddedPrefixes:   []string{"192.0.2.128/25"},
+			RemovedPrefixes: []string{"100.64.0.0/10"},
+			ChangedPrefixes: []PrefixChange{{Prefix: "198.51.100.0/24", OldNHG: 1, NewNHG: 2}},
+			ChangedNHGs: []NHGChange{
+				{ID: 1, AddedNHs: []uint64{3}, RemovedNHs: []uint64{2}, WeightChanges: []WeightChange{{NHID: 1, OldWeight: 3, NewWeight: 1}}},
+				{ID: 3, OldBackupNHG: 4, NewBackupNHG: 2},
+			},
+			RemovedNHs: []uint64{5},
+			ChangedNHs: []NHChange{{ID: 4, Old: aftNextHop{IP: "192.0.2.14", IntfName: "Ethernet4"}, New: aftNextHop{IP: "192.0.2.14", IntfName: "Ethernet5"}}},
+		},
+		"VRF-A": {AddedPrefixes: []string{"10.0.0.0/8"}},
+	}}
+	if diff := cmp.Diff(want, DiffAFT(before, after)); diff != "" {
+		t.Errorf("DiffAFT() diff (-want +got):\n%s", diff)
+	}
+}
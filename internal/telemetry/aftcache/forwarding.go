@@ -0,0 +1,331 @@
+// Copyright 2026 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package aftcache
+
+import (
+	"cmp"
+	"fmt"
+	"net/netip"
+	"sort"
+)
+
+// maxRecursionDepth bounds the number of next hops resolved recursively through other
+// prefixes, or through backup next hop groups, for a single lookup.
+const maxRecursionDepth = 16
+
+// prefixTrie is a path-compressed binary trie of IP prefixes of one address family
+// supporting longest prefix match lookups. Nodes only exist for stored prefixes and for
+// branching points, so the trie holds at most 2n nodes for n prefixes.
+type prefixTrie struct {
+	root *trieNode
+}
+
+type trieNode struct {
+	prefix   netip.Prefix // Masked prefix covered by the node.
+	key      string       // AFT prefix stored at the node, if set.
+	set      bool
+	children [2]*trieNode
+}
+
+// bitAt returns the i-th most significant bit of the address.
+func bitAt(a netip.Addr, i int) int {
+	b := a.AsSlice()
+	return int(b[i/8]>>(7-i%8)) & 1
+}
+
+// commonBits returns the number of leading bits shared by the two prefixes, limited to the
+// length of the shorter prefix.
+func commonBits(a, b netip.Prefix) int {
+	n := min(a.Bits(), b.Bits())
+	ab, bb := a.Addr().AsSlice(), b.Addr().AsSlice()
+	for i := 0; i < n; i++ {
+		if (ab[i/8]>>(7-i%8))&1 != (bb[i/8]>>(7-i%8))&1 {
+			return i
+		}
+	}
+	return n
+}
+
+// insert stores the AFT prefix key under the masked prefix p.
+func (t *prefixTrie) insert(p netip.Prefix, key string) {
+	n := &t.root
+	for {
+		cur := *n
+		if cur == nil {
+			*n = &trieNode{prefix: p, key: key, set: true}
+			return
+		}
+		common := commonBits(cur.prefix, p)
+		switch {
+		case common == cur.prefix.Bits() && common == p.Bits():
+			cur.key, cur.set = key, true
+			return
+		case common == cur.prefix.Bits():
+			n = &cur.children[bitAt(p.Addr(), common)]
+			continue
+		case common == p.Bits():
+			leaf := &trieNode{prefix: p, key: key, set: true}
+			leaf.children[bitAt(cur.prefix.Addr(), common)] = cur
+			*n = leaf
+		default:
+			branch := &trieNode{prefix: netip.PrefixFrom(p.Addr(), common).Masked()}
+			branch.children[bitAt(p.Addr(), common)] = &trieNode{prefix: p, key: key, set: true}
+			branch.children[bitAt(cur.prefix.Addr(), common)] = cur
+			*n = branch
+		}
+		return
+	}
+}
+
+// lookup returns the AFT prefix key of the longest prefix containing the address.
+func (t *prefixTrie) lookup(a netip.Addr) (string, bool) {
+	var key string
+	var found bool
+	for n := t.root; n != nil && n.prefix.Contains(a); {
+		if n.set {
+			key, found = n.key, true
+		}
+		if n.prefix.Bits() == a.BitLen() {
+			break
+		}
+		n = n.children[bitAt(a, n.prefix.Bits())]
+	}
+	return key, found
+}
+
+// networkInstanceTries holds the IPv4 and IPv6 prefixes of a network instance.
+type networkInstanceTries struct {
+	v4, v6 prefixTrie
+}
+
+func newNetworkInstanceTries(ni *NetworkInstanceAFT) *networkInstanceTries {
+	tries := &networkInstanceTries{}
+	for key := range ni.Prefixes {
+		p, err := netip.ParsePrefix(key)
+		if err != nil {
+			continue
+		}
+		p = p.Masked()
+		if p.Addr().Is4() {
+			tries.v4.insert(p, key)
+		} else {
+			tries.v6.insert(p, key)
+		}
+	}
+	return tries
+}
+
+func (tries *networkInstanceTries) lookup(a netip.Addr) (string, bool) {
+	a = a.Unmap()
+	if a.Is4() {
+		return tries.v4.lookup(a)
+	}
+	return tries.v6.lookup(a)
+}
+
+// Egress is a final egress of traffic resolved from an AFT.
+type Egress struct {
+	// Interface is the egress interface, if the next hop has one.
+	Interface string
+	// IP is the IP address of the final next hop, if any.
+	IP string
+	// LSPName is the LSP of the final next hop, if any.
+	LSPName string
+	// NetworkInstance is set instead of the other fields for traffic handed to a network
+	// instance for another lookup, e.g. of the inner packet after decapsulation, which the
+	// AFT alone cannot resolve.
+	NetworkInstance string
+	// DecapHeader is the header type, e.g. "IPV4", removed before the lookup in
+	// NetworkInstance, if any.
+	DecapHeader string
+	// Share is the fraction of the traffic sent to this egress, between 0 and 1.
+	Share float64
+}
+
+type egressKey struct {
+	intf, ip, lsp, ni, decap string
+}
+
+// Forwarding predicts where packets are forwarded according to an AFT snapshot. It holds
+// longest prefix match tries for every network instance of the AFT, so it should be
+// rebuilt with AFTData.Forwarding when the AFT changes.
+type Forwarding struct {
+	aft   *AFTData
+	tries map[string]*networkInstanceTries
+}
+
+// Forwarding builds the longest prefix match tries of every network instance of the AFT.
+func (a *AFTData) Forwarding() *Forwarding {
+	f := &Forwarding{aft: a, tries: map[string]*networkInstanceTries{}}
+	f.tries[a.DefaultNetworkInstance] = newNetworkInstanceTries(a.defaultNetworkInstanceAFT())
+	for name, ni := range a.NetworkInstances {
+		if name != a.DefaultNetworkInstance {
+			f.tries[name] = newNetworkInstanceTries(ni)
+		}
+	}
+	return f
+}
+
+// LongestMatch returns the longest prefix of the network instance which contains dst.
+func (f *Forwarding) LongestMatch(networkInstance string, dst netip.Addr) (string, bool) {
+	tries, ok := f.tries[networkInstance]
+	if !ok {
+		return "", false
+	}
+	return tries.lookup(dst)
+}
+
+// Egress returns where a packet to dst with the given DSCP bits in the network instance is
+// forwarded, as the final egresses and the share of traffic each receives. The matching
+// prefix is found by longest prefix match. Next hops without an interface or LSP are
+// resolved recursively by looking up their IP, and next hops that only decapsulate or only
+// name a network instance are egresses to that network instance. Traffic is spread over the
+// next hops of a next hop group by weight, skipping next hops that cannot be resolved, and
+// falls back to the backup next hop group if no next hop is usable. A nil result means the
+// packet is dropped.
+func (f *Forwarding) Egress(networkInstance string, dst netip.Addr, dscp uint8) ([]*Egress, error) {
+	prefix, ok := f.LongestMatch(networkInstance, dst)
+	if !ok {
+		return nil, fmt.Errorf("no route to %v in network instance %s: %w", dst, networkInstance, ErrNotExist)
+	}
+	shares, err := f.prefixEgress(networkInstance, prefix, dscp, 0)
+	if err != nil {
+		return nil, err
+	}
+	var out []*Egress
+	for k, share := range shares {
+		out = append(out, &Egress{Interface: k.intf, IP: k.ip, LSPName: k.lsp, NetworkInstance: k.ni, DecapHeader: k.decap, Share: share})
+	}
+	sort.Slice(out, func(i, j int) bool {
+		a, b := out[i], out[j]
+		return cmp.Or(
+			cmp.Compare(a.Interface, b.Interface),
+			cmp.Compare(a.IP, b.IP),
+			cmp.Compare(a.LSPName, b.LSPName),
+			cmp.Compare(a.NetworkInstance, b.NetworkInstance),
+			cmp.Compare(a.DecapHeader, b.DecapHeader),
+		) < 0
+	})
+	return out, nil
+}
+
+// InterfaceShares returns the share of traffic to dst sent out of each egress interface.
+// Traffic handed to a network instance is counted under the empty interface name.
+func (f *Forwarding) InterfaceShares(networkInstance string, dst netip.Addr, dscp uint8) (map[string]float64, error) {
+	egress, err := f.Egress(networkInstance, dst, dscp)
+	if err != nil {
+		return nil, err
+	}
+	shares := map[string]float64{}
+	for _, e := range egress {
+		shares[e.Interface] += e.Share
+	}
+	return shares, nil
+}
+
+// prefixEgress returns the egress shares of a prefix of the network instance.
+func (f *Forwarding) prefixEgress(name, prefix string, dscp uint8, depth int) (map[egressKey]float64, error) {
+	ni, err := f.aft.networkInstanceAFT(name)
+	if err != nil {
+		return nil, err
+	}
+	nhgID, ok := ni.Prefixes[prefix]
+	if !ok {
+		return nil, fmt.Errorf("missing prefix. want %s, %w", prefix, ErrNotExist)
+	}
+	if nhgNI, ok := ni.PrefixNHGNetworkInstances[prefix]; ok {
+		name = nhgNI
+	}
+	return f.groupEgress(name, nhgID, dscp, depth, "prefix "+prefix)
+}
+
+// groupEgress returns the egress shares of a next hop group of the network instance.
+func (f *Forwarding) groupEgress(name string, nhgID uint64, dscp uint8, depth int, entry string) (map[egressKey]float64, error) {
+	if depth > maxRecursionDepth {
+		return nil, fmt.Errorf("recursion for %s exceeds depth %d", entry, maxRecursionDepth)
+	}
+	ni, err := f.aft.networkInstanceAFT(name)
+	if err != nil {
+		return nil, fmt.Errorf("missing reference for %s: %w", entry, err)
+	}
+	if _, ok := ni.NextHopGroups[nhgID]; !ok {
+		return nil, fmt.Errorf("missing reference for %s, NHG %d not found: %w", entry, nhgID, ErrNotExist)
+	}
+	nhgID, ok, err := ni.selectNHG(nhgID, dscp, entry)
+	if err != nil || !ok {
+		return nil, err
+	}
+	nhg := ni.NextHopGroups[nhgID]
+	shares := map[egressKey]float64{}
+	var usable float64
+	for _, nhID := range nhg.NHIDs {
+		nh, ok := ni.NextHops[nhID]
+		if !ok {
+			return nil, fmt.Errorf("missing reference for %s, NH %d not found, %w", entry, nhID, ErrNotExist)
+		}
+		nhShares, err := f.nextHopEgress(name, nh, dscp, depth, entry)
+		if err != nil {
+			return nil, err
+		}
+		if len(nhShares) == 0 {
+			continue
+		}
+		w := float64(nhg.NHWeights[nhID])
+		if w == 0 {
+			w = 1
+		}
+		usable += w
+		for k, s := range nhShares {
+			shares[k] += w * s
+		}
+	}
+	if usable == 0 {
+		if nhg.BackupNHGID != 0 {
+			return f.groupEgress(name, nhg.BackupNHGID, dscp, depth+1, fmt.Sprintf("%s backup NHG %d", entry, nhg.BackupNHGID))
+		}
+		return nil, nil
+	}
+	for k := range shares {
+		shares[k] /= usable
+	}
+	return shares, nil
+}
+
+// nextHopEgress returns the egress shares of a next hop. A next hop with only an IP is
+// resolved through the longest matching prefix of its network instance, by default that of
+// the next hop group. A next hop without an IP which decapsulates or names a network instance
+// hands the traffic to that network instance. It returns no shares if the next hop cannot be
+// resolved.
+func (f *Forwarding) nextHopEgress(name string, nh *aftNextHop, dscp uint8, depth int, entry string) (map[egressKey]float64, error) {
+	if nh.IntfName != "" || nh.LSPName != "" {
+		return map[egressKey]float64{{intf: nh.IntfName, ip: nh.IP, lsp: nh.LSPName}: 1}, nil
+	}
+	lookupNI := cmp.Or(nh.NetworkInstance, name)
+	if nh.IP == "" && (nh.DecapHeader != "" || nh.NetworkInstance != "") {
+		return map[egressKey]float64{{ni: lookupNI, decap: nh.DecapHeader}: 1}, nil
+	}
+	addr, err := netip.ParseAddr(nh.IP)
+	if err != nil {
+		return nil, nil
+	}
+	prefix, ok := f.LongestMatch(lookupNI, addr)
+	if !ok {
+		return nil, nil
+	}
+	if depth+1 > maxRecursionDepth {
+		return nil, fmt.Errorf("recursion for %s exceeds depth %d resolving next hop %s", entry, maxRecursionDepth, nh.IP)
+	}
+	return f.prefixEgress(lookupNI, prefix, dscp, depth+1)
+}
@@ -0,0 +1,163 @@
+// Copyright 2026 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package aftcache
+
+import (
+	"errors"
+	"fmt"
+	"net/netip"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestLongestMatch(t *testing.T) {
+	a := newAFT("DEFAULT", nil)
+	for _, p := range []string{"0.0.0.0/0", "198.51.100.0/24", "198.51.100.0/25", "198.51.100.128/26", "198.51.0.0/16", "2001:db8::/32", "2001:db8:1::/48"} {
+		a.Prefixes[p] = 1
+	}
+	f := a.Forwarding()
+	tests := []struct {
+		dst    string
+		want   string
+		wantOK bool
+	}{
+		{dst: "198.51.100.7", want: "198.51.100.0/25", wantOK: true},
+		{dst: "198.51.100.130", want: "198.51.100.128/26", wantOK: true},
+		{dst: "198.51.100.200", want: "198.51.100.0/24", wantOK: true},
+		{dst: "198.51.7.1", want: "198.51.0.0/16", wantOK: true},
+		{dst: "203.0.113.1", want: "0.0.0.0/0", wantOK: true},
+		{dst: "2001:db8:1::1", want: "2001:db8:1::/48", wantOK: true},
+		{dst: "2001:db8:2::1", want: "2001:db8::/32", wantOK: true},
+		{dst: "2001:db9::1"},
+	}
+	for _, tc := range tests {
+		got, ok := f.LongestMatch("DEFAULT", netip.MustParseAddr(tc.dst))
+		if got != tc.want || ok != tc.wantOK {
+			t.Errorf("LongestMatch(%s) got %q, %v, want %q, %v", tc.dst, got, ok, tc.want, tc.wantOK)
+		}
+	}
+}
+
+// forwardingAFT returns an AFT where 198.51.100.0/24 is spread 3:1 over two next hops, the
+// first of which is resolved recursively, and 203.0.113.0/24 uses a backup NHG because its
+// primary next hop cannot be resolved.
+func forwardingAFT() *AFTData {
+	a := newAFT("DEFAULT", nil)
+	a.NextHops[1] = &aftNextHop{IP: "192.0.2.1"}
+	a.NextHops[2] = &aftNextHop{IP: "192.0.2.6", IntfName: "Ethernet2"}
+	a.NextHops[3] = &aftNextHop{IP: "192.0.2.10", IntfName: "Ethernet3"}
+	a.NextHops[4] = &aftNextHop{IP: "192.0.2.14", IntfName: "Ethernet4"}
+	a.NextHops[5] = &aftNextHop{IP: "10.99.0.1"}
+	a.NextHopGroups[1] = &aftNextHopGroup{NHIDs: []uint64{1, 2}, NHWeights: map[uint64]uint64{1: 3, 2: 1}}
+	a.NextHopGroups[2] = &aftNextHopGroup{NHIDs: []uint64{3, 4}, NHWeights: map[uint64]uint64{3: 1, 4: 1}}
+	a.NextHopGroups[3] = &aftNextHopGroup{NHIDs: []uint64{5}, NHWeights: map[uint64]uint64{5: 1}, BackupNHGID: 4}
+	a.NextHopGroups[4] = &aftNextHopGroup{NHIDs: []uint64{2}, NHWeights: map[uint64]uint64{2: 1}}
+	a.NextHopGroups[5] = &aftNextHopGroup{Conditionals: []*aftNextHopGroupConditional{{DSCP: []uint8{10}, NHGID: 4}, {DSCP: []uint8{0}, NHGID: 2}}}
+	a.Prefixes["198.51.100.0/24"] = 1
+	a.Prefixes["192.0.2.0/30"] = 2
+	a.Prefixes["203.0.113.0/24"] = 3
+	a.Prefixes["100.64.0.0/10"] = 5
+	return a
+}
+
+func TestEgress(t *testing.T) {
+	f := forwardingAFT().Forwarding()
+	tests := []struct {
+		desc    string
+		dst     string
+		dscp    uint8
+		want    map[string]float64
+		wantErr error
+	}{{
+		desc: "weighted ecmp with recursive next hop",
+		dst:  "198.51.100.7",
+		want: map[string]float64{"Ethernet2": 0.25, "Ethernet3": 0.375, "Ethernet4": 0.375},
+	}, {
+		desc: "backup next hop group",
+		dst:  "203.0.113.1",
+		want: map[string]float64{"Ethernet2": 1},
+	}, {
+		desc: "conditional next hop group",
+		dst:  "100.64.1.1",
+		dscp: 10,
+		want: map[string]float64{"Ethernet2": 1},
+	}, {
+		desc: "conditional next hop group default",
+		dst:  "100.64.1.1",
+		want: map[string]float64{"Ethernet3": 0.5, "Ethernet4": 0.5},
+	}, {
+		desc:    "no route",
+		dst:     "10.0.0.1",
+		wantErr: ErrNotExist,
+	}}
+	for _, tc := range tests {
+		t.Run(tc.desc, func(t *testing.T) {
+			got, err := f.InterfaceShares("DEFAULT", netip.MustParseAddr(tc.dst), tc.dscp)
+			if !errors.Is(err, tc.wantErr) {
+				t.Fatalf("InterfaceShares() got error %v, want %v", err, tc.wantErr)
+			}
+			if diff := cmp.Diff(tc.want, got); diff != "" {
+				t.Errorf("InterfaceShares() diff (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestEgressNetworkInstance(t *testing.T) {
+	a := newAFT("DEFAULT", []string{"VRF-A"})
+	a.NextHops[1] = &aftNextHop{DecapHeader: "IPV4", NetworkInstance: "VRF-A"}
+	a.NextHops[2] = &aftNextHop{DecapHeader: "IPV6"}
+	a.NextHops[3] = &aftNextHop{NetworkInstance: "VRF-A"}
+	a.NextHops[4] = &aftNextHop{IP: "192.0.2.1", NetworkInstance: "VRF-A"}
+	for id := range uint64(4) {
+		a.NextHopGroups[id+1] = &aftNextHopGroup{NHIDs: []uint64{id + 1}, NHWeights: map[uint64]uint64{id + 1: 1}}
+		a.Prefixes[fmt.Sprintf("198.51.100.%d/32", id+1)] = id + 1
+	}
+	vrf := a.NetworkInstances["VRF-A"]
+	vrf.NextHops[1] = &aftNextHop{IP: "192.0.2.2", IntfName: "Ethernet2"}
+	vrf.NextHopGroups[1] = &aftNextHopGroup{NHIDs: []uint64{1}, NHWeights: map[uint64]uint64{1: 1}}
+	vrf.Prefixes["192.0.2.0/30"] = 1
+	f := a.Forwarding()
+
+	tests := []struct {
+		dst  string
+		want []*Egress
+	}{
+		{dst: "198.51.100.1", want: []*Egress{{NetworkInstance: "VRF-A", DecapHeader: "IPV4", Share: 1}}},
+		{dst: "198.51.100.2", want: []*Egress{{NetworkInstance: "DEFAULT", DecapHeader: "IPV6", Share: 1}}},
+		{dst: "198.51.100.3", want: []*Egress{{NetworkInstance: "VRF-A", Share: 1}}},
+		{dst: "198.51.100.4", want: []*Egress{{Interface: "Ethernet2", IP: "192.0.2.2", Share: 1}}},
+	}
+	for _, tc := range tests {
+		got, err := f.Egress("DEFAULT", netip.MustParseAddr(tc.dst), 0)
+		if err != nil {
+			t.Fatalf("Egress(%s) got error: %v", tc.dst, err)
+		}
+		if diff := cmp.Diff(tc.want, got); diff != "" {
+			t.Errorf("Egress(%s) diff (-want +got):\n%s", tc.dst, diff)
+		}
+	}
+}
+
+func TestEgressRecursionLoop(t *testing.T) {
+	a := newAFT("DEFAULT", nil)
+	a.NextHops[1] = &aftNextHop{IP: "192.0.2.1"}
+	a.NextHopGroups[1] = &aftNextHopGroup{NHIDs: []uint64{1}, NHWeights: map[uint64]uint64{1: 1}}
+	a.Prefixes["192.0.2.0/24"] = 1
+	if _, err := a.Forwarding().Egress("DEFAULT", netip.MustParseAddr("192.0.2.7"), 0); err == nil {
+		t.Errorf("Egress() with a recursion loop got no error")
+	}
+}
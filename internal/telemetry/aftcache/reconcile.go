@@ -0,0 +1,446 @@
+// Copyright 2026 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package aftcache
+
+import (
+	"fmt"
+	"maps"
+	"net/netip"
+	"slices"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/openconfig/gribigo/fluent"
+	"github.com/openconfig/ondatra"
+
+	aftpb "github.com/openconfig/gribi/v1/proto/gribi_aft"
+	enumspb "github.com/openconfig/gribi/v1/proto/gribi_aft/enums"
+)
+
+// EntryRef identifies a prefix or MPLS label entry of a network instance. Entry is a
+// canonical prefix such as "198.51.100.0/24" or a label such as "label:100".
+type EntryRef struct {
+	NetworkInstance string
+	Entry           string
+}
+
+func (r EntryRef) String() string {
+	return r.NetworkInstance + "/" + r.Entry
+}
+
+// NHGMismatch is an entry whose next hop group differs in membership or weights from the
+// one programmed. Want and Got describe the next hops with their weights, reduced to lowest
+// terms since devices may scale weights.
+type NHGMismatch struct {
+	Ref  EntryRef
+	Want []string
+	Got  []string
+}
+
+// HeaderMismatch is a next hop of an entry whose encapsulation or decapsulation differs from
+// the one programmed.
+type HeaderMismatch struct {
+	Ref  EntryRef
+	Want aftNextHop
+	Got  aftNextHop
+}
+
+// Reconciliation is the result of comparing programmed gRIBI entries against an AFT.
+type Reconciliation struct {
+	// Missing contains the programmed entries not found in the AFT.
+	Missing []EntryRef
+	// Unresolved contains the programmed entries found in the AFT whose next hop group or one
+	// of its next hops is not in the AFT.
+	Unresolved []EntryRef
+	// NHGMismatches contains the entries resolving to different next hops or weights.
+	NHGMismatches []NHGMismatch
+	// HeaderMismatches contains the next hops with different encap or decap headers.
+	HeaderMismatches []HeaderMismatch
+	// Extra contains entries of the reconciled network instances that were not programmed.
+	Extra []EntryRef
+}
+
+// OK reports whether the AFT matches the programmed entries.
+func (r *Reconciliation) OK() bool {
+	return len(r.Missing)+len(r.Unresolved)+len(r.NHGMismatches)+len(r.HeaderMismatches)+len(r.Extra) == 0
+}
+
+// String returns a summary of the differences with up to maxSample examples of each.
+func (r *Reconciliation) String() string {
+	const maxSample = 10
+	if r.OK() {
+		return "AFT matches the programmed gRIBI entries"
+	}
+	var b strings.Builder
+	sample := func(name string, n int, item func(i int) string) {
+		if n == 0 {
+			return
+		}
+		fmt.Fprintf(&b, "%d %s:\n", n, name)
+		for i := range min(n, maxSample) {
+			fmt.Fprintf(&b, "  %s\n", item(i))
+		}
+	}
+	sample("missing entries", len(r.Missing), func(i int) string { return r.Missing[i].String() })
+	sample("unresolved entries", len(r.Unresolved), func(i int) string { return r.Unresolved[i].String() })
+	sample("next hop group mismatches", len(r.NHGMismatches), func(i int) string {
+		m := r.NHGMismatches[i]
+		return fmt.Sprintf("%s: want %v, got %v", m.Ref, m.Want, m.Got)
+	})
+	sample("header mismatches", len(r.HeaderMismatches), func(i int) string {
+		m := r.HeaderMismatches[i]
+		return fmt.Sprintf("%s: want %+v, got %+v", m.Ref, m.Want, m.Got)
+	})
+	sample("extra entries", len(r.Extra), func(i int) string { return r.Extra[i].String() })
+	return b.String()
+}
+
+// ReconcileOption configures Reconcile.
+type ReconcileOption func(*reconcileOptions)
+
+type reconcileOptions struct {
+	ignoreExtra func(networkInstance, entry string) bool
+}
+
+// IgnoreExtra excludes entries for which ignore returns true from Reconciliation.Extra, e.g.
+// connected or static routes of the default network instance.
+func IgnoreExtra(ignore func(networkInstance, entry string) bool) ReconcileOption {
+	return func(o *reconcileOptions) {
+		o.ignoreExtra = ignore
+	}
+}
+
+// expectedEntry is a programmed prefix or label entry.
+type expectedEntry struct {
+	nhg   uint64
+	nhgNI string
+}
+
+// expectedNetworkInstance is the AFT graph of one network instance built from gRIBI entries.
+type expectedNetworkInstance struct {
+	entries map[string]expectedEntry
+	nhgs    map[uint64]map[uint64]uint64 // NHG ID to NH ID to weight.
+	nhs     map[uint64]aftNextHop
+}
+
+// expectedAFT builds the AFT graph that programming entries is expected to produce.
+func expectedAFT(entries []fluent.GRIBIEntry) (map[string]*expectedNetworkInstance, error) {
+	nis := map[string]*expectedNetworkInstance{}
+	for _, e := range entries {
+		ep, err := e.EntryProto()
+		if err != nil {
+			return nil, fmt.Errorf("cannot build gRIBI entry %v: %v", e, err)
+		}
+		name := ep.GetNetworkInstance()
+		ni, ok := nis[name]
+		if !ok {
+			ni = &expectedNetworkInstance{entries: map[string]expectedEntry{}, nhgs: map[uint64]map[uint64]uint64{}, nhs: map[uint64]aftNextHop{}}
+			nis[name] = ni
+		}
+		switch {
+		case ep.GetIpv4() != nil:
+			p, err := canonicalPrefix(ep.GetIpv4().GetPrefix())
+			if err != nil {
+				return nil, err
+			}
+			v := ep.GetIpv4().GetIpv4Entry()
+			ni.entries[p] = expectedEntry{nhg: v.GetNextHopGroup().GetValue(), nhgNI: v.GetNextHopGroupNetworkInstance().GetValue()}
+		case ep.GetIpv6() != nil:
+			p, err := canonicalPrefix(ep.GetIpv6().GetPrefix())
+			if err != nil {
+				return nil, err
+			}
+			v := ep.GetIpv6().GetIpv6Entry()
+			ni.entries[p] = expectedEntry{nhg: v.GetNextHopGroup().GetValue(), nhgNI: v.GetNextHopGroupNetworkInstance().GetValue()}
+		case ep.GetMpls() != nil:
+			l, ok := ep.GetMpls().GetLabel().(*aftpb.Afts_LabelEntryKey_LabelUint64)
+			if !ok {
+				return nil, fmt.Errorf("label entry %v without numeric label: %w", ep.GetMpls(), ErrUnsupported)
+			}
+			v := ep.GetMpls().GetLabelEntry()
+			ni.entries[labelEntry(l.LabelUint64)] = expectedEntry{nhg: v.GetNextHopGroup().GetValue(), nhgNI: v.GetNextHopGroupNetworkInstance().GetValue()}
+		case ep.GetNextHopGroup() != nil:
+			members := map[uint64]uint64{}
+			for _, nh := range ep.GetNextHopGroup().GetNextHopGroup().GetNextHop() {
+				members[nh.GetIndex()] = nh.GetNextHop().GetWeight().GetValue()
+			}
+			ni.nhgs[ep.GetNextHopGroup().GetId()] = members
+		case ep.GetNextHop() != nil:
+			ni.nhs[ep.GetNextHop().GetIndex()] = gribiNextHop(ep.GetNextHop().GetNextHop())
+		default:
+			return nil, fmt.Errorf("gRIBI entry %v: %w", ep, ErrUnsupported)
+		}
+	}
+	return nis, nil
+}
+
+// gribiNextHop converts a gRIBI next hop to the attributes reported in AFT telemetry.
+func gribiNextHop(nh *aftpb.Afts_NextHop) aftNextHop {
+	return aftNextHop{
+		IP:              nh.GetIpAddress().GetValue(),
+		IntfName:        nh.GetInterfaceRef().GetInterface().GetValue(),
+		NetworkInstance: nh.GetNetworkInstance().GetValue(),
+		EncapHeader:     encapHeaderName(nh.GetEncapsulateHeader()),
+		DecapHeader:     encapHeaderName(nh.GetDecapsulateHeader()),
+		TunnelSrcIP:     nh.GetIpInIp().GetSrcIp().GetValue(),
+		TunnelDstIP:     nh.GetIpInIp().GetDstIp().GetValue(),
+	}
+}
+
+// encapHeaderName returns the OpenConfig name of a header type, e.g. "IPV4".
+func encapHeaderName(h enumspb.OpenconfigAftTypesEncapsulationHeaderType) string {
+	if h == enumspb.OpenconfigAftTypesEncapsulationHeaderType_OPENCONFIGAFTTYPESENCAPSULATIONHEADERTYPE_UNSET {
+		return ""
+	}
+	return strings.TrimPrefix(h.String(), "OPENCONFIGAFTTYPESENCAPSULATIONHEADERTYPE_")
+}
+
+func canonicalPrefix(s string) (string, error) {
+	p, err := netip.ParsePrefix(s)
+	if err != nil {
+		return "", err
+	}
+	return p.Masked().String(), nil
+}
+
+func labelEntry(l uint64) string {
+	return "label:" + strconv.FormatUint(l, 10)
+}
+
+// identity reports whether got is the next hop identified by want. Attributes not
+// programmed are ignored since the device may add them, such as the resolved interface of a
+// next hop programmed with only an IP. Headers are compared separately.
+func identity(want, got aftNextHop) bool {
+	return (want.IP == "" || want.IP == got.IP) &&
+		(want.IntfName == "" || want.IntfName == got.IntfName) &&
+		(want.NetworkInstance == "" || want.NetworkInstance == got.NetworkInstance) &&
+		(want.TunnelDstIP == "" || want.TunnelDstIP == got.TunnelDstIP)
+}
+
+func headersMatch(want, got aftNextHop) bool {
+	return want.EncapHeader == got.EncapHeader && want.DecapHeader == got.DecapHeader &&
+		(want.TunnelSrcIP == "" || want.TunnelSrcIP == got.TunnelSrcIP)
+}
+
+// member is a next hop of a next hop group with its weight.
+type member struct {
+	nh     aftNextHop
+	weight uint64
+}
+
+func describeMembers(ms []member) []string {
+	var weights []uint64
+	for _, m := range ms {
+		weights = append(weights, m.weight)
+	}
+	weights = reduceWeights(weights)
+	var out []string
+	for i, m := range ms {
+		out = append(out, fmt.Sprintf("%+v*%d", m.nh, weights[i]))
+	}
+	slices.Sort(out)
+	return out
+}
+
+// reduceWeights divides weights by their greatest common divisor.
+func reduceWeights(weights []uint64) []uint64 {
+	var g uint64
+	for _, w := range weights {
+		g = gcd(g, w)
+	}
+	if g <= 1 {
+		return weights
+	}
+	out := make([]uint64, len(weights))
+	for i, w := range weights {
+		out[i] = w / g
+	}
+	return out
+}
+
+func gcd(a, b uint64) uint64 {
+	for b != 0 {
+		a, b = b, a%b
+	}
+	return a
+}
+
+// actualEntries returns the prefix and label entries of a network instance keyed like
+// expectedNetworkInstance.entries.
+func actualEntries(ni *NetworkInstanceAFT) map[string]expectedEntry {
+	out := map[string]expectedEntry{}
+	for p, nhg := range ni.Prefixes {
+		key := p
+		if c, err := canonicalPrefix(p); err == nil {
+			key = c
+		}
+		out[key] = expectedEntry{nhg: nhg, nhgNI: ni.PrefixNHGNetworkInstances[p]}
+	}
+	for l, nhg := range ni.LabelEntries {
+		out[labelEntry(l)] = expectedEntry{nhg: nhg, nhgNI: ni.LabelNHGNetworkInstances[l]}
+	}
+	return out
+}
+
+// Reconcile compares the AFT against the AFT graph that programming the gRIBI entries is
+// expected to produce. Entries are compared by their resolved next hops rather than by
+// next hop group and next hop IDs, which devices may allocate themselves. Every network
+// instance with programmed prefix or label entries is reconciled.
+func Reconcile(entries []fluent.GRIBIEntry, a *AFTData, opts ...ReconcileOption) (*Reconciliation, error) {
+	o := &reconcileOptions{}
+	for _, opt := range opts {
+		opt(o)
+	}
+	want, err := expectedAFT(entries)
+	if err != nil {
+		return nil, err
+	}
+	r := &Reconciliation{}
+	for _, name := range slices.Sorted(maps.Keys(want)) {
+		wantNI := want[name]
+		if len(wantNI.entries) == 0 {
+			continue
+		}
+		gotNI, err := a.networkInstanceAFT(name)
+		if err != nil {
+			gotNI = newNetworkInstanceAFT()
+		}
+		got := actualEntries(gotNI)
+		for _, key := range slices.Sorted(maps.Keys(wantNI.entries)) {
+			ref := EntryRef{NetworkInstance: name, Entry: key}
+			gotEntry, ok := got[key]
+			if !ok {
+				r.Missing = append(r.Missing, ref)
+				continue
+			}
+			wantMembers, err := expectedMembers(want, name, wantNI.entries[key])
+			if err != nil {
+				return nil, fmt.Errorf("inconsistent gRIBI entries for %s: %w", ref, err)
+			}
+			gotMembers, ok := actualMembers(a, name, gotEntry)
+			if !ok {
+				r.Unresolved = append(r.Unresolved, ref)
+				continue
+			}
+			r.compare(ref, wantMembers, gotMembers)
+		}
+		for _, key := range slices.Sorted(maps.Keys(got)) {
+			if _, ok := wantNI.entries[key]; ok {
+				continue
+			}
+			if o.ignoreExtra != nil && o.ignoreExtra(name, key) {
+				continue
+			}
+			r.Extra = append(r.Extra, EntryRef{NetworkInstance: name, Entry: key})
+		}
+	}
+	return r, nil
+}
+
+// compare matches the expected next hops of an entry to the actual ones and records the
+// differences.
+func (r *Reconciliation) compare(ref EntryRef, want, got []member) {
+	used := make([]bool, len(got))
+	var wantWeights, gotWeights []uint64
+	matched := 0
+	for _, w := range want {
+		for i, g := range got {
+			if used[i] || !identity(w.nh, g.nh) {
+				continue
+			}
+			used[i] = true
+			matched++
+			wantWeights = append(wantWeights, w.weight)
+			gotWeights = append(gotWeights, g.weight)
+			if !headersMatch(w.nh, g.nh) {
+				r.HeaderMismatches = append(r.HeaderMismatches, HeaderMismatch{Ref: ref, Want: w.nh, Got: g.nh})
+			}
+			break
+		}
+	}
+	if matched != len(want) || matched != len(got) || !slices.Equal(reduceWeights(wantWeights), reduceWeights(gotWeights)) {
+		r.NHGMismatches = append(r.NHGMismatches, NHGMismatch{Ref: ref, Want: describeMembers(want), Got: describeMembers(got)})
+	}
+}
+
+// expectedMembers returns the next hops a programmed entry is expected to resolve to.
+func expectedMembers(want map[string]*expectedNetworkInstance, name string, e expectedEntry) ([]member, error) {
+	if e.nhgNI != "" {
+		name = e.nhgNI
+	}
+	ni, ok := want[name]
+	if !ok {
+		return nil, fmt.Errorf("network instance %s not programmed: %w", name, ErrNotExist)
+	}
+	nhg, ok := ni.nhgs[e.nhg]
+	if !ok {
+		return nil, fmt.Errorf("NHG %d not programmed: %w", e.nhg, ErrNotExist)
+	}
+	var ms []member
+	for _, id := range slices.Sorted(maps.Keys(nhg)) {
+		nh, ok := ni.nhs[id]
+		if !ok {
+			return nil, fmt.Errorf("NH %d not programmed: %w", id, ErrNotExist)
+		}
+		ms = append(ms, member{nh: nh, weight: nhg[id]})
+	}
+	return ms, nil
+}
+
+// actualMembers returns the next hops an AFT entry resolves to, or false if its next hop
+// group or one of its next hops is missing.
+func actualMembers(a *AFTData, name string, e expectedEntry) ([]member, bool) {
+	if e.nhgNI != "" {
+		name = e.nhgNI
+	}
+	ni, err := a.networkInstanceAFT(name)
+	if err != nil {
+		return nil, false
+	}
+	nhg, ok := ni.NextHopGroups[e.nhg]
+	if !ok {
+		return nil, false
+	}
+	var ms []member
+	for _, id := range nhg.NHIDs {
+		nh, ok := ni.NextHops[id]
+		if !ok {
+			return nil, false
+		}
+		ms = append(ms, member{nh: *nh, weight: nhg.NHWeights[id]})
+	}
+	return ms, true
+}
+
+// ReconcileStoppingCondition returns a PeriodicHook which is done once the streamed AFT
+// matches the programmed gRIBI entries. The differences are logged on every run.
+func ReconcileStoppingCondition(t *testing.T, dut *ondatra.DUTDevice, entries []fluent.GRIBIEntry, opts ...ReconcileOption) PeriodicHook {
+	return PeriodicHook{
+		Description: "gRIBI reconciliation stopping condition",
+		PeriodicFunc: func(ss *AFTStreamSession) (bool, error) {
+			a, err := ss.ToAFT(t, dut)
+			if err != nil {
+				return false, err
+			}
+			r, err := Reconcile(entries, a, opts...)
+			if err != nil {
+				return false, err
+			}
+			t.Logf("%s %s", ss.sessionPrefix(), r)
+			return r.OK(), nil
+		},
+	}
+}
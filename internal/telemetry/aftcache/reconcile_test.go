@@ -0,0 +1,105 @@
+// Copyright 2026 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package aftcache
+
+import (
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/openconfig/gribigo/fluent"
+)
+
+// programmedEntries returns gRIBI entries programming two VRF prefixes and a label through
+// NHGs in DEFAULT, one of them over an IP-in-IP encapsulating next hop.
+func programmedEntries() []fluent.GRIBIEntry {
+	return []fluent.GRIBIEntry{
+		fluent.NextHopEntry().WithNetworkInstance("DEFAULT").WithIndex(1).WithIPAddress("192.0.2.1"),
+		fluent.NextHopEntry().WithNetworkInstance("DEFAULT").WithIndex(2).WithIPAddress("192.0.2.5"),
+		fluent.NextHopEntry().WithNetworkInstance("DEFAULT").WithIndex(3).
+			WithEncapsulateHeader(fluent.IPinIP).WithIPinIP("198.18.0.1", "203.0.113.9").WithNextHopNetworkInstance("DEFAULT"),
+		fluent.NextHopGroupEntry().WithNetworkInstance("DEFAULT").WithID(10).AddNextHop(1, 1).AddNextHop(2, 3),
+		fluent.NextHopGroupEntry().WithNetworkInstance("DEFAULT").WithID(11).AddNextHop(3, 1),
+		fluent.IPv4Entry().WithNetworkInstance("VRF-A").WithPrefix("198.51.100.0/24").WithNextHopGroup(10).WithNextHopGroupNetworkInstance("DEFAULT"),
+		fluent.IPv6Entry().WithNetworkInstance("VRF-A").WithPrefix("2001:DB8::/32").WithNextHopGroup(11).WithNextHopGroupNetworkInstance("DEFAULT"),
+		fluent.LabelEntry().WithNetworkInstance("VRF-A").WithLabel(100).WithNextHopGroup(10).WithNextHopGroupNetworkInstance("DEFAULT"),
+	}
+}
+
+// installedAFT returns the AFT a device reports for programmedEntries, with its own NHG
+// and NH IDs and doubled weights.
+func installedAFT() *AFTData {
+	a := newAFT("DEFAULT", []string{"VRF-A"})
+	a.NextHops[101] = &aftNextHop{IP: "192.0.2.1", IntfName: "Ethernet1"}
+	a.NextHops[102] = &aftNextHop{IP: "192.0.2.5", IntfName: "Ethernet2"}
+	a.NextHops[103] = &aftNextHop{NetworkInstance: "DEFAULT", EncapHeader: "IPV4", TunnelSrcIP: "198.18.0.1", TunnelDstIP: "203.0.113.9"}
+	a.NextHopGroups[1001] = &aftNextHopGroup{NHIDs: []uint64{101, 102}, NHWeights: map[uint64]uint64{101: 2, 102: 6}}
+	a.NextHopGroups[1002] = &aftNextHopGroup{NHIDs: []uint64{103}, NHWeights: map[uint64]uint64{103: 1}}
+	a.Prefixes["192.0.2.0/30"] = 1001
+	vrf := a.NetworkInstances["VRF-A"]
+	for p, nhg := range map[string]uint64{"198.51.100.0/24": 1001, "2001:db8::/32": 1002} {
+		vrf.Prefixes[p] = nhg
+		vrf.PrefixNHGNetworkInstances[p] = "DEFAULT"
+	}
+	vrf.LabelEntries[100] = 1001
+	vrf.LabelNHGNetworkInstances[100] = "DEFAULT"
+	return a
+}
+
+func TestReconcile(t *testing.T) {
+	r, err := Reconcile(programmedEntries(), installedAFT())
+	if err != nil {
+		t.Fatalf("Reconcile() failed: %v", err)
+	}
+	if !r.OK() {
+		t.Errorf("Reconcile() of the installed AFT got differences:\n%s", r)
+	}
+
+	a := installedAFT()
+	a.NextHopGroups[1001].NHWeights[102] = 2
+	a.NextHops[103].EncapHeader = ""
+	vrf := a.NetworkInstances["VRF-A"]
+	delete(vrf.LabelEntries, 100)
+	vrf.Prefixes["10.0.0.0/8"] = 1001
+	vrf.Prefixes["10.1.0.0/16"] = 1001
+	r, err = Reconcile(programmedEntries(), a, IgnoreExtra(func(_, entry string) bool { return entry == "10.1.0.0/16" }))
+	if err != nil {
+		t.Fatalf("Reconcile() failed: %v", err)
+	}
+	ref := func(entry string) EntryRef { return EntryRef{NetworkInstance: "VRF-A", Entry: entry} }
+	if diff := cmp.Diff([]EntryRef{ref("label:100")}, r.Missing); diff != "" {
+		t.Errorf("Reconcile() missing diff (-want +got):\n%s", diff)
+	}
+	if diff := cmp.Diff([]EntryRef{ref("10.0.0.0/8")}, r.Extra); diff != "" {
+		t.Errorf("Reconcile() extra diff (-want +got):\n%s", diff)
+	}
+	if got := len(r.NHGMismatches); got != 1 || r.NHGMismatches[0].Ref != ref("198.51.100.0/24") {
+		t.Errorf("Reconcile() got NHG mismatches %v, want one for 198.51.100.0/24", r.NHGMismatches)
+	}
+	if got := len(r.HeaderMismatches); got != 1 || r.HeaderMismatches[0].Ref != ref("2001:db8::/32") {
+		t.Errorf("Reconcile() got header mismatches %v, want one for 2001:db8::/32", r.HeaderMismatches)
+	}
+
+	delete(a.NextHops, 103)
+	if r, err = Reconcile(programmedEntries(), a); err != nil {
+		t.Fatalf("Reconcile() failed: %v", err)
+	}
+	if diff := cmp.Diff([]EntryRef{ref("2001:db8::/32")}, r.Unresolved); diff != "" {
+		t.Errorf("Reconcile() unresolved diff (-want +got):\n%s", diff)
+	}
+
+	if _, err := Reconcile(programmedEntries()[3:], installedAFT()); err == nil {
+		t.Errorf("Reconcile() with entries referencing unprogrammed NHs got no error")
+	}
+}
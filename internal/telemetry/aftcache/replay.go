@@ -0,0 +1,168 @@
+// Copyright 2026 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package aftcache
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"google.golang.org/protobuf/encoding/protodelim"
+	"google.golang.org/protobuf/encoding/prototext"
+
+	gnmipb "github.com/openconfig/gnmi/proto/gnmi"
+)
+
+// NotificationFormat is the encoding of a file of recorded notifications.
+type NotificationFormat int
+
+const (
+	// FormatText is one text proto SubscribeResponse per line, as written by WithDebug.
+	FormatText NotificationFormat = iota
+	// FormatBinaryDelimited is length-delimited binary SubscribeResponse protos.
+	FormatBinaryDelimited
+)
+
+// maxNotificationLine is the maximum length of a single text notification.
+const maxNotificationLine = 64 << 20
+
+// ReadNotifications reads recorded notifications in the given format.
+func ReadNotifications(r io.Reader, format NotificationFormat) ([]*gnmipb.SubscribeResponse, error) {
+	var out []*gnmipb.SubscribeResponse
+	switch format {
+	case FormatText:
+		sc := bufio.NewScanner(r)
+		sc.Buffer(nil, maxNotificationLine)
+		for line := 1; sc.Scan(); line++ {
+			if strings.TrimSpace(sc.Text()) == "" {
+				continue
+			}
+			n := &gnmipb.SubscribeResponse{}
+			if err := prototext.Unmarshal(sc.Bytes(), n); err != nil {
+				return nil, fmt.Errorf("error parsing notification on line %d: %v", line, err)
+			}
+			out = append(out, n)
+		}
+		if err := sc.Err(); err != nil {
+			return nil, err
+		}
+	case FormatBinaryDelimited:
+		br := bufio.NewReader(r)
+		for {
+			n := &gnmipb.SubscribeResponse{}
+			err := protodelim.UnmarshalFrom(br, n)
+			if errors.Is(err, io.EOF) {
+				break
+			}
+			if err != nil {
+				return nil, fmt.Errorf("error parsing notification %d: %v", len(out)+1, err)
+			}
+			out = append(out, n)
+		}
+	default:
+		return nil, fmt.Errorf("unknown notification format %v", format)
+	}
+	return out, nil
+}
+
+// WriteNotifications writes notifications as length-delimited binary protos, which can be
+// read back with ReadNotifications and FormatBinaryDelimited.
+func WriteNotifications(w io.Writer, notifications []*gnmipb.SubscribeResponse) error {
+	for _, n := range notifications {
+		if _, err := protodelim.MarshalTo(w, n); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// LoadNotifications reads a file of recorded notifications. Files with a .txt or .textproto
+// extension, such as the notifications.txt written by WithDebug, are read as FormatText and
+// all other files as FormatBinaryDelimited.
+func LoadNotifications(path string) ([]*gnmipb.SubscribeResponse, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	format := FormatBinaryDelimited
+	switch filepath.Ext(path) {
+	case ".txt", ".textproto":
+		format = FormatText
+	}
+	return ReadNotifications(f, format)
+}
+
+// NewReplaySession constructs an AFTStreamSession that is fed from recorded notifications
+// instead of a DUT. target is the name of the recorded DUT and defaultNetworkInstance the
+// name of its default network instance. Use Replay to process the notifications.
+func NewReplaySession(target, defaultNetworkInstance string, notifications []*gnmipb.SubscribeResponse, opts ...SessionOption) *AFTStreamSession {
+	ss := &AFTStreamSession{
+		notifications:          []*gnmipb.SubscribeResponse{},
+		missingPrefixes:        make(map[string]bool),
+		failingNHPrefixes:      make(map[string]bool),
+		defaultNetworkInstance: defaultNetworkInstance,
+		networkInstances:       []string{defaultNetworkInstance},
+		families:               defaultFamilies,
+		replay:                 notifications,
+	}
+	for _, opt := range opts {
+		opt(ss)
+	}
+	ss.Cache = ss.newCache(target)
+	return ss
+}
+
+// Replay applies the remaining recorded notifications of a replay session to the AFT cache,
+// running preUpdateHooks on each notification like ListenUntilPreUpdateHook. Time is
+// simulated from the notification timestamps: stoppingCondition runs whenever they advance
+// by interval (the live periodic interval if 0), and once more after the last notification.
+// Replay returns true as soon as stoppingCondition is met, leaving the remaining
+// notifications for the next call, and false if the recording ends first.
+func (ss *AFTStreamSession) Replay(preUpdateHooks []NotificationHook, stoppingCondition PeriodicHook, interval time.Duration) (bool, error) {
+	if interval == 0 {
+		interval = periodicInterval
+	}
+	hooks := []PeriodicHook{stoppingCondition}
+	for len(ss.replay) > 0 {
+		n := ss.replay[0]
+		if ts := n.GetUpdate().GetTimestamp(); ts != 0 {
+			now := time.Unix(0, ts)
+			switch {
+			case ss.start.IsZero():
+				ss.start, ss.replayNext = now, now.Add(interval)
+			case !now.Before(ss.replayNext):
+				done, err := runPeriodicHooks(ss, hooks)
+				if err != nil || done {
+					return done, err
+				}
+				for !now.Before(ss.replayNext) {
+					ss.replayNext = ss.replayNext.Add(interval)
+				}
+			}
+		}
+		ss.replay = ss.replay[1:]
+		// Replayed notifications are received at their timestamp.
+		if err := ss.handleNotification(n, time.Unix(0, n.GetUpdate().GetTimestamp()), preUpdateHooks); err != nil {
+			return false, err
+		}
+	}
+	return runPeriodicHooks(ss, hooks)
+}
@@ -0,0 +1,155 @@
+// Copyright 2026 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package aftcache
+
+import (
+	"bytes"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/openconfig/ygot/ygot"
+	"google.golang.org/protobuf/testing/protocmp"
+
+	gnmipb "github.com/openconfig/gnmi/proto/gnmi"
+)
+
+// aftNotification returns an atomic AFT notification at the given prefix path and time.
+func aftNotification(t *testing.T, ts time.Time, prefix string, updates map[string]*gnmipb.TypedValue) *gnmipb.SubscribeResponse {
+	t.Helper()
+	pp, err := ygot.StringToStructuredPath(prefix)
+	if err != nil {
+		t.Fatalf("StringToStructuredPath(%q) failed: %v", prefix, err)
+	}
+	n := &gnmipb.Notification{Timestamp: ts.UnixNano(), Prefix: pp, Atomic: true}
+	for p, v := range updates {
+		up, err := ygot.StringToStructuredPath(p)
+		if err != nil {
+			t.Fatalf("StringToStructuredPath(%q) failed: %v", p, err)
+		}
+		n.Update = append(n.Update, &gnmipb.Update{Path: up, Val: v})
+	}
+	return &gnmipb.SubscribeResponse{Response: &gnmipb.SubscribeResponse_Update{Update: n}}
+}
+
+func uintVal(v uint64) *gnmipb.TypedValue {
+	return &gnmipb.TypedValue{Value: &gnmipb.TypedValue_UintVal{UintVal: v}}
+}
+
+func stringVal(v string) *gnmipb.TypedValue {
+	return &gnmipb.TypedValue{Value: &gnmipb.TypedValue_StringVal{StringVal: v}}
+}
+
+// recordedAFT returns notifications installing two prefixes, where the second prefix only
+// arrives after several periodic intervals.
+func recordedAFT(t *testing.T) []*gnmipb.SubscribeResponse {
+	start := time.Unix(1700000000, 0)
+	const ni = "/network-instances/network-instance[name=DEFAULT]/afts"
+	prefixNotification := func(ts time.Time, p string) *gnmipb.SubscribeResponse {
+		return aftNotification(t, ts, fmt.Sprintf("%s/ipv4-unicast/ipv4-entry[prefix=%s]", ni, p), map[string]*gnmipb.TypedValue{
+			"state/prefix":         stringVal(p),
+			"state/next-hop-group": uintVal(1),
+		})
+	}
+	return []*gnmipb.SubscribeResponse{
+		aftNotification(t, start, ni+"/next-hops/next-hop[index=10]", map[string]*gnmipb.TypedValue{
+			"state/index":                   uintVal(10),
+			"state/ip-address":              stringVal("192.0.2.1"),
+			"interface-ref/state/interface": stringVal("Ethernet1"),
+		}),
+		aftNotification(t, start, ni+"/next-hop-groups/next-hop-group[id=1]", map[string]*gnmipb.TypedValue{
+			"state/id": uintVal(1),
+			"next-hops/next-hop[index=10]/state/index":  uintVal(10),
+			"next-hops/next-hop[index=10]/state/weight": uintVal(1),
+		}),
+		prefixNotification(start.Add(time.Second), "198.51.100.0/24"),
+		{Response: &gnmipb.SubscribeResponse_SyncResponse{SyncResponse: true}},
+		prefixNotification(start.Add(10*time.Minute), "203.0.113.0/24"),
+	}
+}
+
+func TestReadNotifications(t *testing.T) {
+	want := recordedAFT(t)
+	var text bytes.Buffer
+	for _, n := range want {
+		fmt.Fprintln(&text, n.String())
+	}
+	var binary bytes.Buffer
+	if err := WriteNotifications(&binary, want); err != nil {
+		t.Fatalf("WriteNotifications() failed: %v", err)
+	}
+	for _, tc := range []struct {
+		desc   string
+		buf    *bytes.Buffer
+		format NotificationFormat
+	}{
+		{desc: "text", buf: &text, format: FormatText},
+		{desc: "binary", buf: &binary, format: FormatBinaryDelimited},
+	} {
+		t.Run(tc.desc, func(t *testing.T) {
+			got, err := ReadNotifications(tc.buf, tc.format)
+			if err != nil {
+				t.Fatalf("ReadNotifications() failed: %v", err)
+			}
+			if diff := cmp.Diff(want, got, protocmp.Transform()); diff != "" {
+				t.Errorf("ReadNotifications() diff (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestReplay(t *testing.T) {
+	wantPrefixes := map[string]bool{"198.51.100.0/24": true, "203.0.113.0/24": true}
+	wantNHs := map[string]bool{"192.0.2.1": true}
+
+	ss := NewReplaySession("dut", "DEFAULT", recordedAFT(t))
+	stop := InitialSyncStoppingCondition(t, nil, wantPrefixes, wantNHs, nil)
+	done, err := ss.Replay([]NotificationHook{VerifyAtomicFlagHook(t)}, stop, 0)
+	if err != nil {
+		t.Fatalf("Replay() failed: %v", err)
+	}
+	if !done {
+		t.Fatalf("Replay() did not meet the stopping condition")
+	}
+	if len(ss.replay) != 0 {
+		t.Errorf("Replay() left %d notifications, want 0", len(ss.replay))
+	}
+	a, err := ss.ToAFT(t, nil)
+	if err != nil {
+		t.Fatalf("ToAFT() failed: %v", err)
+	}
+	if got, want := len(a.Prefixes), 2; got != want {
+		t.Errorf("ToAFT() got %d prefixes, want %d", got, want)
+	}
+
+	// The last prefix arrives after the periodic interval, so a stopping condition needing
+	// only the first prefix is met before replaying it.
+	ss = NewReplaySession("dut", "DEFAULT", recordedAFT(t))
+	done, err = ss.Replay(nil, AssertNextHopCount(t, nil, map[string]bool{"198.51.100.0/24": true}, 1), 0)
+	if err != nil || !done {
+		t.Fatalf("Replay() got %v, %v, want true, nil", done, err)
+	}
+	if got, want := len(ss.replay), 1; got != want {
+		t.Errorf("Replay() left %d notifications, want %d", got, want)
+	}
+
+	ss = NewReplaySession("dut", "DEFAULT", recordedAFT(t))
+	missing := map[string]bool{"192.0.2.0/24": true}
+	done, err = ss.Replay(nil, AssertNextHopCount(t, nil, missing, 1), time.Minute)
+	if err != nil || done {
+		t.Errorf("Replay() with a missing prefix got %v, %v, want false, nil", done, err)
+	}
+}
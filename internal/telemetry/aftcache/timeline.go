@@ -0,0 +1,417 @@
+// Copyright 2026 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package aftcache
+
+import (
+	"cmp"
+	"encoding/csv"
+	"fmt"
+	"maps"
+	"math"
+	"os"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/openconfig/featureprofiles/internal/telemetry/schema"
+
+	gnmipb "github.com/openconfig/gnmi/proto/gnmi"
+)
+
+const (
+	// timelineFile is the name of the file where prefix timeline events are written.
+	timelineFile = "timeline.csv"
+	// convergenceFile is the name of the file where per prefix convergence times are written.
+	convergenceFile = "convergence.csv"
+)
+
+// PrefixEventType is the kind of change of a prefix recorded in a Timeline.
+type PrefixEventType int
+
+const (
+	// PrefixAdded is the first appearance of a prefix, or its reappearance after deletion.
+	PrefixAdded PrefixEventType = iota
+	// PrefixNHGChanged is a change of the next hop group of a prefix.
+	PrefixNHGChanged
+	// PrefixDeleted is the deletion of a prefix.
+	PrefixDeleted
+	// PrefixNextHopsChanged is a change of the next hops or weights of the next hop group of
+	// a prefix.
+	PrefixNextHopsChanged
+)
+
+func (e PrefixEventType) String() string {
+	switch e {
+	case PrefixAdded:
+		return "ADDED"
+	case PrefixNHGChanged:
+		return "NHG_CHANGED"
+	case PrefixDeleted:
+		return "DELETED"
+	case PrefixNextHopsChanged:
+		return "NEXT_HOPS_CHANGED"
+	}
+	return fmt.Sprintf("PrefixEventType(%d)", int(e))
+}
+
+// PrefixEvent is a change of a prefix.
+type PrefixEvent struct {
+	Type PrefixEventType
+	// NHG is the next hop group of the prefix after the event, or 0 for deletions.
+	NHG uint64
+	// NextHops maps the next hop indices of the NHG after the event to their weights.
+	NextHops map[uint64]uint64
+	// Timestamp is the notification timestamp set by the DUT.
+	Timestamp time.Time
+	// Received is the time the notification was received. For replayed notifications it is
+	// the notification timestamp.
+	Received time.Time
+}
+
+type timelineKey struct {
+	networkInstance, prefix string
+}
+
+type nhgKey struct {
+	networkInstance string
+	id              uint64
+}
+
+// Timeline records the events of every prefix streamed in an AFTStreamSession. It follows the
+// next hop groups of the prefixes, so that a change of the next hops of a group is an event of
+// each prefix using it.
+type Timeline struct {
+	mu          sync.Mutex
+	events      map[timelineKey][]PrefixEvent
+	prefixNHG   map[timelineKey]nhgKey
+	nhgPrefixes map[nhgKey]map[timelineKey]bool
+	nhgNextHops map[nhgKey]map[uint64]uint64 // Weights by next hop index.
+}
+
+func newTimeline() *Timeline {
+	return &Timeline{
+		events:      map[timelineKey][]PrefixEvent{},
+		prefixNHG:   map[timelineKey]nhgKey{},
+		nhgPrefixes: map[nhgKey]map[timelineKey]bool{},
+		nhgNextHops: map[nhgKey]map[uint64]uint64{},
+	}
+}
+
+// WithTimeline enables recording of a Timeline of prefix events.
+// Warning: This increases memory usage with the number of prefix changes.
+func (ss *AFTStreamSession) WithTimeline() *AFTStreamSession {
+	ss.timeline = newTimeline()
+	return ss
+}
+
+// Timeline returns the timeline of prefix events, or nil if WithTimeline was not used.
+func (ss *AFTStreamSession) Timeline() *Timeline {
+	return ss.timeline
+}
+
+// prefixEntryKey returns the network instance and prefix of a path inside an IPv4 or IPv6
+// entry, such as /network-instances/network-instance[name=X]/afts/ipv4-unicast/ipv4-entry[prefix=Y].
+func prefixEntryKey(elems []*gnmipb.PathElem) (timelineKey, bool) {
+	if len(elems) < 5 || elems[2].GetName() != "afts" {
+		return timelineKey{}, false
+	}
+	switch elems[4].GetName() {
+	case "ipv4-entry", "ipv6-entry":
+	default:
+		return timelineKey{}, false
+	}
+	prefix, ok := elems[4].GetKey()["prefix"]
+	if !ok {
+		return timelineKey{}, false
+	}
+	return timelineKey{networkInstance: elems[1].GetKey()["name"], prefix: prefix}, true
+}
+
+// nhgEntryKey returns the network instance and ID of a path inside a next hop group, such as
+// /network-instances/network-instance[name=X]/afts/next-hop-groups/next-hop-group[id=Y].
+func nhgEntryKey(elems []*gnmipb.PathElem) (nhgKey, bool) {
+	if len(elems) < 5 || elems[2].GetName() != "afts" || elems[4].GetName() != "next-hop-group" {
+		return nhgKey{}, false
+	}
+	id, err := strconv.ParseUint(elems[4].GetKey()["id"], 10, 64)
+	if err != nil {
+		return nhgKey{}, false
+	}
+	return nhgKey{networkInstance: elems[1].GetKey()["name"], id: id}, true
+}
+
+// nextHopIndex returns the index of the next hop of a path inside a next hop of a next hop
+// group, such as next-hop-group[id=Y]/next-hops/next-hop[index=Z].
+func nextHopIndex(elems []*gnmipb.PathElem) (uint64, bool) {
+	if len(elems) < 7 || elems[5].GetName() != "next-hops" || elems[6].GetName() != "next-hop" {
+		return 0, false
+	}
+	index, err := strconv.ParseUint(elems[6].GetKey()["index"], 10, 64)
+	return index, err == nil
+}
+
+// record adds the prefix events contained in a notification: deletions of prefixes, and
+// changes of the NHG of prefixes or of the next hops of their NHG.
+func (tl *Timeline) record(n *gnmipb.Notification, recv time.Time) {
+	if n == nil {
+		return
+	}
+	ts := time.Unix(0, n.GetTimestamp())
+	tl.mu.Lock()
+	defer tl.mu.Unlock()
+	changed := map[timelineKey]bool{}
+	for _, del := range n.GetDelete() {
+		elems := append(append([]*gnmipb.PathElem{}, n.GetPrefix().GetElem()...), del.GetElem()...)
+		// Only the deletion of a whole entry deletes the prefix.
+		if k, ok := prefixEntryKey(elems); ok && len(elems) == 5 {
+			tl.bind(k, nhgKey{})
+			delete(changed, k)
+			tl.add(k, PrefixEvent{Type: PrefixDeleted, Timestamp: ts, Received: recv})
+			continue
+		}
+		nk, ok := nhgEntryKey(elems)
+		if !ok {
+			continue
+		}
+		if index, ok := nextHopIndex(elems); ok && len(elems) == 7 {
+			delete(tl.nhgNextHops[nk], index)
+		} else if len(elems) == 5 {
+			delete(tl.nhgNextHops, nk)
+		} else {
+			continue
+		}
+		maps.Copy(changed, tl.nhgPrefixes[nk])
+	}
+	for _, pt := range schema.NotificationToPoints(n) {
+		elems := pt.Path.GetElem()
+		if k, ok := prefixEntryKey(elems); ok {
+			if len(elems) != 7 || elems[5].GetName() != "state" {
+				continue
+			}
+			nk := tl.prefixNHG[k]
+			switch elems[6].GetName() {
+			case "next-hop-group":
+				nk.id = pt.Val.GetUintVal()
+			case "next-hop-group-network-instance":
+				nk.networkInstance = pt.Val.GetStringVal()
+			default:
+				continue
+			}
+			tl.bind(k, nk)
+			changed[k] = true
+			continue
+		}
+		nk, ok := nhgEntryKey(elems)
+		if !ok {
+			continue
+		}
+		index, ok := nextHopIndex(elems)
+		if !ok || len(elems) != 9 || elems[7].GetName() != "state" {
+			continue
+		}
+		nhs := tl.nhgNextHops[nk]
+		if nhs == nil {
+			nhs = map[uint64]uint64{}
+			tl.nhgNextHops[nk] = nhs
+		}
+		switch elems[8].GetName() {
+		case "weight":
+			nhs[index] = pt.Val.GetUintVal()
+		case "index":
+			if _, ok := nhs[index]; !ok {
+				nhs[index] = 0
+			}
+		default:
+			continue
+		}
+		maps.Copy(changed, tl.nhgPrefixes[nk])
+	}
+	for k := range changed {
+		nk := tl.prefixNHG[k]
+		if nk.id == 0 {
+			continue
+		}
+		tl.add(k, PrefixEvent{Type: PrefixNHGChanged, NHG: nk.id, NextHops: maps.Clone(tl.nhgNextHops[nk]), Timestamp: ts, Received: recv})
+	}
+}
+
+// bind records nk as the NHG of prefix k, or removes the NHG of k if nk is zero. The NHG is in
+// the network instance of the prefix unless nk names another. The caller must hold mu.
+func (tl *Timeline) bind(k timelineKey, nk nhgKey) {
+	if old, ok := tl.prefixNHG[k]; ok {
+		delete(tl.nhgPrefixes[old], k)
+		if len(tl.nhgPrefixes[old]) == 0 {
+			delete(tl.nhgPrefixes, old)
+		}
+		delete(tl.prefixNHG, k)
+	}
+	if nk == (nhgKey{}) {
+		return
+	}
+	if nk.networkInstance == "" {
+		nk.networkInstance = k.networkInstance
+	}
+	tl.prefixNHG[k] = nk
+	if tl.nhgPrefixes[nk] == nil {
+		tl.nhgPrefixes[nk] = map[timelineKey]bool{}
+	}
+	tl.nhgPrefixes[nk][k] = true
+}
+
+// add appends an event, turning the first NHG of a prefix into PrefixAdded, a change of the
+// next hops only into PrefixNextHopsChanged, and dropping updates which change neither. The
+// caller must hold mu.
+func (tl *Timeline) add(k timelineKey, e PrefixEvent) {
+	events := tl.events[k]
+	var last *PrefixEvent
+	if len(events) > 0 {
+		last = &events[len(events)-1]
+	}
+	switch e.Type {
+	case PrefixDeleted:
+		if last == nil || last.Type == PrefixDeleted {
+			return
+		}
+	case PrefixNHGChanged:
+		switch {
+		case last == nil || last.Type == PrefixDeleted:
+			e.Type = PrefixAdded
+		case last.NHG != e.NHG:
+		case maps.Equal(last.NextHops, e.NextHops):
+			return
+		default:
+			e.Type = PrefixNextHopsChanged
+		}
+	}
+	tl.events[k] = append(events, e)
+}
+
+// Events returns the events of a prefix of the network instance in the order received.
+func (tl *Timeline) Events(networkInstance, prefix string) []PrefixEvent {
+	tl.mu.Lock()
+	defer tl.mu.Unlock()
+	return slices.Clone(tl.events[timelineKey{networkInstance, prefix}])
+}
+
+// Convergence contains how long prefixes took to converge after a reference time.
+type Convergence struct {
+	// Since is the reference time, e.g. the time of a link flap or gRIBI flush.
+	Since time.Time
+	// Durations contains, per prefix, the time from Since to the last event of the prefix.
+	Durations map[string]time.Duration
+	// Unchanged contains the prefixes without events after Since.
+	Unchanged []string
+
+	sorted []time.Duration
+}
+
+// Convergence computes for each of the prefixes of the network instance the time from since
+// to the last event received for it after since. Received times are used, so since should
+// be taken from the local clock.
+func (tl *Timeline) Convergence(networkInstance string, prefixes map[string]bool, since time.Time) *Convergence {
+	tl.mu.Lock()
+	defer tl.mu.Unlock()
+	c := &Convergence{Since: since, Durations: map[string]time.Duration{}}
+	for p := range prefixes {
+		events := tl.events[timelineKey{networkInstance, p}]
+		if len(events) == 0 || events[len(events)-1].Received.Before(since) {
+			c.Unchanged = append(c.Unchanged, p)
+			continue
+		}
+		d := events[len(events)-1].Received.Sub(since)
+		c.Durations[p] = d
+		c.sorted = append(c.sorted, d)
+	}
+	slices.Sort(c.sorted)
+	slices.Sort(c.Unchanged)
+	return c
+}
+
+// Percentile returns the p-th percentile (0 <= p <= 100) of the convergence durations using
+// the nearest-rank method, or 0 if no prefix converged.
+func (c *Convergence) Percentile(p float64) time.Duration {
+	if len(c.sorted) == 0 {
+		return 0
+	}
+	rank := int(math.Ceil(p / 100 * float64(len(c.sorted))))
+	rank = min(max(rank, 1), len(c.sorted))
+	return c.sorted[rank-1]
+}
+
+// String returns a one line summary of the convergence.
+func (c *Convergence) String() string {
+	return fmt.Sprintf("%d prefixes converged, %d unchanged: p50=%v p99=%v max=%v",
+		len(c.Durations), len(c.Unchanged), c.Percentile(50), c.Percentile(99), c.Percentile(100))
+}
+
+// WriteCSV writes the convergence duration of every prefix to a CSV file in the test log
+// directory and returns its path.
+func (c *Convergence) WriteCSV(t *testing.T, target string) (string, error) {
+	path := getTestLogPath(t, fmt.Sprintf("%s_%d_%s", target, c.Since.UnixNano(), convergenceFile))
+	var rows [][]string
+	for _, p := range slices.Sorted(maps.Keys(c.Durations)) {
+		rows = append(rows, []string{p, strconv.FormatInt(c.Durations[p].Microseconds(), 10)})
+	}
+	for _, p := range c.Unchanged {
+		rows = append(rows, []string{p, ""})
+	}
+	return path, writeCSV(path, []string{"prefix", "convergence_us"}, rows)
+}
+
+// WriteCSV writes all recorded events to a CSV file in the test log directory and returns
+// its path. Times are in nanoseconds since the epoch, and next hops are written as
+// space-separated index:weight pairs.
+func (tl *Timeline) WriteCSV(t *testing.T, target string, start time.Time) (string, error) {
+	path := getTestLogPath(t, fmt.Sprintf("%s_%d_%s", target, start.UnixNano(), timelineFile))
+	tl.mu.Lock()
+	defer tl.mu.Unlock()
+	keys := slices.SortedFunc(maps.Keys(tl.events), func(a, b timelineKey) int {
+		return cmp.Or(cmp.Compare(a.networkInstance, b.networkInstance), cmp.Compare(a.prefix, b.prefix))
+	})
+	var rows [][]string
+	for _, k := range keys {
+		for _, e := range tl.events[k] {
+			var nhs []string
+			for _, index := range slices.Sorted(maps.Keys(e.NextHops)) {
+				nhs = append(nhs, fmt.Sprintf("%d:%d", index, e.NextHops[index]))
+			}
+			rows = append(rows, []string{
+				k.networkInstance, k.prefix, e.Type.String(), strconv.FormatUint(e.NHG, 10), strings.Join(nhs, " "),
+				strconv.FormatInt(e.Timestamp.UnixNano(), 10), strconv.FormatInt(e.Received.UnixNano(), 10),
+			})
+		}
+	}
+	return path, writeCSV(path, []string{"network_instance", "prefix", "event", "nhg", "next_hops", "timestamp", "received"}, rows)
+}
+
+func writeCSV(path string, header []string, rows [][]string) (err error) {
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0644)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if cerr := f.Close(); err == nil {
+			err = cerr
+		}
+	}()
+	w := csv.NewWriter(f)
+	if err := w.Write(header); err != nil {
+		return err
+	}
+	return w.WriteAll(rows)
+}
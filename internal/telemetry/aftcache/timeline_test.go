@@ -0,0 +1,169 @@
+// Copyright 2026 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package aftcache
+
+import (
+	"fmt"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/openconfig/ygot/ygot"
+
+	gnmipb "github.com/openconfig/gnmi/proto/gnmi"
+)
+
+func TestTimeline(t *testing.T) {
+	start := time.Unix(1700000000, 0)
+	const afts = "/network-instances/network-instance[name=DEFAULT]/afts/ipv4-unicast"
+	prefixNotification := func(ts time.Time, p string, nhg uint64) *gnmipb.SubscribeResponse {
+		return aftNotification(t, ts, fmt.Sprintf("%s/ipv4-entry[prefix=%s]", afts, p), map[string]*gnmipb.TypedValue{
+			"state/prefix":         stringVal(p),
+			"state/next-hop-group": uintVal(nhg),
+		})
+	}
+	deletePath, err := ygot.StringToStructuredPath("ipv4-entry[prefix=198.51.100.0/24]")
+	if err != nil {
+		t.Fatalf("StringToStructuredPath() failed: %v", err)
+	}
+	prefixPath, err := ygot.StringToStructuredPath(afts)
+	if err != nil {
+		t.Fatalf("StringToStructuredPath() failed: %v", err)
+	}
+	deletion := &gnmipb.SubscribeResponse{Response: &gnmipb.SubscribeResponse_Update{Update: &gnmipb.Notification{
+		Timestamp: start.Add(4 * time.Second).UnixNano(),
+		Prefix:    prefixPath,
+		Delete:    []*gnmipb.Path{deletePath},
+	}}}
+
+	ss := NewReplaySession("dut", "DEFAULT", []*gnmipb.SubscribeResponse{
+		prefixNotification(start, "198.51.100.0/24", 1),
+		prefixNotification(start, "203.0.113.0/24", 1),
+		// A refresh with the same NHG is not an event.
+		prefixNotification(start.Add(time.Second), "198.51.100.0/24", 1),
+		prefixNotification(start.Add(2*time.Second), "198.51.100.0/24", 2),
+		prefixNotification(start.Add(3*time.Second), "203.0.113.0/24", 2),
+		deletion,
+	}).WithTimeline()
+	if _, err := ss.Replay(nil, PeriodicHook{Description: "never", PeriodicFunc: func(*AFTStreamSession) (bool, error) { return false, nil }}, 0); err != nil {
+		t.Fatalf("Replay() failed: %v", err)
+	}
+
+	at := func(d time.Duration) time.Time { return start.Add(d) }
+	want := []PrefixEvent{
+		{Type: PrefixAdded, NHG: 1, Timestamp: at(0), Received: at(0)},
+		{Type: PrefixNHGChanged, NHG: 2, Timestamp: at(2 * time.Second), Received: at(2 * time.Second)},
+		{Type: PrefixDeleted, Timestamp: at(4 * time.Second), Received: at(4 * time.Second)},
+	}
+	if diff := cmp.Diff(want, ss.Timeline().Events("DEFAULT", "198.51.100.0/24")); diff != "" {
+		t.Errorf("Events() diff (-want +got):\n%s", diff)
+	}
+
+	prefixes := map[string]bool{"198.51.100.0/24": true, "203.0.113.0/24": true, "192.0.2.0/24": true}
+	c := ss.Timeline().Convergence("DEFAULT", prefixes, at(time.Second))
+	wantDurations := map[string]time.Duration{"198.51.100.0/24": 3 * time.Second, "203.0.113.0/24": 2 * time.Second}
+	if diff := cmp.Diff(wantDurations, c.Durations); diff != "" {
+		t.Errorf("Convergence() durations diff (-want +got):\n%s", diff)
+	}
+	if diff := cmp.Diff([]string{"192.0.2.0/24"}, c.Unchanged); diff != "" {
+		t.Errorf("Convergence() unchanged diff (-want +got):\n%s", diff)
+	}
+	if got, want := c.Percentile(50), 2*time.Second; got != want {
+		t.Errorf("Percentile(50) got %v, want %v", got, want)
+	}
+	if got, want := c.Percentile(99), 3*time.Second; got != want {
+		t.Errorf("Percentile(99) got %v, want %v", got, want)
+	}
+
+	path, err := c.WriteCSV(t, "dut")
+	if err != nil {
+		t.Fatalf("WriteCSV() failed: %v", err)
+	}
+	b, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("ReadFile(%q) failed: %v", path, err)
+	}
+	wantCSV := "prefix,convergence_us\n198.51.100.0/24,3000000\n203.0.113.0/24,2000000\n192.0.2.0/24,\n"
+	if got := string(b); got != wantCSV {
+		t.Errorf("WriteCSV() got %q, want %q", got, wantCSV)
+	}
+	if path, err = ss.Timeline().WriteCSV(t, "dut", start); err != nil {
+		t.Fatalf("Timeline.WriteCSV() failed: %v", err)
+	}
+	if b, err = os.ReadFile(path); err != nil {
+		t.Fatalf("ReadFile(%q) failed: %v", path, err)
+	}
+	if got, want := strings.Count(string(b), "\n"), 6; got != want {
+		t.Errorf("Timeline.WriteCSV() got %d lines, want %d", got, want)
+	}
+}
+
+func TestTimelineNextHops(t *testing.T) {
+	start := time.Unix(1700000000, 0)
+	at := func(d time.Duration) time.Time { return start.Add(d) }
+	const afts = "/network-instances/network-instance[name=DEFAULT]/afts"
+	nhgNotification := func(ts time.Time, id uint64, weights map[uint64]uint64) *gnmipb.SubscribeResponse {
+		updates := map[string]*gnmipb.TypedValue{"state/id": uintVal(id)}
+		for index, w := range weights {
+			updates[fmt.Sprintf("next-hops/next-hop[index=%d]/state/index", index)] = uintVal(index)
+			updates[fmt.Sprintf("next-hops/next-hop[index=%d]/state/weight", index)] = uintVal(w)
+		}
+		return aftNotification(t, ts, fmt.Sprintf("%s/next-hop-groups/next-hop-group[id=%d]", afts, id), updates)
+	}
+	nhgPath, err := ygot.StringToStructuredPath(afts + "/next-hop-groups/next-hop-group[id=1]")
+	if err != nil {
+		t.Fatalf("StringToStructuredPath() failed: %v", err)
+	}
+	nhPath, err := ygot.StringToStructuredPath("next-hops/next-hop[index=10]")
+	if err != nil {
+		t.Fatalf("StringToStructuredPath() failed: %v", err)
+	}
+	nhDeletion := &gnmipb.SubscribeResponse{Response: &gnmipb.SubscribeResponse_Update{Update: &gnmipb.Notification{
+		Timestamp: at(2 * time.Second).UnixNano(),
+		Prefix:    nhgPath,
+		Delete:    []*gnmipb.Path{nhPath},
+	}}}
+
+	ss := NewReplaySession("dut", "DEFAULT", []*gnmipb.SubscribeResponse{
+		nhgNotification(start, 1, map[uint64]uint64{10: 1, 11: 1}),
+		aftNotification(t, start, afts+"/ipv4-unicast/ipv4-entry[prefix=198.51.100.0/24]", map[string]*gnmipb.TypedValue{
+			"state/prefix":         stringVal("198.51.100.0/24"),
+			"state/next-hop-group": uintVal(1),
+		}),
+		nhgNotification(at(time.Second), 1, map[uint64]uint64{11: 3}),
+		nhDeletion,
+		// Neither a refresh of the NHG nor a change of another NHG is an event.
+		nhgNotification(at(3*time.Second), 1, map[uint64]uint64{11: 3}),
+		nhgNotification(at(4*time.Second), 2, map[uint64]uint64{12: 1}),
+	}).WithTimeline()
+	if _, err := ss.Replay(nil, PeriodicHook{Description: "never", PeriodicFunc: func(*AFTStreamSession) (bool, error) { return false, nil }}, 0); err != nil {
+		t.Fatalf("Replay() failed: %v", err)
+	}
+
+	want := []PrefixEvent{
+		{Type: PrefixAdded, NHG: 1, NextHops: map[uint64]uint64{10: 1, 11: 1}, Timestamp: at(0), Received: at(0)},
+		{Type: PrefixNextHopsChanged, NHG: 1, NextHops: map[uint64]uint64{10: 1, 11: 3}, Timestamp: at(time.Second), Received: at(time.Second)},
+		{Type: PrefixNextHopsChanged, NHG: 1, NextHops: map[uint64]uint64{11: 3}, Timestamp: at(2 * time.Second), Received: at(2 * time.Second)},
+	}
+	if diff := cmp.Diff(want, ss.Timeline().Events("DEFAULT", "198.51.100.0/24")); diff != "" {
+		t.Errorf("Events() diff (-want +got):\n%s", diff)
+	}
+	c := ss.Timeline().Convergence("DEFAULT", map[string]bool{"198.51.100.0/24": true}, at(time.Second))
+	if got, want := c.Durations["198.51.100.0/24"], time.Second; got != want {
+		t.Errorf("Convergence() after the weight change got %v, want %v", got, want)
+	}
+}
@@ -0,0 +1,64 @@
+// Copyright 2026 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package vendorcli
+
+// ACLCountersParams are the parameters of ACLCounters.
+type ACLCountersParams struct {
+	// IPv6 is whether the ACL is an IPv6 ACL rather than an IPv4 ACL.
+	IPv6 bool
+	Name string
+}
+
+// ACLCounters enables the per-entry counters of an ACL.
+var ACLCounters = Feature[ACLCountersParams]{Name: "acl_counters"}
+
+// ConfigFileParams are the parameters of BackUpConfig and RestoreConfig.
+type ConfigFileParams struct {
+	// FileName is the name of the file on the local flash storage of the DUT.
+	FileName string
+}
+
+// BackUpConfig saves the running configuration into a file. The command is run over SSH.
+var BackUpConfig = Feature[ConfigFileParams]{Name: "backup_config"}
+
+// RestoreConfig replaces the running configuration with a file. The command is run over SSH.
+var RestoreConfig = Feature[ConfigFileParams]{Name: "restore_config"}
+
+// LACPFallbackParams are the parameters of LACPFallback.
+type LACPFallbackParams struct {
+	Interface   string
+	TimeoutSecs uint16
+}
+
+// LACPFallback enables LACP fallback to individual member ports on a LAG.
+var LACPFallback = Feature[LACPFallbackParams]{Name: "lacp_fallback"}
+
+// DecapVRFMode enables next-hop decapsulation VRF mode of VRF selection policies.
+var DecapVRFMode = Feature[struct{}]{Name: "decap_vrf_mode"}
+
+// BGPTTLSecurityParams are the parameters of BGPTTLSecurity.
+type BGPTTLSecurityParams struct {
+	AS uint32
+	// VRF is the network instance of the neighbor, empty for the default network instance.
+	VRF      string
+	Neighbor string
+	// MaxHops is the number of hops to the neighbor: its packets are only accepted with a TTL of
+	// at least 256 - MaxHops.
+	MaxHops uint8
+}
+
+// BGPTTLSecurity enables the Generalized TTL Security Mechanism (RFC 5082) on a BGP neighbor,
+// which OpenConfig does not model.
+var BGPTTLSecurity = Feature[BGPTTLSecurityParams]{Name: "bgp_ttl_security"}
@@ -0,0 +1,261 @@
+// Copyright 2026 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package vendorcli is a registry of the vendor CLI snippets used as fallbacks where a DUT
+// does not support the OpenConfig configuration of a feature.
+//
+// Snippets are text/template templates, read from the YAML files of the snippets directory,
+// one file per feature named after it:
+//
+//	feature: lacp_fallback
+//	description: Enables LACP fallback to individual ports on a LAG.
+//	snippets:
+//	  - vendor: ARISTA
+//	    software_version_regex: "^4\\."
+//	    template: |
+//	      interface {{.Interface}}
+//	         port-channel lacp fallback individual
+//
+// The snippet used for a DUT is the first of the feature whose vendor is the vendor of the DUT
+// and whose software version regex, if any, matches the software version of the DUT. Snippets
+// are rendered with the typed parameters of their Feature, e.g.
+//
+//	vendorcli.LACPFallback.Config(t, dut, vendorcli.LACPFallbackParams{Interface: "Port-Channel1", TimeoutSecs: 60})
+package vendorcli
+
+import (
+	"bytes"
+	"embed"
+	"errors"
+	"fmt"
+	"io/fs"
+	"path"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+	"testing"
+	"text/template"
+
+	"github.com/openconfig/featureprofiles/internal/helpers"
+	"github.com/openconfig/ondatra"
+	"gopkg.in/yaml.v3"
+
+	opb "github.com/openconfig/ondatra/proto"
+)
+
+//go:embed snippets/*.yaml
+var embedded embed.FS
+
+// ErrUnsupported is returned when a feature has no snippet for the vendor and software version
+// of a DUT.
+var ErrUnsupported = errors.New("no CLI snippet for the vendor and software version")
+
+// Snippet is the CLI template of a feature for a vendor.
+type Snippet struct {
+	Feature              string
+	Description          string
+	Vendor               ondatra.Vendor
+	SoftwareVersionRegex string
+	// Source is the file the snippet is read from.
+	Source string
+
+	re   *regexp.Regexp
+	tmpl *template.Template
+}
+
+// matches returns whether the snippet applies to the vendor and software version.
+func (s *Snippet) matches(vendor ondatra.Vendor, version string) bool {
+	return s.Vendor == vendor && (s.re == nil || s.re.MatchString(version))
+}
+
+// Render renders the snippet with params.
+func (s *Snippet) Render(params any) (string, error) {
+	var b bytes.Buffer
+	if err := s.tmpl.Execute(&b, params); err != nil {
+		return "", fmt.Errorf("%s: cannot render %s snippet for %s: %w", s.Source, s.Feature, s.Vendor, err)
+	}
+	return b.String(), nil
+}
+
+// snippetFile is the YAML form of the snippets of a feature.
+type snippetFile struct {
+	Feature     string `yaml:"feature"`
+	Description string `yaml:"description"`
+	Snippets    []struct {
+		Vendor               string `yaml:"vendor"`
+		SoftwareVersionRegex string `yaml:"software_version_regex"`
+		Template             string `yaml:"template"`
+	} `yaml:"snippets"`
+}
+
+// Registry holds the CLI snippets of features.
+type Registry struct {
+	snippets map[string][]*Snippet
+}
+
+// Load reads the snippets of the YAML files at the root of fsys.
+func Load(fsys fs.FS) (*Registry, error) {
+	files, err := fs.Glob(fsys, "*.yaml")
+	if err != nil {
+		return nil, err
+	}
+	r := &Registry{snippets: map[string][]*Snippet{}}
+	for _, name := range files {
+		b, err := fs.ReadFile(fsys, name)
+		if err != nil {
+			return nil, err
+		}
+		if err := r.add(name, b); err != nil {
+			return nil, err
+		}
+	}
+	return r, nil
+}
+
+func (r *Registry) add(name string, b []byte) error {
+	dec := yaml.NewDecoder(bytes.NewReader(b))
+	dec.KnownFields(true)
+	f := &snippetFile{}
+	if err := dec.Decode(f); err != nil {
+		return fmt.Errorf("%s: %w", name, err)
+	}
+	if f.Feature != featureName(name) {
+		return fmt.Errorf("%s: feature is %q, want the file name %q", name, f.Feature, featureName(name))
+	}
+	if _, ok := r.snippets[f.Feature]; ok {
+		return fmt.Errorf("%s: feature %s is defined twice", name, f.Feature)
+	}
+	if len(f.Snippets) == 0 {
+		return fmt.Errorf("%s: feature %s has no snippet", name, f.Feature)
+	}
+	for i, fsn := range f.Snippets {
+		v, ok := opb.Device_Vendor_value[fsn.Vendor]
+		if !ok || v == 0 {
+			return fmt.Errorf("%s: snippet %d has unknown vendor %q", name, i, fsn.Vendor)
+		}
+		s := &Snippet{
+			Feature:              f.Feature,
+			Description:          f.Description,
+			Vendor:               ondatra.Vendor(v),
+			SoftwareVersionRegex: fsn.SoftwareVersionRegex,
+			Source:               name,
+		}
+		if fsn.SoftwareVersionRegex != "" {
+			re, err := regexp.Compile(fsn.SoftwareVersionRegex)
+			if err != nil {
+				return fmt.Errorf("%s: snippet %d: %w", name, i, err)
+			}
+			s.re = re
+		}
+		tmpl, err := template.New(fmt.Sprintf("%s/%s", f.Feature, fsn.Vendor)).Option("missingkey=error").Parse(fsn.Template)
+		if err != nil {
+			return fmt.Errorf("%s: snippet %d: %w", name, i, err)
+		}
+		s.tmpl = tmpl
+		r.snippets[f.Feature] = append(r.snippets[f.Feature], s)
+	}
+	return nil
+}
+
+// Lookup returns the snippet of the feature for the vendor and software version.
+func (r *Registry) Lookup(feature string, vendor ondatra.Vendor, version string) (*Snippet, error) {
+	for _, s := range r.snippets[feature] {
+		if s.matches(vendor, version) {
+			return s, nil
+		}
+	}
+	if _, ok := r.snippets[feature]; !ok {
+		return nil, fmt.Errorf("unknown CLI feature %q", feature)
+	}
+	return nil, fmt.Errorf("feature %s on %s %s: %w", feature, vendor, version, ErrUnsupported)
+}
+
+// Snippets returns the snippets of all the features, sorted by feature then vendor, in the
+// order they are looked up in.
+func (r *Registry) Snippets() []*Snippet {
+	var out []*Snippet
+	for _, ss := range r.snippets {
+		out = append(out, ss...)
+	}
+	sort.SliceStable(out, func(i, j int) bool {
+		if out[i].Feature != out[j].Feature {
+			return out[i].Feature < out[j].Feature
+		}
+		return out[i].Vendor.String() < out[j].Vendor.String()
+	})
+	return out
+}
+
+var (
+	defaultOnce     sync.Once
+	defaultRegistry *Registry
+)
+
+// Default returns the registry of the snippets embedded in this package.
+func Default() *Registry {
+	defaultOnce.Do(func() {
+		sub, err := fs.Sub(embedded, "snippets")
+		if err == nil {
+			defaultRegistry, err = Load(sub)
+		}
+		if err != nil {
+			panic(fmt.Sprintf("vendorcli: invalid embedded snippets: %v", err))
+		}
+	})
+	return defaultRegistry
+}
+
+// Feature is a feature configured by CLI snippets rendered with parameters of type P.
+type Feature[P any] struct {
+	// Name is the name of the feature in the snippet files.
+	Name string
+}
+
+// Supported returns whether the feature has a snippet for the DUT.
+func (f Feature[P]) Supported(dut *ondatra.DUTDevice) bool {
+	_, err := Default().Lookup(f.Name, dut.Vendor(), dut.Version())
+	return err == nil
+}
+
+// Render returns the CLI of the feature for the DUT. The error wraps ErrUnsupported if the
+// feature has no snippet for the DUT.
+func (f Feature[P]) Render(dut *ondatra.DUTDevice, params P) (string, error) {
+	s, err := Default().Lookup(f.Name, dut.Vendor(), dut.Version())
+	if err != nil {
+		return "", err
+	}
+	return s.Render(params)
+}
+
+// MustRender is Render failing t on error.
+func (f Feature[P]) MustRender(t testing.TB, dut *ondatra.DUTDevice, params P) string {
+	t.Helper()
+	cli, err := f.Render(dut, params)
+	if err != nil {
+		t.Fatalf("Cannot render CLI of %s: %v", f.Name, err)
+	}
+	return cli
+}
+
+// Config renders the CLI of the feature for the DUT and sets it by gNMI.
+func (f Feature[P]) Config(t testing.TB, dut *ondatra.DUTDevice, params P) {
+	t.Helper()
+	helpers.GnmiCLIConfig(t, dut, f.MustRender(t, dut, params))
+}
+
+// featureName returns the feature of a snippet file, its name without extension.
+func featureName(file string) string {
+	return strings.TrimSuffix(path.Base(file), path.Ext(file))
+}
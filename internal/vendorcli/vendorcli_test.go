@@ -0,0 +1,132 @@
+// Copyright 2026 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package vendorcli
+
+import (
+	"errors"
+	"strings"
+	"testing"
+	"testing/fstest"
+
+	"github.com/openconfig/ondatra"
+)
+
+func TestDefault(t *testing.T) {
+	tests := []struct {
+		feature string
+		params  any
+		want    string
+	}{
+		{ACLCounters.Name, ACLCountersParams{IPv6: true, Name: "acl1"}, "ipv6 access-list acl1\n\tcounters per-entry\n\t!\n"},
+		{ACLCounters.Name, ACLCountersParams{Name: "acl1"}, "ip access-list acl1\n\tcounters per-entry\n\t!\n"},
+		{BackUpConfig.Name, ConfigFileParams{FileName: "backup.cfg"}, "copy running-config flash:backup.cfg"},
+		{RestoreConfig.Name, ConfigFileParams{FileName: "backup.cfg"}, "configure replace flash:backup.cfg"},
+		{LACPFallback.Name, LACPFallbackParams{Interface: "Port-Channel1", TimeoutSecs: 30}, "interface Port-Channel1\n   port-channel lacp fallback individual\n   port-channel lacp fallback timeout 30\n"},
+		{DecapVRFMode.Name, struct{}{}, "vrf selection policy\nnext-hop decapsulation vrf\n!\n"},
+		{BGPTTLSecurity.Name, BGPTTLSecurityParams{AS: 65501, Neighbor: "203.0.113.11", MaxHops: 2}, "router bgp 65501\n   neighbor 203.0.113.11 ttl maximum-hops 2\n"},
+		{BGPTTLSecurity.Name, BGPTTLSecurityParams{AS: 65501, VRF: "VRF-A", Neighbor: "203.0.113.11", MaxHops: 2}, "router bgp 65501\n   vrf VRF-A\n   neighbor 203.0.113.11 ttl maximum-hops 2\n"},
+	}
+	for _, tc := range tests {
+		s, err := Default().Lookup(tc.feature, ondatra.ARISTA, "4.33.1F")
+		if err != nil {
+			t.Fatalf("Lookup(%q) got error: %v", tc.feature, err)
+		}
+		got, err := s.Render(tc.params)
+		if err != nil {
+			t.Fatalf("Render(%+v) of %s got error: %v", tc.params, tc.feature, err)
+		}
+		if got != tc.want {
+			t.Errorf("Render(%+v) of %s got %q, want %q", tc.params, tc.feature, got, tc.want)
+		}
+	}
+}
+
+func TestLookup(t *testing.T) {
+	r, err := Load(fstest.MapFS{"mtu.yaml": {Data: []byte(`
+feature: mtu
+snippets:
+  - vendor: CISCO
+    software_version_regex: "^7\\."
+    template: "mtu {{.}} legacy"
+  - vendor: CISCO
+    template: "mtu {{.}}"
+  - vendor: NOKIA
+    template: "set mtu {{.}}"
+`)}})
+	if err != nil {
+		t.Fatalf("Load() got error: %v", err)
+	}
+	tests := []struct {
+		desc    string
+		vendor  ondatra.Vendor
+		version string
+		want    string
+		wantErr error
+	}{
+		{desc: "version regex", vendor: ondatra.CISCO, version: "7.11.1", want: "mtu 9000 legacy"},
+		{desc: "any version", vendor: ondatra.CISCO, version: "24.4.1", want: "mtu 9000"},
+		{desc: "other vendor", vendor: ondatra.NOKIA, want: "set mtu 9000"},
+		{desc: "unsupported vendor", vendor: ondatra.ARISTA, wantErr: ErrUnsupported},
+	}
+	for _, tc := range tests {
+		t.Run(tc.desc, func(t *testing.T) {
+			s, err := r.Lookup("mtu", tc.vendor, tc.version)
+			if !errors.Is(err, tc.wantErr) {
+				t.Fatalf("Lookup() got error %v, want %v", err, tc.wantErr)
+			}
+			if err != nil {
+				return
+			}
+			if got, err := s.Render(9000); err != nil || got != tc.want {
+				t.Errorf("Render() got %q, %v, want %q", got, err, tc.want)
+			}
+		})
+	}
+	if _, err := r.Lookup("unknown", ondatra.CISCO, ""); err == nil || errors.Is(err, ErrUnsupported) {
+		t.Errorf("Lookup() of an unknown feature got error %v, want unknown feature error", err)
+	}
+}
+
+func TestLoadErrors(t *testing.T) {
+	tests := []struct {
+		desc, file, data, wantErr string
+	}{
+		{"feature name", "mtu.yaml", "feature: lacp\nsnippets: [{vendor: CISCO, template: x}]", "want the file name"},
+		{"no snippet", "mtu.yaml", "feature: mtu", "has no snippet"},
+		{"unknown field", "mtu.yaml", "feature: mtu\nsnippets: [{vendor: CISCO, cli: x}]", "not found"},
+		{"unknown vendor", "mtu.yaml", "feature: mtu\nsnippets: [{vendor: ACME, template: x}]", "unknown vendor"},
+		{"bad regex", "mtu.yaml", "feature: mtu\nsnippets: [{vendor: CISCO, software_version_regex: '(', template: x}]", "missing closing"},
+		{"bad template", "mtu.yaml", "feature: mtu\nsnippets: [{vendor: CISCO, template: '{{.'}]", "snippet 0"},
+	}
+	for _, tc := range tests {
+		t.Run(tc.desc, func(t *testing.T) {
+			_, err := Load(fstest.MapFS{tc.file: {Data: []byte(tc.data)}})
+			if err == nil || !strings.Contains(err.Error(), tc.wantErr) {
+				t.Errorf("Load() got error %v, want error containing %q", err, tc.wantErr)
+			}
+		})
+	}
+}
+
+func TestSnippets(t *testing.T) {
+	var got []string
+	for _, s := range Default().Snippets() {
+		got = append(got, s.Feature+"/"+s.Vendor.String())
+	}
+	want := "acl_counters/ARISTA backup_config/ARISTA bgp_ttl_security/ARISTA decap_vrf_mode/ARISTA lacp_fallback/ARISTA restore_config/ARISTA"
+	if strings.Join(got, " ") != want {
+		t.Errorf("Snippets() got %v, want %s", got, want)
+	}
+}
@@ -0,0 +1,81 @@
+// Copyright 2026 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Binary list_vendorcli lists the features configured by vendor CLI snippets of the
+// internal/vendorcli registry, per vendor, to show which features still rely on CLI. It can be
+// run by running:
+//
+//	go run tools/list_vendorcli/list_vendorcli.go [--vendor=ARISTA] [--dir=internal/vendorcli/snippets]
+package main
+
+import (
+	"cmp"
+	"flag"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+	"text/tabwriter"
+
+	log "github.com/golang/glog"
+	"github.com/openconfig/featureprofiles/internal/vendorcli"
+)
+
+var (
+	dir    = flag.String("dir", "", "directory to read the snippets from instead of the snippets built into vendorcli")
+	vendor = flag.String("vendor", "", "vendor to list the features of, e.g. ARISTA; all vendors if empty")
+)
+
+func main() {
+	flag.Parse()
+	r := vendorcli.Default()
+	if *dir != "" {
+		var err error
+		if r, err = vendorcli.Load(os.DirFS(*dir)); err != nil {
+			log.Exitf("cannot load snippets, err: %v", err)
+		}
+	}
+
+	snippets := r.Snippets()
+	sort.SliceStable(snippets, func(i, j int) bool {
+		return snippets[i].Vendor.String() < snippets[j].Vendor.String()
+	})
+	w := tabwriter.NewWriter(os.Stdout, 0, 8, 2, ' ', 0)
+	fmt.Fprintln(w, "VENDOR\tFEATURE\tSOFTWARE VERSION\tSOURCE\tDESCRIPTION")
+	features := map[string]map[string]bool{}
+	for _, s := range snippets {
+		v := s.Vendor.String()
+		if *vendor != "" && !strings.EqualFold(*vendor, v) {
+			continue
+		}
+		if features[v] == nil {
+			features[v] = map[string]bool{}
+		}
+		features[v][s.Feature] = true
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\n", v, s.Feature, cmp.Or(s.SoftwareVersionRegex, "any"), s.Source, s.Description)
+	}
+	if err := w.Flush(); err != nil {
+		log.Exitf("cannot write output, err: %v", err)
+	}
+
+	var vendors []string
+	for v := range features {
+		vendors = append(vendors, v)
+	}
+	sort.Strings(vendors)
+	fmt.Println()
+	for _, v := range vendors {
+		fmt.Printf("%s: %d features rely on CLI\n", v, len(features[v]))
+	}
+}